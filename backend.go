@@ -0,0 +1,130 @@
+package menoh
+
+import (
+	"fmt"
+	"sync"
+)
+
+// TypeBackend is a type of backend, like MKL-DNN.
+type TypeBackend int
+
+const (
+	typeUnknownBackend TypeBackend = iota
+	// TypeMKLDNN is a TypeBackend of MKL-DNN
+	TypeMKLDNN
+	// TypeMKLDNNWithGenericFallback is a TypeBackend of MKL-DNN, operators
+	// not supported by MKL-DNN are run by the generic backend.
+	TypeMKLDNNWithGenericFallback
+	// TypeGeneric is a TypeBackend of the generic reference implementation.
+	TypeGeneric
+	// TypeReference is a TypeBackend of the pure-Go interpreter in
+	// external/reference package. NewRunner runs the model by the interpreter
+	// without Menoh library, and with menoh_reference build tag the binding
+	// itself is replaced by the interpreter.
+	TypeReference
+)
+
+// customBackendOffset is the first value of TypeBackend made by CustomBackend.
+const customBackendOffset TypeBackend = 1 << 16
+
+var builtinBackends = []TypeBackend{TypeMKLDNN, TypeMKLDNNWithGenericFallback, TypeGeneric, TypeReference}
+
+var (
+	customBackendsMu sync.RWMutex
+	customBackends   []string
+)
+
+func (t TypeBackend) String() string {
+	switch t {
+	case TypeMKLDNN:
+		return "mkldnn"
+	case TypeMKLDNNWithGenericFallback:
+		return "mkldnn_with_generic_fallback"
+	case TypeGeneric:
+		return "generic"
+	case TypeReference:
+		return "reference"
+	}
+	customBackendsMu.RLock()
+	defer customBackendsMu.RUnlock()
+	if i := int(t - customBackendOffset); i >= 0 && i < len(customBackends) {
+		return customBackends[i]
+	}
+	return "unknown"
+}
+
+// ParseBackend returns TypeBackend named s, like "mkldnn". Names registered
+// by CustomBackend are also accepted.
+func ParseBackend(s string) (TypeBackend, error) {
+	for _, b := range builtinBackends {
+		if b.String() == s {
+			return b, nil
+		}
+	}
+	customBackendsMu.RLock()
+	defer customBackendsMu.RUnlock()
+	for i, name := range customBackends {
+		if name == s {
+			return customBackendOffset + TypeBackend(i), nil
+		}
+	}
+	return typeUnknownBackend, fmt.Errorf("backend '%s' is not supported", s)
+}
+
+// CustomBackend returns TypeBackend passing the name to Menoh as is, for
+// backends of custom Menoh builds. The name is accepted by ParseBackend
+// after that. Known names return the same TypeBackend as the constants.
+func CustomBackend(name string) TypeBackend {
+	if b, err := ParseBackend(name); err == nil {
+		return b
+	}
+	customBackendsMu.Lock()
+	defer customBackendsMu.Unlock()
+	for i, n := range customBackends {
+		if n == name {
+			return customBackendOffset + TypeBackend(i)
+		}
+	}
+	customBackends = append(customBackends, name)
+	return customBackendOffset + TypeBackend(len(customBackends)-1)
+}
+
+// knownBackends returns built-in backends and backends registered by
+// CustomBackend.
+func knownBackends() []TypeBackend {
+	customBackendsMu.RLock()
+	defer customBackendsMu.RUnlock()
+	backends := append([]TypeBackend{}, builtinBackends...)
+	for i := range customBackends {
+		backends = append(backends, customBackendOffset+TypeBackend(i))
+	}
+	return backends
+}
+
+// ProbeBackend builds a tiny model, single Relu operator, with the backend
+// and returns the error when the linked Menoh library does not support it.
+// Backends registered by RegisterBackend, like TypeReference, do not require
+// the library and are always supported.
+func ProbeBackend(backend TypeBackend) error {
+	if isRegisteredBackend(backend) {
+		return nil
+	}
+	return probeBackend(backend)
+}
+
+func probeBackend(backend TypeBackend) error {
+	return probeOperator(backend, operatorProbes[0])
+}
+
+// SupportedBackends probes built-in backends and backends registered by
+// CustomBackend, and returns ones the linked Menoh library or registered
+// Backend supports.
+func SupportedBackends() []TypeBackend {
+	supported := []TypeBackend{}
+	for _, b := range knownBackends() {
+		if ProbeBackend(b) == nil {
+			supported = append(supported, b)
+		}
+	}
+	return supported
+}
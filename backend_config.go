@@ -0,0 +1,126 @@
+package menoh
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// BackendConfig is a typed configuration of a backend. Encode it by
+// EncodeBackendConfig or Config.SetBackendConfig to pass to Menoh.
+type BackendConfig interface {
+	// Validate returns an error when the configuration would be rejected
+	// by the backend.
+	Validate() error
+}
+
+// EncodeBackendConfig validates the configuration and returns JSON string
+// to be set to Config.BackendConfig.
+func EncodeBackendConfig(c BackendConfig) (string, error) {
+	if err := c.Validate(); err != nil {
+		return "", err
+	}
+	if raw, ok := c.(RawBackendConfig); ok {
+		return string(raw), nil
+	}
+	b, err := json.Marshal(c)
+	if err != nil {
+		return "", fmt.Errorf("cannot encode backend config, %v", err)
+	}
+	return string(b), nil
+}
+
+// SetBackendConfig validates and sets the configuration as BackendConfig.
+func (c *Config) SetBackendConfig(bc BackendConfig) error {
+	s, err := EncodeBackendConfig(bc)
+	if err != nil {
+		return err
+	}
+	c.BackendConfig = s
+	return nil
+}
+
+// validateBackendConfig checks the configuration string is JSON, to report
+// the error before passing it to Menoh.
+func validateBackendConfig(s string) error {
+	if err := RawBackendConfig(s).Validate(); err != nil {
+		return fmt.Errorf("invalid backend config, %v", err)
+	}
+	return nil
+}
+
+// RawBackendConfig is a backend configuration passed to Menoh as is, for
+// backends or options not covered by typed configurations.
+type RawBackendConfig string
+
+// Validate checks the configuration is empty or a JSON object.
+func (c RawBackendConfig) Validate() error {
+	if c == "" {
+		return nil
+	}
+	var obj map[string]interface{}
+	if err := json.Unmarshal([]byte(c), &obj); err != nil {
+		return fmt.Errorf("backend config should be a JSON object, %v", err)
+	}
+	return nil
+}
+
+// MKLDNNConfig is a configuration of MKL-DNN backend. There is no thread
+// count, because the backend of Menoh reads only cpu_id and ignores other
+// keys like num_threads. MKL-DNN runs on OpenMP threads, set the number by
+// OMP_NUM_THREADS environment variable before starting the process.
+type MKLDNNConfig struct {
+	CPUID int `json:"cpu_id"` // index of CPU engine
+}
+
+// Validate checks CPU id is not negative.
+func (c MKLDNNConfig) Validate() error {
+	if c.CPUID < 0 {
+		return fmt.Errorf("cpu_id should not be negative, but %d", c.CPUID)
+	}
+	return nil
+}
+
+// CombinatedConfig is a configuration of the combinated backend, which runs
+// each operator by the first backend supporting it.
+type CombinatedConfig struct {
+	Backends  []CombinatedBackend `json:"backends"`             // in order of priority
+	LogOutput string              `json:"log_output,omitempty"` // "stdout", "file" or empty not to log
+}
+
+// CombinatedBackend is a backend used in the combinated backend.
+type CombinatedBackend struct {
+	Type string `json:"type"` // backend name, like "mkldnn" or "generic"
+
+	// Ops limits operators run by the backend, others fall back to the
+	// following backends. Empty means all supported operators.
+	Ops []string `json:"ops,omitempty"`
+}
+
+// Validate checks backends are listed without duplication.
+func (c CombinatedConfig) Validate() error {
+	if len(c.Backends) == 0 {
+		return errors.New("backends should not be empty")
+	}
+	types := map[string]bool{}
+	for i, b := range c.Backends {
+		if b.Type == "" {
+			return fmt.Errorf("backends[%d].type should not be empty", i)
+		}
+		if types[b.Type] {
+			return fmt.Errorf("backends[%d].type '%s' is duplicated", i, b.Type)
+		}
+		types[b.Type] = true
+		for j, op := range b.Ops {
+			if op == "" {
+				return fmt.Errorf("backends[%d].ops[%d] should not be empty", i, j)
+			}
+		}
+	}
+	switch c.LogOutput {
+	case "", "stdout", "file":
+	default:
+		return fmt.Errorf("log_output should be stdout or file, but '%s'", c.LogOutput)
+	}
+	return nil
+}
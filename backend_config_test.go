@@ -0,0 +1,90 @@
+package menoh
+
+import (
+	"testing"
+)
+
+func TestEncodeBackendConfig(t *testing.T) {
+	testSet := []struct {
+		name     string
+		config   BackendConfig
+		expected string
+	}{
+		{"MKL-DNN", MKLDNNConfig{CPUID: 1}, `{"cpu_id":1}`},
+		{"combinated", CombinatedConfig{
+			Backends: []CombinatedBackend{
+				{Type: "mkldnn", Ops: []string{"Conv", "Gemm"}},
+				{Type: "generic"},
+			},
+			LogOutput: "stdout",
+		}, `{"backends":[{"type":"mkldnn","ops":["Conv","Gemm"]},{"type":"generic"}],"log_output":"stdout"}`},
+		{"raw", RawBackendConfig(`{"cpu_id": 0}`), `{"cpu_id": 0}`},
+		{"empty raw", RawBackendConfig(""), ""},
+	}
+	for _, ts := range testSet {
+		t.Run(ts.name, func(t *testing.T) {
+			actual, err := EncodeBackendConfig(ts.config)
+			if err != nil {
+				t.Fatalf("config should be encoded, %v", err)
+			}
+			if actual != ts.expected {
+				t.Errorf("encoded config should be %v, but %v", ts.expected, actual)
+			}
+			conf := Config{}
+			if err := conf.SetBackendConfig(ts.config); err != nil {
+				t.Fatalf("config should be set, %v", err)
+			}
+			if conf.BackendConfig != ts.expected {
+				t.Errorf("set config should be %v, but %v", ts.expected, conf.BackendConfig)
+			}
+		})
+	}
+}
+
+func TestEncodeBackendConfigFail(t *testing.T) {
+	testSet := []struct {
+		name   string
+		config BackendConfig
+	}{
+		{"negative CPU id", MKLDNNConfig{CPUID: -1}},
+		{"no backend", CombinatedConfig{}},
+		{"empty type", CombinatedConfig{Backends: []CombinatedBackend{{}}}},
+		{"duplicated type", CombinatedConfig{
+			Backends: []CombinatedBackend{{Type: "mkldnn"}, {Type: "mkldnn"}}}},
+		{"empty op", CombinatedConfig{
+			Backends: []CombinatedBackend{{Type: "mkldnn", Ops: []string{""}}}}},
+		{"unknown log output", CombinatedConfig{
+			Backends: []CombinatedBackend{{Type: "mkldnn"}}, LogOutput: "stderr"}},
+		{"malformed raw", RawBackendConfig(`{"cpu_id": 0`)},
+		{"not object raw", RawBackendConfig(`[0]`)},
+	}
+	for _, ts := range testSet {
+		t.Run(ts.name, func(t *testing.T) {
+			if _, err := EncodeBackendConfig(ts.config); err == nil {
+				t.Error("an error should be occurred")
+			}
+			conf := Config{BackendConfig: "{}"}
+			if err := conf.SetBackendConfig(ts.config); err == nil {
+				t.Error("an error should be occurred")
+			}
+			if conf.BackendConfig != "{}" {
+				t.Errorf("config should not be changed on error, but %v", conf.BackendConfig)
+			}
+		})
+	}
+}
+
+func TestNewRunnerWithInvalidBackendConfig(t *testing.T) {
+	conf := Config{
+		ONNXModelPath: "not_existed.onnx",
+		Backend:       TypeMKLDNN,
+		BackendConfig: `{"cpu_id": 0`,
+	}
+	_, err := NewRunner(conf)
+	if err == nil {
+		t.Fatal("an error should be occurred with malformed backend config")
+	}
+	if _, err := NewRunnerWithModelData(&ModelData{}, conf); err == nil {
+		t.Error("an error should be occurred with malformed backend config")
+	}
+}
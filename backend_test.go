@@ -0,0 +1,72 @@
+package menoh
+
+import (
+	"testing"
+)
+
+func TestParseBackend(t *testing.T) {
+	testSet := []struct {
+		name     string
+		expected TypeBackend
+	}{
+		{"mkldnn", TypeMKLDNN},
+		{"mkldnn_with_generic_fallback", TypeMKLDNNWithGenericFallback},
+		{"generic", TypeGeneric},
+		{"reference", TypeReference},
+	}
+	for _, ts := range testSet {
+		actual, err := ParseBackend(ts.name)
+		if err != nil {
+			t.Errorf("'%s' should be parsed, %v", ts.name, err)
+		}
+		if actual != ts.expected {
+			t.Errorf("'%s' should be parsed to %v, but %v", ts.name, ts.expected, actual)
+		}
+		if s := actual.String(); s != ts.name {
+			t.Errorf("backend should be named '%s', but '%s'", ts.name, s)
+		}
+	}
+	if _, err := ParseBackend("not_registered"); err == nil {
+		t.Error("an error should be occurred with unknown backend")
+	}
+	if _, err := ParseBackend(""); err == nil {
+		t.Error("an error should be occurred with empty name")
+	}
+}
+
+func TestCustomBackend(t *testing.T) {
+	custom := CustomBackend("custom_test_backend")
+	if s := custom.String(); s != "custom_test_backend" {
+		t.Errorf("custom backend should be named 'custom_test_backend', but '%s'", s)
+	}
+	if again := CustomBackend("custom_test_backend"); again != custom {
+		t.Errorf("same name should return same backend %v, but %v", custom, again)
+	}
+	if parsed, err := ParseBackend("custom_test_backend"); err != nil || parsed != custom {
+		t.Errorf("custom backend should be parsed, but %v, %v", parsed, err)
+	}
+	if b := CustomBackend("mkldnn"); b != TypeMKLDNN {
+		t.Errorf("known name should return TypeMKLDNN, but %v", b)
+	}
+	if s := TypeBackend(customBackendOffset + 1000).String(); s != "unknown" {
+		t.Errorf("not registered backend should be unknown, but '%s'", s)
+	}
+}
+
+func TestProbeBackend(t *testing.T) {
+	if err := ProbeBackend(TypeMKLDNN); err != nil {
+		t.Errorf("MKL-DNN should be supported, %v", err)
+	}
+	if err := ProbeBackend(CustomBackend("probe_test_dummy")); err == nil {
+		t.Error("an error should be occurred with not existed backend")
+	}
+	found := false
+	for _, b := range SupportedBackends() {
+		if b == TypeMKLDNN {
+			found = true
+		}
+	}
+	if !found {
+		t.Error("MKL-DNN should be listed in supported backends")
+	}
+}
@@ -0,0 +1,195 @@
+package menoh
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Batcher runs inputs of any batch size on an Inferencer built with a fixed
+// batch size, the first dimension of configured inputs. Inputs are split
+// into batches of the size, the last batch is padded with zeros, and outputs
+// of the batches are concatenated without padded rows. All inputs and
+// outputs must have the batch dimension first.
+type Batcher struct {
+	inferencer Inferencer
+	inputs     []InputConfig
+	batchSize  int
+
+	// inputs and outputs of the last Run
+	lastInputs  map[string]Tensor
+	lastOutputs map[string]Tensor
+}
+
+// NewBatcher returns Batcher running on the inferencer built with the
+// configuration. The batcher takes the inferencer and stops it on stopping.
+func NewBatcher(inferencer Inferencer, conf Config) (*Batcher, error) {
+	if len(conf.Inputs) == 0 {
+		return nil, errors.New("no input is configured")
+	}
+	batchSize := int32(0)
+	for _, c := range conf.Inputs {
+		if len(c.Dims) == 0 || c.Dims[0] <= 0 {
+			return nil, fmt.Errorf("input %s does not have batch dimension, %v", c.Name, c.Dims)
+		}
+		if batchSize == 0 {
+			batchSize = c.Dims[0]
+		}
+		if c.Dims[0] != batchSize {
+			return nil, fmt.Errorf("batch size of input %s is %d, but %d of others", c.Name, c.Dims[0], batchSize)
+		}
+	}
+	return &Batcher{
+		inferencer:  inferencer,
+		inputs:      conf.Inputs,
+		batchSize:   int(batchSize),
+		lastInputs:  map[string]Tensor{},
+		lastOutputs: map[string]Tensor{},
+	}, nil
+}
+
+// BatchSize returns the batch size of the inferencer.
+func (b *Batcher) BatchSize() int {
+	return b.batchSize
+}
+
+// batchOf returns the batch size of the inputs, checking the inputs have the
+// configured shapes except the batch dimension.
+func (b *Batcher) batchOf(inputs map[string]Tensor) (int, error) {
+	for name := range inputs {
+		if !hasInput(b.inputs, name) {
+			return 0, fmt.Errorf("input %s is not configured", name)
+		}
+	}
+	n := -1
+	for _, c := range b.inputs {
+		t, ok := inputs[c.Name]
+		if !ok {
+			return 0, fmt.Errorf("input %s is required", c.Name)
+		}
+		dims := t.Shape()
+		if len(dims) != len(c.Dims) || dims[0] <= 0 || !sameDims(dims[1:], c.Dims[1:]) {
+			return 0, fmt.Errorf("input %s should be shaped like %v except batch size, but %v", c.Name, c.Dims, dims)
+		}
+		if n >= 0 && int(dims[0]) != n {
+			return 0, fmt.Errorf("batch size of input %s is %d, but %d of others", c.Name, dims[0], n)
+		}
+		n = int(dims[0])
+	}
+	return n, nil
+}
+
+func hasInput(inputs []InputConfig, name string) bool {
+	for _, c := range inputs {
+		if c.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+func sameDims(a, b []int32) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// Run runs the inputs by batches, all inputs are required and must have the
+// same batch size.
+func (b *Batcher) Run(inputs map[string]Tensor) error {
+	n, err := b.batchOf(inputs)
+	if err != nil {
+		return err
+	}
+	arrays := map[string][]float32{}
+	for name, t := range inputs {
+		a, err := t.FloatArray()
+		if err != nil {
+			return err
+		}
+		arrays[name] = a
+	}
+
+	outputs := map[string]*FloatTensor{}
+	for start := 0; start < n; start += b.batchSize {
+		count := b.batchSize
+		if n-start < count {
+			count = n - start
+		}
+		batch := map[string]Tensor{}
+		for _, c := range b.inputs {
+			a := arrays[c.Name]
+			row := len(a) / n
+			t := zeroTensor(c.Dims)
+			copy(t.Array, a[start*row:(start+count)*row])
+			batch[c.Name] = t
+		}
+		if err := b.inferencer.Run(batch); err != nil {
+			return err
+		}
+		for name, t := range b.inferencer.Outputs() {
+			dims := t.Shape()
+			if len(dims) == 0 || int(dims[0]) != b.batchSize {
+				return fmt.Errorf("output %s does not have batch size %d, %v", name, b.batchSize, dims)
+			}
+			a, err := t.FloatArray()
+			if err != nil {
+				return err
+			}
+			out, ok := outputs[name]
+			if !ok {
+				out = &FloatTensor{Dims: append([]int32{int32(n)}, dims[1:]...)}
+				outputs[name] = out
+			}
+			row := len(a) / b.batchSize
+			out.Array = append(out.Array, a[:count*row]...)
+		}
+	}
+
+	b.lastInputs = inputs
+	b.lastOutputs = map[string]Tensor{}
+	for name, t := range outputs {
+		b.lastOutputs[name] = t
+	}
+	return nil
+}
+
+// RunWithTensor inputs the tensor with the name, and runs.
+func (b *Batcher) RunWithTensor(name string, t Tensor) error {
+	return b.Run(map[string]Tensor{
+		name: t,
+	})
+}
+
+// GetInput returns the input of the last Run.
+func (b *Batcher) GetInput(name string) (Tensor, error) {
+	t, ok := b.lastInputs[name]
+	if !ok {
+		return nil, fmt.Errorf("%s is not attached", name)
+	}
+	return t, nil
+}
+
+// GetOutput returns the output of the last Run, concatenated over batches.
+func (b *Batcher) GetOutput(name string) (Tensor, error) {
+	t, ok := b.lastOutputs[name]
+	if !ok {
+		return nil, fmt.Errorf("%s is not found", name)
+	}
+	return t, nil
+}
+
+// Outputs returns all outputs of the last Run.
+func (b *Batcher) Outputs() map[string]Tensor {
+	return b.lastOutputs
+}
+
+// Stop the inferencer.
+func (b *Batcher) Stop() {
+	b.inferencer.Stop()
+}
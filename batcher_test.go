@@ -0,0 +1,138 @@
+package menoh
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+// doubler is Inferencer doubling input "x" to output "y" of the same shape,
+// failing with inputs of other shapes like a built model.
+type doubler struct {
+	dims    []int32
+	runs    int
+	stopped bool
+	x, y    *FloatTensor
+}
+
+func newDoubler(dims []int32) *doubler {
+	return &doubler{dims: dims, x: zeroTensor(dims), y: zeroTensor(dims)}
+}
+
+func (d *doubler) Run(inputs map[string]Tensor) error {
+	if d.stopped {
+		return errors.New("stopped")
+	}
+	x, ok := inputs["x"]
+	if !ok {
+		return errors.New("x is required")
+	}
+	if !sameDims(x.Shape(), d.dims) {
+		return fmt.Errorf("x should be %v, but %v", d.dims, x.Shape())
+	}
+	a, _ := x.FloatArray()
+	copy(d.x.Array, a)
+	for i, v := range a {
+		d.y.Array[i] = 2 * v
+	}
+	d.runs++
+	return nil
+}
+
+func (d *doubler) RunWithTensor(name string, t Tensor) error {
+	return d.Run(map[string]Tensor{name: t})
+}
+
+func (d *doubler) GetInput(name string) (Tensor, error) {
+	if name != "x" {
+		return nil, errors.New("not attached")
+	}
+	return d.x, nil
+}
+
+func (d *doubler) GetOutput(name string) (Tensor, error) {
+	if name != "y" {
+		return nil, errors.New("not found")
+	}
+	return d.y, nil
+}
+
+func (d *doubler) Outputs() map[string]Tensor {
+	return map[string]Tensor{"y": d.y}
+}
+
+func (d *doubler) Stop() {
+	d.stopped = true
+}
+
+func doublerConfig(dims ...int32) Config {
+	return Config{
+		Inputs:  []InputConfig{{Name: "x", Dtype: TypeFloat, Dims: dims}},
+		Outputs: []OutputConfig{{Name: "y", Dtype: TypeFloat}},
+	}
+}
+
+func TestBatcher(t *testing.T) {
+	inner := newDoubler([]int32{2, 3})
+	b, err := NewBatcher(inner, doublerConfig(2, 3))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b.BatchSize() != 2 {
+		t.Errorf("batch size should be 2, but %d", b.BatchSize())
+	}
+
+	x := &FloatTensor{Dims: []int32{5, 3}, Array: make([]float32, 15)}
+	for i := range x.Array {
+		x.Array[i] = float32(i)
+	}
+	if err := b.RunWithTensor("x", x); err != nil {
+		t.Fatalf("inputs should be run by batches, %v", err)
+	}
+	if inner.runs != 3 {
+		t.Errorf("5 rows should be run by 3 batches, but %d", inner.runs)
+	}
+	y, err := b.GetOutput("y")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := &FloatTensor{Dims: []int32{5, 3}, Array: make([]float32, 15)}
+	for i := range expected.Array {
+		expected.Array[i] = float32(2 * i)
+	}
+	if !reflect.DeepEqual(y, Tensor(expected)) {
+		t.Errorf("outputs should be concatenated without padding, but %v", y)
+	}
+	if in, err := b.GetInput("x"); err != nil || in != Tensor(x) {
+		t.Errorf("input of the last run should be returned, but %v, %v", in, err)
+	}
+	if last, _ := inner.GetInput("x"); !reflect.DeepEqual(last.(*FloatTensor).Array[3:], []float32{0, 0, 0}) {
+		t.Errorf("the last batch should be padded by zeros, but %v", last)
+	}
+
+	t.Run("invalid inputs", func(t *testing.T) {
+		for _, inputs := range []map[string]Tensor{
+			{},
+			{"x": &FloatTensor{Dims: []int32{5, 2}, Array: make([]float32, 10)}},
+			{"x": &FloatTensor{Dims: []int32{0, 3}}},
+			{"x": x, "z": x},
+		} {
+			if err := b.Run(inputs); err == nil {
+				t.Errorf("an error should be occurred with %v", inputs)
+			}
+		}
+	})
+
+	b.Stop()
+	if !inner.stopped {
+		t.Error("inferencer should be stopped")
+	}
+
+	if _, err := NewBatcher(inner, doublerConfig()); err == nil {
+		t.Error("an error should be occurred without batch dimension")
+	}
+	if _, err := NewBatcher(inner, Config{}); err == nil {
+		t.Error("an error should be occurred without input")
+	}
+}
@@ -0,0 +1,242 @@
+/*
+Package bench measures performance of Menoh runner, build time of each phase,
+latency percentiles of running, overhead to copy inputs, and throughput with
+multiple runners executed concurrently.
+*/
+package bench
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/pfnet-research/go-menoh"
+)
+
+// Options is setup information of benchmark.
+type Options struct {
+	Config      menoh.Config
+	Inputs      map[string]menoh.Tensor // if not set, zero-filled tensors are used
+	Warmup      int                     // number of runs before measurement, default is 10, negative disables warmup
+	Iterations  int                     // number of measured runs per runner, default is 100
+	Concurrency int                     // number of runners for throughput, default is 1
+}
+
+func (o Options) withDefaults() Options {
+	if o.Warmup < 0 {
+		o.Warmup = 0
+	} else if o.Warmup == 0 {
+		o.Warmup = 10
+	}
+	if o.Iterations <= 0 {
+		o.Iterations = 100
+	}
+	if o.Concurrency <= 0 {
+		o.Concurrency = 1
+	}
+	return o
+}
+
+// Stats is summary of measured durations.
+type Stats struct {
+	Min  time.Duration `json:"min"`
+	Mean time.Duration `json:"mean"`
+	P50  time.Duration `json:"p50"`
+	P90  time.Duration `json:"p90"`
+	P99  time.Duration `json:"p99"`
+	Max  time.Duration `json:"max"`
+}
+
+// NewStats returns summary of the durations.
+func NewStats(durations []time.Duration) Stats {
+	if len(durations) == 0 {
+		return Stats{}
+	}
+	sorted := make([]time.Duration, len(durations))
+	copy(sorted, durations)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	var sum time.Duration
+	for _, d := range sorted {
+		sum += d
+	}
+	return Stats{
+		Min:  sorted[0],
+		Mean: sum / time.Duration(len(sorted)),
+		P50:  percentile(sorted, 50),
+		P90:  percentile(sorted, 90),
+		P99:  percentile(sorted, 99),
+		Max:  sorted[len(sorted)-1],
+	}
+}
+
+// percentile returns p-th percentile of sorted durations by nearest-rank
+// method.
+func percentile(sorted []time.Duration, p int) time.Duration {
+	rank := (p*len(sorted) + 99) / 100
+	if rank < 1 {
+		rank = 1
+	}
+	return sorted[rank-1]
+}
+
+// Result is a result of benchmark. Durations are nanoseconds in JSON.
+type Result struct {
+	Model       string                `json:"model"`
+	Backend     string                `json:"backend"`
+	Build       []menoh.PhaseDuration `json:"build"`
+	BuildTotal  time.Duration         `json:"build_total"`
+	Latency     Stats                 `json:"latency"`
+	Copy        Stats                 `json:"copy"`
+	Iterations  int                   `json:"iterations"`
+	Concurrency int                   `json:"concurrency"`
+	Throughput  float64               `json:"throughput"` // runs per second
+}
+
+// Run builds runners with the configuration and measures them.
+func Run(opts Options) (*Result, error) {
+	opts = opts.withDefaults()
+	result := &Result{
+		Model:       opts.Config.ONNXModelPath,
+		Backend:     opts.Config.Backend.String(),
+		Iterations:  opts.Iterations,
+		Concurrency: opts.Concurrency,
+	}
+
+	start := time.Now()
+	runner, err := menoh.NewRunner(opts.Config)
+	if err != nil {
+		return nil, fmt.Errorf("cannot build runner, %v", err)
+	}
+	defer runner.Stop()
+	result.BuildTotal = time.Since(start)
+	result.Build = buildPhases(runner, result.BuildTotal)
+
+	inputs := opts.Inputs
+	if inputs == nil {
+		inputs = zeroInputs(opts.Config)
+	}
+	for i := 0; i < opts.Warmup; i++ {
+		if err := runner.Run(inputs); err != nil {
+			return nil, fmt.Errorf("cannot run on warming up, %v", err)
+		}
+	}
+	latencies, copies, err := measure(runner, inputs, opts.Iterations)
+	if err != nil {
+		return nil, err
+	}
+	result.Latency = NewStats(latencies)
+	result.Copy = NewStats(copies)
+
+	if opts.Concurrency == 1 {
+		var total time.Duration
+		for i := range latencies {
+			total += latencies[i] + copies[i]
+		}
+		result.Throughput = float64(opts.Iterations) / total.Seconds()
+		return result, nil
+	}
+	if result.Throughput, err = throughput(opts, inputs); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// buildPhases returns phase durations of the runner, loading ONNX model is
+// the rest of total build time.
+func buildPhases(runner *menoh.Runner, total time.Duration) []menoh.PhaseDuration {
+	load := total
+	for _, p := range runner.BuildDurations() {
+		load -= p.Duration
+	}
+	return append([]menoh.PhaseDuration{{Phase: "loadModelData", Duration: load}},
+		runner.BuildDurations()...)
+}
+
+// measure returns latencies of running and of copying inputs separately.
+func measure(runner *menoh.Runner, inputs map[string]menoh.Tensor, n int) (
+	[]time.Duration, []time.Duration, error) {
+
+	latencies := make([]time.Duration, n)
+	copies := make([]time.Duration, n)
+	for i := 0; i < n; i++ {
+		start := time.Now()
+		if err := runner.SetInputs(inputs); err != nil {
+			return nil, nil, fmt.Errorf("cannot set inputs, %v", err)
+		}
+		copied := time.Now()
+		if err := runner.Run(nil); err != nil {
+			return nil, nil, fmt.Errorf("cannot run, %v", err)
+		}
+		latencies[i] = time.Since(copied)
+		copies[i] = copied.Sub(start)
+	}
+	return latencies, copies, nil
+}
+
+// throughput runs opts.Concurrency runners in parallel, each runs
+// opts.Iterations times, and returns total runs per second.
+func throughput(opts Options, inputs map[string]menoh.Tensor) (float64, error) {
+	runners := make([]*menoh.Runner, opts.Concurrency)
+	defer func() {
+		for _, r := range runners {
+			if r != nil {
+				r.Stop()
+			}
+		}
+	}()
+	for i := range runners {
+		r, err := menoh.NewRunner(opts.Config)
+		if err != nil {
+			return 0, fmt.Errorf("cannot build runner, %v", err)
+		}
+		runners[i] = r
+		for j := 0; j < opts.Warmup; j++ {
+			if err := r.Run(inputs); err != nil {
+				return 0, fmt.Errorf("cannot run on warming up, %v", err)
+			}
+		}
+	}
+
+	var wg sync.WaitGroup
+	errs := make(chan error, len(runners))
+	start := time.Now()
+	for _, r := range runners {
+		wg.Add(1)
+		go func(r *menoh.Runner) {
+			defer wg.Done()
+			for i := 0; i < opts.Iterations; i++ {
+				if err := r.Run(inputs); err != nil {
+					errs <- err
+					return
+				}
+			}
+		}(r)
+	}
+	wg.Wait()
+	elapsed := time.Since(start)
+	close(errs)
+	if err := <-errs; err != nil {
+		return 0, fmt.Errorf("cannot run concurrently, %v", err)
+	}
+	if elapsed <= 0 {
+		return 0, errors.New("elapsed time is too short to measure")
+	}
+	return float64(opts.Concurrency*opts.Iterations) / elapsed.Seconds(), nil
+}
+
+func zeroInputs(conf menoh.Config) map[string]menoh.Tensor {
+	inputs := map[string]menoh.Tensor{}
+	for _, c := range conf.Inputs {
+		size := 1
+		for _, d := range c.Dims {
+			size *= int(d)
+		}
+		inputs[c.Name] = &menoh.FloatTensor{
+			Dims:  c.Dims,
+			Array: make([]float32, size),
+		}
+	}
+	return inputs
+}
@@ -0,0 +1,75 @@
+package bench
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pfnet-research/go-menoh"
+)
+
+func TestNewStats(t *testing.T) {
+	durations := make([]time.Duration, 100)
+	for i := range durations {
+		// reversed order to check sorting
+		durations[i] = time.Duration(100-i) * time.Millisecond
+	}
+	actual := NewStats(durations)
+	expected := Stats{
+		Min:  1 * time.Millisecond,
+		Mean: 50500 * time.Microsecond,
+		P50:  50 * time.Millisecond,
+		P90:  90 * time.Millisecond,
+		P99:  99 * time.Millisecond,
+		Max:  100 * time.Millisecond,
+	}
+	if actual != expected {
+		t.Errorf("stats should be %+v, but %+v", expected, actual)
+	}
+	if durations[0] != 100*time.Millisecond {
+		t.Error("original durations should not be sorted")
+	}
+	if s := NewStats(nil); s != (Stats{}) {
+		t.Errorf("stats of empty durations should be zero, but %+v", s)
+	}
+}
+
+func TestWriteResult(t *testing.T) {
+	result := &Result{
+		Model:   "MLP.onnx",
+		Backend: "mkldnn",
+		Build: []menoh.PhaseDuration{
+			{Phase: "buildModel", Duration: 2 * time.Millisecond},
+		},
+		Latency:     Stats{P50: time.Millisecond},
+		Iterations:  10,
+		Concurrency: 2,
+		Throughput:  123.4,
+	}
+	t.Run("table", func(t *testing.T) {
+		buf := bytes.NewBuffer(nil)
+		if err := result.WriteTable(buf); err != nil {
+			t.Fatal(err)
+		}
+		for _, expected := range []string{"buildModel", "2.000ms", "123.40 runs/s"} {
+			if !strings.Contains(buf.String(), expected) {
+				t.Errorf("table should contain '%s'\n%s", expected, buf.String())
+			}
+		}
+	})
+	t.Run("json", func(t *testing.T) {
+		buf := bytes.NewBuffer(nil)
+		if err := result.WriteJSON(buf); err != nil {
+			t.Fatal(err)
+		}
+		actual := &Result{}
+		if err := json.Unmarshal(buf.Bytes(), actual); err != nil {
+			t.Fatalf("written JSON should be parsed, %v", err)
+		}
+		if actual.Latency.P50 != time.Millisecond || actual.Build[0].Phase != "buildModel" {
+			t.Errorf("parsed result should be same as original, but %+v", actual)
+		}
+	})
+}
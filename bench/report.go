@@ -0,0 +1,51 @@
+package bench
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"text/tabwriter"
+	"time"
+)
+
+// WriteTable writes the result as human-readable table.
+func (r *Result) WriteTable(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(tw, "model\t%s\n", r.Model)
+	fmt.Fprintf(tw, "backend\t%s\n", r.Backend)
+	fmt.Fprintln(tw)
+	fmt.Fprintln(tw, "build phase\ttime")
+	for _, p := range r.Build {
+		fmt.Fprintf(tw, "%s\t%s\n", p.Phase, formatDuration(p.Duration))
+	}
+	fmt.Fprintf(tw, "total\t%s\n", formatDuration(r.BuildTotal))
+	fmt.Fprintln(tw)
+	fmt.Fprintln(tw, "\tmin\tmean\tp50\tp90\tp99\tmax")
+	for _, s := range []struct {
+		name  string
+		stats Stats
+	}{
+		{"run", r.Latency},
+		{"copy", r.Copy},
+	} {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n", s.name,
+			formatDuration(s.stats.Min), formatDuration(s.stats.Mean),
+			formatDuration(s.stats.P50), formatDuration(s.stats.P90),
+			formatDuration(s.stats.P99), formatDuration(s.stats.Max))
+	}
+	fmt.Fprintln(tw)
+	fmt.Fprintf(tw, "throughput\t%.2f runs/s (%d runners x %d runs)\n",
+		r.Throughput, r.Concurrency, r.Iterations)
+	return tw.Flush()
+}
+
+// WriteJSON writes the result as JSON, for regression tracking.
+func (r *Result) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(r)
+}
+
+func formatDuration(d time.Duration) string {
+	return fmt.Sprintf("%.3fms", float64(d)/float64(time.Millisecond))
+}
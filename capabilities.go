@@ -0,0 +1,210 @@
+package menoh
+
+import (
+	"fmt"
+)
+
+// LibraryCapabilities is a report of features supported by linked Menoh.
+type LibraryCapabilities struct {
+	Version  Version
+	Dtypes   []TypeDtype
+	Backends []TypeBackend
+
+	// Operators are probed operator types supported by each backend.
+	Operators map[TypeBackend][]string
+}
+
+// Capabilities probes linked Menoh and reports supported dtypes, backends and
+// operators. Operators are probed by building a single operator model for
+// each operator type and each backend, so it takes time.
+func Capabilities() (*LibraryCapabilities, error) {
+	version, err := LibraryVersion()
+	if err != nil {
+		return nil, err
+	}
+	c := &LibraryCapabilities{
+		Version:   version,
+		Dtypes:    []TypeDtype{TypeFloat},
+		Backends:  []TypeBackend{},
+		Operators: map[TypeBackend][]string{},
+	}
+	// backends registered by RegisterBackend are not of the library
+	for _, b := range knownBackends() {
+		if probeBackend(b) == nil {
+			c.Backends = append(c.Backends, b)
+		}
+	}
+	for _, b := range c.Backends {
+		ops := []string{}
+		for _, p := range operatorProbes {
+			if probeOperator(b, p) == nil {
+				ops = append(ops, p.opType)
+			}
+		}
+		c.Operators[b] = ops
+	}
+	return c, nil
+}
+
+// ProbeOperator returns the error when the backend of linked Menoh cannot
+// build the operator type. Operator types not known by this package are
+// reported as error.
+func ProbeOperator(backend TypeBackend, opType string) error {
+	for _, p := range operatorProbes {
+		if p.opType == opType {
+			return probeOperator(backend, p)
+		}
+	}
+	return fmt.Errorf("operator '%s' cannot be probed", opType)
+}
+
+// operatorProbe is a single operator model, input "x" is a variable and
+// others are parameters filled with zero.
+type operatorProbe struct {
+	opType     string
+	inputs     []probeInput
+	intAttrs   map[string]int
+	floatAttrs map[string]float32
+	intsAttrs  map[string][]int
+}
+
+type probeInput struct {
+	name string
+	dims []int32
+}
+
+var probeImage = probeInput{"x", []int32{1, 2, 4, 4}}
+var probeVector = probeInput{"x", []int32{1, 4}}
+
+// operatorProbes lists operators to probe, the first one is used to probe
+// backends.
+var operatorProbes = []operatorProbe{
+	{opType: "Relu", inputs: []probeInput{probeImage}},
+	{opType: "LeakyRelu", inputs: []probeInput{probeImage}},
+	{opType: "Elu", inputs: []probeInput{probeImage}},
+	{opType: "Sigmoid", inputs: []probeInput{probeImage}},
+	{opType: "Tanh", inputs: []probeInput{probeImage}},
+	{opType: "Abs", inputs: []probeInput{probeImage}},
+	{opType: "Sqrt", inputs: []probeInput{probeImage}},
+	{opType: "Identity", inputs: []probeInput{probeImage}},
+	{opType: "Softmax", inputs: []probeInput{probeVector}},
+	{opType: "Add", inputs: []probeInput{probeImage, {"b", []int32{1, 2, 4, 4}}}},
+	{opType: "Sum", inputs: []probeInput{probeImage, {"b", []int32{1, 2, 4, 4}}}},
+	{opType: "Mul", inputs: []probeInput{probeImage, {"b", []int32{1, 2, 4, 4}}}},
+	{
+		opType:   "Gemm",
+		inputs:   []probeInput{probeVector, {"W", []int32{3, 4}}, {"B", []int32{3}}},
+		intAttrs: map[string]int{"transB": 1},
+	},
+	{opType: "FC", inputs: []probeInput{probeVector, {"W", []int32{3, 4}}, {"b", []int32{3}}}},
+	{
+		opType:    "Conv",
+		inputs:    []probeInput{probeImage, {"W", []int32{3, 2, 3, 3}}, {"B", []int32{3}}},
+		intsAttrs: map[string][]int{"kernel_shape": {3, 3}},
+	},
+	{
+		opType:    "ConvTranspose",
+		inputs:    []probeInput{probeImage, {"W", []int32{2, 3, 3, 3}}, {"B", []int32{3}}},
+		intsAttrs: map[string][]int{"kernel_shape": {3, 3}},
+	},
+	{
+		opType:    "MaxPool",
+		inputs:    []probeInput{probeImage},
+		intsAttrs: map[string][]int{"kernel_shape": {2, 2}, "strides": {2, 2}},
+	},
+	{
+		opType:    "AveragePool",
+		inputs:    []probeInput{probeImage},
+		intsAttrs: map[string][]int{"kernel_shape": {2, 2}, "strides": {2, 2}},
+	},
+	{opType: "GlobalMaxPool", inputs: []probeInput{probeImage}},
+	{opType: "GlobalAveragePool", inputs: []probeInput{probeImage}},
+	{
+		opType: "BatchNormalization",
+		inputs: []probeInput{probeImage,
+			{"scale", []int32{2}}, {"B", []int32{2}}, {"mean", []int32{2}}, {"var", []int32{2}}},
+		floatAttrs: map[string]float32{"epsilon": 1e-5},
+	},
+	{
+		opType:   "Concat",
+		inputs:   []probeInput{probeImage, {"c", []int32{1, 2, 4, 4}}},
+		intAttrs: map[string]int{"axis": 1},
+	},
+	{
+		opType:    "Transpose",
+		inputs:    []probeInput{probeImage},
+		intsAttrs: map[string][]int{"perm": {0, 1, 3, 2}},
+	},
+	{
+		opType:     "LRN",
+		inputs:     []probeInput{probeImage},
+		intAttrs:   map[string]int{"size": 3},
+		floatAttrs: map[string]float32{"alpha": 1e-4, "beta": 0.75, "bias": 1},
+	},
+}
+
+// probeOperator builds the operator model with the backend. Version checks
+// of NewRunner are skipped, probing is used to estimate the version.
+func probeOperator(backend TypeBackend, p operatorProbe) error {
+	md, err := NewRawModelData()
+	if err != nil {
+		return err
+	}
+	if err := p.setup(md); err != nil {
+		md.Delete()
+		return err
+	}
+	// the runner takes the model data and deletes it on stopping
+	runner, err := buildRunner(newBindingBackend(&md.ModelData), Config{
+		Backend: backend,
+		Inputs:  []InputConfig{{Name: p.inputs[0].name, Dtype: TypeFloat, Dims: p.inputs[0].dims}},
+		Outputs: []OutputConfig{{Name: "y", Dtype: TypeFloat}},
+		// failures of probing are expected, not logged
+		Logger: nopLogger{},
+	})
+	if err != nil {
+		return err
+	}
+	runner.Stop()
+	return nil
+}
+
+func (p operatorProbe) setup(md *ModelData) error {
+	for _, in := range p.inputs[1:] {
+		size := 1
+		for _, d := range in.dims {
+			size *= int(d)
+		}
+		param := &FloatTensor{Dims: in.dims, Array: make([]float32, size)}
+		if err := md.AddTensorParameter(in.name, param); err != nil {
+			return err
+		}
+	}
+	if err := md.AddNewNode(p.opType); err != nil {
+		return err
+	}
+	for _, in := range p.inputs {
+		if err := md.AddInputNameToCurrentNode(in.name); err != nil {
+			return err
+		}
+	}
+	if err := md.AddOutputNameToCurrentNode("y"); err != nil {
+		return err
+	}
+	for name, v := range p.intAttrs {
+		if err := md.AddAttributeIntToCurrentNode(name, v); err != nil {
+			return err
+		}
+	}
+	for name, v := range p.floatAttrs {
+		if err := md.AddAttributeFloatToCurrentNode(name, v); err != nil {
+			return err
+		}
+	}
+	for name, v := range p.intsAttrs {
+		if err := md.AddAttributeIntsToCurrentNode(name, v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,145 @@
+/*
+Command menoh-bench measures build time, latency and throughput of an ONNX
+model with Menoh.
+
+	$ menoh-bench -model MLP.onnx -input input=1,3 -output fc2 -concurrency 4
+	$ menoh-bench -model MLP.onnx -input input=1,3 -output fc2 -json > result.json
+
+Inputs are set as name=dims and filled with zero, or as name=path of ONNX
+tensor (.pb) or NumPy (.npy) file.
+*/
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/pfnet-research/go-menoh"
+	"github.com/pfnet-research/go-menoh/bench"
+	"github.com/pfnet-research/go-menoh/tools/npy"
+	"github.com/pfnet-research/go-menoh/tools/onnx"
+)
+
+// multiFlag is a flag which can be set multiple times.
+type multiFlag []string
+
+func (f *multiFlag) String() string {
+	return strings.Join(*f, ",")
+}
+
+func (f *multiFlag) Set(v string) error {
+	*f = append(*f, v)
+	return nil
+}
+
+func main() {
+	var inputs, outputs multiFlag
+	var (
+		modelPath     = flag.String("model", "", "ONNX model path (required)")
+		backend       = flag.String("backend", menoh.TypeMKLDNN.String(), "backend name")
+		backendConfig = flag.String("backend-config", "", "backend configuration JSON")
+		warmup        = flag.Int("warmup", 10, "number of runs before measurement, 0 or negative disables warmup")
+		iterations    = flag.Int("iterations", 100, "number of measured runs per runner")
+		concurrency   = flag.Int("concurrency", 1, "number of runners executed concurrently")
+		asJSON        = flag.Bool("json", false, "output result as JSON")
+	)
+	flag.Var(&inputs, "input", "input as name=dims (like input=1,3) or name=path, repeatable")
+	flag.Var(&outputs, "output", "output variable name, repeatable")
+	flag.Parse()
+
+	if err := run(*modelPath, *backend, *backendConfig, inputs, outputs,
+		*warmup, *iterations, *concurrency, *asJSON); err != nil {
+		fmt.Fprintf(os.Stderr, "menoh-bench: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func run(modelPath, backend, backendConfig string, inputs, outputs []string,
+	warmup, iterations, concurrency int, asJSON bool) error {
+
+	if modelPath == "" {
+		return fmt.Errorf("-model is required")
+	}
+	typeBackend, err := menoh.ParseBackend(backend)
+	if err != nil {
+		return err
+	}
+	conf := menoh.Config{
+		ONNXModelPath: modelPath,
+		Backend:       typeBackend,
+		BackendConfig: backendConfig,
+	}
+	tensors := map[string]menoh.Tensor{}
+	for _, in := range inputs {
+		idx := strings.Index(in, "=")
+		if idx < 0 {
+			return fmt.Errorf("input must be set as name=dims or name=path, but '%s'", in)
+		}
+		name := in[:idx]
+		t, err := loadInput(in[idx+1:])
+		if err != nil {
+			return err
+		}
+		tensors[name] = t
+		conf.Inputs = append(conf.Inputs, menoh.InputConfig{
+			Name:  name,
+			Dtype: menoh.TypeFloat,
+			Dims:  t.Shape(),
+		})
+	}
+	for _, name := range outputs {
+		conf.Outputs = append(conf.Outputs, menoh.OutputConfig{
+			Name:  name,
+			Dtype: menoh.TypeFloat,
+		})
+	}
+
+	if warmup == 0 {
+		warmup = -1 // 0 means the default of bench.Options
+	}
+	result, err := bench.Run(bench.Options{
+		Config:      conf,
+		Inputs:      tensors,
+		Warmup:      warmup,
+		Iterations:  iterations,
+		Concurrency: concurrency,
+	})
+	if err != nil {
+		return err
+	}
+	if asJSON {
+		return result.WriteJSON(os.Stdout)
+	}
+	return result.WriteTable(os.Stdout)
+}
+
+func loadInput(v string) (menoh.Tensor, error) {
+	switch strings.ToLower(filepath.Ext(v)) {
+	case ".pb":
+		t, err := onnx.LoadONNXTensorFromFile(v)
+		if err != nil {
+			return nil, err
+		}
+		return onnx.ConvertToMenohTensor(t)
+	case ".npy":
+		return npy.LoadNpyFromFile(v)
+	}
+	dims := []int32{}
+	size := 1
+	for _, e := range strings.Split(v, ",") {
+		d, err := strconv.Atoi(strings.TrimSpace(e))
+		if err != nil || d <= 0 {
+			return nil, fmt.Errorf("invalid dims '%s'", v)
+		}
+		dims = append(dims, int32(d))
+		size *= d
+	}
+	return &menoh.FloatTensor{
+		Dims:  dims,
+		Array: make([]float32, size),
+	}, nil
+}
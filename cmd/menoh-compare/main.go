@@ -0,0 +1,174 @@
+/*
+Command menoh-compare runs same inputs through two targets and reports
+differences of outputs, like between backends, models or builds of Menoh.
+
+	$ menoh-compare -model vgg16.onnx -dataset vgg16 -backend mkldnn -backend-b generic
+	$ menoh-compare -model vgg16.onnx -dataset vgg16 -layers \
+		-command-b "env LD_LIBRARY_PATH=/opt/menoh-1.0/lib menoh-run"
+
+Inputs are loaded from "test_data_set_<index>" directories in the dataset
+directory, laid out like ONNX model zoo. Settings of target B default to
+those of target A. With -command-a or -command-b, the target runs menoh-run
+command as a subprocess, to compare with another build of Menoh library.
+With -layers, all intermediate variables existing in both models are
+compared too.
+*/
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/pfnet-research/go-menoh"
+	"github.com/pfnet-research/go-menoh/compare"
+	"github.com/pfnet-research/go-menoh/conformance"
+	"github.com/pfnet-research/go-menoh/profile"
+	"github.com/pfnet-research/go-menoh/tools/onnx"
+)
+
+// multiFlag is a flag which can be set multiple times.
+type multiFlag []string
+
+func (f *multiFlag) String() string {
+	return strings.Join(*f, ",")
+}
+
+func (f *multiFlag) Set(v string) error {
+	*f = append(*f, v)
+	return nil
+}
+
+// targetFlags are settings of a target.
+type targetFlags struct {
+	model, backend, backendConfig, command string
+}
+
+func main() {
+	var outputs multiFlag
+	var a, b targetFlags
+	flag.StringVar(&a.model, "model", "", "ONNX model path of target A (required)")
+	flag.StringVar(&b.model, "model-b", "", "ONNX model path of target B")
+	flag.StringVar(&a.backend, "backend", menoh.TypeMKLDNN.String(), "backend name of target A")
+	flag.StringVar(&b.backend, "backend-b", "", "backend name of target B")
+	flag.StringVar(&a.backendConfig, "backend-config", "", "backend configuration JSON of target A")
+	flag.StringVar(&b.backendConfig, "backend-config-b", "", "backend configuration JSON of target B")
+	flag.StringVar(&a.command, "command-a", "", "menoh-run command to run target A as a subprocess")
+	flag.StringVar(&b.command, "command-b", "", "menoh-run command to run target B as a subprocess")
+	var (
+		datasetDir = flag.String("dataset", "", "directory of test_data_set_<index> directories (required)")
+		layers     = flag.Bool("layers", false, "compare all intermediate variables too")
+		asJSON     = flag.Bool("json", false, "output report as JSON")
+	)
+	flag.Var(&outputs, "output", "compared output variable name, repeatable, default is all graph outputs")
+	flag.Parse()
+
+	if err := run(a, b, *datasetDir, outputs, *layers, *asJSON); err != nil {
+		fmt.Fprintf(os.Stderr, "menoh-compare: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func run(a, b targetFlags, datasetDir string, outputs []string, layers, asJSON bool) error {
+	if a.model == "" {
+		return fmt.Errorf("-model is required")
+	}
+	if datasetDir == "" {
+		return fmt.Errorf("-dataset is required")
+	}
+	if b.model == "" {
+		b.model = a.model
+	}
+	if b.backend == "" {
+		b.backend = a.backend
+	}
+	if b.backendConfig == "" {
+		b.backendConfig = a.backendConfig
+	}
+
+	model, err := onnx.LoadONNXModelFromFile(a.model)
+	if err != nil {
+		return err
+	}
+	inputNames := []string{}
+	for _, v := range onnx.GraphInputs(model.GetGraph()) {
+		inputNames = append(inputNames, v.GetName())
+	}
+	if len(outputs) == 0 {
+		for _, v := range model.GetGraph().GetOutput() {
+			outputs = append(outputs, v.GetName())
+		}
+	}
+	opts := compare.Options{Outputs: outputs}
+	if layers {
+		if opts.Layers, err = commonLayers(model, b.model, outputs); err != nil {
+			return err
+		}
+	}
+	datasets, err := conformance.LoadDatasets(datasetDir, inputNames, outputs)
+	if err != nil {
+		return err
+	}
+
+	targetA, err := newTarget(a)
+	if err != nil {
+		return err
+	}
+	targetB, err := newTarget(b)
+	if err != nil {
+		return err
+	}
+	report, err := compare.Run(targetA, targetB, datasets, opts)
+	if err != nil {
+		return err
+	}
+	if asJSON {
+		return report.WriteJSON(os.Stdout)
+	}
+	return report.WriteTable(os.Stdout)
+}
+
+// commonLayers returns layers of the model existing in the other model,
+// except outputs.
+func commonLayers(model *onnx.ModelProto, otherPath string, outputs []string) ([]string, error) {
+	other, err := onnx.LoadONNXModelFromFile(otherPath)
+	if err != nil {
+		return nil, err
+	}
+	excluded := map[string]bool{}
+	for _, name := range outputs {
+		excluded[name] = true
+	}
+	exists := map[string]bool{}
+	for _, l := range profile.Layers(other) {
+		exists[l.Name] = true
+	}
+	names := []string{}
+	for _, l := range profile.Layers(model) {
+		if exists[l.Name] && !excluded[l.Name] {
+			names = append(names, l.Name)
+		}
+	}
+	return names, nil
+}
+
+func newTarget(f targetFlags) (compare.Target, error) {
+	if f.command != "" {
+		return &compare.CommandTarget{
+			Command:       strings.Fields(f.command),
+			Model:         f.model,
+			Backend:       f.backend,
+			BackendConfig: f.backendConfig,
+		}, nil
+	}
+	backend, err := menoh.ParseBackend(f.backend)
+	if err != nil {
+		return nil, err
+	}
+	return &compare.RunnerTarget{Config: menoh.Config{
+		ONNXModelPath: f.model,
+		Backend:       backend,
+		BackendConfig: f.backendConfig,
+	}}, nil
+}
@@ -0,0 +1,96 @@
+/*
+Command menoh-quantize quantizes an ONNX model to int8 with a calibration
+dataset, and compares outputs of the quantized model with the float model.
+
+	$ menoh-quantize -model vgg16.onnx -dataset calibration -out vgg16_int8.onnx
+	$ menoh-quantize -model vgg16.onnx -dataset calibration -out vgg16_int8.onnx \
+		-method percentile -per-channel
+
+Inputs are loaded from "test_data_set_<index>" directories in the dataset
+directory, laid out like ONNX model zoo. The float model is run by the
+backend, and the quantized model by the reference interpreter, so operators
+of the model must be supported by the interpreter. The quantized model cannot
+be served by Menoh.
+*/
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/pfnet-research/go-menoh"
+	"github.com/pfnet-research/go-menoh/conformance"
+	"github.com/pfnet-research/go-menoh/quantize"
+	"github.com/pfnet-research/go-menoh/tools/onnx"
+)
+
+func main() {
+	var (
+		modelPath     = flag.String("model", "", "ONNX model path (required)")
+		datasetDir    = flag.String("dataset", "", "directory of test_data_set_<index> directories (required)")
+		outPath       = flag.String("out", "", "output path of the quantized model (required)")
+		backend       = flag.String("backend", menoh.TypeMKLDNN.String(), "backend name to run the float model")
+		backendConfig = flag.String("backend-config", "", "backend configuration JSON")
+		method        = flag.String("method", "minmax", "calibration method, minmax or percentile")
+		percentile    = flag.Float64("percentile", 99.99, "percentile of absolute values for percentile method")
+		perChannel    = flag.Bool("per-channel", false, "quantize weights per output channel")
+		weightsOnly   = flag.Bool("weights-only", false, "quantize only weights")
+	)
+	flag.Parse()
+
+	if err := run(*modelPath, *datasetDir, *outPath, *backend, *backendConfig, *method, *percentile,
+		*perChannel, *weightsOnly); err != nil {
+		fmt.Fprintf(os.Stderr, "menoh-quantize: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func run(modelPath, datasetDir, outPath, backend, backendConfig, method string, percentile float64,
+	perChannel, weightsOnly bool) error {
+
+	if modelPath == "" || datasetDir == "" || outPath == "" {
+		return fmt.Errorf("-model, -dataset and -out are required")
+	}
+	typeBackend, err := menoh.ParseBackend(backend)
+	if err != nil {
+		return err
+	}
+	m, err := quantize.ParseMethod(method)
+	if err != nil {
+		return err
+	}
+	model, err := onnx.LoadONNXModelFromFile(modelPath)
+	if err != nil {
+		return err
+	}
+	inputNames, outputNames := []string{}, []string{}
+	for _, v := range onnx.GraphInputs(model.GetGraph()) {
+		inputNames = append(inputNames, v.GetName())
+	}
+	for _, v := range model.GetGraph().GetOutput() {
+		outputNames = append(outputNames, v.GetName())
+	}
+	datasets, err := conformance.LoadDatasets(datasetDir, inputNames, outputNames)
+	if err != nil {
+		return err
+	}
+
+	conf := menoh.Config{
+		ONNXModelPath: modelPath,
+		Backend:       typeBackend,
+		BackendConfig: backendConfig,
+	}
+	result, err := quantize.QuantizeFile(conf, datasets, outPath, quantize.Options{
+		Method:      m,
+		Percentile:  percentile,
+		PerChannel:  perChannel,
+		WeightsOnly: weightsOnly,
+	})
+	if err != nil {
+		return err
+	}
+	fmt.Print(result.Report)
+	fmt.Println()
+	return result.Accuracy.WriteTable(os.Stdout)
+}
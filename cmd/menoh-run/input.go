@@ -0,0 +1,276 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"image"
+	_ "image/jpeg" // register JPEG decoder
+	_ "image/png"  // register PNG decoder
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/disintegration/imaging"
+	"github.com/pfnet-research/go-menoh"
+	"github.com/pfnet-research/go-menoh/tools/npy"
+	"github.com/pfnet-research/go-menoh/tools/onnx"
+)
+
+// imageOptions is preprocessing setup to convert an image to NCHW tensor.
+type imageOptions struct {
+	size  string
+	mean  string
+	scale float64
+	order string
+}
+
+func (o *imageOptions) register(fs *flag.FlagSet) {
+	fs.StringVar(&o.size, "image-size", "", "resize input images to WIDTHxHEIGHT")
+	fs.StringVar(&o.mean, "image-mean", "0,0,0", "mean values subtracted from R,G,B channels")
+	fs.Float64Var(&o.scale, "image-scale", 1, "scale multiplied to pixel values after subtracting mean")
+	fs.StringVar(&o.order, "image-order", "rgb", "channel order of input tensor, rgb or bgr")
+}
+
+// loadInputs loads input tensors. Each input is set as "name=path" or
+// "path", unnamed inputs are assigned to graph inputs in order of unnamed
+// ones. ".npz" file provides all arrays in it, named by its keys. An input
+// assigned twice is an error.
+func loadInputs(model *onnx.ModelProto, inputs []string, imageOpts *imageOptions) (map[string]menoh.Tensor, error) {
+	graphInputs := onnx.GraphInputs(model.GetGraph())
+	tensors := map[string]menoh.Tensor{}
+	assign := func(name string, t menoh.Tensor) error {
+		if _, ok := tensors[name]; ok {
+			return fmt.Errorf("input %s is assigned twice", name)
+		}
+		tensors[name] = t
+		return nil
+	}
+	unnamed := 0
+	for _, in := range inputs {
+		name, path := "", in
+		if idx := strings.Index(in, "="); idx >= 0 {
+			name, path = in[:idx], in[idx+1:]
+		}
+		if strings.ToLower(filepath.Ext(path)) == ".npz" {
+			ts, err := npy.LoadNpzFromFile(path)
+			if err != nil {
+				return nil, err
+			}
+			for n, t := range ts {
+				if err := assign(n, t); err != nil {
+					return nil, err
+				}
+			}
+			continue
+		}
+		if name == "" {
+			if unnamed >= len(graphInputs) {
+				return nil, fmt.Errorf("cannot assign '%s' to any input of the model", path)
+			}
+			name = graphInputs[unnamed].GetName()
+			unnamed++
+		}
+		t, err := loadTensor(path, imageOpts)
+		if err != nil {
+			return nil, err
+		}
+		if err := assign(name, t); err != nil {
+			return nil, err
+		}
+	}
+	return tensors, nil
+}
+
+func loadTensor(path string, imageOpts *imageOptions) (menoh.Tensor, error) {
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".pb":
+		t, err := onnx.LoadONNXTensorFromFile(path)
+		if err != nil {
+			return nil, err
+		}
+		return onnx.ConvertToMenohTensor(t)
+	case ".npy":
+		return npy.LoadNpyFromFile(path)
+	case ".json":
+		b, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("cannot load '%s', %v", path, err)
+		}
+		return parseJSONTensor(b)
+	case ".jpg", ".jpeg", ".png":
+		return loadImage(path, imageOpts)
+	default:
+		return nil, fmt.Errorf("format of '%s' is not supported", path)
+	}
+}
+
+// parseJSONTensor parses {"dims": [1, 3], "data": [0.1, 0.2, 0.3]} or nested
+// array like [[0.1, 0.2, 0.3]].
+func parseJSONTensor(b []byte) (menoh.Tensor, error) {
+	var obj struct {
+		Dims []int32   `json:"dims"`
+		Data []float32 `json:"data"`
+	}
+	if err := json.Unmarshal(b, &obj); err == nil {
+		size := 1
+		for _, d := range obj.Dims {
+			size *= int(d)
+		}
+		if size != len(obj.Data) {
+			return nil, fmt.Errorf("size of data %d does not match to dims %v", len(obj.Data), obj.Dims)
+		}
+		return &menoh.FloatTensor{Dims: obj.Dims, Array: obj.Data}, nil
+	}
+
+	var nested interface{}
+	if err := json.Unmarshal(b, &nested); err != nil {
+		return nil, fmt.Errorf("cannot parse JSON tensor, %v", err)
+	}
+	t := &menoh.FloatTensor{}
+	if err := flatten(nested, 0, t); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+func flatten(v interface{}, depth int, t *menoh.FloatTensor) error {
+	switch e := v.(type) {
+	case float64:
+		if depth != len(t.Dims) {
+			return errors.New("nested array is not rectangular")
+		}
+		t.Array = append(t.Array, float32(e))
+	case []interface{}:
+		if depth == len(t.Dims) && len(t.Array) == 0 {
+			t.Dims = append(t.Dims, int32(len(e)))
+		}
+		if depth >= len(t.Dims) || int(t.Dims[depth]) != len(e) {
+			return errors.New("nested array is not rectangular")
+		}
+		for _, c := range e {
+			if err := flatten(c, depth+1, t); err != nil {
+				return err
+			}
+		}
+	default:
+		return fmt.Errorf("unsupported JSON value %v", v)
+	}
+	return nil
+}
+
+func loadImage(path string, opts *imageOptions) (menoh.Tensor, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("cannot load '%s', %v", path, err)
+	}
+	defer f.Close()
+	img, _, err := image.Decode(f)
+	if err != nil {
+		return nil, fmt.Errorf("cannot decode '%s', %v", path, err)
+	}
+	if opts.size != "" {
+		var w, h int
+		if _, err := fmt.Sscanf(opts.size, "%dx%d", &w, &h); err != nil {
+			return nil, fmt.Errorf("invalid image size '%s', %v", opts.size, err)
+		}
+		img = imaging.Resize(img, w, h, imaging.Linear)
+	}
+	mean, err := parseFloats(opts.mean)
+	if err != nil || len(mean) != 3 {
+		return nil, fmt.Errorf("image mean must be 3 values, but '%s'", opts.mean)
+	}
+	var channels [3]int // index of R, G, B in the tensor
+	switch opts.order {
+	case "rgb":
+		channels = [3]int{0, 1, 2}
+	case "bgr":
+		channels = [3]int{2, 1, 0}
+	default:
+		return nil, fmt.Errorf("image order '%s' is not supported", opts.order)
+	}
+
+	bounds := img.Bounds()
+	w, h := bounds.Dx(), bounds.Dy()
+	floats := make([]float32, 3*h*w)
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			r, g, b, _ := img.At(bounds.Min.X+x, bounds.Min.Y+y).RGBA()
+			for c, v := range []uint32{r, g, b} {
+				floats[channels[c]*(w*h)+y*w+x] = float32((float64(v/257) - mean[c]) * opts.scale)
+			}
+		}
+	}
+	return &menoh.FloatTensor{
+		Dims:  []int32{1, 3, int32(h), int32(w)},
+		Array: floats,
+	}, nil
+}
+
+func parseFloats(s string) ([]float64, error) {
+	fs := []float64{}
+	for _, e := range strings.Split(s, ",") {
+		f, err := strconv.ParseFloat(strings.TrimSpace(e), 64)
+		if err != nil {
+			return nil, err
+		}
+		fs = append(fs, f)
+	}
+	return fs, nil
+}
+
+func parseDims(s string) ([]int32, error) {
+	dims := []int32{}
+	for _, e := range strings.Split(s, ",") {
+		d, err := strconv.ParseInt(strings.TrimSpace(e), 10, 32)
+		if err != nil {
+			return nil, err
+		}
+		if d <= 0 {
+			return nil, fmt.Errorf("dim must be positive, but %d", d)
+		}
+		dims = append(dims, int32(d))
+	}
+	return dims, nil
+}
+
+// inputConfigs makes input configuration from loaded tensors, dims can be
+// overridden by "name=1,3,224,224".
+func inputConfigs(tensors map[string]menoh.Tensor, inputDims []string) ([]menoh.InputConfig, error) {
+	dims := map[string][]int32{}
+	for name, t := range tensors {
+		dims[name] = t.Shape()
+	}
+	for _, d := range inputDims {
+		idx := strings.Index(d, "=")
+		if idx < 0 {
+			return nil, fmt.Errorf("input dims must be set as name=dims, but '%s'", d)
+		}
+		name := d[:idx]
+		if _, ok := tensors[name]; !ok {
+			return nil, fmt.Errorf("input '%s' is not set", name)
+		}
+		ds, err := parseDims(d[idx+1:])
+		if err != nil {
+			return nil, fmt.Errorf("invalid dims '%s', %v", d, err)
+		}
+		dims[name] = ds
+	}
+	configs := []menoh.InputConfig{}
+	for name := range tensors {
+		configs = append(configs, menoh.InputConfig{
+			Name:  name,
+			Dtype: menoh.TypeFloat,
+			Dims:  dims[name],
+		})
+	}
+	// in stable order for the configuration and the output
+	sort.Slice(configs, func(i, j int) bool {
+		return configs[i].Name < configs[j].Name
+	})
+	return configs, nil
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/pfnet-research/go-menoh"
+	"github.com/pfnet-research/go-menoh/tools/onnx"
+)
+
+func TestParseJSONTensor(t *testing.T) {
+	testSet := []struct {
+		name  string
+		input string
+		dims  []int32
+		data  []float32
+	}{
+		{"dims and data", `{"dims": [1, 3], "data": [0.5, 1, 2]}`, []int32{1, 3}, []float32{0.5, 1, 2}},
+		{"nested array", `[[0.5, 1], [2, 3]]`, []int32{2, 2}, []float32{0.5, 1, 2, 3}},
+	}
+	for _, ts := range testSet {
+		t.Run(ts.name, func(t *testing.T) {
+			actual, err := parseJSONTensor([]byte(ts.input))
+			if err != nil {
+				t.Fatalf("JSON should be parsed, %v", err)
+			}
+			ft := actual.(*menoh.FloatTensor)
+			if len(ft.Dims) != len(ts.dims) {
+				t.Fatalf("dims should be %v, but %v", ts.dims, ft.Dims)
+			}
+			for i, d := range ts.dims {
+				if ft.Dims[i] != d {
+					t.Errorf("dims should be %v, but %v", ts.dims, ft.Dims)
+				}
+			}
+			for i, f := range ts.data {
+				if ft.Array[i] != f {
+					t.Errorf("data should be %v, but %v", ts.data, ft.Array)
+				}
+			}
+		})
+	}
+
+	// fail
+	for _, input := range []string{
+		`[[1, 2], [3]]`,
+		`{"dims": [2, 2], "data": [1, 2, 3]}`,
+		`"text"`,
+	} {
+		if _, err := parseJSONTensor([]byte(input)); err == nil {
+			t.Errorf("'%s' should not be parsed", input)
+		}
+	}
+}
+
+func TestInputConfigs(t *testing.T) {
+	tensors := map[string]menoh.Tensor{
+		"x": &menoh.FloatTensor{Dims: []int32{1, 4}, Array: make([]float32, 4)},
+		"a": &menoh.FloatTensor{Dims: []int32{1, 2}, Array: make([]float32, 2)},
+		"m": &menoh.FloatTensor{Dims: []int32{1, 1}, Array: make([]float32, 1)},
+	}
+	configs, err := inputConfigs(tensors, []string{"x=1, 1,2,2"})
+	if err != nil {
+		t.Fatalf("input configs should be made, %v", err)
+	}
+	names := []string{}
+	for _, c := range configs {
+		names = append(names, c.Name)
+	}
+	if !reflect.DeepEqual(names, []string{"a", "m", "x"}) {
+		t.Errorf("configs should be sorted by name, but %v", names)
+	}
+	if !reflect.DeepEqual(configs[2].Dims, []int32{1, 1, 2, 2}) {
+		t.Errorf("dims should be overridden, but %v", configs[2].Dims)
+	}
+	if _, err := inputConfigs(tensors, []string{"y=1,4"}); err == nil {
+		t.Error("an error should be occurred with unknown input")
+	}
+	for _, d := range []string{"x=1,2.5", "x=1,0", "x=1,-4", "x=1,1e3", "x=4294967297"} {
+		if _, err := inputConfigs(tensors, []string{d}); err == nil {
+			t.Errorf("an error should be occurred with invalid dims '%s'", d)
+		}
+	}
+}
+
+func TestLoadInputs(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "go-menoh-test-")
+	if err != nil {
+		t.Fatal("cannot make temporary directory")
+	}
+	defer os.RemoveAll(tempDir)
+	paths := map[string]string{}
+	for name, data := range map[string]string{"a": "[[1]]", "b": "[[2]]", "c": "[[3]]"} {
+		paths[name] = filepath.Join(tempDir, name+".json")
+		if err := ioutil.WriteFile(paths[name], []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	model := &onnx.ModelProto{Graph: &onnx.GraphProto{
+		Input: []*onnx.ValueInfoProto{{Name: proto.String("x")}, {Name: proto.String("y")}},
+	}}
+
+	// unnamed inputs are assigned in order of unnamed ones
+	tensors, err := loadInputs(model, []string{"y=" + paths["a"], paths["b"]}, &imageOptions{})
+	if err != nil {
+		t.Fatalf("inputs should be loaded, %v", err)
+	}
+	if x, _ := tensors["x"].FloatArray(); len(tensors) != 2 || !reflect.DeepEqual(x, []float32{2}) {
+		t.Errorf("unnamed input should be assigned to x, but %v", tensors)
+	}
+
+	for _, inputs := range [][]string{
+		{"x=" + paths["a"], paths["b"]},
+		{paths["a"], paths["b"], paths["c"]},
+	} {
+		if _, err := loadInputs(model, inputs, &imageOptions{}); err == nil {
+			t.Errorf("an error should be occurred with %v", inputs)
+		}
+	}
+}
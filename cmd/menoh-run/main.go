@@ -0,0 +1,148 @@
+/*
+Command menoh-run runs an ONNX model with Menoh and prints or saves outputs.
+
+	$ menoh-run -model vgg16.onnx -input Input_0=hen.jpg -image-size 224x224 \
+		-image-mean 123.68,116.779,103.939 -output Softmax_0
+
+Inputs are loaded from ONNX tensor (.pb), NumPy (.npy, .npz), JSON (.json)
+or image (.jpg, .png) files, formats are detected by file extensions. When
+input names are omitted, unnamed inputs are assigned to graph inputs in
+order, and an input assigned twice is an error. When outputs are not set, all graph outputs are taken. With -profile, all
+intermediate variables are tapped and their statistics are dumped to stderr.
+*/
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/pfnet-research/go-menoh"
+	"github.com/pfnet-research/go-menoh/profile"
+	"github.com/pfnet-research/go-menoh/tools/onnx"
+)
+
+// multiFlag is a flag which can be set multiple times.
+type multiFlag []string
+
+func (f *multiFlag) String() string {
+	return strings.Join(*f, ",")
+}
+
+func (f *multiFlag) Set(v string) error {
+	*f = append(*f, v)
+	return nil
+}
+
+func main() {
+	var (
+		inputs, inputDims, outputs, internals multiFlag
+		imageOpts                             imageOptions
+	)
+	var (
+		modelPath     = flag.String("model", "", "ONNX model path (required)")
+		backend       = flag.String("backend", menoh.TypeMKLDNN.String(), "backend name")
+		backendConfig = flag.String("backend-config", "", "backend configuration JSON")
+		list          = flag.Bool("list", false, "print all variable names in the model and exit")
+		listBackends  = flag.Bool("list-backends", false, "print backends supported by the linked Menoh and exit")
+		format        = flag.String("format", "text", "output format, one of text, json, pb, npy or npz")
+		outPath       = flag.String("out", "", "output path, file for json/npz or directory for pb/npy, default is stdout")
+		profileLayers = flag.Bool("profile", false, "dump statistics of all intermediate variables to stderr")
+	)
+	flag.Var(&inputs, "input", "input as [name=]path, repeatable")
+	flag.Var(&inputDims, "input-dims", "override input dims as name=1,3,224,224, repeatable")
+	flag.Var(&outputs, "output", "output variable name, repeatable")
+	flag.Var(&internals, "internal", "output variable name taken from internal of the model, repeatable")
+	imageOpts.register(flag.CommandLine)
+	flag.Parse()
+
+	if *listBackends {
+		for _, b := range menoh.SupportedBackends() {
+			fmt.Println(b)
+		}
+		return
+	}
+	if err := run(*modelPath, *backend, *backendConfig, *list, *profileLayers, *format, *outPath,
+		inputs, inputDims, outputs, internals, &imageOpts); err != nil {
+		fmt.Fprintf(os.Stderr, "menoh-run: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func run(modelPath, backend, backendConfig string, list, profileLayers bool, format, outPath string,
+	inputs, inputDims, outputs, internals []string, imageOpts *imageOptions) error {
+
+	if modelPath == "" {
+		return fmt.Errorf("-model is required")
+	}
+	model, err := onnx.LoadONNXModelFromFile(modelPath)
+	if err != nil {
+		return err
+	}
+	if list {
+		printVariables(os.Stdout, model)
+		return nil
+	}
+	typeBackend, err := menoh.ParseBackend(backend)
+	if err != nil {
+		return err
+	}
+
+	tensors, err := loadInputs(model, inputs, imageOpts)
+	if err != nil {
+		return err
+	}
+	conf := menoh.Config{
+		ONNXModelPath: modelPath,
+		Backend:       typeBackend,
+		BackendConfig: backendConfig,
+	}
+	if conf.Inputs, err = inputConfigs(tensors, inputDims); err != nil {
+		return err
+	}
+	conf.Outputs = outputConfigs(model, outputs, internals)
+
+	var runner menoh.Inferencer
+	if profileLayers {
+		runner, err = profile.NewProfiler(conf, os.Stderr)
+	} else {
+		runner, err = menoh.NewRunner(conf)
+	}
+	if err != nil {
+		return fmt.Errorf("cannot build runner, %v", err)
+	}
+	defer runner.Stop()
+	if err := runner.Run(tensors); err != nil {
+		return fmt.Errorf("cannot run, %v", err)
+	}
+
+	names := make([]string, len(conf.Outputs))
+	for i, c := range conf.Outputs {
+		names[i] = c.Name
+	}
+	return writeOutputs(runner.Outputs(), names, format, outPath)
+}
+
+func outputConfigs(model *onnx.ModelProto, outputs, internals []string) []menoh.OutputConfig {
+	if len(outputs) == 0 && len(internals) == 0 {
+		for _, v := range model.GetGraph().GetOutput() {
+			outputs = append(outputs, v.GetName())
+		}
+	}
+	configs := []menoh.OutputConfig{}
+	for _, name := range outputs {
+		configs = append(configs, menoh.OutputConfig{
+			Name:  name,
+			Dtype: menoh.TypeFloat,
+		})
+	}
+	for _, name := range internals {
+		configs = append(configs, menoh.OutputConfig{
+			Name:         name,
+			Dtype:        menoh.TypeFloat,
+			FromInternal: true,
+		})
+	}
+	return configs
+}
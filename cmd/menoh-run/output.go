@@ -0,0 +1,140 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pfnet-research/go-menoh"
+	"github.com/pfnet-research/go-menoh/tools/npy"
+	"github.com/pfnet-research/go-menoh/tools/onnx"
+)
+
+type jsonTensor struct {
+	Name string    `json:"name"`
+	Dims []int32   `json:"dims"`
+	Data []float32 `json:"data"`
+}
+
+// writeOutputs writes outputs, ordered by names, with the format. text and
+// json are written to stdout when outPath is empty.
+func writeOutputs(outputs map[string]menoh.Tensor, names []string, format, outPath string) error {
+	switch format {
+	case "text", "json":
+		w := io.Writer(os.Stdout)
+		if outPath != "" {
+			f, err := os.Create(outPath)
+			if err != nil {
+				return fmt.Errorf("cannot create '%s', %v", outPath, err)
+			}
+			defer f.Close()
+			w = f
+		}
+		if format == "text" {
+			return writeText(w, outputs, names)
+		}
+		return writeJSON(w, outputs, names)
+	case "npz":
+		if outPath == "" {
+			return fmt.Errorf("-out is required for format '%s'", format)
+		}
+		return npy.SaveNpzToFile(outPath, outputs, false)
+	case "pb", "npy":
+		if outPath == "" {
+			return fmt.Errorf("-out is required for format '%s'", format)
+		}
+		if err := os.MkdirAll(outPath, 0755); err != nil {
+			return fmt.Errorf("cannot create '%s', %v", outPath, err)
+		}
+		for _, name := range names {
+			path := filepath.Join(outPath, fileName(name)+"."+format)
+			if err := saveTensor(path, name, outputs[name], format); err != nil {
+				return err
+			}
+		}
+		return nil
+	default:
+		return fmt.Errorf("format '%s' is not supported", format)
+	}
+}
+
+func saveTensor(path, name string, t menoh.Tensor, format string) error {
+	if format == "npy" {
+		return npy.SaveNpyToFile(path, t)
+	}
+	proto, err := onnx.ConvertToONNXTensor(name, t)
+	if err != nil {
+		return err
+	}
+	return onnx.SaveONNXTensorToFile(path, proto)
+}
+
+// fileName replaces characters not suitable for file name, ONNX variable
+// names sometimes contain "/" or ":".
+func fileName(name string) string {
+	return strings.NewReplacer("/", "_", "\\", "_", ":", "_").Replace(name)
+}
+
+func writeText(w io.Writer, outputs map[string]menoh.Tensor, names []string) error {
+	for _, name := range names {
+		floats, err := outputs[name].FloatArray()
+		if err != nil {
+			return err
+		}
+		if _, err := fmt.Fprintf(w, "%s %v\n%v\n", name, outputs[name].Shape(), floats); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func writeJSON(w io.Writer, outputs map[string]menoh.Tensor, names []string) error {
+	tensors := []jsonTensor{}
+	for _, name := range names {
+		floats, err := outputs[name].FloatArray()
+		if err != nil {
+			return err
+		}
+		tensors = append(tensors, jsonTensor{
+			Name: name,
+			Dims: outputs[name].Shape(),
+			Data: floats,
+		})
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(tensors)
+}
+
+// printVariables prints graph inputs, parameters, internal variables and
+// graph outputs of the model.
+func printVariables(w io.Writer, model *onnx.ModelProto) {
+	graph := model.GetGraph()
+	fmt.Fprintln(w, "inputs:")
+	for _, v := range onnx.GraphInputs(graph) {
+		fmt.Fprintf(w, "  %s %v\n", v.GetName(), onnx.ValueInfoDims(v))
+	}
+	fmt.Fprintln(w, "parameters:")
+	for _, t := range graph.GetInitializer() {
+		fmt.Fprintf(w, "  %s %v\n", t.GetName(), t.GetDims())
+	}
+	outputs := map[string]bool{}
+	for _, v := range graph.GetOutput() {
+		outputs[v.GetName()] = true
+	}
+	fmt.Fprintln(w, "internals:")
+	for _, n := range graph.GetNode() {
+		for _, o := range n.GetOutput() {
+			if !outputs[o] {
+				fmt.Fprintf(w, "  %s (%s)\n", o, n.GetOpType())
+			}
+		}
+	}
+	fmt.Fprintln(w, "outputs:")
+	for _, v := range graph.GetOutput() {
+		fmt.Fprintf(w, "  %s %v\n", v.GetName(), onnx.ValueInfoDims(v))
+	}
+}
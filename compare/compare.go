@@ -0,0 +1,223 @@
+/*
+Package compare runs same inputs through two targets, like different models,
+backends, backend configurations or builds of Menoh library, and reports
+numerical differences of outputs and, optionally, of all layers.
+
+	report, err := compare.Run(
+		&compare.RunnerTarget{Config: menoh.Config{ONNXModelPath: "model.onnx", Backend: menoh.TypeMKLDNN}},
+		&compare.RunnerTarget{Config: menoh.Config{ONNXModelPath: "model.onnx", Backend: menoh.TypeGeneric}},
+		datasets, compare.Options{Outputs: []string{"prob"}})
+
+Datasets are loaded by conformance.LoadDatasets, laid out like ONNX model zoo.
+*/
+package compare
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"text/tabwriter"
+
+	"github.com/pfnet-research/go-menoh"
+	"github.com/pfnet-research/go-menoh/conformance"
+)
+
+// Options is setup information to compare.
+type Options struct {
+	Outputs []string // compared outputs
+	Layers  []string // compared intermediate variables, taken as internal outputs
+}
+
+// OutputStats is differences of an output of target B from target A over
+// datasets.
+type OutputStats struct {
+	Name        string
+	Layer       bool // true for an intermediate variable
+	Samples     int
+	MaxAbsError float64 // max of |b - a|
+	MaxRelError float64 // max of |b - a| / |a|
+	MinCosine   float64 // min of cosine similarity per sample
+	MeanCosine  float64 // mean of cosine similarity per sample
+	ArgmaxRows  int     // number of compared rows, argmax is taken on the last axis
+	ArgmaxMatch int     // number of rows whose argmax are same
+
+	cosineSum float64
+}
+
+// ArgmaxAgreement returns the ratio of rows whose argmax are same.
+func (s *OutputStats) ArgmaxAgreement() float64 {
+	if s.ArgmaxRows == 0 {
+		return math.NaN()
+	}
+	return float64(s.ArgmaxMatch) / float64(s.ArgmaxRows)
+}
+
+// Report is a result of comparison.
+type Report struct {
+	Samples int
+	Outputs []*OutputStats
+}
+
+// Run runs inputs of the datasets through both targets and compares the
+// outputs and layers. Expected outputs of the datasets are not used.
+func Run(a, b Target, datasets []*conformance.Dataset, opts Options) (*Report, error) {
+	report := &Report{}
+	stats := map[string]*OutputStats{}
+	for _, name := range opts.Outputs {
+		s := &OutputStats{Name: name, MinCosine: math.Inf(1)}
+		stats[name] = s
+		report.Outputs = append(report.Outputs, s)
+	}
+	for _, name := range opts.Layers {
+		s := &OutputStats{Name: name, Layer: true, MinCosine: math.Inf(1)}
+		stats[name] = s
+		report.Outputs = append(report.Outputs, s)
+	}
+
+	for _, d := range datasets {
+		outA, err := a.Run(d.Inputs, opts.Outputs, opts.Layers)
+		if err != nil {
+			return nil, fmt.Errorf("target A cannot run %s, %v", d.Name, err)
+		}
+		outB, err := b.Run(d.Inputs, opts.Outputs, opts.Layers)
+		if err != nil {
+			return nil, fmt.Errorf("target B cannot run %s, %v", d.Name, err)
+		}
+		for _, s := range report.Outputs {
+			ta, ok := outA[s.Name]
+			if !ok {
+				return nil, fmt.Errorf("%s is not output by target A on %s", s.Name, d.Name)
+			}
+			tb, ok := outB[s.Name]
+			if !ok {
+				return nil, fmt.Errorf("%s is not output by target B on %s", s.Name, d.Name)
+			}
+			if err := s.add(ta, tb); err != nil {
+				return nil, fmt.Errorf("cannot compare %s on %s, %v", s.Name, d.Name, err)
+			}
+		}
+		report.Samples++
+	}
+	for _, s := range report.Outputs {
+		if s.Samples > 0 {
+			s.MeanCosine = s.cosineSum / float64(s.Samples)
+		}
+	}
+	return report, nil
+}
+
+func (s *OutputStats) add(a, b menoh.Tensor) error {
+	r := conformance.Compare(s.Name, b, a, conformance.Tolerance{}, 0)
+	if r.Err != nil {
+		return r.Err
+	}
+	s.MaxAbsError = math.Max(s.MaxAbsError, r.MaxAbsError)
+	s.MaxRelError = math.Max(s.MaxRelError, r.MaxRelError)
+
+	fa, _ := a.FloatArray()
+	fb, _ := b.FloatArray()
+	c := cosine(fa, fb)
+	s.MinCosine = math.Min(s.MinCosine, c)
+	s.cosineSum += c
+
+	cols := len(fa)
+	if dims := a.Shape(); len(dims) > 0 && dims[len(dims)-1] > 0 {
+		cols = int(dims[len(dims)-1])
+	}
+	for i := 0; i+cols <= len(fa) && cols > 0; i += cols {
+		s.ArgmaxRows++
+		if argmax(fa[i:i+cols]) == argmax(fb[i:i+cols]) {
+			s.ArgmaxMatch++
+		}
+	}
+	s.Samples++
+	return nil
+}
+
+// cosine returns cosine similarity, 1 when both are zero vectors.
+func cosine(a, b []float32) float64 {
+	var dot, na, nb float64
+	for i := range a {
+		x, y := float64(a[i]), float64(b[i])
+		dot += x * y
+		na += x * x
+		nb += y * y
+	}
+	if na == 0 && nb == 0 {
+		return 1
+	}
+	if na == 0 || nb == 0 {
+		return 0
+	}
+	return dot / math.Sqrt(na*nb)
+}
+
+func argmax(a []float32) int {
+	idx := 0
+	for i, v := range a {
+		if v > a[idx] {
+			idx = i
+		}
+	}
+	return idx
+}
+
+// WriteTable writes the report as human-readable table, layers are marked
+// with "*".
+func (r *Report) WriteTable(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(tw, "samples\t%d\n", r.Samples)
+	fmt.Fprintln(tw)
+	fmt.Fprintln(tw, "output\tmax abs\tmax rel\tmin cos\tmean cos\targmax")
+	for _, s := range r.Outputs {
+		name := s.Name
+		if s.Layer {
+			name += " *"
+		}
+		fmt.Fprintf(tw, "%s\t%.4g\t%.4g\t%.6f\t%.6f\t%d/%d\n", name, s.MaxAbsError, s.MaxRelError,
+			s.MinCosine, s.MeanCosine, s.ArgmaxMatch, s.ArgmaxRows)
+	}
+	return tw.Flush()
+}
+
+// WriteJSON writes the report as JSON, values not finite like NaN are
+// written as strings.
+func (r *Report) WriteJSON(w io.Writer) error {
+	type outputJSON struct {
+		Name            string      `json:"name"`
+		Layer           bool        `json:"layer,omitempty"`
+		Samples         int         `json:"samples"`
+		MaxAbsError     interface{} `json:"max_abs_error"`
+		MaxRelError     interface{} `json:"max_rel_error"`
+		MinCosine       interface{} `json:"min_cosine"`
+		MeanCosine      interface{} `json:"mean_cosine"`
+		ArgmaxAgreement interface{} `json:"argmax_agreement"`
+	}
+	v := struct {
+		Samples int          `json:"samples"`
+		Outputs []outputJSON `json:"outputs"`
+	}{Samples: r.Samples, Outputs: []outputJSON{}}
+	for _, s := range r.Outputs {
+		v.Outputs = append(v.Outputs, outputJSON{
+			Name:            s.Name,
+			Layer:           s.Layer,
+			Samples:         s.Samples,
+			MaxAbsError:     jsonFloat(s.MaxAbsError),
+			MaxRelError:     jsonFloat(s.MaxRelError),
+			MinCosine:       jsonFloat(s.MinCosine),
+			MeanCosine:      jsonFloat(s.MeanCosine),
+			ArgmaxAgreement: jsonFloat(s.ArgmaxAgreement()),
+		})
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+func jsonFloat(f float64) interface{} {
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return fmt.Sprint(f)
+	}
+	return f
+}
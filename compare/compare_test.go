@@ -0,0 +1,147 @@
+package compare
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/pfnet-research/go-menoh"
+	"github.com/pfnet-research/go-menoh/conformance"
+)
+
+// funcTarget returns outputs made by f from the input "x".
+type funcTarget func(x []float32) []float32
+
+func (f funcTarget) Run(inputs map[string]menoh.Tensor, outputs, internals []string) (
+	map[string]menoh.Tensor, error) {
+
+	x, err := inputs["x"].FloatArray()
+	if err != nil {
+		return nil, err
+	}
+	results := map[string]menoh.Tensor{}
+	for _, name := range append(append([]string{}, outputs...), internals...) {
+		y := f(x)
+		results[name] = &menoh.FloatTensor{Dims: []int32{int32(len(y) / 2), 2}, Array: y}
+	}
+	return results, nil
+}
+
+func makeDatasets(xs ...[]float32) []*conformance.Dataset {
+	datasets := []*conformance.Dataset{}
+	for i, x := range xs {
+		datasets = append(datasets, &conformance.Dataset{
+			Name: fmt.Sprintf("test_data_set_%d", i),
+			Inputs: map[string]menoh.Tensor{
+				"x": &menoh.FloatTensor{Dims: []int32{1, int32(len(x))}, Array: x},
+			},
+		})
+	}
+	return datasets
+}
+
+func TestRun(t *testing.T) {
+	a := funcTarget(func(x []float32) []float32 { return append([]float32{}, x...) })
+	b := funcTarget(func(x []float32) []float32 {
+		y := append([]float32{}, x...)
+		y[0] += 0.5 // changes argmax of the first row in the second dataset
+		return y
+	})
+	datasets := makeDatasets([]float32{2, 1, 0, 1}, []float32{1, 1.2, 3, 4})
+
+	report, err := Run(a, b, datasets, Options{Outputs: []string{"y"}, Layers: []string{"h"}})
+	if err != nil {
+		t.Fatalf("unexpected error, %v", err)
+	}
+	if report.Samples != 2 {
+		t.Errorf("samples should be 2, but %d", report.Samples)
+	}
+	if len(report.Outputs) != 2 {
+		t.Fatalf("outputs and layers should be reported, but %d", len(report.Outputs))
+	}
+	s := report.Outputs[0]
+	if s.Name != "y" || s.Layer || s.Samples != 2 {
+		t.Errorf("unexpected output stats, %+v", s)
+	}
+	if s.MaxAbsError != 0.5 {
+		t.Errorf("max abs error should be 0.5, but %v", s.MaxAbsError)
+	}
+	if s.MaxRelError != 0.5 {
+		t.Errorf("max rel error should be 0.5, but %v", s.MaxRelError)
+	}
+	if s.ArgmaxMatch != 3 || s.ArgmaxRows != 4 || s.ArgmaxAgreement() != 0.75 {
+		t.Errorf("argmax should agree on 3/4 rows, but %d/%d", s.ArgmaxMatch, s.ArgmaxRows)
+	}
+	if s.MinCosine >= 1 || s.MinCosine > s.MeanCosine || s.MeanCosine >= 1 {
+		t.Errorf("unexpected cosine similarity, min %v, mean %v", s.MinCosine, s.MeanCosine)
+	}
+	if !report.Outputs[1].Layer || report.Outputs[1].Name != "h" {
+		t.Errorf("layer should be reported, %+v", report.Outputs[1])
+	}
+
+	table := &bytes.Buffer{}
+	if err := report.WriteTable(table); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(table.String(), "h *") || !strings.Contains(table.String(), "3/4") {
+		t.Errorf("unexpected table,\n%s", table)
+	}
+	j := &bytes.Buffer{}
+	if err := report.WriteJSON(j); err != nil {
+		t.Fatal(err)
+	}
+	v := struct {
+		Samples int `json:"samples"`
+		Outputs []struct {
+			Name            string  `json:"name"`
+			ArgmaxAgreement float64 `json:"argmax_agreement"`
+		} `json:"outputs"`
+	}{}
+	if err := json.Unmarshal(j.Bytes(), &v); err != nil {
+		t.Fatalf("invalid JSON, %v", err)
+	}
+	if v.Samples != 2 || len(v.Outputs) != 2 || v.Outputs[0].ArgmaxAgreement != 0.75 {
+		t.Errorf("unexpected JSON, %s", j)
+	}
+}
+
+func TestRunFail(t *testing.T) {
+	a := funcTarget(func(x []float32) []float32 { return x })
+	b := funcTarget(func(x []float32) []float32 { return append(x, 0, 0) })
+	_, err := Run(a, b, makeDatasets([]float32{0, 1}), Options{Outputs: []string{"y"}})
+	if err == nil {
+		t.Error("shape mismatch should be error")
+	}
+}
+
+func TestCosine(t *testing.T) {
+	var testCases = []struct {
+		a, b     []float32
+		expected float64
+	}{
+		{[]float32{1, 0}, []float32{2, 0}, 1},
+		{[]float32{1, 0}, []float32{0, 1}, 0},
+		{[]float32{1, 0}, []float32{-1, 0}, -1},
+		{[]float32{0, 0}, []float32{0, 0}, 1},
+		{[]float32{0, 0}, []float32{1, 0}, 0},
+	}
+	for _, tc := range testCases {
+		if actual := cosine(tc.a, tc.b); math.Abs(actual-tc.expected) > 1e-9 {
+			t.Errorf("cosine of %v and %v should be %v, but %v", tc.a, tc.b, tc.expected, actual)
+		}
+	}
+}
+
+func TestWriteJSONNotFinite(t *testing.T) {
+	report := &Report{Outputs: []*OutputStats{{Name: "y", MinCosine: math.Inf(1)}}}
+	w := &bytes.Buffer{}
+	if err := report.WriteJSON(w); err != nil {
+		t.Fatalf("not finite values should be written, %v", err)
+	}
+	if !strings.Contains(w.String(), `"+Inf"`) || !strings.Contains(w.String(), `"NaN"`) {
+		t.Errorf("not finite values should be strings, %s", w)
+	}
+}
@@ -0,0 +1,131 @@
+package compare
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/pfnet-research/go-menoh"
+	"github.com/pfnet-research/go-menoh/tools/npy"
+)
+
+// Target runs inputs and returns the outputs and internal variables.
+type Target interface {
+	Run(inputs map[string]menoh.Tensor, outputs, internals []string) (map[string]menoh.Tensor, error)
+}
+
+// RunnerTarget runs in process, with a runner built for each run. Inputs and
+// outputs of Config are configured by the run.
+type RunnerTarget struct {
+	Config menoh.Config
+}
+
+// Run builds a runner, runs the inputs and returns copies of the outputs.
+func (t *RunnerTarget) Run(inputs map[string]menoh.Tensor, outputs, internals []string) (
+	map[string]menoh.Tensor, error) {
+
+	conf := t.Config
+	conf.Inputs = nil
+	conf.Outputs = nil
+	names := make([]string, 0, len(inputs))
+	for name := range inputs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		conf.Inputs = append(conf.Inputs, menoh.InputConfig{
+			Name:  name,
+			Dtype: menoh.TypeFloat,
+			Dims:  inputs[name].Shape(),
+		})
+	}
+	for _, name := range outputs {
+		conf.Outputs = append(conf.Outputs, menoh.OutputConfig{Name: name, Dtype: menoh.TypeFloat})
+	}
+	for _, name := range internals {
+		conf.Outputs = append(conf.Outputs, menoh.OutputConfig{
+			Name:         name,
+			Dtype:        menoh.TypeFloat,
+			FromInternal: true,
+		})
+	}
+	runner, err := menoh.NewRunner(conf)
+	if err != nil {
+		return nil, fmt.Errorf("cannot build runner, %v", err)
+	}
+	defer runner.Stop()
+	if err := runner.Run(inputs); err != nil {
+		return nil, fmt.Errorf("cannot run, %v", err)
+	}
+	results := map[string]menoh.Tensor{}
+	for name, out := range runner.Outputs() {
+		a, err := out.FloatArray()
+		if err != nil {
+			return nil, err
+		}
+		results[name] = &menoh.FloatTensor{
+			Dims:  append([]int32{}, out.Shape()...),
+			Array: append([]float32{}, a...),
+		}
+	}
+	return results, nil
+}
+
+// CommandTarget runs menoh-run command as a subprocess, to compare with
+// another build of Menoh library. Inputs and outputs are passed by NumPy
+// npz files in a temporary directory.
+type CommandTarget struct {
+	// Command is menoh-run command and its leading arguments, like
+	// {"env", "LD_LIBRARY_PATH=/opt/menoh-1.0/lib", "menoh-run"}
+	Command       []string
+	Model         string
+	Backend       string
+	BackendConfig string
+}
+
+// Run runs the command and loads the outputs.
+func (t *CommandTarget) Run(inputs map[string]menoh.Tensor, outputs, internals []string) (
+	map[string]menoh.Tensor, error) {
+
+	if len(t.Command) == 0 {
+		return nil, fmt.Errorf("command is empty")
+	}
+	dir, err := ioutil.TempDir("", "menoh-compare-")
+	if err != nil {
+		return nil, fmt.Errorf("cannot make temporary directory, %v", err)
+	}
+	defer os.RemoveAll(dir)
+	inPath := filepath.Join(dir, "inputs.npz")
+	outPath := filepath.Join(dir, "outputs.npz")
+	if err := npy.SaveNpzToFile(inPath, inputs, false); err != nil {
+		return nil, err
+	}
+
+	args := append([]string{}, t.Command[1:]...)
+	args = append(args, "-model", t.Model, "-input", inPath, "-format", "npz", "-out", outPath)
+	if t.Backend != "" {
+		args = append(args, "-backend", t.Backend)
+	}
+	if t.BackendConfig != "" {
+		args = append(args, "-backend-config", t.BackendConfig)
+	}
+	for _, name := range outputs {
+		args = append(args, "-output", name)
+	}
+	for _, name := range internals {
+		args = append(args, "-internal", name)
+	}
+	cmd := exec.Command(t.Command[0], args...)
+	stderr := &bytes.Buffer{}
+	cmd.Stderr = stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("cannot run '%s', %v: %s", strings.Join(t.Command, " "), err,
+			strings.TrimSpace(stderr.String()))
+	}
+	return npy.LoadNpzFromFile(outPath)
+}
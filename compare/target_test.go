@@ -0,0 +1,57 @@
+package compare
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/pfnet-research/go-menoh"
+	"github.com/pfnet-research/go-menoh/external/errcode"
+	"github.com/pfnet-research/go-menoh/menohtest"
+)
+
+func TestRunnerTarget(t *testing.T) {
+	fake := menohtest.New()
+	fake.Output("y", 1, 2)
+	fake.Output("h", 1, 2)
+	fake.Default(map[string][]float32{"y": {1, 2}, "h": {3, 4}})
+	backend := fake.Register("compare_test_fake")
+	defer menoh.RegisterBackend(backend, nil)
+
+	target := &RunnerTarget{Config: menoh.Config{ONNXModelPath: "model.onnx", Backend: backend}}
+	inputs := map[string]menoh.Tensor{"x": &menoh.FloatTensor{Dims: []int32{1, 2}, Array: []float32{0, 1}}}
+	outputs, err := target.Run(inputs, []string{"y"}, []string{"h"})
+	if err != nil {
+		t.Fatalf("unexpected error, %v", err)
+	}
+	for name, expected := range map[string][]float32{"y": {1, 2}, "h": {3, 4}} {
+		out, ok := outputs[name]
+		if !ok {
+			t.Errorf("%s should be output", name)
+			continue
+		}
+		actual, _ := out.FloatArray()
+		if !reflect.DeepEqual(actual, expected) {
+			t.Errorf("%s should be %v, but %v", name, expected, actual)
+		}
+	}
+	if fake.RunCount() != 1 {
+		t.Errorf("runner should run once, but %d", fake.RunCount())
+	}
+
+	fake.FailBuild(menohtest.NewError(errcode.VariableNotFound, "y"))
+	if _, err := target.Run(inputs, []string{"y"}, nil); err == nil {
+		t.Error("failure of building should be error")
+	}
+}
+
+func TestCommandTargetFail(t *testing.T) {
+	target := &CommandTarget{}
+	if _, err := target.Run(nil, nil, nil); err == nil {
+		t.Error("empty command should be error")
+	}
+	target = &CommandTarget{Command: []string{"false"}, Model: "model.onnx"}
+	inputs := map[string]menoh.Tensor{"x": &menoh.FloatTensor{Dims: []int32{1}, Array: []float32{0}}}
+	if _, err := target.Run(inputs, []string{"y"}, nil); err == nil {
+		t.Error("failure of the command should be error")
+	}
+}
@@ -1,12 +1,31 @@
 package menoh
 
+import (
+	"fmt"
+)
+
 // Config is setup information to build Menoh model.
 type Config struct {
+	Name          string         // model name for metrics, base name of ONNXModelPath if empty
 	ONNXModelPath string         // path of ONNX file
 	Backend       TypeBackend    // backend type, like menoh.TypeMKLDNN
-	BackendConfig string         // backend configuration
+	BackendConfig string         // backend configuration in JSON, see SetBackendConfig
 	Inputs        []InputConfig  // list of input configuration
 	Outputs       []OutputConfig // list of output configuration
+
+	// Optimize makes the backend optimize the model for the inputs and
+	// outputs on building, like pruning nodes unreachable from the outputs,
+	// see Optimizer.
+	Optimize bool
+
+	// Metrics records runs and builds of runners if set, see Metrics.
+	Metrics *Metrics
+	// Hooks are called on building and running runners if set, see
+	// RunnerHooks.
+	Hooks RunnerHooks
+	// Logger logs building and stopping runners if set, otherwise the logger
+	// set by SetLogger is used.
+	Logger Logger
 }
 
 // InputConfig is input variable information to pass to the model.
@@ -14,6 +33,18 @@ type InputConfig struct {
 	Name  string    // layer name
 	Dtype TypeDtype // data type
 	Dims  []int32   // list of dimension size
+
+	// Preprocess is a hint how to make the input from raw data like image,
+	// Runner does not apply it.
+	Preprocess *Preprocess
+}
+
+// Preprocess is a hint to make an input tensor from an image.
+type Preprocess struct {
+	Resize       []int32   `json:"resize,omitempty" yaml:"resize,omitempty,flow"`          // width and height
+	ChannelOrder string    `json:"channel_order,omitempty" yaml:"channel_order,omitempty"` // "rgb" or "bgr"
+	Mean         []float32 `json:"mean,omitempty" yaml:"mean,omitempty,flow"`              // subtracted from each channel
+	Scale        float32   `json:"scale,omitempty" yaml:"scale,omitempty"`                 // multiplied after subtracting mean
 }
 
 // OutputConfig is output variable information to get from the model.
@@ -21,6 +52,17 @@ type OutputConfig struct {
 	Name         string    // layer name
 	Dtype        TypeDtype // data type
 	FromInternal bool      // if the output comes from operator variable such as weight, set true
+
+	// Postprocess is a hint how to interpret the output, Runner does not
+	// apply it.
+	Postprocess *Postprocess
+}
+
+// Postprocess is a hint to interpret an output tensor as classification.
+type Postprocess struct {
+	Softmax bool   `json:"softmax,omitempty" yaml:"softmax,omitempty"` // apply softmax to the output
+	TopK    int    `json:"top_k,omitempty" yaml:"top_k,omitempty"`     // number of reported classes
+	Labels  string `json:"labels,omitempty" yaml:"labels,omitempty"`   // path of label file, a label per line
 }
 
 // TypeDtype is a type of data.
@@ -32,20 +74,22 @@ const (
 	TypeFloat
 )
 
-// TypeBackend is a type of backend, like MKL-DNN.
-type TypeBackend int
-
-const (
-	typeUnknownBackend TypeBackend = iota
-	// TypeMKLDNN is a TypeBackend of MKL-DNN
-	TypeMKLDNN
-)
-
-func (t TypeBackend) String() string {
+func (t TypeDtype) String() string {
 	switch t {
-	case TypeMKLDNN:
-		return "mkldnn"
+	case TypeFloat:
+		return "float32"
 	default:
 		return "unknown"
 	}
 }
+
+// ParseDtype returns TypeDtype named s, like "float32". "float" is accepted
+// as an alias of "float32" and empty string is treated as TypeFloat.
+func ParseDtype(s string) (TypeDtype, error) {
+	switch s {
+	case "", "float", "float32":
+		return TypeFloat, nil
+	default:
+		return typeUnknownDtype, fmt.Errorf("dtype '%s' is not supported", s)
+	}
+}
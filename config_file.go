@@ -0,0 +1,379 @@
+package menoh
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+// configFile is a file representation of Config.
+type configFile struct {
+	Name          string       `json:"name,omitempty" yaml:"name,omitempty"`
+	Model         string       `json:"model,omitempty" yaml:"model,omitempty"`
+	Backend       string       `json:"backend" yaml:"backend"`
+	BackendConfig interface{}  `json:"backend_config,omitempty" yaml:"backend_config,omitempty"`
+	Optimize      bool         `json:"optimize,omitempty" yaml:"optimize,omitempty"`
+	Inputs        []inputFile  `json:"inputs" yaml:"inputs"`
+	Outputs       []outputFile `json:"outputs,omitempty" yaml:"outputs,omitempty"`
+}
+
+type inputFile struct {
+	Name       string      `json:"name" yaml:"name"`
+	Dtype      string      `json:"dtype" yaml:"dtype"`
+	Dims       []int32     `json:"dims" yaml:"dims,flow"`
+	Preprocess *Preprocess `json:"preprocess,omitempty" yaml:"preprocess,omitempty"`
+}
+
+type outputFile struct {
+	Name         string       `json:"name" yaml:"name"`
+	Dtype        string       `json:"dtype" yaml:"dtype"`
+	FromInternal bool         `json:"from_internal,omitempty" yaml:"from_internal,omitempty"`
+	Postprocess  *Postprocess `json:"postprocess,omitempty" yaml:"postprocess,omitempty"`
+}
+
+// LoadConfig returns Config loaded from JSON (".json") or YAML (".yaml",
+// ".yml") file placed on the path, the format is detected by the extension.
+// A relative model path is resolved from the directory of the file. Backend
+// is "mkldnn" when omitted, names of custom backends must be registered by
+// CustomBackend in advance. The loaded configuration is validated.
+//
+//	model: mlp.onnx
+//	backend: mkldnn
+//	backend_config: {"cpu_id": 0}
+//	optimize: true
+//	inputs:
+//	  - name: input
+//	    dtype: float32
+//	    dims: [1, 3]
+//	outputs:
+//	  - name: fc2
+//	    dtype: float32
+func LoadConfig(path string) (Config, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return Config{}, fmt.Errorf("cannot load '%s', %v", path, err)
+	}
+	format, err := configFormat(path)
+	if err != nil {
+		return Config{}, err
+	}
+	conf, err := UnmarshalConfig(b, format)
+	if err != nil {
+		return Config{}, fmt.Errorf("cannot load '%s', %v", path, err)
+	}
+	if conf.ONNXModelPath != "" && !filepath.IsAbs(conf.ONNXModelPath) {
+		conf.ONNXModelPath = filepath.Join(filepath.Dir(path), conf.ONNXModelPath)
+	}
+	return conf, nil
+}
+
+func configFormat(path string) (string, error) {
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".json":
+		return "json", nil
+	case ".yaml", ".yml":
+		return "yaml", nil
+	default:
+		return "", fmt.Errorf("config format '%s' is not supported", ext)
+	}
+}
+
+// UnmarshalConfig parses configuration data with the format, "json" or
+// "yaml", and validates it.
+func UnmarshalConfig(data []byte, format string) (Config, error) {
+	f := configFile{}
+	switch format {
+	case "json":
+		if err := json.Unmarshal(data, &f); err != nil {
+			return Config{}, fmt.Errorf("cannot parse JSON, %v", err)
+		}
+	case "yaml":
+		if err := yaml.Unmarshal(data, &f); err != nil {
+			return Config{}, fmt.Errorf("cannot parse YAML, %v", err)
+		}
+	default:
+		return Config{}, fmt.Errorf("config format '%s' is not supported", format)
+	}
+
+	errs := ValidationErrors{}
+	conf := Config{Name: f.Name, ONNXModelPath: f.Model, Optimize: f.Optimize}
+	conf.Backend = TypeMKLDNN
+	if f.Backend != "" {
+		backend, err := ParseBackend(f.Backend)
+		if err != nil {
+			errs = append(errs, &ValidationError{Field: "backend", Message: err.Error()})
+		}
+		conf.Backend = backend
+	}
+	switch c := f.BackendConfig.(type) {
+	case nil:
+	case string:
+		conf.BackendConfig = c
+	default:
+		b, err := json.Marshal(jsonCompatible(c))
+		if err != nil {
+			errs = append(errs, &ValidationError{Field: "backend_config", Message: err.Error()})
+		}
+		conf.BackendConfig = string(b)
+	}
+	for i, in := range f.Inputs {
+		dtype, err := ParseDtype(in.Dtype)
+		if err != nil {
+			errs = append(errs, &ValidationError{
+				Field: fmt.Sprintf("inputs[%d].dtype", i), Message: err.Error()})
+		}
+		conf.Inputs = append(conf.Inputs, InputConfig{
+			Name:       in.Name,
+			Dtype:      dtype,
+			Dims:       in.Dims,
+			Preprocess: in.Preprocess,
+		})
+	}
+	for i, out := range f.Outputs {
+		dtype, err := ParseDtype(out.Dtype)
+		if err != nil {
+			errs = append(errs, &ValidationError{
+				Field: fmt.Sprintf("outputs[%d].dtype", i), Message: err.Error()})
+		}
+		conf.Outputs = append(conf.Outputs, OutputConfig{
+			Name:         out.Name,
+			Dtype:        dtype,
+			FromInternal: out.FromInternal,
+			Postprocess:  out.Postprocess,
+		})
+	}
+	if len(errs) > 0 {
+		return Config{}, errs
+	}
+	if err := conf.Validate(); err != nil {
+		return Config{}, err
+	}
+	return conf, nil
+}
+
+// jsonCompatible converts maps decoded from YAML, keyed by interface{}, to
+// maps keyed by string to be encoded to JSON.
+func jsonCompatible(v interface{}) interface{} {
+	switch e := v.(type) {
+	case map[interface{}]interface{}:
+		m := map[string]interface{}{}
+		for k, c := range e {
+			m[fmt.Sprint(k)] = jsonCompatible(c)
+		}
+		return m
+	case map[string]interface{}:
+		m := map[string]interface{}{}
+		for k, c := range e {
+			m[k] = jsonCompatible(c)
+		}
+		return m
+	case []interface{}:
+		l := make([]interface{}, len(e))
+		for i, c := range e {
+			l[i] = jsonCompatible(c)
+		}
+		return l
+	default:
+		return v
+	}
+}
+
+// SaveConfig saves the configuration to JSON (".json") or YAML (".yaml",
+// ".yml") file placed on the path, the format is detected by the extension.
+// The model path is written relative to the directory of the file, so the
+// file is loaded to same configuration by LoadConfig.
+func SaveConfig(path string, conf Config) error {
+	format, err := configFormat(path)
+	if err != nil {
+		return err
+	}
+	if conf.ONNXModelPath != "" {
+		model, err := relativeModelPath(filepath.Dir(path), conf.ONNXModelPath)
+		if err != nil {
+			return fmt.Errorf("cannot save '%s', %v", path, err)
+		}
+		conf.ONNXModelPath = model
+	}
+	b, err := MarshalConfig(conf, format)
+	if err != nil {
+		return fmt.Errorf("cannot save '%s', %v", path, err)
+	}
+	if err := ioutil.WriteFile(path, b, 0644); err != nil {
+		return fmt.Errorf("cannot save '%s', %v", path, err)
+	}
+	return nil
+}
+
+// relativeModelPath returns the model path relative to dir, or the absolute
+// path if not reachable, like on another volume.
+func relativeModelPath(dir, model string) (string, error) {
+	absModel, err := filepath.Abs(model)
+	if err != nil {
+		return "", err
+	}
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", err
+	}
+	rel, err := filepath.Rel(absDir, absModel)
+	if err != nil {
+		return absModel, nil
+	}
+	return rel, nil
+}
+
+// MarshalConfig returns configuration data with the format, "json" or
+// "yaml". Data is loaded to same configuration by UnmarshalConfig. The model
+// path is written as is, LoadConfig resolves a relative one from the
+// directory of the file, use SaveConfig to write a file for LoadConfig.
+func MarshalConfig(conf Config, format string) ([]byte, error) {
+	if err := conf.Validate(); err != nil {
+		return nil, err
+	}
+	f := configFile{
+		Name:     conf.Name,
+		Model:    conf.ONNXModelPath,
+		Backend:  conf.Backend.String(),
+		Optimize: conf.Optimize,
+		Inputs:   []inputFile{},
+	}
+	if conf.BackendConfig != "" {
+		// embed as an object when possible to be readable
+		var obj map[string]interface{}
+		if err := json.Unmarshal([]byte(conf.BackendConfig), &obj); err == nil {
+			f.BackendConfig = obj
+		} else {
+			f.BackendConfig = conf.BackendConfig
+		}
+	}
+	for _, in := range conf.Inputs {
+		f.Inputs = append(f.Inputs, inputFile{
+			Name:       in.Name,
+			Dtype:      in.Dtype.String(),
+			Dims:       in.Dims,
+			Preprocess: in.Preprocess,
+		})
+	}
+	for _, out := range conf.Outputs {
+		f.Outputs = append(f.Outputs, outputFile{
+			Name:         out.Name,
+			Dtype:        out.Dtype.String(),
+			FromInternal: out.FromInternal,
+			Postprocess:  out.Postprocess,
+		})
+	}
+	switch format {
+	case "json":
+		return json.MarshalIndent(f, "", "  ")
+	case "yaml":
+		return yaml.Marshal(f)
+	default:
+		return nil, fmt.Errorf("config format '%s' is not supported", format)
+	}
+}
+
+// ValidationError is an invalid field of configuration.
+type ValidationError struct {
+	Field   string // path of the field, like "inputs[0].dims[1]"
+	Message string
+}
+
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Field, e.Message)
+}
+
+// ValidationErrors is a list of invalid fields.
+type ValidationErrors []*ValidationError
+
+func (e ValidationErrors) Error() string {
+	msgs := make([]string, len(e))
+	for i, err := range e {
+		msgs[i] = err.Error()
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// Validate checks the configuration and returns ValidationErrors listing
+// all invalid fields.
+func (c Config) Validate() error {
+	errs := ValidationErrors{}
+	add := func(field, format string, args ...interface{}) {
+		errs = append(errs, &ValidationError{Field: field, Message: fmt.Sprintf(format, args...)})
+	}
+	if c.Backend.String() == "unknown" {
+		add("backend", "backend is not set")
+	}
+	if err := RawBackendConfig(c.BackendConfig).Validate(); err != nil {
+		add("backend_config", "%v", err)
+	}
+	if len(c.Inputs) == 0 {
+		add("inputs", "at least one input is required")
+	}
+	names := map[string]bool{}
+	for i, in := range c.Inputs {
+		field := fmt.Sprintf("inputs[%d]", i)
+		if in.Name == "" {
+			add(field+".name", "name is required")
+		} else if names[in.Name] {
+			add(field+".name", "'%s' is duplicated", in.Name)
+		}
+		names[in.Name] = true
+		if in.Dtype.String() == "unknown" {
+			add(field+".dtype", "dtype is not set")
+		}
+		if len(in.Dims) == 0 {
+			add(field+".dims", "dims is required")
+		}
+		for j, d := range in.Dims {
+			if d <= 0 {
+				add(fmt.Sprintf("%s.dims[%d]", field, j), "must be positive, but %d", d)
+			}
+		}
+		if in.Preprocess != nil {
+			in.Preprocess.validate(field+".preprocess", add)
+		}
+	}
+	names = map[string]bool{}
+	for i, out := range c.Outputs {
+		field := fmt.Sprintf("outputs[%d]", i)
+		if out.Name == "" {
+			add(field+".name", "name is required")
+		} else if names[out.Name] {
+			add(field+".name", "'%s' is duplicated", out.Name)
+		}
+		names[out.Name] = true
+		if out.Dtype.String() == "unknown" {
+			add(field+".dtype", "dtype is not set")
+		}
+		if out.Postprocess != nil && out.Postprocess.TopK < 0 {
+			add(field+".postprocess.top_k", "must not be negative, but %d", out.Postprocess.TopK)
+		}
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+func (p *Preprocess) validate(field string, add func(string, string, ...interface{})) {
+	if len(p.Resize) != 0 && len(p.Resize) != 2 {
+		add(field+".resize", "must be [width, height]")
+	}
+	for i, r := range p.Resize {
+		if r <= 0 {
+			add(fmt.Sprintf("%s.resize[%d]", field, i), "must be positive, but %d", r)
+		}
+	}
+	if len(p.Mean) != 0 && len(p.Mean) != 1 && len(p.Mean) != 3 {
+		add(field+".mean", "must have 1 or 3 values, but %d", len(p.Mean))
+	}
+	switch p.ChannelOrder {
+	case "", "rgb", "bgr":
+	default:
+		add(field+".channel_order", "must be rgb or bgr, but '%s'", p.ChannelOrder)
+	}
+}
@@ -0,0 +1,194 @@
+package menoh
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const testYAMLConfig = `name: mlp
+model: MLP.onnx
+backend: mkldnn
+backend_config:
+  cpu_id: 0
+optimize: true
+inputs:
+  - name: input
+    dtype: float32
+    dims: [1, 3]
+    preprocess:
+      resize: [224, 224]
+      channel_order: bgr
+      mean: [103.9, 116.8, 123.7]
+outputs:
+  - name: fc1
+    dtype: float32
+    from_internal: true
+  - name: fc2
+    dtype: float32
+    postprocess:
+      softmax: true
+      top_k: 5
+`
+
+func TestLoadConfig(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "go-menoh-test-")
+	if err != nil {
+		t.Fatal("cannot make temporary directory")
+	}
+	defer os.RemoveAll(tempDir)
+	path := filepath.Join(tempDir, "config.yaml")
+	if err := ioutil.WriteFile(path, []byte(testYAMLConfig), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	conf, err := LoadConfig(path)
+	if err != nil {
+		t.Fatalf("config should be loaded, %v", err)
+	}
+	if conf.ONNXModelPath != filepath.Join(tempDir, "MLP.onnx") {
+		t.Errorf("model path should be relative to the config file, but %v", conf.ONNXModelPath)
+	}
+	if conf.Name != "mlp" {
+		t.Errorf("name should be loaded, but %v", conf.Name)
+	}
+	if conf.Backend != TypeMKLDNN {
+		t.Errorf("backend should be MKL-DNN, but %v", conf.Backend)
+	}
+	if !conf.Optimize {
+		t.Error("optimize should be loaded")
+	}
+	if conf.BackendConfig != `{"cpu_id":0}` {
+		t.Errorf("backend config should be converted to JSON, but %v", conf.BackendConfig)
+	}
+	if len(conf.Inputs) != 1 || !reflect.DeepEqual(conf.Inputs[0].Dims, []int32{1, 3}) {
+		t.Errorf("inputs should be loaded, but %v", conf.Inputs)
+	}
+	if p := conf.Inputs[0].Preprocess; p == nil || p.ChannelOrder != "bgr" || len(p.Mean) != 3 {
+		t.Errorf("preprocess hint should be loaded, but %v", p)
+	}
+	if len(conf.Outputs) != 2 || !conf.Outputs[0].FromInternal {
+		t.Errorf("outputs should be loaded, but %v", conf.Outputs)
+	}
+	if p := conf.Outputs[1].Postprocess; p == nil || !p.Softmax || p.TopK != 5 {
+		t.Errorf("postprocess hint should be loaded, but %v", p)
+	}
+
+	for _, format := range []string{"json", "yaml"} {
+		t.Run("round trip "+format, func(t *testing.T) {
+			b, err := MarshalConfig(conf, format)
+			if err != nil {
+				t.Fatalf("config should be marshaled, %v", err)
+			}
+			actual, err := UnmarshalConfig(b, format)
+			if err != nil {
+				t.Fatalf("marshaled config should be unmarshaled, %v\n%s", err, b)
+			}
+			if !reflect.DeepEqual(actual, conf) {
+				t.Errorf("config should be %+v, but %+v", conf, actual)
+			}
+		})
+	}
+
+	t.Run("save and load", func(t *testing.T) {
+		for _, path := range []string{
+			filepath.Join(tempDir, "saved.yaml"),
+			filepath.Join(tempDir, "configs", "saved.json"),
+		} {
+			if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+				t.Fatal(err)
+			}
+			if err := SaveConfig(path, conf); err != nil {
+				t.Fatalf("config should be saved, %v", err)
+			}
+			actual, err := LoadConfig(path)
+			if err != nil {
+				t.Fatalf("saved config should be loaded, %v", err)
+			}
+			if !reflect.DeepEqual(actual, conf) {
+				t.Errorf("config should be %+v, but %+v", conf, actual)
+			}
+		}
+	})
+	t.Run("unsupported extension", func(t *testing.T) {
+		path := filepath.Join(tempDir, "config.toml")
+		if err := ioutil.WriteFile(path, []byte(testYAMLConfig), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := LoadConfig(path); err == nil {
+			t.Error("an error should be occurred with unsupported extension")
+		}
+	})
+	t.Run("not existed path", func(t *testing.T) {
+		if _, err := LoadConfig(filepath.Join(tempDir, "dummy.json")); err == nil {
+			t.Error("an error should be occurred with not existed path")
+		}
+	})
+}
+
+func TestUnmarshalConfigFail(t *testing.T) {
+	testSet := []struct {
+		name    string
+		content string
+		fields  []string
+	}{
+		{"invalid JSON", "{", nil},
+		{"unknown backend", `{"backend": "dummy", "inputs": [{"name": "x", "dims": [1]}]}`,
+			[]string{"backend"}},
+		{"unknown dtype", `{"inputs": [{"name": "x", "dtype": "int8", "dims": [1]}]}`,
+			[]string{"inputs[0].dtype"}},
+		{"no input", `{"backend": "mkldnn"}`, []string{"inputs"}},
+		{"invalid inputs", `{"inputs": [{"name": "x", "dims": [1, 0]}, {"name": "x"}]}`,
+			[]string{"inputs[0].dims[1]", "inputs[1].name", "inputs[1].dims"}},
+		{"invalid preprocess",
+			`{"inputs": [{"name": "x", "dims": [1], "preprocess": {"mean": [1, 2], "channel_order": "gbr"}}]}`,
+			[]string{"inputs[0].preprocess.mean", "inputs[0].preprocess.channel_order"}},
+		{"invalid outputs",
+			`{"inputs": [{"name": "x", "dims": [1]}], "outputs": [{"dtype": "float"}, {"name": "y", "postprocess": {"top_k": -1}}]}`,
+			[]string{"outputs[0].name", "outputs[1].postprocess.top_k"}},
+	}
+	for _, ts := range testSet {
+		t.Run(ts.name, func(t *testing.T) {
+			_, err := UnmarshalConfig([]byte(ts.content), "json")
+			if err == nil {
+				t.Fatal("an error should be occurred")
+			}
+			if ts.fields == nil {
+				return
+			}
+			errs, ok := err.(ValidationErrors)
+			if !ok {
+				t.Fatalf("error should be ValidationErrors, but %v", err)
+			}
+			fields := []string{}
+			for _, e := range errs {
+				fields = append(fields, e.Field)
+			}
+			if !reflect.DeepEqual(fields, ts.fields) {
+				t.Errorf("invalid fields should be %v, but %v", ts.fields, fields)
+			}
+			for _, f := range ts.fields {
+				if !strings.Contains(err.Error(), f+": ") {
+					t.Errorf("error message should contain '%s', but %v", f, err)
+				}
+			}
+		})
+	}
+}
+
+func TestParseDtype(t *testing.T) {
+	for _, s := range []string{"", "float", "float32"} {
+		if dtype, err := ParseDtype(s); err != nil || dtype != TypeFloat {
+			t.Errorf("'%s' should be parsed as float32, but %v, %v", s, dtype, err)
+		}
+	}
+	if _, err := ParseDtype("int8"); err == nil {
+		t.Error("an error should be occurred with not supported dtype")
+	}
+	if s := TypeFloat.String(); s != "float32" {
+		t.Errorf("TypeFloat should be named float32, but %v", s)
+	}
+}
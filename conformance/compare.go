@@ -0,0 +1,114 @@
+package conformance
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/pfnet-research/go-menoh"
+)
+
+// Tolerance is a criterion to compare actual value with expected one. Two
+// values are treated as same when |actual - expected| <= ATol + RTol * |expected|,
+// same as numpy.testing.assert_allclose.
+type Tolerance struct {
+	RTol float64 // relative tolerance
+	ATol float64 // absolute tolerance
+}
+
+// DefaultTolerance is used in ONNX backend test.
+var DefaultTolerance = Tolerance{RTol: 1e-3, ATol: 1e-7}
+
+// OutputResult is a comparison result of an output variable.
+type OutputResult struct {
+	Name            string
+	Size            int     // number of elements
+	MaxAbsError     float64 // max of |actual - expected|
+	MaxRelError     float64 // max of |actual - expected| / |expected|
+	Mismatches      int     // number of elements out of tolerance
+	MismatchIndices []int   // indices of mismatched elements, up to the limit
+	Err             error   // set when cannot compare, like shape mismatch
+}
+
+// Passed returns true when all elements are within tolerance.
+func (r *OutputResult) Passed() bool {
+	return r.Err == nil && r.Mismatches == 0
+}
+
+func (r *OutputResult) String() string {
+	if r.Err != nil {
+		return fmt.Sprintf("%s: %v", r.Name, r.Err)
+	}
+	s := fmt.Sprintf("%s: max abs error %g, max rel error %g", r.Name, r.MaxAbsError, r.MaxRelError)
+	if r.Mismatches > 0 {
+		s += fmt.Sprintf(", %d/%d mismatched at %v", r.Mismatches, r.Size, r.MismatchIndices)
+		if len(r.MismatchIndices) < r.Mismatches {
+			s = s[:len(s)-1] + " ...]"
+		}
+	}
+	return s
+}
+
+// Compare actual tensor with expected tensor element-wise. maxIndices limits
+// the number of recorded mismatched indices.
+func Compare(name string, actual, expected menoh.Tensor, tol Tolerance, maxIndices int) *OutputResult {
+	result := &OutputResult{Name: name}
+	if !sameShape(actual.Shape(), expected.Shape()) {
+		result.Err = fmt.Errorf("shape should be %v, but %v", expected.Shape(), actual.Shape())
+		return result
+	}
+	a, err := actual.FloatArray()
+	if err != nil {
+		result.Err = err
+		return result
+	}
+	e, err := expected.FloatArray()
+	if err != nil {
+		result.Err = err
+		return result
+	}
+	if len(a) != len(e) {
+		result.Err = fmt.Errorf("size should be %d, but %d", len(e), len(a))
+		return result
+	}
+	result.Size = len(e)
+	for i := range e {
+		av, ev := float64(a[i]), float64(e[i])
+		diff := math.Abs(av - ev)
+		mismatched := diff > tol.ATol+tol.RTol*math.Abs(ev)
+		if math.IsNaN(av) || math.IsNaN(ev) {
+			// NaN is same only when both are NaN
+			mismatched = math.IsNaN(av) != math.IsNaN(ev)
+			diff = 0
+			if mismatched {
+				diff = math.Inf(1)
+			}
+		}
+		if diff > result.MaxAbsError {
+			result.MaxAbsError = diff
+		}
+		if ev != 0 {
+			if rel := diff / math.Abs(ev); rel > result.MaxRelError {
+				result.MaxRelError = rel
+			}
+		}
+		if mismatched {
+			result.Mismatches++
+			if len(result.MismatchIndices) < maxIndices {
+				result.MismatchIndices = append(result.MismatchIndices, i)
+			}
+		}
+	}
+	return result
+}
+
+func sameShape(s1, s2 []int32) bool {
+	if len(s1) != len(s2) {
+		return false
+	}
+	for i, d := range s1 {
+		if d != s2[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,58 @@
+package conformance
+
+import (
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/pfnet-research/go-menoh"
+)
+
+func TestCompare(t *testing.T) {
+	expected := &menoh.FloatTensor{
+		Dims:  []int32{1, 4},
+		Array: []float32{1, 2, 0, float32(math.NaN())},
+	}
+	t.Run("within tolerance", func(t *testing.T) {
+		actual := &menoh.FloatTensor{
+			Dims:  []int32{1, 4},
+			Array: []float32{1.0005, 2, 0, float32(math.NaN())},
+		}
+		result := Compare("y", actual, expected, DefaultTolerance, 10)
+		if !result.Passed() {
+			t.Errorf("comparison should be passed, %v", result)
+		}
+		if result.MaxAbsError < 0.0004 || result.MaxAbsError > 0.0006 {
+			t.Errorf("max abs error should be about 0.0005, but %v", result.MaxAbsError)
+		}
+	})
+	t.Run("out of tolerance", func(t *testing.T) {
+		actual := &menoh.FloatTensor{
+			Dims:  []int32{1, 4},
+			Array: []float32{1, 3, 0.1, 0},
+		}
+		result := Compare("y", actual, expected, DefaultTolerance, 2)
+		if result.Passed() {
+			t.Fatal("comparison should not be passed")
+		}
+		if result.Mismatches != 3 {
+			t.Errorf("3 elements should be mismatched, but %d", result.Mismatches)
+		}
+		if len(result.MismatchIndices) != 2 || result.MismatchIndices[0] != 1 {
+			t.Errorf("mismatched indices should be [1 2], but %v", result.MismatchIndices)
+		}
+		if !strings.Contains(result.String(), "3/4 mismatched") {
+			t.Errorf("report should contain number of mismatches, but '%v'", result)
+		}
+	})
+	t.Run("shape mismatch", func(t *testing.T) {
+		actual := &menoh.FloatTensor{
+			Dims:  []int32{4},
+			Array: []float32{1, 2, 0, 0},
+		}
+		result := Compare("y", actual, expected, DefaultTolerance, 10)
+		if result.Err == nil {
+			t.Error("an error should be occurred")
+		}
+	})
+}
@@ -0,0 +1,333 @@
+/*
+Package conformance provides a harness to certify Menoh with ONNX models and
+their test datasets, laid out like ONNX model zoo:
+
+	model_dir
+	  |- model.onnx
+	  |- test_data_set_0
+	  |    |- input_0.pb
+	  |    |- output_0.pb
+	  |- test_data_set_1
+	  ...
+
+Inputs and outputs of the runner are configured automatically from the ONNX
+graph and the input tensors, all outputs are compared element-wise with
+expected ones.
+*/
+package conformance
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pfnet-research/go-menoh"
+	"github.com/pfnet-research/go-menoh/tools/onnx"
+)
+
+const (
+	modelFileName    = "model.onnx"
+	datasetDirPrefix = "test_data_set_"
+)
+
+// Options is setup information to run conformance test.
+type Options struct {
+	Backend       menoh.TypeBackend // default is menoh.TypeMKLDNN
+	BackendConfig string
+	Tolerance     *Tolerance // default is DefaultTolerance
+	MaxMismatches int        // max number of reported mismatched indices, default is 10
+}
+
+func (o Options) withDefaults() Options {
+	if o.Backend == menoh.TypeBackend(0) {
+		o.Backend = menoh.TypeMKLDNN
+	}
+	if o.Tolerance == nil {
+		tol := DefaultTolerance
+		o.Tolerance = &tol
+	}
+	if o.MaxMismatches <= 0 {
+		o.MaxMismatches = 10
+	}
+	return o
+}
+
+// Dataset is a set of input and expected output tensors.
+type Dataset struct {
+	Name    string
+	Inputs  map[string]menoh.Tensor
+	Outputs map[string]menoh.Tensor
+}
+
+// Model is an ONNX model and its datasets.
+type Model struct {
+	Dir         string
+	InputNames  []string // graph inputs, excluding initializers
+	OutputNames []string // graph outputs
+	Datasets    []*Dataset
+}
+
+// Load reads ONNX model and datasets from the directory.
+func Load(dir string) (*Model, error) {
+	model, err := onnx.LoadONNXModelFromFile(filepath.Join(dir, modelFileName))
+	if err != nil {
+		return nil, err
+	}
+	m := &Model{Dir: dir}
+	for _, v := range onnx.GraphInputs(model.GetGraph()) {
+		m.InputNames = append(m.InputNames, v.GetName())
+	}
+	for _, v := range model.GetGraph().GetOutput() {
+		m.OutputNames = append(m.OutputNames, v.GetName())
+	}
+
+	datasets, err := LoadDatasets(dir, m.InputNames, m.OutputNames)
+	if err != nil {
+		return nil, err
+	}
+	m.Datasets = datasets
+	return m, nil
+}
+
+// LoadDatasets reads "test_data_set_<index>" directories in the directory.
+// Tensors are named by their own names if they are in inputNames or
+// outputNames, otherwise named by the names at their indices.
+func LoadDatasets(dir string, inputNames, outputNames []string) ([]*Dataset, error) {
+	dirs, err := filepath.Glob(filepath.Join(dir, datasetDirPrefix+"*"))
+	if err != nil {
+		return nil, err
+	}
+	sortByIndex(dirs, datasetDirPrefix, "")
+	datasets := []*Dataset{}
+	for _, d := range dirs {
+		dataset, err := loadDataset(d, inputNames, outputNames)
+		if err != nil {
+			return nil, err
+		}
+		datasets = append(datasets, dataset)
+	}
+	if len(datasets) == 0 {
+		return nil, fmt.Errorf("no dataset is found in '%s'", dir)
+	}
+	return datasets, nil
+}
+
+func loadDataset(dir string, inputNames, outputNames []string) (*Dataset, error) {
+	inputs, err := loadTensors(dir, "input_", inputNames)
+	if err != nil {
+		return nil, err
+	}
+	outputs, err := loadTensors(dir, "output_", outputNames)
+	if err != nil {
+		return nil, err
+	}
+	return &Dataset{
+		Name:    filepath.Base(dir),
+		Inputs:  inputs,
+		Outputs: outputs,
+	}, nil
+}
+
+// loadTensors loads "<prefix><index>.pb" files in the directory. A tensor is
+// named by its own name if the name is one of names, otherwise named by
+// names[index].
+func loadTensors(dir, prefix string, names []string) (map[string]menoh.Tensor, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, prefix+"*.pb"))
+	if err != nil {
+		return nil, err
+	}
+	sortByIndex(paths, prefix, ".pb")
+	known := map[string]bool{}
+	for _, n := range names {
+		known[n] = true
+	}
+	tensors := map[string]menoh.Tensor{}
+	for i, p := range paths {
+		proto, err := onnx.LoadONNXTensorFromFile(p)
+		if err != nil {
+			return nil, err
+		}
+		name := proto.GetName()
+		if !known[name] {
+			if i >= len(names) {
+				return nil, fmt.Errorf("'%s' does not correspond to any variable of the model", p)
+			}
+			name = names[i]
+		}
+		t, err := onnx.ConvertToMenohTensor(proto)
+		if err != nil {
+			return nil, fmt.Errorf("cannot convert '%s', %v", p, err)
+		}
+		tensors[name] = t
+	}
+	return tensors, nil
+}
+
+func sortByIndex(paths []string, prefix, suffix string) {
+	index := func(p string) int {
+		s := strings.TrimSuffix(strings.TrimPrefix(filepath.Base(p), prefix), suffix)
+		i, err := strconv.Atoi(s)
+		if err != nil {
+			return -1
+		}
+		return i
+	}
+	sort.Slice(paths, func(i, j int) bool {
+		return index(paths[i]) < index(paths[j])
+	})
+}
+
+// Config returns runner configuration to run the dataset.
+func (m *Model) Config(d *Dataset, opts Options) menoh.Config {
+	opts = opts.withDefaults()
+	conf := menoh.Config{
+		ONNXModelPath: filepath.Join(m.Dir, modelFileName),
+		Backend:       opts.Backend,
+		BackendConfig: opts.BackendConfig,
+	}
+	for _, name := range m.InputNames {
+		t, ok := d.Inputs[name]
+		if !ok {
+			continue
+		}
+		conf.Inputs = append(conf.Inputs, menoh.InputConfig{
+			Name:  name,
+			Dtype: menoh.TypeFloat,
+			Dims:  t.Shape(),
+		})
+	}
+	for _, name := range m.OutputNames {
+		if _, ok := d.Outputs[name]; !ok {
+			continue
+		}
+		conf.Outputs = append(conf.Outputs, menoh.OutputConfig{
+			Name:  name,
+			Dtype: menoh.TypeFloat,
+		})
+	}
+	return conf
+}
+
+// DatasetResult is a result of running a dataset.
+type DatasetResult struct {
+	Name    string
+	Outputs []*OutputResult
+	Err     error // set when cannot build runner or run
+}
+
+// Passed returns true when all outputs are within tolerance.
+func (r *DatasetResult) Passed() bool {
+	if r.Err != nil {
+		return false
+	}
+	for _, o := range r.Outputs {
+		if !o.Passed() {
+			return false
+		}
+	}
+	return true
+}
+
+// Report is a result of running all datasets of a model.
+type Report struct {
+	Dir      string
+	Datasets []*DatasetResult
+}
+
+// Passed returns true when all datasets are passed.
+func (r *Report) Passed() bool {
+	for _, d := range r.Datasets {
+		if !d.Passed() {
+			return false
+		}
+	}
+	return true
+}
+
+func (r *Report) String() string {
+	lines := []string{r.Dir}
+	for _, d := range r.Datasets {
+		status := "ok"
+		if !d.Passed() {
+			status = "FAIL"
+		}
+		lines = append(lines, fmt.Sprintf("  %s %s", status, d.Name))
+		if d.Err != nil {
+			lines = append(lines, fmt.Sprintf("    %v", d.Err))
+		}
+		for _, o := range d.Outputs {
+			lines = append(lines, "    "+o.String())
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Run loads the model directory and runs all datasets. A runner is built
+// for each dataset because input shapes can differ between datasets.
+func Run(dir string, opts Options) (*Report, error) {
+	m, err := Load(dir)
+	if err != nil {
+		return nil, err
+	}
+	report := &Report{Dir: dir}
+	for _, d := range m.Datasets {
+		report.Datasets = append(report.Datasets, m.RunDataset(d, opts))
+	}
+	return report, nil
+}
+
+// RunDataset runs the dataset and compares outputs with expected ones.
+func (m *Model) RunDataset(d *Dataset, opts Options) *DatasetResult {
+	opts = opts.withDefaults()
+	result := &DatasetResult{Name: d.Name}
+	runner, err := menoh.NewRunner(m.Config(d, opts))
+	if err != nil {
+		result.Err = fmt.Errorf("cannot build runner, %v", err)
+		return result
+	}
+	defer runner.Stop()
+	if err := runner.Run(d.Inputs); err != nil {
+		result.Err = fmt.Errorf("cannot run, %v", err)
+		return result
+	}
+	for _, name := range m.OutputNames {
+		expected, ok := d.Outputs[name]
+		if !ok {
+			continue
+		}
+		actual, err := runner.GetOutput(name)
+		if err != nil {
+			result.Outputs = append(result.Outputs, &OutputResult{Name: name, Err: err})
+			continue
+		}
+		result.Outputs = append(result.Outputs,
+			Compare(name, actual, expected, *opts.Tolerance, opts.MaxMismatches))
+	}
+	return result
+}
+
+// FindModels returns model directories, which have "model.onnx", under the
+// root directory.
+func FindModels(root string) ([]string, error) {
+	dirs := []string{}
+	err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.IsDir() && info.Name() == modelFileName {
+			dirs = append(dirs, filepath.Dir(path))
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(dirs) == 0 {
+		return nil, errors.New("no model is found")
+	}
+	return dirs, nil
+}
@@ -0,0 +1,115 @@
+package conformance
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/pfnet-research/go-menoh"
+	"github.com/pfnet-research/go-menoh/tools/onnx"
+)
+
+func writeProto(t *testing.T, path string, m proto.Message) {
+	b, err := proto.Marshal(m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(path, b, 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func makeTensorProto(name string, dims []int64, floats []float32) *onnx.TensorProto {
+	floatType := onnx.TensorProto_FLOAT
+	return &onnx.TensorProto{
+		Name:      proto.String(name),
+		DataType:  &floatType,
+		Dims:      dims,
+		FloatData: floats,
+	}
+}
+
+func makeModelDir(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "go-menoh-test-")
+	if err != nil {
+		t.Fatal("cannot make temporary directory")
+	}
+	writeProto(t, filepath.Join(dir, "zoo", "mlp", modelFileName), &onnx.ModelProto{
+		Graph: &onnx.GraphProto{
+			Initializer: []*onnx.TensorProto{makeTensorProto("W", []int64{3, 2}, make([]float32, 6))},
+			Input: []*onnx.ValueInfoProto{
+				{Name: proto.String("x")},
+				{Name: proto.String("W")},
+			},
+			Output: []*onnx.ValueInfoProto{{Name: proto.String("y")}},
+		},
+	})
+	for i := 0; i < 2; i++ {
+		dataset := filepath.Join(dir, "zoo", "mlp", fmt.Sprintf("%s%d", datasetDirPrefix, i))
+		// input is not named, matched by the position
+		writeProto(t, filepath.Join(dataset, "input_0.pb"),
+			makeTensorProto("", []int64{1, 3}, []float32{0, 1, float32(i)}))
+		writeProto(t, filepath.Join(dataset, "output_0.pb"),
+			makeTensorProto("y", []int64{1, 2}, []float32{0, float32(i)}))
+	}
+	return dir
+}
+
+func TestLoad(t *testing.T) {
+	dir := makeModelDir(t)
+	defer os.RemoveAll(dir)
+
+	t.Run("find models", func(t *testing.T) {
+		dirs, err := FindModels(dir)
+		if err != nil {
+			t.Fatalf("models should be found, %v", err)
+		}
+		if len(dirs) != 1 || filepath.Base(dirs[0]) != "mlp" {
+			t.Errorf("only 'mlp' should be found, but %v", dirs)
+		}
+	})
+
+	t.Run("load model and datasets", func(t *testing.T) {
+		m, err := Load(filepath.Join(dir, "zoo", "mlp"))
+		if err != nil {
+			t.Fatalf("model should be loaded, %v", err)
+		}
+		if len(m.InputNames) != 1 || m.InputNames[0] != "x" {
+			t.Errorf("input names should be [x], but %v", m.InputNames)
+		}
+		if len(m.Datasets) != 2 {
+			t.Fatalf("2 datasets should be loaded, but %d", len(m.Datasets))
+		}
+		d := m.Datasets[1]
+		if d.Name != "test_data_set_1" {
+			t.Errorf("dataset should be sorted, but %s", d.Name)
+		}
+		if _, ok := d.Inputs["x"]; !ok {
+			t.Errorf("input should be named 'x', but %v", d.Inputs)
+		}
+
+		conf := m.Config(d, Options{})
+		if conf.Backend != menoh.TypeMKLDNN {
+			t.Errorf("default backend should be MKL-DNN, but %v", conf.Backend)
+		}
+		if len(conf.Inputs) != 1 || len(conf.Inputs[0].Dims) != 2 || conf.Inputs[0].Dims[1] != 3 {
+			t.Errorf("input should be configured with dataset shape, but %v", conf.Inputs)
+		}
+		if len(conf.Outputs) != 1 || conf.Outputs[0].Name != "y" {
+			t.Errorf("output should be configured by graph output, but %v", conf.Outputs)
+		}
+	})
+
+	// fail
+	t.Run("no model", func(t *testing.T) {
+		if _, err := Load(dir); err == nil {
+			t.Error("an error should be occurred")
+		}
+	})
+}
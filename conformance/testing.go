@@ -0,0 +1,54 @@
+package conformance
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+// TestModel runs all datasets in the model directory as sub-tests of t. Each
+// mismatched output is reported with max error and mismatched indices.
+//
+//	func TestSqueezeNet(t *testing.T) {
+//		conformance.TestModel(t, "testdata/squeezenet", conformance.Options{})
+//	}
+func TestModel(t *testing.T, dir string, opts Options) {
+	m, err := Load(dir)
+	if err != nil {
+		t.Fatalf("cannot load model, %v", err)
+	}
+	for _, d := range m.Datasets {
+		d := d
+		t.Run(d.Name, func(t *testing.T) {
+			result := m.RunDataset(d, opts)
+			if result.Err != nil {
+				t.Fatal(result.Err)
+			}
+			for _, o := range result.Outputs {
+				if o.Passed() {
+					t.Log(o)
+				} else {
+					t.Error(o)
+				}
+			}
+		})
+	}
+}
+
+// TestModelZoo runs all models found under the root directory as sub-tests
+// of t, named by the relative path of each model directory.
+func TestModelZoo(t *testing.T, root string, opts Options) {
+	dirs, err := FindModels(root)
+	if err != nil {
+		t.Fatalf("cannot find models in '%s', %v", root, err)
+	}
+	for _, dir := range dirs {
+		dir := dir
+		name, err := filepath.Rel(root, dir)
+		if err != nil || name == "." {
+			name = filepath.Base(dir)
+		}
+		t.Run(name, func(t *testing.T) {
+			TestModel(t, dir, opts)
+		})
+	}
+}
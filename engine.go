@@ -0,0 +1,93 @@
+package menoh
+
+import (
+	"sync"
+)
+
+// Backend is an engine to load, build and run a model, Runner is written
+// against it. A Backend holds a model of a runner, Runner calls LoadModel,
+// AddProfiles, ProfileVariables, AttachBuffer for each input and internal
+// output, Build, then Run repeatedly, and Close on stopping. Each step is
+// timed as a build phase of the runner.
+//
+// The default Backend binds Menoh library, by cgo or by DLL on Windows.
+// Other engines are registered by RegisterBackend, or passed to
+// NewRunnerWithBackend directly, like fakes for unit testing.
+type Backend interface {
+	// LoadModel loads ONNX model from the path.
+	LoadModel(path string) error
+
+	// AddProfiles fixes dtype and dims of the inputs and dtype of the
+	// outputs.
+	AddProfiles(inputs []InputConfig, outputs []OutputConfig) error
+
+	// ProfileVariables infers shapes of the variables from the profiles.
+	ProfileVariables() error
+
+	// VariableProfile returns dtype and dims of the variable, available after
+	// ProfileVariables.
+	VariableProfile(name string) (TypeDtype, []int32, error)
+
+	// AttachBuffer attaches the tensor to the variable, the model reads and
+	// writes the variable through the array of the tensor.
+	AttachBuffer(name string, t Tensor) error
+
+	// Build builds the model on the backend type with the backend
+	// configuration.
+	Build(backend TypeBackend, backendConfig string) error
+
+	// Run the model.
+	Run() error
+
+	// GetVariable returns a tensor sharing the array of the variable,
+	// available after Build.
+	GetVariable(name string) (Tensor, error)
+
+	// Close releases the model.
+	Close()
+}
+
+// Optimizer is implemented by Backend which optimizes the model for the
+// profiled variables, like pruning nodes unreachable from the outputs. Runner
+// calls Optimize after ProfileVariables when Config.Optimize is set, the
+// binding calls menoh_model_data_optimize.
+type Optimizer interface {
+	Optimize() error
+}
+
+var (
+	backendEnginesMu sync.RWMutex
+	backendEngines   = map[TypeBackend]func() Backend{
+		TypeReference: newReferenceBackend,
+	}
+)
+
+// RegisterBackend makes NewRunner use Backend returned by newBackend for the
+// backend type, instead of Menoh library. Set nil to unregister.
+func RegisterBackend(backend TypeBackend, newBackend func() Backend) {
+	backendEnginesMu.Lock()
+	defer backendEnginesMu.Unlock()
+	if newBackend == nil {
+		delete(backendEngines, backend)
+		return
+	}
+	backendEngines[backend] = newBackend
+}
+
+// registeredBackend returns Backend registered for the backend type.
+func registeredBackend(backend TypeBackend) (Backend, bool) {
+	backendEnginesMu.RLock()
+	defer backendEnginesMu.RUnlock()
+	newBackend, ok := backendEngines[backend]
+	if !ok {
+		return nil, false
+	}
+	return newBackend(), true
+}
+
+func isRegisteredBackend(backend TypeBackend) bool {
+	backendEnginesMu.RLock()
+	defer backendEnginesMu.RUnlock()
+	_, ok := backendEngines[backend]
+	return ok
+}
@@ -0,0 +1,135 @@
+package menoh
+
+import (
+	"unsafe"
+
+	"github.com/pfnet-research/go-menoh/external"
+)
+
+// newBindingBackend returns Backend binding Menoh library by cgo, or by DLL
+// on Windows. The backend takes ownership of modelData if set, and LoadModel
+// is not required.
+func newBindingBackend(modelData *external.ModelData) Backend {
+	lib := &bindingLibrary{}
+	b := &libraryBackend{lib: lib, dtypes: bindingDtypes}
+	if modelData != nil {
+		lib.setModelData(modelData)
+		b.stage = stageLoaded
+	}
+	return b
+}
+
+// bindingDtypes are dtypes supported by Menoh.
+var bindingDtypes = dtypeAdapter{TypeFloat: int(external.TypeFloat)}
+
+// bindingLibrary is library of the external package.
+type bindingLibrary struct {
+	modelData    *external.ModelData
+	vptBuilder   *external.VariableProfileTableBuilder
+	vpTable      *external.VariableProfileTable
+	modelBuilder *external.ModelBuilder
+	model        *external.Model
+	made         handles
+}
+
+func (l *bindingLibrary) setModelData(modelData *external.ModelData) {
+	l.modelData = modelData
+	l.made.keep(modelData)
+}
+
+func (l *bindingLibrary) loadModel(path string) error {
+	modelData, err := external.MakeModelDataFromONNX(path)
+	if err != nil {
+		return err
+	}
+	l.setModelData(modelData)
+	return nil
+}
+
+func (l *bindingLibrary) makeProfileTableBuilder() error {
+	vptBuilder, err := external.MakeVariableProfileTableBuilder()
+	if err != nil {
+		return err
+	}
+	l.vptBuilder = vptBuilder
+	l.made.keep(vptBuilder)
+	return nil
+}
+
+func (l *bindingLibrary) addInputProfile(name string, dtype int, dims []int32) error {
+	return l.vptBuilder.AddInputProfile(name, external.TypeMenohDtype(dtype), dims...)
+}
+
+func (l *bindingLibrary) addOutputProfile(name string, dtype int) error {
+	return l.vptBuilder.AddOutputProfile(name, external.TypeMenohDtype(dtype))
+}
+
+func (l *bindingLibrary) buildProfileTable() error {
+	vpt, err := l.vptBuilder.BuildVariableProfileTable(*l.modelData)
+	if err != nil {
+		return err
+	}
+	l.vpTable = vpt
+	l.made.keep(vpt)
+	return nil
+}
+
+func (l *bindingLibrary) variableProfile(name string) (int, []int32, error) {
+	vp, err := l.vpTable.GetVariableProfile(name)
+	if err != nil {
+		return 0, nil, err
+	}
+	return int(vp.Dtype), vp.Dims, nil
+}
+
+func (l *bindingLibrary) optimize() error {
+	return l.modelData.Optimize(*l.vpTable)
+}
+
+func (l *bindingLibrary) makeModelBuilder() error {
+	modelBuilder, err := external.MakeModelBuilder(*l.vpTable)
+	if err != nil {
+		return err
+	}
+	l.modelBuilder = modelBuilder
+	l.made.keep(modelBuilder)
+	return nil
+}
+
+func (l *bindingLibrary) attachBuffer(name string, ptr unsafe.Pointer) error {
+	return l.modelBuilder.AttachExternalBuffer(name, ptr)
+}
+
+func (l *bindingLibrary) buildModel(backend, backendConfig string) error {
+	model, err := l.modelBuilder.BuildModel(*l.modelData, backend, backendConfig)
+	if err != nil {
+		return err
+	}
+	l.model = model
+	l.made.keep(model)
+	return nil
+}
+
+func (l *bindingLibrary) run() error {
+	return l.model.Run()
+}
+
+func (l *bindingLibrary) getVariable(name string) (int, []int32, unsafe.Pointer, error) {
+	v, err := l.model.GetVariable(name)
+	if err != nil {
+		return 0, nil, nil, err
+	}
+	return int(v.Dtype), v.Dims, v.BufferHandle, nil
+}
+
+func (l *bindingLibrary) close() {
+	l.made.deleteAll()
+}
+
+func toMenohDtype(dtype TypeDtype) (external.TypeMenohDtype, error) {
+	code, err := bindingDtypes.toLibrary(dtype)
+	if err != nil {
+		return -1, err
+	}
+	return external.TypeMenohDtype(code), nil
+}
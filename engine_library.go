@@ -0,0 +1,217 @@
+package menoh
+
+import (
+	"errors"
+	"fmt"
+	"unsafe"
+
+	"github.com/pfnet-research/go-menoh/external/errcode"
+)
+
+// library is Menoh API on handles of a model, implemented by the binding of
+// the external package and by the pure-Go interpreter of the reference
+// package. Dtypes are codes of the library, converted by dtypeAdapter.
+// Implementations keep handles they made by handles and delete them on
+// close.
+type library interface {
+	loadModel(path string) error
+	makeProfileTableBuilder() error
+	addInputProfile(name string, dtype int, dims []int32) error
+	addOutputProfile(name string, dtype int) error
+	buildProfileTable() error
+	variableProfile(name string) (int, []int32, error)
+	optimize() error
+	makeModelBuilder() error
+	attachBuffer(name string, ptr unsafe.Pointer) error
+	buildModel(backend, backendConfig string) error
+	run() error
+	getVariable(name string) (int, []int32, unsafe.Pointer, error)
+	close()
+}
+
+// dtypeAdapter maps dtypes supported by a library to codes of the library,
+// like menoh_dtype of the C API.
+type dtypeAdapter map[TypeDtype]int
+
+func (a dtypeAdapter) toLibrary(dtype TypeDtype) (int, error) {
+	if code, ok := a[dtype]; ok {
+		return code, nil
+	}
+	return 0, &errcode.Error{Code: errcode.InvalidDtype, Message: fmt.Sprintf("not supported dtype %v", dtype)}
+}
+
+func (a dtypeAdapter) fromLibrary(code int) (TypeDtype, error) {
+	for dtype, c := range a {
+		if c == code {
+			return dtype, nil
+		}
+	}
+	return typeUnknownDtype, &errcode.Error{
+		Code: errcode.InvalidDtype, Message: fmt.Sprintf("not supported menoh dtype %d", code)}
+}
+
+// handle is a handle made by a library, like ModelData.
+type handle interface {
+	Delete()
+}
+
+// handles are handles made by a library, deleted in reverse order of making.
+type handles []handle
+
+func (h *handles) keep(handle handle) {
+	*h = append(*h, handle)
+}
+
+func (h *handles) deleteAll() {
+	for i := len(*h) - 1; i >= 0; i-- {
+		(*h)[i].Delete()
+	}
+	*h = nil
+}
+
+// stages of libraryBackend
+const (
+	stageEmpty = iota
+	stageLoaded
+	stageProfiling // variable profile table builder is made
+	stageProfiled
+	stageAttaching // model builder is made
+	stageBuilt
+)
+
+// libraryBackend is Backend running a model by the library, in the same
+// order as Menoh C API.
+type libraryBackend struct {
+	lib    library
+	dtypes dtypeAdapter
+	stage  int
+}
+
+func (b *libraryBackend) LoadModel(path string) error {
+	if err := b.lib.loadModel(path); err != nil {
+		return err
+	}
+	b.stage = stageLoaded
+	return nil
+}
+
+func (b *libraryBackend) AddProfiles(inputs []InputConfig, outputs []OutputConfig) error {
+	if b.stage < stageLoaded {
+		return errors.New("model is not loaded")
+	}
+	if err := b.lib.makeProfileTableBuilder(); err != nil {
+		return err
+	}
+	b.stage = stageProfiling
+	for _, c := range inputs {
+		dtype, err := b.dtypes.toLibrary(c.Dtype)
+		if err != nil {
+			return err
+		}
+		if err := b.lib.addInputProfile(c.Name, dtype, c.Dims); err != nil {
+			return err
+		}
+	}
+	for _, c := range outputs {
+		dtype, err := b.dtypes.toLibrary(c.Dtype)
+		if err != nil {
+			return err
+		}
+		if err := b.lib.addOutputProfile(c.Name, dtype); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (b *libraryBackend) ProfileVariables() error {
+	if b.stage < stageProfiling {
+		return errors.New("profiles are not added")
+	}
+	if err := b.lib.buildProfileTable(); err != nil {
+		return err
+	}
+	b.stage = stageProfiled
+	return nil
+}
+
+func (b *libraryBackend) Optimize() error {
+	if b.stage < stageProfiled {
+		return errors.New("variables are not profiled")
+	}
+	return b.lib.optimize()
+}
+
+func (b *libraryBackend) VariableProfile(name string) (TypeDtype, []int32, error) {
+	if b.stage < stageProfiled {
+		return typeUnknownDtype, nil, errors.New("variables are not profiled")
+	}
+	code, dims, err := b.lib.variableProfile(name)
+	if err != nil {
+		return typeUnknownDtype, nil, err
+	}
+	dtype, err := b.dtypes.fromLibrary(code)
+	if err != nil {
+		return typeUnknownDtype, nil, err
+	}
+	return dtype, dims, nil
+}
+
+// makeModelBuilder makes the model builder on the first attachment or build.
+func (b *libraryBackend) makeModelBuilder() error {
+	if b.stage < stageProfiled {
+		return errors.New("variables are not profiled")
+	}
+	if b.stage >= stageAttaching {
+		return nil
+	}
+	if err := b.lib.makeModelBuilder(); err != nil {
+		return err
+	}
+	b.stage = stageAttaching
+	return nil
+}
+
+func (b *libraryBackend) AttachBuffer(name string, t Tensor) error {
+	if err := b.makeModelBuilder(); err != nil {
+		return err
+	}
+	return b.lib.attachBuffer(name, t.ptr())
+}
+
+func (b *libraryBackend) Build(backend TypeBackend, backendConfig string) error {
+	if err := b.makeModelBuilder(); err != nil {
+		return err
+	}
+	if err := b.lib.buildModel(backend.String(), backendConfig); err != nil {
+		return err
+	}
+	b.stage = stageBuilt
+	return nil
+}
+
+func (b *libraryBackend) Run() error {
+	if b.stage < stageBuilt {
+		return errors.New("model is not built")
+	}
+	return b.lib.run()
+}
+
+func (b *libraryBackend) GetVariable(name string) (Tensor, error) {
+	if b.stage < stageBuilt {
+		return nil, errors.New("model is not built")
+	}
+	code, dims, ptr, err := b.lib.getVariable(name)
+	if err != nil {
+		return nil, err
+	}
+	dtype, err := b.dtypes.fromLibrary(code)
+	if err != nil {
+		return nil, err
+	}
+	return newTensorHandleByPtr(dtype, ptr, dims...), nil
+}
+
+func (b *libraryBackend) Close() {
+	b.lib.close()
+}
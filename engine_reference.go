@@ -0,0 +1,118 @@
+package menoh
+
+import (
+	"unsafe"
+
+	"github.com/pfnet-research/go-menoh/external/reference"
+)
+
+// newReferenceBackend returns Backend of the pure-Go interpreter, used for
+// TypeReference without Menoh library.
+func newReferenceBackend() Backend {
+	return &libraryBackend{lib: &referenceLibrary{}, dtypes: referenceDtypes}
+}
+
+// referenceDtypes are dtypes supported by the interpreter.
+var referenceDtypes = dtypeAdapter{TypeFloat: int(reference.TypeFloat)}
+
+// referenceLibrary is library of the reference package. Build accepts
+// TypeReference and the other names known by the interpreter, so that a
+// model for Menoh can be checked as is.
+type referenceLibrary struct {
+	modelData    *reference.ModelData
+	vptBuilder   *reference.VariableProfileTableBuilder
+	vpTable      *reference.VariableProfileTable
+	modelBuilder *reference.ModelBuilder
+	model        *reference.Model
+	made         handles
+}
+
+func (l *referenceLibrary) loadModel(path string) error {
+	modelData, err := reference.MakeModelDataFromONNX(path)
+	if err != nil {
+		return err
+	}
+	l.modelData = modelData
+	l.made.keep(modelData)
+	return nil
+}
+
+func (l *referenceLibrary) makeProfileTableBuilder() error {
+	vptBuilder, err := reference.MakeVariableProfileTableBuilder()
+	if err != nil {
+		return err
+	}
+	l.vptBuilder = vptBuilder
+	l.made.keep(vptBuilder)
+	return nil
+}
+
+func (l *referenceLibrary) addInputProfile(name string, dtype int, dims []int32) error {
+	return l.vptBuilder.AddInputProfile(name, reference.TypeMenohDtype(dtype), dims...)
+}
+
+func (l *referenceLibrary) addOutputProfile(name string, dtype int) error {
+	return l.vptBuilder.AddOutputProfile(name, reference.TypeMenohDtype(dtype))
+}
+
+func (l *referenceLibrary) buildProfileTable() error {
+	vpt, err := l.vptBuilder.BuildVariableProfileTable(*l.modelData)
+	if err != nil {
+		return err
+	}
+	l.vpTable = vpt
+	l.made.keep(vpt)
+	return nil
+}
+
+func (l *referenceLibrary) variableProfile(name string) (int, []int32, error) {
+	vp, err := l.vpTable.GetVariableProfile(name)
+	if err != nil {
+		return 0, nil, err
+	}
+	return int(vp.Dtype), vp.Dims, nil
+}
+
+func (l *referenceLibrary) optimize() error {
+	return l.modelData.Optimize(*l.vpTable)
+}
+
+func (l *referenceLibrary) makeModelBuilder() error {
+	modelBuilder, err := reference.MakeModelBuilder(*l.vpTable)
+	if err != nil {
+		return err
+	}
+	l.modelBuilder = modelBuilder
+	l.made.keep(modelBuilder)
+	return nil
+}
+
+func (l *referenceLibrary) attachBuffer(name string, ptr unsafe.Pointer) error {
+	return l.modelBuilder.AttachExternalBuffer(name, ptr)
+}
+
+func (l *referenceLibrary) buildModel(backend, backendConfig string) error {
+	model, err := l.modelBuilder.BuildModel(*l.modelData, backend, backendConfig)
+	if err != nil {
+		return err
+	}
+	l.model = model
+	l.made.keep(model)
+	return nil
+}
+
+func (l *referenceLibrary) run() error {
+	return l.model.Run()
+}
+
+func (l *referenceLibrary) getVariable(name string) (int, []int32, unsafe.Pointer, error) {
+	v, err := l.model.GetVariable(name)
+	if err != nil {
+		return 0, nil, nil, err
+	}
+	return int(v.Dtype), v.Dims, v.BufferHandle, nil
+}
+
+func (l *referenceLibrary) close() {
+	l.made.deleteAll()
+}
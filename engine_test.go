@@ -0,0 +1,266 @@
+package menoh
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/pfnet-research/go-menoh/external/errcode"
+)
+
+// fakeBackend doubles inputs to outputs and records called methods.
+type fakeBackend struct {
+	calls    []string
+	loadErr  error
+	buildErr error
+
+	inputs  map[string]Tensor
+	outputs map[string]*FloatTensor
+}
+
+func (b *fakeBackend) LoadModel(path string) error {
+	b.calls = append(b.calls, "LoadModel")
+	b.inputs = map[string]Tensor{}
+	b.outputs = map[string]*FloatTensor{}
+	return b.loadErr
+}
+
+func (b *fakeBackend) AddProfiles(inputs []InputConfig, outputs []OutputConfig) error {
+	b.calls = append(b.calls, "AddProfiles")
+	for _, c := range outputs {
+		b.outputs[c.Name] = &FloatTensor{Dims: []int32{1, 3}, Array: make([]float32, 3)}
+	}
+	return nil
+}
+
+func (b *fakeBackend) ProfileVariables() error {
+	b.calls = append(b.calls, "ProfileVariables")
+	return nil
+}
+
+func (b *fakeBackend) VariableProfile(name string) (TypeDtype, []int32, error) {
+	b.calls = append(b.calls, "VariableProfile")
+	return TypeFloat, []int32{1, 3}, nil
+}
+
+func (b *fakeBackend) AttachBuffer(name string, t Tensor) error {
+	b.calls = append(b.calls, "AttachBuffer")
+	b.inputs[name] = t
+	return nil
+}
+
+func (b *fakeBackend) Build(backend TypeBackend, backendConfig string) error {
+	b.calls = append(b.calls, "Build")
+	return b.buildErr
+}
+
+func (b *fakeBackend) Run() error {
+	b.calls = append(b.calls, "Run")
+	in, _ := b.inputs["input"].FloatArray()
+	for _, out := range b.outputs {
+		for i, v := range in {
+			out.Array[i] = 2 * v
+		}
+	}
+	return nil
+}
+
+func (b *fakeBackend) GetVariable(name string) (Tensor, error) {
+	b.calls = append(b.calls, "GetVariable")
+	t, ok := b.outputs[name]
+	if !ok {
+		return nil, errors.New("variable not found")
+	}
+	return t, nil
+}
+
+func (b *fakeBackend) Close() {
+	b.calls = append(b.calls, "Close")
+}
+
+// fakeOptimizer is fakeBackend implementing Optimizer.
+type fakeOptimizer struct {
+	fakeBackend
+}
+
+func (b *fakeOptimizer) Optimize() error {
+	b.calls = append(b.calls, "Optimize")
+	return nil
+}
+
+func fakeConfig() Config {
+	return Config{
+		Backend: TypeMKLDNN,
+		Inputs:  []InputConfig{{Name: "input", Dtype: TypeFloat, Dims: []int32{1, 3}}},
+		Outputs: []OutputConfig{{Name: "output", Dtype: TypeFloat}},
+	}
+}
+
+func TestNewRunnerWithBackend(t *testing.T) {
+	backend := &fakeBackend{}
+	runner, err := NewRunnerWithBackend(backend, fakeConfig())
+	if err != nil {
+		t.Fatalf("runner should be created with the backend, %v", err)
+	}
+	input := &FloatTensor{Dims: []int32{1, 3}, Array: []float32{1, 2, 3}}
+	if err := runner.RunWithTensor("input", input); err != nil {
+		t.Fatalf("runner should run on the backend, %v", err)
+	}
+	out, err := runner.GetOutput("output")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := &FloatTensor{Dims: []int32{1, 3}, Array: []float32{2, 4, 6}}
+	if !tensorEquals(out, expected) {
+		t.Errorf("output should be %v, but %v", expected, out)
+	}
+	runner.Stop()
+
+	calls := []string{"LoadModel", "AddProfiles", "ProfileVariables", "AttachBuffer", "Build", "GetVariable", "Run", "Close"}
+	if !reflect.DeepEqual(backend.calls, calls) {
+		t.Errorf("backend should be called in order of %v, but %v", calls, backend.calls)
+	}
+}
+
+func TestNewRunnerWithBackendFail(t *testing.T) {
+	t.Run("build error", func(t *testing.T) {
+		backend := &fakeBackend{buildErr: errors.New("build error")}
+		runner, err := NewRunnerWithBackend(backend, fakeConfig())
+		if err == nil || runner != nil {
+			t.Fatal("an error should be occurred on building")
+		}
+		if last := backend.calls[len(backend.calls)-1]; last != "Close" {
+			t.Errorf("backend should be closed on error, but %v", backend.calls)
+		}
+	})
+	t.Run("invalid backend config", func(t *testing.T) {
+		conf := fakeConfig()
+		conf.BackendConfig = "[]"
+		backend := &fakeBackend{}
+		if _, err := NewRunnerWithBackend(backend, conf); err == nil {
+			t.Error("an error should be occurred with invalid backend config")
+		}
+		if len(backend.calls) != 0 {
+			t.Errorf("backend should not be called, but %v", backend.calls)
+		}
+	})
+}
+
+func TestOptimize(t *testing.T) {
+	conf := fakeConfig()
+	conf.Optimize = true
+
+	backend := &fakeOptimizer{}
+	runner, err := NewRunnerWithBackend(backend, conf)
+	if err != nil {
+		t.Fatalf("runner should be created with optimization, %v", err)
+	}
+	runner.Stop()
+	calls := []string{"LoadModel", "AddProfiles", "ProfileVariables", "Optimize", "AttachBuffer", "Build", "GetVariable", "Close"}
+	if !reflect.DeepEqual(backend.calls, calls) {
+		t.Errorf("backend should be called in order of %v, but %v", calls, backend.calls)
+	}
+	phases := []string{}
+	for _, d := range runner.BuildDurations() {
+		phases = append(phases, d.Phase)
+	}
+	if expected := []string{"makeVariableProfileTableBuilder", "buildVariableProfileTable", "optimize",
+		"makeModelBuilder", "buildModel"}; !reflect.DeepEqual(phases, expected) {
+		t.Errorf("phases should be %v, but %v", expected, phases)
+	}
+
+	// backends not implementing Optimizer are built as is
+	runner, err = NewRunnerWithBackend(&fakeBackend{}, conf)
+	if err != nil {
+		t.Fatalf("runner should be created without Optimizer, %v", err)
+	}
+	runner.Stop()
+}
+
+func TestRegisterBackend(t *testing.T) {
+	typeBackend := CustomBackend("engine_test_backend")
+	backend := &fakeBackend{}
+	RegisterBackend(typeBackend, func() Backend { return backend })
+
+	conf := fakeConfig()
+	conf.Backend = typeBackend
+	runner, err := NewRunner(conf)
+	if err != nil {
+		t.Fatalf("runner should be created with the registered backend, %v", err)
+	}
+	runner.Stop()
+	if len(backend.calls) == 0 {
+		t.Error("the registered backend should be used")
+	}
+	if err := ProbeBackend(typeBackend); err != nil {
+		t.Errorf("registered backend should be supported, %v", err)
+	}
+
+	RegisterBackend(typeBackend, nil)
+	if isRegisteredBackend(typeBackend) {
+		t.Error("backend should be unregistered")
+	}
+}
+
+func TestReferenceBackend(t *testing.T) {
+	onnxPath, inputConfig, outputConfig, err := getTestONNXDataset()
+	if err != nil {
+		t.Fatal(err)
+	}
+	runner, err := NewRunner(Config{
+		ONNXModelPath: onnxPath,
+		Backend:       TypeReference,
+		Inputs:        []InputConfig{inputConfig},
+		Outputs:       []OutputConfig{outputConfig},
+	})
+	if err != nil {
+		t.Fatalf("runner should be created with the reference interpreter, %v", err)
+	}
+	defer runner.Stop()
+
+	input := &FloatTensor{Dims: []int32{1, 3}, Array: []float32{0, 1, 2}}
+	if err := runner.RunWithTensor("input", input); err != nil {
+		t.Fatal(err)
+	}
+	actual, _ := runner.GetOutput("fc2")
+	expected := &FloatTensor{Dims: []int32{1, 5}, Array: []float32{0, 0, 15, 96, 177}}
+	if !tensorEquals(actual, expected) {
+		t.Errorf("output should be %v, but %v", expected, actual)
+	}
+}
+
+func TestLibraryBackendOrder(t *testing.T) {
+	backend := newReferenceBackend()
+	defer backend.Close()
+	if err := backend.AddProfiles(nil, nil); err == nil {
+		t.Error("an error should be occurred before loading model")
+	}
+	if err := backend.ProfileVariables(); err == nil {
+		t.Error("an error should be occurred before adding profiles")
+	}
+	if err := backend.AttachBuffer("input", &FloatTensor{Dims: []int32{1}, Array: []float32{0}}); err == nil {
+		t.Error("an error should be occurred before profiling")
+	}
+	if err := backend.Run(); err == nil {
+		t.Error("an error should be occurred before building")
+	}
+	if _, err := backend.GetVariable("output"); err == nil {
+		t.Error("an error should be occurred before building")
+	}
+}
+
+func TestDtypeAdapter(t *testing.T) {
+	code, err := referenceDtypes.toLibrary(TypeFloat)
+	if err != nil {
+		t.Fatalf("float should be supported, %v", err)
+	}
+	if dtype, err := referenceDtypes.fromLibrary(code); err != nil || dtype != TypeFloat {
+		t.Errorf("dtype should be float, but %v, %v", dtype, err)
+	}
+	if _, err := referenceDtypes.toLibrary(typeUnknownDtype); errcode.Of(err) != errcode.InvalidDtype {
+		t.Errorf("an error of invalid dtype should be occurred, but %v", err)
+	}
+	if _, err := referenceDtypes.fromLibrary(-1); errcode.Of(err) != errcode.InvalidDtype {
+		t.Errorf("an error of invalid dtype should be occurred, but %v", err)
+	}
+}
@@ -1,4 +1,4 @@
-// +build !windows
+// +build !windows,!menoh_reference
 
 // Package external provides APIs to operate Menoh model directory.
 // API design follows menoh.h interface.
@@ -12,8 +12,9 @@ package external
 */
 import "C"
 import (
-	"errors"
 	"unsafe"
+
+	"github.com/pfnet-research/go-menoh/external/errcode"
 )
 
 // ModelData bind. Required to delete after making, call Delete function.
@@ -323,79 +324,14 @@ func toDtype(typeCode C.int) TypeMenohDtype {
 	return TypeMenohDtype(int(typeCode))
 }
 
-type typeMenohError C.menoh_error_code
-
-// Menoh Error type
-const (
-	typeSuccess typeMenohError = iota
-	typeSTDError
-	typeUnknownError
-	typeInvalidFilename
-	typeUnsupportedONNXOpsetVersion
-	typeONNXParseError
-	typeInvalidDtype
-	typeInvalidAttributeType
-	typeUnsupportedOperatorAttribute
-	typeDimensionMismatch
-	typeVariableNotFound
-	typeIndexOutOfRange
-	typeJSONParseError
-	typeInvalidBackendName
-	typeUnsupportedOperator
-	typeFailedToConfigureOperator
-	typeBackendError
-	typeSameNamedVariableAlreadyExist
-	typeUnsupportedInputDims
-	typeSameNamedParameterAlreadyExist
-	typeSameNamedAttributeAlreadyExist
-	typeInvalidBackendConfigError
-	typeInputNotFoundError
-	typeOutputNotFoundError
-)
-
-func (e typeMenohError) String() string {
-	messages := []string{
-		"success",
-		"std error",
-		"unknown error",
-		"invalid filename",
-		"unsupported ONNX opset version",
-		"ONNX parse error",
-		"invalid dtype",
-		"invalid attribute type",
-		"unsupported operator attribute",
-		"dimension mismatch",
-		"variable not found",
-		"index out of range",
-		"JSON parse error",
-		"invalid backend name",
-		"unsupported operator",
-		"failed to configure operator",
-		"backend error",
-		"same named variable already exist",
-		"unsupported input dims",
-		"same named parameter already exist",
-		"same named attribute already exist",
-		"invalid backend config",
-		"input not found",
-		"output not found",
-	}
-
-	if e < typeSuccess || e > typeOutputNotFoundError {
-		return "unknown type error"
-	}
-	return messages[e]
-}
-
 func checkError(errCode C.int) error {
-	errType := typeMenohError(int(errCode))
-	if errType == typeSuccess {
+	code := errcode.Code(int(errCode))
+	if code == errcode.Success {
 		return nil
 	}
-	lastMessage := C.GoString(C.menoh_get_last_error_message())
-	if lastMessage == "" {
-		return errors.New(errType.String())
-	}
 	// last message includes error type
-	return errors.New(lastMessage)
+	return &errcode.Error{
+		Code:    code,
+		Message: C.GoString(C.menoh_get_last_error_message()),
+	}
 }
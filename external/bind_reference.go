@@ -0,0 +1,69 @@
+// +build menoh_reference
+
+package external
+
+import (
+	"github.com/pfnet-research/go-menoh/external/reference"
+)
+
+// With "menoh_reference" build tag, the binding is replaced by the pure-Go
+// interpreter of the reference package, Menoh library is not required.
+
+// ModelData is the reference interpreter's ModelData.
+type ModelData = reference.ModelData
+
+// VariableProfileTableBuilder is the reference interpreter's VariableProfileTableBuilder.
+type VariableProfileTableBuilder = reference.VariableProfileTableBuilder
+
+// VariableProfile is the reference interpreter's VariableProfile.
+type VariableProfile = reference.VariableProfile
+
+// VariableProfileTable is the reference interpreter's VariableProfileTable.
+type VariableProfileTable = reference.VariableProfileTable
+
+// ModelBuilder is the reference interpreter's ModelBuilder.
+type ModelBuilder = reference.ModelBuilder
+
+// Variable is the reference interpreter's Variable.
+type Variable = reference.Variable
+
+// Model is the reference interpreter's Model.
+type Model = reference.Model
+
+// TypeMenohDtype is the reference interpreter's TypeMenohDtype.
+type TypeMenohDtype = reference.TypeMenohDtype
+
+// Dtype
+const (
+	TypeFloat = reference.TypeFloat
+)
+
+// MakeModelDataFromONNX returns ModelData using ONNX file path.
+func MakeModelDataFromONNX(path string) (*ModelData, error) {
+	return reference.MakeModelDataFromONNX(path)
+}
+
+// MakeModelDataFromONNXBytes return ModelData with ONNX file byte data.
+func MakeModelDataFromONNXBytes(data []byte) (*ModelData, error) {
+	return reference.MakeModelDataFromONNXBytes(data)
+}
+
+// MakeModelData returns empty ModelData object, to build manually.
+func MakeModelData() (*ModelData, error) {
+	return reference.MakeModelData()
+}
+
+// MakeVariableProfileTableBuilder returns VariableProfileTableBuilder.
+func MakeVariableProfileTableBuilder() (*VariableProfileTableBuilder, error) {
+	return reference.MakeVariableProfileTableBuilder()
+}
+
+// MakeModelBuilder returns ModelBuilder.
+func MakeModelBuilder(vpt VariableProfileTable) (*ModelBuilder, error) {
+	return reference.MakeModelBuilder(vpt)
+}
+
+// HeaderVersion returns false, the interpreter has no Menoh version.
+func HeaderVersion() (major, minor, patch int, ok bool) {
+	return 0, 0, 0, false
+}
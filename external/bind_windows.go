@@ -1,10 +1,13 @@
+// +build !menoh_reference
+
 package external
 
 import "C"
 import (
-	"errors"
 	"syscall"
 	"unsafe"
+
+	"github.com/pfnet-research/go-menoh/external/errcode"
 )
 
 // ModelData bind. Required to delete after making, call Delete function.
@@ -270,70 +273,6 @@ func toDtype(typeCode int) TypeMenohDtype {
 	return TypeMenohDtype(int(typeCode))
 }
 
-type typeMenohError int
-
-// Menoh Error type
-const (
-	typeSuccess typeMenohError = iota
-	typeSTDError
-	typeUnknownError
-	typeInvalidFilename
-	typeUnsupportedONNXOpsetVersion
-	typeONNXParseError
-	typeInvalidDtype
-	typeInvalidAttributeType
-	typeUnsupportedOperatorAttribute
-	typeDimensionMismatch
-	typeVariableNotFound
-	typeIndexOutOfRange
-	typeJSONParseError
-	typeInvalidBackendName
-	typeUnsupportedOperator
-	typeFailedToConfigureOperator
-	typeBackendError
-	typeSameNamedVariableAlreadyExist
-	typeUnsupportedInputDims
-	typeSameNamedParameterAlreadyExist
-	typeSameNamedAttributeAlreadyExist
-	typeInvalidBackendConfigError
-	typeInputNotFoundError
-	typeOutputNotFoundError
-)
-
-func (e typeMenohError) String() string {
-	messages := []string{
-		"success",
-		"std error",
-		"unknown error",
-		"invalid filename",
-		"unsupported ONNX opset version",
-		"ONNX parse error",
-		"invalid dtype",
-		"invalid attribute type",
-		"unsupported operator attribute",
-		"dimension mismatch",
-		"variable not found",
-		"index out of range",
-		"JSON parse error",
-		"invalid backend name",
-		"unsupported operator",
-		"failed to configure operator",
-		"backend error",
-		"same named variable already exist",
-		"unsupported input dims",
-		"same named parameter already exist",
-		"same named attribute already exist",
-		"invalid backend config",
-		"input not found",
-		"output not found",
-	}
-
-	if e < typeSuccess || e > typeOutputNotFoundError {
-		return "unknown type error"
-	}
-	return messages[e]
-}
-
 func checkError(err error) error {
 	if err == nil {
 		return nil
@@ -341,11 +280,11 @@ func checkError(err error) error {
 	if err == syscall.EINVAL {
 		return err
 	}
-	errType := typeMenohError(uintptr(err.(syscall.Errno)))
-	if errType == typeSuccess {
+	code := errcode.Code(uintptr(err.(syscall.Errno)))
+	if code == errcode.Success {
 		return nil
 	}
 	// MenohGetLastErrorMessage returns uintptr, not convert to string correctly
 	// So not get last message and only return error type
-	return errors.New(errType.String())
+	return &errcode.Error{Code: code}
 }
@@ -0,0 +1,127 @@
+/*
+Package errcode defines error codes of Menoh, same as menoh_error_code of the
+C API, and Error carrying a code. The binding of the external package, the
+reference interpreter and the fake of menohtest package return *Error, so
+that callers classify errors by the code instead of messages:
+
+	if errcode.Of(err) == errcode.VariableNotFound {
+		...
+	}
+*/
+package errcode
+
+import (
+	"strings"
+)
+
+// Code is an error code of Menoh.
+type Code int
+
+// Menoh error codes
+const (
+	Success Code = iota
+	STDError
+	UnknownError
+	InvalidFilename
+	UnsupportedONNXOpsetVersion
+	ONNXParseError
+	InvalidDtype
+	InvalidAttributeType
+	UnsupportedOperatorAttribute
+	DimensionMismatch
+	VariableNotFound
+	IndexOutOfRange
+	JSONParseError
+	InvalidBackendName
+	UnsupportedOperator
+	FailedToConfigureOperator
+	BackendError
+	SameNamedVariableAlreadyExist
+	UnsupportedInputDims
+	SameNamedParameterAlreadyExist
+	SameNamedAttributeAlreadyExist
+	InvalidBackendConfig
+	InputNotFound
+	OutputNotFound
+)
+
+var messages = []string{
+	"success",
+	"std error",
+	"unknown error",
+	"invalid filename",
+	"unsupported ONNX opset version",
+	"ONNX parse error",
+	"invalid dtype",
+	"invalid attribute type",
+	"unsupported operator attribute",
+	"dimension mismatch",
+	"variable not found",
+	"index out of range",
+	"JSON parse error",
+	"invalid backend name",
+	"unsupported operator",
+	"failed to configure operator",
+	"backend error",
+	"same named variable already exist",
+	"unsupported input dims",
+	"same named parameter already exist",
+	"same named attribute already exist",
+	"invalid backend config",
+	"input not found",
+	"output not found",
+}
+
+func (c Code) String() string {
+	if c < Success || c > OutputNotFound {
+		return "unknown type error"
+	}
+	return messages[c]
+}
+
+// Label returns the message of the code in snake case, like
+// "variable_not_found", for labels of metrics.
+func (c Code) Label() string {
+	return strings.Replace(strings.ToLower(c.String()), " ", "_", -1)
+}
+
+// Error is an error of Menoh with the code.
+type Error struct {
+	Code    Code
+	Message string // message of Menoh including the code, or empty
+}
+
+func (e *Error) Error() string {
+	if e.Message == "" {
+		return e.Code.String()
+	}
+	return e.Message
+}
+
+// As returns *Error in the chain of err, following Unwrap methods of
+// wrapping errors, or false if no error is from Menoh.
+func As(err error) (*Error, bool) {
+	for err != nil {
+		if e, ok := err.(*Error); ok {
+			return e, true
+		}
+		w, ok := err.(interface{ Unwrap() error })
+		if !ok {
+			return nil, false
+		}
+		err = w.Unwrap()
+	}
+	return nil, false
+}
+
+// Of returns the code of *Error in the chain of err, Success for nil and
+// UnknownError for errors not from Menoh.
+func Of(err error) Code {
+	if err == nil {
+		return Success
+	}
+	if e, ok := As(err); ok {
+		return e.Code
+	}
+	return UnknownError
+}
@@ -0,0 +1,50 @@
+package errcode
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+// wrapper wraps an error like fmt.Errorf with %w of newer Go.
+type wrapper struct {
+	msg string
+	err error
+}
+
+func (w *wrapper) Error() string { return w.msg + ", " + w.err.Error() }
+func (w *wrapper) Unwrap() error { return w.err }
+
+func TestOf(t *testing.T) {
+	testSet := []struct {
+		name     string
+		err      error
+		expected Code
+	}{
+		{"nil", nil, Success},
+		{"error", &Error{Code: VariableNotFound, Message: "menoh variable not found error: x"}, VariableNotFound},
+		{"wrapped", &wrapper{"cannot build", &Error{Code: InvalidBackendName}}, InvalidBackendName},
+		{"wrapped other", &wrapper{"cannot build", errors.New("backend error")}, UnknownError},
+		{"formatted", fmt.Errorf("cannot build, %v", &Error{Code: InvalidBackendName}), UnknownError},
+		{"other", errors.New("variable not found"), UnknownError},
+	}
+	for _, ts := range testSet {
+		t.Run(ts.name, func(t *testing.T) {
+			if actual := Of(ts.err); actual != ts.expected {
+				t.Errorf("code should be %v, but %v", ts.expected, actual)
+			}
+		})
+	}
+}
+
+func TestError(t *testing.T) {
+	if msg := (&Error{Code: DimensionMismatch}).Error(); msg != "dimension mismatch" {
+		t.Errorf("message of the code should be used without message, but %s", msg)
+	}
+	if label := SameNamedVariableAlreadyExist.Label(); label != "same_named_variable_already_exist" {
+		t.Errorf("label should be snake case, but %s", label)
+	}
+	if s := Code(100).String(); s != "unknown type error" {
+		t.Errorf("unknown code should be described, but %s", s)
+	}
+}
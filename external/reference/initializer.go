@@ -0,0 +1,163 @@
+package reference
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+)
+
+// Initializer is a parameter of ONNX model, values of any supported type
+// are converted to float32.
+type Initializer struct {
+	Name    string
+	Dims    []int32
+	Data    []float32
+	IsFloat bool // false when the values are converted from other type
+}
+
+// ReadInitializers returns initializers of the graph of ONNX model, in order
+// of the model.
+func ReadInitializers(model []byte) ([]*Initializer, error) {
+	initializers := []*Initializer{}
+	err := decodeMessage(model, func(f field) error {
+		if f.number != modelGraph || f.wire != wireBytes {
+			return nil
+		}
+		return decodeMessage(f.raw, func(f field) error {
+			if f.number != graphInitializer || f.wire != wireBytes {
+				return nil
+			}
+			name, t, err := parseTensor(f.raw)
+			if err != nil {
+				return err
+			}
+			initializers = append(initializers, &Initializer{
+				Name:    name,
+				Dims:    t.dims,
+				Data:    t.data,
+				IsFloat: t.dataType == dataTypeFloat,
+			})
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, newError(errONNXParseError, "%v", err)
+	}
+	return initializers, nil
+}
+
+// ReplaceInitializer returns ONNX model whose float32 initializer of the
+// name is replaced by the values, other fields are kept as they are. The
+// shape must be same as the replaced one.
+func ReplaceInitializer(model []byte, name string, dims []int32, data []float32) ([]byte, error) {
+	if len(data) != sizeOf(dims) {
+		return nil, newError(errDimensionMismatch, "%s should have %d values, but %d", name, sizeOf(dims), len(data))
+	}
+	replaced := false
+	replaceGraph := func(graph []byte) ([]byte, error) {
+		b := []byte{}
+		err := decodeMessage(graph, func(f field) error {
+			if f.number != graphInitializer || f.wire != wireBytes {
+				b = appendField(b, f)
+				return nil
+			}
+			n, t, err := parseTensor(f.raw)
+			if err != nil {
+				return err
+			}
+			if n != name {
+				b = appendField(b, f)
+				return nil
+			}
+			if t.dataType != dataTypeFloat {
+				return fmt.Errorf("initializer '%s' is not float32", name)
+			}
+			if !equalDims(t.dims, dims) {
+				return newError(errDimensionMismatch, "%s is %v, but %v", name, t.dims, dims)
+			}
+			replaced = true
+			b = appendBytes(b, graphInitializer, encodeFloatTensor(name, dims, data))
+			return nil
+		})
+		return b, err
+	}
+
+	b := []byte{}
+	err := decodeMessage(model, func(f field) error {
+		if f.number != modelGraph || f.wire != wireBytes {
+			b = appendField(b, f)
+			return nil
+		}
+		graph, err := replaceGraph(f.raw)
+		if err != nil {
+			return err
+		}
+		b = appendBytes(b, modelGraph, graph)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if !replaced {
+		return nil, newError(errVariableNotFound, "%s", name)
+	}
+	return b, nil
+}
+
+func equalDims(a, b []int32) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// encodeFloatTensor encodes TensorProto with raw data.
+func encodeFloatTensor(name string, dims []int32, data []float32) []byte {
+	b := []byte{}
+	for _, d := range dims {
+		b = appendVarint(appendKey(b, tensorDims, wireVarint), uint64(d))
+	}
+	b = appendVarint(appendKey(b, tensorDataType, wireVarint), dataTypeFloat)
+	b = appendBytes(b, tensorName, []byte(name))
+	raw := make([]byte, 4*len(data))
+	for i, v := range data {
+		binary.LittleEndian.PutUint32(raw[i*4:], math.Float32bits(v))
+	}
+	return appendBytes(b, tensorRawData, raw)
+}
+
+// appendField encodes the decoded field again.
+func appendField(b []byte, f field) []byte {
+	b = appendKey(b, f.number, f.wire)
+	switch f.wire {
+	case wireVarint:
+		return appendVarint(b, f.num)
+	case wireFixed64:
+		buf := make([]byte, 8)
+		binary.LittleEndian.PutUint64(buf, f.num)
+		return append(b, buf...)
+	case wireFixed32:
+		buf := make([]byte, 4)
+		binary.LittleEndian.PutUint32(buf, uint32(f.num))
+		return append(b, buf...)
+	}
+	return append(appendVarint(b, uint64(len(f.raw))), f.raw...)
+}
+
+func appendKey(b []byte, number, wire int) []byte {
+	return appendVarint(b, uint64(number<<3|wire))
+}
+
+func appendVarint(b []byte, v uint64) []byte {
+	buf := make([]byte, binary.MaxVarintLen64)
+	return append(b, buf[:binary.PutUvarint(buf, v)]...)
+}
+
+func appendBytes(b []byte, number int, raw []byte) []byte {
+	return append(appendVarint(appendKey(b, number, wireBytes), uint64(len(raw))), raw...)
+}
@@ -0,0 +1,85 @@
+package reference
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestReadInitializers(t *testing.T) {
+	model := encodeModel(
+		[]encoder{encodeNode("Gemm", []string{"x", "W", "B"}, []string{"y"})},
+		[]encoder{
+			encodeTensor("W", []int64{2, 3}, []float32{1, 2, 3, 4, 5, 6}),
+			encoder{}.str(tensorName, "shape").packedInts(tensorDims, []int64{2}).
+				int(tensorDataType, dataTypeInt64).packedInts(tensorInt64Data, []int64{1, 6}),
+		})
+	initializers, err := ReadInitializers(model)
+	if err != nil {
+		t.Fatalf("initializers should be read, %v", err)
+	}
+	expected := []*Initializer{
+		{Name: "W", Dims: []int32{2, 3}, Data: []float32{1, 2, 3, 4, 5, 6}, IsFloat: true},
+		{Name: "shape", Dims: []int32{2}, Data: []float32{1, 6}},
+	}
+	if !reflect.DeepEqual(initializers, expected) {
+		t.Errorf("initializers should be %v, but %v", expected, initializers)
+	}
+
+	if _, err := ReadInitializers([]byte("dummy data")); err == nil {
+		t.Error("an error should be occurred with invalid model")
+	}
+}
+
+func TestReplaceInitializer(t *testing.T) {
+	model := encodeModel(
+		[]encoder{encodeNode("Gemm", []string{"x", "W", "B"}, []string{"y"})},
+		[]encoder{
+			encodeTensor("W", []int64{2, 3}, []float32{1, 2, 3, 4, 5, 6}),
+			encodeTensor("B", []int64{2}, []float32{1, 1}),
+			encoder{}.str(tensorName, "shape").packedInts(tensorDims, []int64{2}).
+				int(tensorDataType, dataTypeInt64).packedInts(tensorInt64Data, []int64{1, 6}),
+		})
+	replaced, err := ReplaceInitializer(model, "W", []int32{2, 3}, []float32{6, 5, 4, 3, 2, 1})
+	if err != nil {
+		t.Fatalf("initializer should be replaced, %v", err)
+	}
+	m, err := MakeModelDataFromONNXBytes(replaced)
+	if err != nil {
+		t.Fatalf("replaced model should be parsed, %v", err)
+	}
+	if len(m.nodes) != 1 || m.nodes[0].opType != "Gemm" {
+		t.Errorf("nodes should be kept, but %v", m.nodes)
+	}
+	if w := m.params["W"]; w == nil || !reflect.DeepEqual(w.data, []float32{6, 5, 4, 3, 2, 1}) {
+		t.Errorf("W should be replaced, but %v", w)
+	}
+	if b := m.params["B"]; b == nil || !reflect.DeepEqual(b.data, []float32{1, 1}) {
+		t.Errorf("B should be kept, but %v", b)
+	}
+
+	// fail
+	testSet := []struct {
+		name   string
+		target string
+		dims   []int32
+		data   []float32
+		msg    string
+	}{
+		{"not found", "dummy", []int32{1}, []float32{0}, errVariableNotFound.String()},
+		{"size mismatch", "W", []int32{2, 3}, []float32{0}, errDimensionMismatch.String()},
+		{"shape mismatch", "W", []int32{3, 2}, make([]float32, 6), errDimensionMismatch.String()},
+		{"not float", "shape", []int32{2}, []float32{1, 6}, "not float32"},
+	}
+	for _, ts := range testSet {
+		t.Run(ts.name, func(t *testing.T) {
+			_, err := ReplaceInitializer(model, ts.target, ts.dims, ts.data)
+			if err == nil {
+				t.Fatal("an error should be occurred")
+			}
+			if !strings.Contains(err.Error(), ts.msg) {
+				t.Errorf("error message should contain '%s', but %v", ts.msg, err)
+			}
+		})
+	}
+}
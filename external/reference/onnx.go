@@ -0,0 +1,364 @@
+package reference
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+)
+
+// Field numbers and data types of onnx.proto used by the interpreter.
+const (
+	modelGraph = 7
+
+	graphNode        = 1
+	graphInitializer = 5
+
+	nodeInput     = 1
+	nodeOutput    = 2
+	nodeOpType    = 4
+	nodeAttribute = 5
+
+	attributeName   = 1
+	attributeF      = 2
+	attributeI      = 3
+	attributeS      = 4
+	attributeT      = 5
+	attributeFloats = 7
+	attributeInts   = 8
+
+	tensorDims      = 1
+	tensorDataType  = 2
+	tensorFloatData = 4
+	tensorInt32Data = 5
+	tensorInt64Data = 7
+	tensorName      = 8
+	tensorRawData   = 9
+	tensorDouble    = 10
+
+	dataTypeFloat  = 1
+	dataTypeUint8  = 2
+	dataTypeInt8   = 3
+	dataTypeInt32  = 6
+	dataTypeInt64  = 7
+	dataTypeDouble = 11
+)
+
+// Wire types of protocol buffers.
+const (
+	wireVarint  = 0
+	wireFixed64 = 1
+	wireBytes   = 2
+	wireFixed32 = 5
+)
+
+var errTruncated = errors.New("truncated message")
+
+// field is a decoded field of a protocol buffers message. num is set to
+// varint values and fixed values, raw is set to length-delimited values.
+type field struct {
+	number int
+	wire   int
+	num    uint64
+	raw    []byte
+}
+
+// decodeMessage calls f with each field of the message.
+func decodeMessage(b []byte, f func(field) error) error {
+	for len(b) > 0 {
+		key, n := binary.Uvarint(b)
+		if n <= 0 {
+			return errTruncated
+		}
+		b = b[n:]
+		fd := field{number: int(key >> 3), wire: int(key & 7)}
+		switch fd.wire {
+		case wireVarint:
+			v, n := binary.Uvarint(b)
+			if n <= 0 {
+				return errTruncated
+			}
+			fd.num, b = v, b[n:]
+		case wireFixed64:
+			if len(b) < 8 {
+				return errTruncated
+			}
+			fd.num, b = binary.LittleEndian.Uint64(b), b[8:]
+		case wireFixed32:
+			if len(b) < 4 {
+				return errTruncated
+			}
+			fd.num, b = uint64(binary.LittleEndian.Uint32(b)), b[4:]
+		case wireBytes:
+			l, n := binary.Uvarint(b)
+			if n <= 0 || uint64(len(b)-n) < l {
+				return errTruncated
+			}
+			fd.raw, b = b[n:n+int(l)], b[n+int(l):]
+		default:
+			return fmt.Errorf("unsupported wire type %d", fd.wire)
+		}
+		if err := f(fd); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// varints returns values of a repeated integer field, packed or not.
+func (f field) varints() ([]int64, error) {
+	if f.wire == wireVarint {
+		return []int64{int64(f.num)}, nil
+	}
+	values := []int64{}
+	b := f.raw
+	for len(b) > 0 {
+		v, n := binary.Uvarint(b)
+		if n <= 0 {
+			return nil, errTruncated
+		}
+		values = append(values, int64(v))
+		b = b[n:]
+	}
+	return values, nil
+}
+
+// floats returns values of a repeated float field, packed or not.
+func (f field) floats() ([]float32, error) {
+	if f.wire == wireFixed32 {
+		return []float32{math.Float32frombits(uint32(f.num))}, nil
+	}
+	if len(f.raw)%4 != 0 {
+		return nil, errTruncated
+	}
+	values := make([]float32, len(f.raw)/4)
+	for i := range values {
+		values[i] = math.Float32frombits(binary.LittleEndian.Uint32(f.raw[i*4:]))
+	}
+	return values, nil
+}
+
+// doubles returns values of a repeated double field as float32.
+func (f field) doubles() ([]float32, error) {
+	if f.wire == wireFixed64 {
+		return []float32{float32(math.Float64frombits(f.num))}, nil
+	}
+	if len(f.raw)%8 != 0 {
+		return nil, errTruncated
+	}
+	values := make([]float32, len(f.raw)/8)
+	for i := range values {
+		values[i] = float32(math.Float64frombits(binary.LittleEndian.Uint64(f.raw[i*8:])))
+	}
+	return values, nil
+}
+
+// parseModel reads nodes and initializers of ModelProto. Constant nodes are
+// converted to parameters.
+func parseModel(b []byte) (*ModelData, error) {
+	m := newModelData()
+	hasGraph := false
+	err := decodeMessage(b, func(f field) error {
+		if f.number != modelGraph || f.wire != wireBytes {
+			return nil
+		}
+		hasGraph = true
+		return parseGraph(f.raw, m)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if !hasGraph {
+		return nil, errors.New("graph is not found")
+	}
+	return m, nil
+}
+
+func parseGraph(b []byte, m *ModelData) error {
+	return decodeMessage(b, func(f field) error {
+		if f.wire != wireBytes {
+			return nil
+		}
+		switch f.number {
+		case graphNode:
+			n, err := parseNode(f.raw)
+			if err != nil {
+				return err
+			}
+			if n.opType == "Constant" && len(n.outputs) == 1 && n.attrs["value"] != nil {
+				m.params[n.outputs[0]] = n.attrs["value"].t
+				return nil
+			}
+			m.nodes = append(m.nodes, n)
+		case graphInitializer:
+			name, t, err := parseTensor(f.raw)
+			if err != nil {
+				return err
+			}
+			m.params[name] = t
+		}
+		return nil
+	})
+}
+
+func parseNode(b []byte) (*node, error) {
+	n := &node{attrs: map[string]*attribute{}}
+	err := decodeMessage(b, func(f field) error {
+		if f.wire != wireBytes {
+			return nil
+		}
+		switch f.number {
+		case nodeInput:
+			n.inputs = append(n.inputs, string(f.raw))
+		case nodeOutput:
+			n.outputs = append(n.outputs, string(f.raw))
+		case nodeOpType:
+			n.opType = string(f.raw)
+		case nodeAttribute:
+			name, a, err := parseAttribute(f.raw)
+			if err != nil {
+				return err
+			}
+			n.attrs[name] = a
+		}
+		return nil
+	})
+	return n, err
+}
+
+func parseAttribute(b []byte) (string, *attribute, error) {
+	name := ""
+	a := &attribute{}
+	err := decodeMessage(b, func(f field) error {
+		switch f.number {
+		case attributeName:
+			name = string(f.raw)
+		case attributeF:
+			a.f = math.Float32frombits(uint32(f.num))
+		case attributeI:
+			a.i = int(int64(f.num))
+		case attributeS:
+			a.s = string(f.raw)
+		case attributeT:
+			_, t, err := parseTensor(f.raw)
+			if err != nil {
+				return err
+			}
+			a.t = t
+		case attributeFloats:
+			v, err := f.floats()
+			if err != nil {
+				return err
+			}
+			a.floats = append(a.floats, v...)
+		case attributeInts:
+			v, err := f.varints()
+			if err != nil {
+				return err
+			}
+			for _, i := range v {
+				a.ints = append(a.ints, int(i))
+			}
+		}
+		return nil
+	})
+	return name, a, err
+}
+
+// parseTensor reads TensorProto, values of any supported type are converted
+// to float32.
+func parseTensor(b []byte) (string, *tensor, error) {
+	name := ""
+	dataType := 0
+	t := &tensor{dims: []int32{}}
+	var raw []byte
+	var data []float32
+	err := decodeMessage(b, func(f field) error {
+		switch f.number {
+		case tensorDims:
+			v, err := f.varints()
+			if err != nil {
+				return err
+			}
+			for _, d := range v {
+				t.dims = append(t.dims, int32(d))
+			}
+		case tensorDataType:
+			dataType = int(f.num)
+		case tensorName:
+			name = string(f.raw)
+		case tensorRawData:
+			raw = f.raw
+		case tensorFloatData:
+			v, err := f.floats()
+			if err != nil {
+				return err
+			}
+			data = append(data, v...)
+		case tensorInt32Data, tensorInt64Data:
+			v, err := f.varints()
+			if err != nil {
+				return err
+			}
+			for _, i := range v {
+				if f.number == tensorInt32Data {
+					i = int64(int32(i))
+				}
+				data = append(data, float32(i))
+			}
+		case tensorDouble:
+			v, err := f.doubles()
+			if err != nil {
+				return err
+			}
+			data = append(data, v...)
+		}
+		return nil
+	})
+	if err != nil {
+		return "", nil, err
+	}
+	if raw != nil {
+		if data, err = decodeRaw(raw, dataType); err != nil {
+			return "", nil, fmt.Errorf("tensor '%s', %v", name, err)
+		}
+	}
+	if len(data) != sizeOf(t.dims) {
+		return "", nil, fmt.Errorf("tensor '%s' should have %d values, but %d",
+			name, sizeOf(t.dims), len(data))
+	}
+	t.data = data
+	t.dataType = dataType
+	return name, t, nil
+}
+
+func decodeRaw(raw []byte, dataType int) ([]float32, error) {
+	size := map[int]int{
+		dataTypeFloat: 4, dataTypeUint8: 1, dataTypeInt8: 1, dataTypeInt32: 4, dataTypeInt64: 8, dataTypeDouble: 8,
+	}[dataType]
+	if size == 0 {
+		return nil, fmt.Errorf("data type %d is not supported", dataType)
+	}
+	if len(raw)%size != 0 {
+		return nil, errTruncated
+	}
+	data := make([]float32, len(raw)/size)
+	for i := range data {
+		b := raw[i*size:]
+		switch dataType {
+		case dataTypeFloat:
+			data[i] = math.Float32frombits(binary.LittleEndian.Uint32(b))
+		case dataTypeUint8:
+			data[i] = float32(b[0])
+		case dataTypeInt8:
+			data[i] = float32(int8(b[0]))
+		case dataTypeInt32:
+			data[i] = float32(int32(binary.LittleEndian.Uint32(b)))
+		case dataTypeInt64:
+			data[i] = float32(int64(binary.LittleEndian.Uint64(b)))
+		case dataTypeDouble:
+			data[i] = float32(math.Float64frombits(binary.LittleEndian.Uint64(b)))
+		}
+	}
+	return data, nil
+}
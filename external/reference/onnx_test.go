@@ -0,0 +1,157 @@
+package reference
+
+import (
+	"encoding/binary"
+	"math"
+	"testing"
+)
+
+// encoder writes protocol buffers messages for tests.
+type encoder []byte
+
+func (e encoder) key(number, wire int) encoder {
+	return e.varint(uint64(number<<3 | wire))
+}
+
+func (e encoder) varint(v uint64) encoder {
+	buf := make([]byte, binary.MaxVarintLen64)
+	return append(e, buf[:binary.PutUvarint(buf, v)]...)
+}
+
+func (e encoder) bytes(number int, b []byte) encoder {
+	return append(e.key(number, wireBytes).varint(uint64(len(b))), b...)
+}
+
+func (e encoder) str(number int, s string) encoder {
+	return e.bytes(number, []byte(s))
+}
+
+func (e encoder) int(number int, v int64) encoder {
+	return e.key(number, wireVarint).varint(uint64(v))
+}
+
+func (e encoder) float(number int, v float32) encoder {
+	buf := make([]byte, 4)
+	binary.LittleEndian.PutUint32(buf, math.Float32bits(v))
+	return append(e.key(number, wireFixed32), buf...)
+}
+
+func (e encoder) packedFloats(number int, values []float32) encoder {
+	buf := make([]byte, 4*len(values))
+	for i, v := range values {
+		binary.LittleEndian.PutUint32(buf[i*4:], math.Float32bits(v))
+	}
+	return e.bytes(number, buf)
+}
+
+func (e encoder) packedInts(number int, values []int64) encoder {
+	packed := encoder{}
+	for _, v := range values {
+		packed = packed.varint(uint64(v))
+	}
+	return e.bytes(number, packed)
+}
+
+func encodeTensor(name string, dims []int64, data []float32) encoder {
+	return encoder{}.str(tensorName, name).packedInts(tensorDims, dims).
+		int(tensorDataType, dataTypeFloat).packedFloats(tensorFloatData, data)
+}
+
+func encodeNode(opType string, inputs, outputs []string, attrs ...encoder) encoder {
+	e := encoder{}
+	for _, in := range inputs {
+		e = e.str(nodeInput, in)
+	}
+	for _, out := range outputs {
+		e = e.str(nodeOutput, out)
+	}
+	e = e.str(nodeOpType, opType)
+	for _, a := range attrs {
+		e = e.bytes(nodeAttribute, a)
+	}
+	return e
+}
+
+func encodeModel(nodes []encoder, initializers []encoder) []byte {
+	graph := encoder{}
+	for _, n := range nodes {
+		graph = graph.bytes(graphNode, n)
+	}
+	for _, t := range initializers {
+		graph = graph.bytes(graphInitializer, t)
+	}
+	// ir_version and producer_name are skipped
+	return encoder{}.int(1, 3).str(2, "test").bytes(modelGraph, graph)
+}
+
+func TestParseModel(t *testing.T) {
+	rawShape := make([]byte, 16)
+	binary.LittleEndian.PutUint64(rawShape, 1)
+	binary.LittleEndian.PutUint64(rawShape[8:], uint64(0xffffffffffffffff)) // -1
+	shape := encoder{}.str(tensorName, "shape").packedInts(tensorDims, []int64{2}).
+		int(tensorDataType, dataTypeInt64).bytes(tensorRawData, rawShape)
+	data := encodeModel([]encoder{
+		encodeNode("Gemm", []string{"x", "W", "b"}, []string{"y"},
+			encoder{}.str(attributeName, "transB").int(attributeI, 1),
+			encoder{}.str(attributeName, "alpha").float(attributeF, 0.5)),
+		encodeNode("Constant", nil, []string{"c"},
+			encoder{}.str(attributeName, "value").bytes(attributeT, encodeTensor("", []int64{1}, []float32{2}))),
+		encodeNode("MaxPool", []string{"z"}, []string{"p"},
+			encoder{}.str(attributeName, "kernel_shape").packedInts(attributeInts, []int64{2, 2})),
+	}, []encoder{
+		encodeTensor("W", []int64{2, 3}, []float32{1, 2, 3, 4, 5, 6}),
+		encodeTensor("b", []int64{2}, []float32{1, 1}),
+		shape,
+		encoder{}.str(tensorName, "q").packedInts(tensorDims, []int64{2}).
+			int(tensorDataType, dataTypeInt8).bytes(tensorRawData, []byte{0xff, 0x7f}),
+	})
+
+	m, err := MakeModelDataFromONNXBytes(data)
+	if err != nil {
+		t.Fatalf("model should be parsed, %v", err)
+	}
+	if len(m.nodes) != 2 {
+		t.Fatalf("constant should be converted to parameter, but nodes %d", len(m.nodes))
+	}
+	gemm := m.nodes[0]
+	if gemm.opType != "Gemm" || len(gemm.inputs) != 3 || gemm.outputs[0] != "y" {
+		t.Errorf("Gemm node should be parsed, but %+v", gemm)
+	}
+	if gemm.intAttr("transB", 0) != 1 || gemm.floatAttr("alpha", 1) != 0.5 {
+		t.Errorf("attributes should be parsed, but %+v", gemm.attrs)
+	}
+	if ints := m.nodes[1].intsAttr("kernel_shape", nil); len(ints) != 2 || ints[0] != 2 {
+		t.Errorf("ints attribute should be parsed, but %v", ints)
+	}
+	if w := m.params["W"]; w == nil || !sameDims(w.dims, []int32{2, 3}) || w.data[5] != 6 {
+		t.Errorf("initializer should be parsed, but %+v", w)
+	}
+	if c := m.params["c"]; c == nil || c.data[0] != 2 {
+		t.Errorf("constant should be parsed, but %+v", c)
+	}
+	if s := m.params["shape"]; s == nil || s.data[0] != 1 || s.data[1] != -1 {
+		t.Errorf("int64 raw data should be converted, but %+v", s)
+	}
+	if q := m.params["q"]; q == nil || q.data[0] != -1 || q.data[1] != 127 || q.dataType != dataTypeInt8 {
+		t.Errorf("int8 raw data should be converted, but %+v", q)
+	}
+
+	// fail
+	testSet := []struct {
+		name string
+		data []byte
+	}{
+		{"no graph", encoder{}.int(1, 3)},
+		{"truncated", data[:len(data)-1]},
+		{"broken tensor", encodeModel(nil, []encoder{
+			encoder{}.str(tensorName, "W").packedInts(tensorDims, []int64{2}).packedFloats(tensorFloatData, []float32{1}),
+		})},
+	}
+	for _, ts := range testSet {
+		t.Run(ts.name, func(t *testing.T) {
+			if _, err := MakeModelDataFromONNXBytes(ts.data); err == nil {
+				t.Error("an error should be occurred")
+			}
+		})
+	}
+}
@@ -0,0 +1,712 @@
+package reference
+
+import (
+	"math"
+)
+
+// operator infers the output shape and computes the output of a node.
+type operator struct {
+	infer func(n *node, in [][]int32, params map[string]*tensor) ([]int32, error)
+	run   func(n *node, in []*tensor, out *tensor) error
+}
+
+var operators = map[string]operator{
+	"Relu":               {inferUnary, runRelu},
+	"Softmax":            {inferUnary, runSoftmax},
+	"Add":                {inferBinary, runBinary(func(a, b float32) float32 { return a + b })},
+	"Mul":                {inferBinary, runBinary(func(a, b float32) float32 { return a * b })},
+	"Gemm":               {inferGemm, runGemm},
+	"Conv":               {inferConv, runConv},
+	"MaxPool":            {inferPool, runMaxPool},
+	"AveragePool":        {inferPool, runAveragePool},
+	"Reshape":            {inferReshape, runCopy},
+	"Concat":             {inferConcat, runConcat},
+	"BatchNormalization": {inferBatchNormalization, runBatchNormalization},
+	"QuantizeLinear":     {inferQuantization, runQuantizeLinear},
+	"DequantizeLinear":   {inferQuantization, runDequantizeLinear},
+}
+
+// IsSupported returns whether the interpreter runs the operator type.
+func IsSupported(opType string) bool {
+	_, ok := operators[opType]
+	return ok
+}
+
+func (n *node) intAttr(name string, def int) int {
+	if a, ok := n.attrs[name]; ok {
+		return a.i
+	}
+	return def
+}
+
+func (n *node) floatAttr(name string, def float32) float32 {
+	if a, ok := n.attrs[name]; ok {
+		return a.f
+	}
+	return def
+}
+
+func (n *node) intsAttr(name string, def []int) []int {
+	if a, ok := n.attrs[name]; ok && len(a.ints) > 0 {
+		return a.ints
+	}
+	return def
+}
+
+func checkInputs(n *node, in [][]int32, required int) error {
+	if len(in) < required {
+		return newError(errVariableNotFound, "%s requires %d inputs, but %d", n.opType, required, len(in))
+	}
+	for i := 0; i < required; i++ {
+		if in[i] == nil {
+			return newError(errVariableNotFound, "input %d of %s is omitted", i, n.opType)
+		}
+	}
+	return nil
+}
+
+func checkRank(n *node, dims []int32, rank int) error {
+	if len(dims) != rank {
+		return newError(errDimensionMismatch, "%s requires %d-D input, but %v", n.opType, rank, dims)
+	}
+	return nil
+}
+
+// normalizeAxis converts a negative axis to positive.
+func normalizeAxis(n *node, axis, rank int) (int, error) {
+	if axis < 0 {
+		axis += rank
+	}
+	if axis < 0 || axis >= rank {
+		return 0, newError(errUnsupportedAttribute, "axis %d of %s is out of range", axis, n.opType)
+	}
+	return axis, nil
+}
+
+func inferUnary(n *node, in [][]int32, params map[string]*tensor) ([]int32, error) {
+	if err := checkInputs(n, in, 1); err != nil {
+		return nil, err
+	}
+	return in[0], nil
+}
+
+func runRelu(n *node, in []*tensor, out *tensor) error {
+	for i, x := range in[0].data {
+		if x < 0 {
+			x = 0
+		}
+		out.data[i] = x
+	}
+	return nil
+}
+
+func runCopy(n *node, in []*tensor, out *tensor) error {
+	copy(out.data, in[0].data)
+	return nil
+}
+
+func runSoftmax(n *node, in []*tensor, out *tensor) error {
+	axis, err := normalizeAxis(n, n.intAttr("axis", 1), len(in[0].dims))
+	if err != nil {
+		return err
+	}
+	inner := sizeOf(in[0].dims[axis:])
+	x := in[0].data
+	for base := 0; base < len(x); base += inner {
+		max := x[base]
+		for _, v := range x[base : base+inner] {
+			if v > max {
+				max = v
+			}
+		}
+		sum := float32(0)
+		for i := base; i < base+inner; i++ {
+			out.data[i] = float32(math.Exp(float64(x[i] - max)))
+			sum += out.data[i]
+		}
+		for i := base; i < base+inner; i++ {
+			out.data[i] /= sum
+		}
+	}
+	return nil
+}
+
+// alignDims returns dims of the second input aligned to the first one. Old
+// opsets use "broadcast" and "axis" attributes instead of numpy-style
+// broadcasting.
+func alignDims(n *node, a, b []int32) []int32 {
+	axis, ok := n.attrs["axis"]
+	if n.intAttr("broadcast", 0) == 0 || !ok || axis.i < 0 || axis.i+len(b) > len(a) {
+		return b
+	}
+	aligned := make([]int32, len(a)-axis.i)
+	for i := range aligned {
+		aligned[i] = 1
+	}
+	copy(aligned, b)
+	return aligned
+}
+
+// broadcastDims returns the shape of multidirectional broadcasting.
+func broadcastDims(a, b []int32) ([]int32, bool) {
+	rank := len(a)
+	if len(b) > rank {
+		rank = len(b)
+	}
+	out := make([]int32, rank)
+	for i := range out {
+		da, db := int32(1), int32(1)
+		if j := i - (rank - len(a)); j >= 0 {
+			da = a[j]
+		}
+		if j := i - (rank - len(b)); j >= 0 {
+			db = b[j]
+		}
+		switch {
+		case da == db || db == 1:
+			out[i] = da
+		case da == 1:
+			out[i] = db
+		default:
+			return nil, false
+		}
+	}
+	return out, true
+}
+
+// broadcastStrides returns strides of dims broadcast to outDims, broadcast
+// dimensions have zero stride.
+func broadcastStrides(dims, outDims []int32) []int {
+	strides := make([]int, len(outDims))
+	stride := 1
+	for i := len(outDims) - 1; i >= 0; i-- {
+		j := i - (len(outDims) - len(dims))
+		if j < 0 {
+			continue
+		}
+		if dims[j] != 1 {
+			strides[i] = stride
+		}
+		stride *= int(dims[j])
+	}
+	return strides
+}
+
+// broadcastOffsets returns offsets of each element of outDims in the tensors
+// with dims.
+func broadcastOffsets(dims, outDims []int32) []int {
+	strides := broadcastStrides(dims, outDims)
+	offsets := make([]int, sizeOf(outDims))
+	index := make([]int32, len(outDims))
+	offset := 0
+	for i := range offsets {
+		offsets[i] = offset
+		for d := len(outDims) - 1; d >= 0; d-- {
+			index[d]++
+			offset += strides[d]
+			if index[d] < outDims[d] {
+				break
+			}
+			offset -= strides[d] * int(index[d])
+			index[d] = 0
+		}
+	}
+	return offsets
+}
+
+func inferBinary(n *node, in [][]int32, params map[string]*tensor) ([]int32, error) {
+	if err := checkInputs(n, in, 2); err != nil {
+		return nil, err
+	}
+	out, ok := broadcastDims(in[0], alignDims(n, in[0], in[1]))
+	if !ok {
+		return nil, newError(errDimensionMismatch, "%s cannot broadcast %v and %v", n.opType, in[0], in[1])
+	}
+	return out, nil
+}
+
+func runBinary(f func(a, b float32) float32) func(n *node, in []*tensor, out *tensor) error {
+	return func(n *node, in []*tensor, out *tensor) error {
+		a, b := in[0], in[1]
+		aOffsets := broadcastOffsets(a.dims, out.dims)
+		bOffsets := broadcastOffsets(alignDims(n, a.dims, b.dims), out.dims)
+		for i := range out.data {
+			out.data[i] = f(a.data[aOffsets[i]], b.data[bOffsets[i]])
+		}
+		return nil
+	}
+}
+
+// gemmDims returns M, K of A and K, N of B. A with rank more than 2 is
+// flattened to 2-D.
+func gemmDims(n *node, a, b []int32) (m, ka, kb, nn int, err error) {
+	if len(a) < 2 {
+		return 0, 0, 0, 0, newError(errDimensionMismatch, "Gemm requires 2-D A, but %v", a)
+	}
+	if err := checkRank(n, b, 2); err != nil {
+		return 0, 0, 0, 0, err
+	}
+	m, ka = int(a[0]), sizeOf(a[1:])
+	if n.intAttr("transA", 0) != 0 {
+		m, ka = ka, m
+	}
+	kb, nn = int(b[0]), int(b[1])
+	if n.intAttr("transB", 0) != 0 {
+		kb, nn = nn, kb
+	}
+	return m, ka, kb, nn, nil
+}
+
+func inferGemm(n *node, in [][]int32, params map[string]*tensor) ([]int32, error) {
+	if err := checkInputs(n, in, 2); err != nil {
+		return nil, err
+	}
+	m, ka, kb, nn, err := gemmDims(n, in[0], in[1])
+	if err != nil {
+		return nil, err
+	}
+	if ka != kb {
+		return nil, newError(errDimensionMismatch, "Gemm cannot multiply %v and %v", in[0], in[1])
+	}
+	out := []int32{int32(m), int32(nn)}
+	if len(in) > 2 && in[2] != nil {
+		if d, ok := broadcastDims(out, in[2]); !ok || !sameDims(d, out) {
+			return nil, newError(errDimensionMismatch, "Gemm cannot broadcast C %v to %v", in[2], out)
+		}
+	}
+	return out, nil
+}
+
+func sameDims(a, b []int32) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func runGemm(n *node, in []*tensor, out *tensor) error {
+	m, k, _, nn, err := gemmDims(n, in[0].dims, in[1].dims)
+	if err != nil {
+		return err
+	}
+	transA, transB := n.intAttr("transA", 0) != 0, n.intAttr("transB", 0) != 0
+	alpha, beta := n.floatAttr("alpha", 1), n.floatAttr("beta", 1)
+	a, b := in[0].data, in[1].data
+	var c []float32
+	var cOffsets []int
+	if len(in) > 2 && in[2] != nil {
+		c, cOffsets = in[2].data, broadcastOffsets(in[2].dims, out.dims)
+	}
+	for i := 0; i < m; i++ {
+		for j := 0; j < nn; j++ {
+			sum := float32(0)
+			for l := 0; l < k; l++ {
+				av := a[i*k+l]
+				if transA {
+					av = a[l*m+i]
+				}
+				bv := b[l*nn+j]
+				if transB {
+					bv = b[j*k+l]
+				}
+				sum += av * bv
+			}
+			v := alpha * sum
+			if c != nil {
+				v += beta * c[cOffsets[i*nn+j]]
+			}
+			out.data[i*nn+j] = v
+		}
+	}
+	return nil
+}
+
+// window is a 2-D sliding window of Conv and pooling.
+type window struct {
+	kernel, strides, dilations []int
+	pads                       []int // top, left, bottom, right
+}
+
+func makeWindow(n *node, kernel []int) (*window, error) {
+	if autoPad, ok := n.attrs["auto_pad"]; ok && autoPad.s != "" && autoPad.s != "NOTSET" {
+		return nil, newError(errUnsupportedAttribute, "auto_pad %s of %s", autoPad.s, n.opType)
+	}
+	w := &window{
+		kernel:    n.intsAttr("kernel_shape", kernel),
+		strides:   n.intsAttr("strides", []int{1, 1}),
+		dilations: n.intsAttr("dilations", []int{1, 1}),
+		pads:      n.intsAttr("pads", []int{0, 0, 0, 0}),
+	}
+	if len(w.kernel) != 2 || len(w.strides) != 2 || len(w.dilations) != 2 || len(w.pads) != 4 {
+		return nil, newError(errUnsupportedAttribute, "%s supports only 2-D window", n.opType)
+	}
+	return w, nil
+}
+
+// outSize returns output size of the spatial axis, 0 for height and 1 for
+// width.
+func (w *window) outSize(size int32, axis int, ceil bool) int32 {
+	extent := (w.kernel[axis]-1)*w.dilations[axis] + 1
+	padded := int(size) + w.pads[axis] + w.pads[axis+2] - extent
+	if ceil {
+		padded += w.strides[axis] - 1
+	}
+	return int32(padded/w.strides[axis] + 1)
+}
+
+func inferConv(n *node, in [][]int32, params map[string]*tensor) ([]int32, error) {
+	if err := checkInputs(n, in, 2); err != nil {
+		return nil, err
+	}
+	x, w := in[0], in[1]
+	if err := checkRank(n, x, 4); err != nil {
+		return nil, err
+	}
+	if err := checkRank(n, w, 4); err != nil {
+		return nil, err
+	}
+	win, err := makeWindow(n, []int{int(w[2]), int(w[3])})
+	if err != nil {
+		return nil, err
+	}
+	group := int32(n.intAttr("group", 1))
+	if group <= 0 || x[1] != w[1]*group || w[0]%group != 0 {
+		return nil, newError(errDimensionMismatch, "Conv cannot apply W %v to X %v with group %d", w, x, group)
+	}
+	if len(in) > 2 && in[2] != nil && !sameDims(in[2], []int32{w[0]}) {
+		return nil, newError(errDimensionMismatch, "Conv requires B [%d], but %v", w[0], in[2])
+	}
+	return []int32{x[0], w[0], win.outSize(x[2], 0, false), win.outSize(x[3], 1, false)}, nil
+}
+
+func runConv(n *node, in []*tensor, out *tensor) error {
+	x, w := in[0], in[1]
+	win, err := makeWindow(n, []int{int(w.dims[2]), int(w.dims[3])})
+	if err != nil {
+		return err
+	}
+	batch, channels, h, wd := int(x.dims[0]), int(x.dims[1]), int(x.dims[2]), int(x.dims[3])
+	filters, oh, ow := int(out.dims[1]), int(out.dims[2]), int(out.dims[3])
+	kh, kw := win.kernel[0], win.kernel[1]
+	group := n.intAttr("group", 1)
+	groupChannels, groupFilters := channels/group, filters/group
+	for b := 0; b < batch; b++ {
+		for f := 0; f < filters; f++ {
+			g := f / groupFilters
+			bias := float32(0)
+			if len(in) > 2 && in[2] != nil {
+				bias = in[2].data[f]
+			}
+			for oy := 0; oy < oh; oy++ {
+				for ox := 0; ox < ow; ox++ {
+					sum := bias
+					for c := 0; c < groupChannels; c++ {
+						xc := g*groupChannels + c
+						for ky := 0; ky < kh; ky++ {
+							iy := oy*win.strides[0] - win.pads[0] + ky*win.dilations[0]
+							if iy < 0 || iy >= h {
+								continue
+							}
+							for kx := 0; kx < kw; kx++ {
+								ix := ox*win.strides[1] - win.pads[1] + kx*win.dilations[1]
+								if ix < 0 || ix >= wd {
+									continue
+								}
+								sum += x.data[((b*channels+xc)*h+iy)*wd+ix] *
+									w.data[((f*groupChannels+c)*kh+ky)*kw+kx]
+							}
+						}
+					}
+					out.data[((b*filters+f)*oh+oy)*ow+ox] = sum
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func inferPool(n *node, in [][]int32, params map[string]*tensor) ([]int32, error) {
+	if err := checkInputs(n, in, 1); err != nil {
+		return nil, err
+	}
+	x := in[0]
+	if err := checkRank(n, x, 4); err != nil {
+		return nil, err
+	}
+	if _, ok := n.attrs["kernel_shape"]; !ok {
+		return nil, newError(errUnsupportedAttribute, "%s requires kernel_shape", n.opType)
+	}
+	win, err := makeWindow(n, nil)
+	if err != nil {
+		return nil, err
+	}
+	ceil := n.intAttr("ceil_mode", 0) != 0
+	return []int32{x[0], x[1], win.outSize(x[2], 0, ceil), win.outSize(x[3], 1, ceil)}, nil
+}
+
+// runPool calls reduce with values in each window. count is the number of
+// elements of the window including paddings.
+func runPool(n *node, in []*tensor, out *tensor, reduce func(values []float32, count int) float32) error {
+	x := in[0]
+	win, err := makeWindow(n, nil)
+	if err != nil {
+		return err
+	}
+	planes, h, w := int(x.dims[0]*x.dims[1]), int(x.dims[2]), int(x.dims[3])
+	oh, ow := int(out.dims[2]), int(out.dims[3])
+	values := make([]float32, 0, win.kernel[0]*win.kernel[1])
+	for p := 0; p < planes; p++ {
+		for oy := 0; oy < oh; oy++ {
+			for ox := 0; ox < ow; ox++ {
+				values = values[:0]
+				count := 0
+				for ky := 0; ky < win.kernel[0]; ky++ {
+					iy := oy*win.strides[0] - win.pads[0] + ky*win.dilations[0]
+					if iy >= h+win.pads[2] {
+						continue
+					}
+					for kx := 0; kx < win.kernel[1]; kx++ {
+						ix := ox*win.strides[1] - win.pads[1] + kx*win.dilations[1]
+						if ix >= w+win.pads[3] {
+							continue
+						}
+						count++
+						if iy >= 0 && iy < h && ix >= 0 && ix < w {
+							values = append(values, x.data[(p*h+iy)*w+ix])
+						}
+					}
+				}
+				out.data[(p*oh+oy)*ow+ox] = reduce(values, count)
+			}
+		}
+	}
+	return nil
+}
+
+func runMaxPool(n *node, in []*tensor, out *tensor) error {
+	return runPool(n, in, out, func(values []float32, count int) float32 {
+		max := float32(math.Inf(-1))
+		for _, v := range values {
+			if v > max {
+				max = v
+			}
+		}
+		return max
+	})
+}
+
+func runAveragePool(n *node, in []*tensor, out *tensor) error {
+	includePad := n.intAttr("count_include_pad", 0) != 0
+	return runPool(n, in, out, func(values []float32, count int) float32 {
+		sum := float32(0)
+		for _, v := range values {
+			sum += v
+		}
+		if !includePad {
+			count = len(values)
+		}
+		if count == 0 {
+			return 0
+		}
+		return sum / float32(count)
+	})
+}
+
+func inferReshape(n *node, in [][]int32, params map[string]*tensor) ([]int32, error) {
+	if err := checkInputs(n, in, 1); err != nil {
+		return nil, err
+	}
+	shape := n.intsAttr("shape", nil)
+	if len(in) > 1 && in[1] != nil {
+		p, ok := params[n.inputs[1]]
+		if !ok {
+			return nil, newError(errUnsupportedAttribute, "shape of Reshape must be a parameter")
+		}
+		shape = make([]int, len(p.data))
+		for i, v := range p.data {
+			shape[i] = int(v)
+		}
+	}
+	if shape == nil {
+		return nil, newError(errUnsupportedAttribute, "Reshape requires shape")
+	}
+	x := in[0]
+	out := make([]int32, len(shape))
+	inferred := -1
+	known := 1
+	for i, d := range shape {
+		switch {
+		case d == 0 && i < len(x):
+			out[i] = x[i]
+		case d == -1 && inferred < 0:
+			inferred = i
+			continue
+		case d > 0:
+			out[i] = int32(d)
+		default:
+			return nil, newError(errDimensionMismatch, "invalid shape %v of Reshape", shape)
+		}
+		known *= int(out[i])
+	}
+	size := sizeOf(x)
+	if inferred >= 0 && known > 0 {
+		out[inferred] = int32(size / known)
+	}
+	if sizeOf(out) != size {
+		return nil, newError(errDimensionMismatch, "Reshape cannot reshape %v to %v", x, shape)
+	}
+	return out, nil
+}
+
+func inferConcat(n *node, in [][]int32, params map[string]*tensor) ([]int32, error) {
+	if err := checkInputs(n, in, 1); err != nil {
+		return nil, err
+	}
+	first := in[0]
+	axis, err := normalizeAxis(n, n.intAttr("axis", 1), len(first))
+	if err != nil {
+		return nil, err
+	}
+	out := append([]int32{}, first...)
+	for _, d := range in[1:] {
+		if d == nil || len(d) != len(first) {
+			return nil, newError(errDimensionMismatch, "Concat cannot concatenate %v and %v", first, d)
+		}
+		for i := range d {
+			if i != axis && d[i] != first[i] {
+				return nil, newError(errDimensionMismatch, "Concat cannot concatenate %v and %v", first, d)
+			}
+		}
+		out[axis] += d[axis]
+	}
+	return out, nil
+}
+
+func runConcat(n *node, in []*tensor, out *tensor) error {
+	axis, err := normalizeAxis(n, n.intAttr("axis", 1), len(out.dims))
+	if err != nil {
+		return err
+	}
+	outer := sizeOf(out.dims[:axis])
+	offset := 0
+	for o := 0; o < outer; o++ {
+		for _, t := range in {
+			chunk := sizeOf(t.dims[axis:])
+			copy(out.data[offset:offset+chunk], t.data[o*chunk:(o+1)*chunk])
+			offset += chunk
+		}
+	}
+	return nil
+}
+
+func inferBatchNormalization(n *node, in [][]int32, params map[string]*tensor) ([]int32, error) {
+	if err := checkInputs(n, in, 5); err != nil {
+		return nil, err
+	}
+	if n.intAttr("spatial", 1) == 0 {
+		return nil, newError(errUnsupportedAttribute, "BatchNormalization supports only spatial")
+	}
+	x := in[0]
+	if len(x) < 2 {
+		return nil, newError(errDimensionMismatch, "BatchNormalization requires channel axis, but %v", x)
+	}
+	for _, d := range in[1:5] {
+		if !sameDims(d, x[1:2]) {
+			return nil, newError(errDimensionMismatch, "BatchNormalization requires [%d], but %v", x[1], d)
+		}
+	}
+	return x, nil
+}
+
+func runBatchNormalization(n *node, in []*tensor, out *tensor) error {
+	x, scale, bias, mean, variance := in[0], in[1], in[2], in[3], in[4]
+	eps := n.floatAttr("epsilon", 1e-5)
+	channels := int(x.dims[1])
+	inner := sizeOf(x.dims[2:])
+	for i := range x.data {
+		c := i / inner % channels
+		std := float32(math.Sqrt(float64(variance.data[c] + eps)))
+		out.data[i] = scale.data[c]*(x.data[i]-mean.data[c])/std + bias.data[c]
+	}
+	return nil
+}
+
+// inferQuantization checks that scale and zero point are scalars, or 1-D of
+// the size of "axis" for per-axis quantization.
+func inferQuantization(n *node, in [][]int32, params map[string]*tensor) ([]int32, error) {
+	if err := checkInputs(n, in, 2); err != nil {
+		return nil, err
+	}
+	x, scale := in[0], in[1]
+	if sizeOf(scale) != 1 {
+		axis, err := normalizeAxis(n, n.intAttr("axis", 1), len(x))
+		if err != nil {
+			return nil, err
+		}
+		if !sameDims(scale, x[axis:axis+1]) {
+			return nil, newError(errDimensionMismatch, "%s requires scale [%d], but %v", n.opType, x[axis], scale)
+		}
+	}
+	if len(in) > 2 && in[2] != nil && sizeOf(in[2]) != sizeOf(scale) {
+		return nil, newError(errDimensionMismatch, "%s requires zero point %v, but %v", n.opType, scale, in[2])
+	}
+	return x, nil
+}
+
+// quantizationIndex returns a function which maps an index of x to the index
+// of scale and zero point.
+func quantizationIndex(n *node, x, scale *tensor) func(i int) int {
+	if len(scale.data) == 1 {
+		return func(int) int { return 0 }
+	}
+	axis, _ := normalizeAxis(n, n.intAttr("axis", 1), len(x.dims))
+	inner, channels := sizeOf(x.dims[axis+1:]), int(x.dims[axis])
+	return func(i int) int { return i / inner % channels }
+}
+
+// runQuantizeLinear saturates to uint8, or to int8 when the zero point is
+// int8.
+func runQuantizeLinear(n *node, in []*tensor, out *tensor) error {
+	x, scale := in[0], in[1]
+	index := quantizationIndex(n, x, scale)
+	var zeroPoint *tensor
+	lower, upper := float32(0), float32(255)
+	if len(in) > 2 && in[2] != nil {
+		zeroPoint = in[2]
+		if zeroPoint.dataType == dataTypeInt8 {
+			lower, upper = -128, 127
+		}
+	}
+	for i, v := range x.data {
+		c := index(i)
+		q := float32(math.RoundToEven(float64(v / scale.data[c])))
+		if zeroPoint != nil {
+			q += zeroPoint.data[c]
+		}
+		if q < lower {
+			q = lower
+		} else if q > upper {
+			q = upper
+		}
+		out.data[i] = q
+	}
+	return nil
+}
+
+func runDequantizeLinear(n *node, in []*tensor, out *tensor) error {
+	x, scale := in[0], in[1]
+	index := quantizationIndex(n, x, scale)
+	for i, v := range x.data {
+		c := index(i)
+		if len(in) > 2 && in[2] != nil {
+			v -= in[2].data[c]
+		}
+		out.data[i] = v * scale.data[c]
+	}
+	return nil
+}
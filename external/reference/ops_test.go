@@ -0,0 +1,229 @@
+package reference
+
+import (
+	"math"
+	"testing"
+)
+
+type opInput struct {
+	dims  []int32
+	data  []float32
+	param bool // parameter is available on shape inference
+
+	dataType int
+}
+
+// runOp infers and runs a single node.
+func runOp(t *testing.T, n *node, inputs ...opInput) *tensor {
+	t.Helper()
+	dims := make([][]int32, len(inputs))
+	ins := make([]*tensor, len(inputs))
+	params := map[string]*tensor{}
+	for i, in := range inputs {
+		dims[i] = in.dims
+		ins[i] = &tensor{dims: in.dims, data: in.data, dataType: in.dataType}
+		if in.param {
+			params[n.inputs[i]] = ins[i]
+		}
+	}
+	op, ok := operators[n.opType]
+	if !ok {
+		t.Fatalf("%s should be supported", n.opType)
+	}
+	outDims, err := op.infer(n, dims, params)
+	if err != nil {
+		t.Fatalf("shape of %s should be inferred, %v", n.opType, err)
+	}
+	out := &tensor{dims: outDims, data: make([]float32, sizeOf(outDims))}
+	if err := op.run(n, ins, out); err != nil {
+		t.Fatalf("%s should run, %v", n.opType, err)
+	}
+	return out
+}
+
+func makeNode(opType string, attrs map[string]*attribute, inputs ...string) *node {
+	if attrs == nil {
+		attrs = map[string]*attribute{}
+	}
+	return &node{opType: opType, inputs: inputs, outputs: []string{"y"}, attrs: attrs}
+}
+
+func checkTensor(t *testing.T, actual *tensor, dims []int32, data []float32) {
+	t.Helper()
+	if !sameDims(actual.dims, dims) {
+		t.Fatalf("dims should be %v, but %v", dims, actual.dims)
+	}
+	for i := range data {
+		if math.Abs(float64(actual.data[i]-data[i])) > 1e-5 {
+			t.Fatalf("data should be %v, but %v", data, actual.data)
+		}
+	}
+}
+
+func TestRelu(t *testing.T) {
+	out := runOp(t, makeNode("Relu", nil, "x"), opInput{dims: []int32{1, 3}, data: []float32{-1, 0, 2}})
+	checkTensor(t, out, []int32{1, 3}, []float32{0, 0, 2})
+}
+
+func TestSoftmax(t *testing.T) {
+	out := runOp(t, makeNode("Softmax", nil, "x"),
+		opInput{dims: []int32{2, 2}, data: []float32{0, 0, 1, 1 + float32(math.Log(3))}})
+	checkTensor(t, out, []int32{2, 2}, []float32{0.5, 0.5, 0.25, 0.75})
+}
+
+func TestAddAndMul(t *testing.T) {
+	x := opInput{dims: []int32{2, 3}, data: []float32{1, 2, 3, 4, 5, 6}}
+	out := runOp(t, makeNode("Add", nil, "x", "b"), x, opInput{dims: []int32{3}, data: []float32{10, 20, 30}})
+	checkTensor(t, out, []int32{2, 3}, []float32{11, 22, 33, 14, 25, 36})
+	out = runOp(t, makeNode("Mul", nil, "x", "b"), x, opInput{dims: []int32{2, 1}, data: []float32{2, 3}})
+	checkTensor(t, out, []int32{2, 3}, []float32{2, 4, 6, 12, 15, 18})
+
+	// old opsets broadcast along the axis
+	legacy := makeNode("Add", map[string]*attribute{"broadcast": {i: 1}, "axis": {i: 0}}, "x", "b")
+	out = runOp(t, legacy, x, opInput{dims: []int32{2}, data: []float32{10, 20}})
+	checkTensor(t, out, []int32{2, 3}, []float32{11, 12, 13, 24, 25, 26})
+
+	if _, err := inferBinary(makeNode("Add", nil, "x", "b"), [][]int32{{2, 3}, {2}}, nil); err == nil {
+		t.Error("an error should be occurred with not broadcastable dims")
+	}
+}
+
+func TestGemm(t *testing.T) {
+	a := opInput{dims: []int32{2, 2}, data: []float32{1, 2, 3, 4}}
+	b := opInput{dims: []int32{2, 2}, data: []float32{5, 6, 7, 8}}
+	c := opInput{dims: []int32{2}, data: []float32{1, 2}}
+	out := runOp(t, makeNode("Gemm", nil, "a", "b", "c"), a, b, c)
+	checkTensor(t, out, []int32{2, 2}, []float32{20, 24, 44, 52})
+
+	attrs := map[string]*attribute{"transA": {i: 1}, "transB": {i: 1}, "alpha": {f: 2}, "beta": {f: 0}}
+	out = runOp(t, makeNode("Gemm", attrs, "a", "b", "c"), a, b, c)
+	// A^T B^T = [[1*5+3*6, 1*7+3*8], [2*5+4*6, 2*7+4*8]]
+	checkTensor(t, out, []int32{2, 2}, []float32{46, 62, 68, 92})
+
+	if _, err := inferGemm(makeNode("Gemm", nil, "a", "b"), [][]int32{{1, 3}, {2, 2}}, nil); err == nil {
+		t.Error("an error should be occurred with dimension mismatch")
+	}
+}
+
+func TestConv(t *testing.T) {
+	x := opInput{dims: []int32{1, 1, 3, 3}, data: []float32{1, 2, 3, 4, 5, 6, 7, 8, 9}}
+	w := opInput{dims: []int32{1, 1, 2, 2}, data: []float32{1, 0, 0, 1}}
+	b := opInput{dims: []int32{1}, data: []float32{1}}
+	out := runOp(t, makeNode("Conv", nil, "x", "W", "B"), x, w, b)
+	checkTensor(t, out, []int32{1, 1, 2, 2}, []float32{7, 9, 13, 15})
+
+	attrs := map[string]*attribute{"pads": {ints: []int{1, 1, 1, 1}}, "strides": {ints: []int{2, 2}}}
+	out = runOp(t, makeNode("Conv", attrs, "x", "W"), x, w)
+	checkTensor(t, out, []int32{1, 1, 2, 2}, []float32{1, 3, 7, 14})
+
+	// depthwise
+	x2 := opInput{dims: []int32{1, 2, 1, 1}, data: []float32{2, 3}}
+	w2 := opInput{dims: []int32{2, 1, 1, 1}, data: []float32{10, 100}}
+	out = runOp(t, makeNode("Conv", map[string]*attribute{"group": {i: 2}}, "x", "W"), x2, w2)
+	checkTensor(t, out, []int32{1, 2, 1, 1}, []float32{20, 300})
+}
+
+func TestPool(t *testing.T) {
+	x := opInput{dims: []int32{1, 1, 4, 4}, data: []float32{
+		1, 2, 3, 4,
+		5, 6, 7, 8,
+		9, 10, 11, 12,
+		13, 14, 15, 16,
+	}}
+	attrs := map[string]*attribute{"kernel_shape": {ints: []int{2, 2}}, "strides": {ints: []int{2, 2}}}
+	out := runOp(t, makeNode("MaxPool", attrs, "x"), x)
+	checkTensor(t, out, []int32{1, 1, 2, 2}, []float32{6, 8, 14, 16})
+	out = runOp(t, makeNode("AveragePool", attrs, "x"), x)
+	checkTensor(t, out, []int32{1, 1, 2, 2}, []float32{3.5, 5.5, 11.5, 13.5})
+
+	padded := map[string]*attribute{
+		"kernel_shape": {ints: []int{2, 2}}, "strides": {ints: []int{2, 2}}, "pads": {ints: []int{1, 1, 1, 1}},
+	}
+	out = runOp(t, makeNode("AveragePool", padded, "x"), x)
+	checkTensor(t, out, []int32{1, 1, 3, 3}, []float32{1, 2.5, 4, 7, 8.5, 10, 13, 14.5, 16})
+	padded["count_include_pad"] = &attribute{i: 1}
+	out = runOp(t, makeNode("AveragePool", padded, "x"), x)
+	checkTensor(t, out, []int32{1, 1, 3, 3}, []float32{0.25, 1.25, 1, 3.5, 8.5, 5, 3.25, 7.25, 4})
+
+	if _, err := inferPool(makeNode("MaxPool", nil, "x"), [][]int32{x.dims}, nil); err == nil {
+		t.Error("an error should be occurred without kernel_shape")
+	}
+}
+
+func TestReshape(t *testing.T) {
+	x := opInput{dims: []int32{2, 3, 2}, data: []float32{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12}}
+	shape := opInput{dims: []int32{2}, data: []float32{0, -1}, param: true}
+	out := runOp(t, makeNode("Reshape", nil, "x", "shape"), x, shape)
+	checkTensor(t, out, []int32{2, 6}, x.data)
+
+	out = runOp(t, makeNode("Reshape", map[string]*attribute{"shape": {ints: []int{3, 4}}}, "x"), x)
+	checkTensor(t, out, []int32{3, 4}, x.data)
+
+	n := makeNode("Reshape", nil, "x", "shape")
+	if _, err := inferReshape(n, [][]int32{x.dims, {2}}, map[string]*tensor{}); err == nil {
+		t.Error("an error should be occurred with variable shape")
+	}
+	if _, err := inferReshape(n, [][]int32{x.dims, {1}},
+		map[string]*tensor{"shape": {dims: []int32{1}, data: []float32{5}}}); err == nil {
+		t.Error("an error should be occurred with size mismatch")
+	}
+}
+
+func TestConcat(t *testing.T) {
+	a := opInput{dims: []int32{2, 1}, data: []float32{1, 2}}
+	b := opInput{dims: []int32{2, 2}, data: []float32{3, 4, 5, 6}}
+	out := runOp(t, makeNode("Concat", map[string]*attribute{"axis": {i: -1}}, "a", "b"), a, b)
+	checkTensor(t, out, []int32{2, 3}, []float32{1, 3, 4, 2, 5, 6})
+
+	if _, err := inferConcat(makeNode("Concat", map[string]*attribute{"axis": {i: 0}}, "a", "b"),
+		[][]int32{a.dims, b.dims}, nil); err == nil {
+		t.Error("an error should be occurred with dimension mismatch")
+	}
+}
+
+func TestBatchNormalization(t *testing.T) {
+	x := opInput{dims: []int32{1, 2, 1, 2}, data: []float32{1, 2, 3, 4}}
+	scale := opInput{dims: []int32{2}, data: []float32{1, 2}}
+	bias := opInput{dims: []int32{2}, data: []float32{0, 1}}
+	mean := opInput{dims: []int32{2}, data: []float32{1, 3}}
+	variance := opInput{dims: []int32{2}, data: []float32{1, 4}}
+	n := makeNode("BatchNormalization", map[string]*attribute{"epsilon": {f: 0}}, "x", "s", "b", "m", "v")
+	out := runOp(t, n, x, scale, bias, mean, variance)
+	checkTensor(t, out, []int32{1, 2, 1, 2}, []float32{0, 1, 1, 2})
+}
+
+func TestQuantizeLinear(t *testing.T) {
+	x := opInput{dims: []int32{1, 4}, data: []float32{-1, 0.25, 1, 200}}
+	scale := opInput{dims: []int32{}, data: []float32{0.5}}
+	zeroPoint := opInput{dims: []int32{}, data: []float32{2}}
+	out := runOp(t, makeNode("QuantizeLinear", nil, "x", "s", "z"), x, scale, zeroPoint)
+	// 0.25 / 0.5 is rounded half to even
+	checkTensor(t, out, []int32{1, 4}, []float32{0, 2, 4, 255})
+
+	zeroPoint.dataType = dataTypeInt8
+	out = runOp(t, makeNode("QuantizeLinear", nil, "x", "s", "z"), x, scale, zeroPoint)
+	checkTensor(t, out, []int32{1, 4}, []float32{0, 2, 4, 127})
+
+	// per-axis
+	x = opInput{dims: []int32{2, 2}, data: []float32{1, 2, 3, 4}}
+	scale = opInput{dims: []int32{2}, data: []float32{1, 0.5}}
+	out = runOp(t, makeNode("QuantizeLinear", map[string]*attribute{"axis": {i: 0}}, "x", "s"), x, scale)
+	checkTensor(t, out, []int32{2, 2}, []float32{1, 2, 6, 8})
+}
+
+func TestDequantizeLinear(t *testing.T) {
+	x := opInput{dims: []int32{2, 2}, data: []float32{0, 2, 4, 255}}
+	scale := opInput{dims: []int32{2}, data: []float32{0.5, 2}}
+	zeroPoint := opInput{dims: []int32{2}, data: []float32{2, 0}}
+	out := runOp(t, makeNode("DequantizeLinear", nil, "x", "s", "z"), x, scale, zeroPoint)
+	checkTensor(t, out, []int32{2, 2}, []float32{-1, 4, 1, 510})
+}
+
+func TestIsSupported(t *testing.T) {
+	if !IsSupported("Gemm") || !IsSupported("DequantizeLinear") {
+		t.Error("Gemm and DequantizeLinear should be supported")
+	}
+	if IsSupported("MatMul") {
+		t.Error("MatMul should not be supported")
+	}
+}
@@ -0,0 +1,492 @@
+/*
+Package reference is a pure-Go interpreter of ONNX models with the same API
+as the external package. It implements a core operator set, Gemm, Conv, Relu,
+MaxPool, AveragePool, Softmax, Add, Mul, Reshape, Concat and
+BatchNormalization, with float32 only, and is intended for tests without
+Menoh library, not for performance. QuantizeLinear and DequantizeLinear are
+implemented too, to check quantized models, int8 and uint8 values are held
+as float32.
+
+Build with "menoh_reference" tag to replace the binding of the external
+package by this interpreter:
+
+	$ go test -tags menoh_reference ./...
+*/
+package reference
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"unsafe"
+
+	"github.com/pfnet-research/go-menoh/external/errcode"
+)
+
+// Backends are backend names accepted by BuildModel, all of them run the
+// interpreter.
+var Backends = []string{"reference", "mkldnn", "mkldnn_with_generic_fallback", "generic"}
+
+// Error codes, messages of errors start with them like Menoh.
+const (
+	errInvalidFilename          = errcode.InvalidFilename
+	errONNXParseError           = errcode.ONNXParseError
+	errInvalidDtype             = errcode.InvalidDtype
+	errDimensionMismatch        = errcode.DimensionMismatch
+	errVariableNotFound         = errcode.VariableNotFound
+	errIndexOutOfRange          = errcode.IndexOutOfRange
+	errJSONParseError           = errcode.JSONParseError
+	errInvalidBackendName       = errcode.InvalidBackendName
+	errUnsupportedOperator      = errcode.UnsupportedOperator
+	errUnsupportedAttribute     = errcode.UnsupportedOperatorAttribute
+	errSameNamedParameter       = errcode.SameNamedParameterAlreadyExist
+	errSameNamedAttribute       = errcode.SameNamedAttributeAlreadyExist
+	errInputNotFound            = errcode.InputNotFound
+	errOutputNotFound           = errcode.OutputNotFound
+	errSameNamedVariableProfile = errcode.SameNamedVariableAlreadyExist
+)
+
+func newError(code errcode.Code, format string, args ...interface{}) error {
+	return &errcode.Error{
+		Code:    code,
+		Message: fmt.Sprintf("%s: %s", code, fmt.Sprintf(format, args...)),
+	}
+}
+
+// TypeMenohDtype is a type of data, same values as menoh_dtype.
+type TypeMenohDtype int
+
+// Dtype
+const (
+	TypeFloat TypeMenohDtype = iota
+)
+
+func checkDtype(dtype TypeMenohDtype) error {
+	if dtype != TypeFloat {
+		return newError(errInvalidDtype, "%d", dtype)
+	}
+	return nil
+}
+
+type tensor struct {
+	dims     []int32
+	data     []float32
+	dataType int // ONNX data type of a parameter before conversion, like int8 zero point
+}
+
+func sizeOf(dims []int32) int {
+	size := 1
+	for _, d := range dims {
+		size *= int(d)
+	}
+	return size
+}
+
+// floatsOf returns a slice on the buffer, the buffer is not copied.
+func floatsOf(ptr unsafe.Pointer, size int) []float32 {
+	if size == 0 || ptr == nil {
+		return []float32{}
+	}
+	return (*[1 << 30]float32)(ptr)[:size:size]
+}
+
+type attribute struct {
+	f      float32
+	i      int
+	s      string
+	floats []float32
+	ints   []int
+	t      *tensor
+}
+
+type node struct {
+	opType  string
+	inputs  []string
+	outputs []string
+	attrs   map[string]*attribute
+}
+
+// ModelData is a graph of nodes and parameters. Required to delete after
+// making, call Delete function.
+type ModelData struct {
+	nodes  []*node
+	params map[string]*tensor
+}
+
+func newModelData() *ModelData {
+	return &ModelData{params: map[string]*tensor{}}
+}
+
+// Delete object.
+func (m *ModelData) Delete() {
+	m.nodes = nil
+	m.params = nil
+}
+
+// MakeModelDataFromONNX returns ModelData using ONNX file path.
+func MakeModelDataFromONNX(path string) (*ModelData, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, newError(errInvalidFilename, "%s", path)
+	}
+	return MakeModelDataFromONNXBytes(data)
+}
+
+// MakeModelDataFromONNXBytes return ModelData with ONNX file byte data.
+func MakeModelDataFromONNXBytes(data []byte) (*ModelData, error) {
+	m, err := parseModel(data)
+	if err != nil {
+		return nil, newError(errONNXParseError, "%v", err)
+	}
+	return m, nil
+}
+
+// MakeModelData returns empty ModelData object, to build manually.
+func MakeModelData() (*ModelData, error) {
+	return newModelData(), nil
+}
+
+// AddParameter adds named parameter, the buffer is copied.
+func (m *ModelData) AddParameter(name string, param Variable) error {
+	if err := checkDtype(param.Dtype); err != nil {
+		return err
+	}
+	if _, ok := m.params[name]; ok {
+		return newError(errSameNamedParameter, "%s", name)
+	}
+	src := floatsOf(param.BufferHandle, sizeOf(param.Dims))
+	m.params[name] = &tensor{
+		dims: append([]int32{}, param.Dims...),
+		data: append([]float32{}, src...),
+	}
+	return nil
+}
+
+// AddNewNode adds new opType.
+func (m *ModelData) AddNewNode(opType string) error {
+	m.nodes = append(m.nodes, &node{opType: opType, attrs: map[string]*attribute{}})
+	return nil
+}
+
+func (m *ModelData) currentNode() (*node, error) {
+	if len(m.nodes) == 0 {
+		return nil, newError(errIndexOutOfRange, "no node is added")
+	}
+	return m.nodes[len(m.nodes)-1], nil
+}
+
+// AddInputNameToCurrentNode adds input name to current node.
+func (m *ModelData) AddInputNameToCurrentNode(inputName string) error {
+	n, err := m.currentNode()
+	if err != nil {
+		return err
+	}
+	n.inputs = append(n.inputs, inputName)
+	return nil
+}
+
+// AddOutputNameToCurrentNode adds output name to current node.
+func (m *ModelData) AddOutputNameToCurrentNode(outputName string) error {
+	n, err := m.currentNode()
+	if err != nil {
+		return err
+	}
+	n.outputs = append(n.outputs, outputName)
+	return nil
+}
+
+func (m *ModelData) addAttribute(name string, a *attribute) error {
+	n, err := m.currentNode()
+	if err != nil {
+		return err
+	}
+	if _, ok := n.attrs[name]; ok {
+		return newError(errSameNamedAttribute, "%s", name)
+	}
+	n.attrs[name] = a
+	return nil
+}
+
+// AddAttributeIntToCurrentNode adds integer type attribute to current node.
+func (m *ModelData) AddAttributeIntToCurrentNode(attributeName string, value int) error {
+	return m.addAttribute(attributeName, &attribute{i: value})
+}
+
+// AddAttributeFloatToCurrentNode adds float type attribute to current node.
+func (m *ModelData) AddAttributeFloatToCurrentNode(attributeName string, value float32) error {
+	return m.addAttribute(attributeName, &attribute{f: value})
+}
+
+// AddAttributeIntsToCurrentNode adds int array type attribute to current node.
+func (m *ModelData) AddAttributeIntsToCurrentNode(attributeName string, value []int) error {
+	return m.addAttribute(attributeName, &attribute{ints: append([]int{}, value...)})
+}
+
+// AddAttributeFloatsToCurrentNode adds float array type attribute to current node.
+func (m *ModelData) AddAttributeFloatsToCurrentNode(attributeName string, value []float32) error {
+	return m.addAttribute(attributeName, &attribute{floats: append([]float32{}, value...)})
+}
+
+// Optimize ModelData with profiling table, the interpreter does nothing.
+func (m *ModelData) Optimize(table VariableProfileTable) error {
+	return nil
+}
+
+type inputProfile struct {
+	name  string
+	dtype TypeMenohDtype
+	dims  []int32
+}
+
+// VariableProfileTableBuilder collects input and output profiles. Required to
+// delete after making, call Delete function.
+type VariableProfileTableBuilder struct {
+	inputs  []inputProfile
+	outputs []string
+}
+
+// Delete object.
+func (b *VariableProfileTableBuilder) Delete() {
+	b.inputs = nil
+	b.outputs = nil
+}
+
+// MakeVariableProfileTableBuilder returns VariableProfileTableBuilder.
+func MakeVariableProfileTableBuilder() (*VariableProfileTableBuilder, error) {
+	return &VariableProfileTableBuilder{}, nil
+}
+
+// AddInputProfile adds input profile with layer name, data type and dimension size.
+func (b *VariableProfileTableBuilder) AddInputProfile(name string, dtype TypeMenohDtype, dims ...int32) error {
+	if err := checkDtype(dtype); err != nil {
+		return err
+	}
+	for _, in := range b.inputs {
+		if in.name == name {
+			return newError(errSameNamedVariableProfile, "%s", name)
+		}
+	}
+	b.inputs = append(b.inputs, inputProfile{name: name, dtype: dtype, dims: append([]int32{}, dims...)})
+	return nil
+}
+
+// AddOutputProfile adds output profile with layer name and data type.
+func (b *VariableProfileTableBuilder) AddOutputProfile(name string, dtype TypeMenohDtype) error {
+	if err := checkDtype(dtype); err != nil {
+		return err
+	}
+	b.outputs = append(b.outputs, name)
+	return nil
+}
+
+// BuildVariableProfileTable infers shapes of all variables in the model.
+func (b *VariableProfileTableBuilder) BuildVariableProfileTable(md ModelData) (
+	*VariableProfileTable, error) {
+
+	used := map[string]bool{}
+	for _, n := range md.nodes {
+		for _, in := range n.inputs {
+			used[in] = true
+		}
+	}
+	requested := map[string]bool{}
+	for _, out := range b.outputs {
+		requested[out] = true
+	}
+	dims := map[string][]int32{}
+	for name, p := range md.params {
+		dims[name] = p.dims
+	}
+	for _, in := range b.inputs {
+		if !used[in.name] {
+			return nil, newError(errInputNotFound, "%s", in.name)
+		}
+		dims[in.name] = in.dims
+	}
+	for _, n := range md.nodes {
+		op, ok := operators[n.opType]
+		if !ok {
+			return nil, newError(errUnsupportedOperator, "%s", n.opType)
+		}
+		inDims := make([][]int32, len(n.inputs))
+		for i, in := range n.inputs {
+			if in == "" {
+				continue // omitted optional input
+			}
+			d, ok := dims[in]
+			if !ok {
+				return nil, newError(errVariableNotFound, "%s", in)
+			}
+			inDims[i] = d
+		}
+		if len(n.outputs) == 0 {
+			return nil, newError(errOutputNotFound, "%s has no output", n.opType)
+		}
+		// operators compute the first output only, like Y of Dropout
+		for _, out := range n.outputs[1:] {
+			if out != "" && (used[out] || requested[out]) {
+				return nil, newError(errUnsupportedOperator, "%s output %s, only the first output is computed",
+					n.opType, out)
+			}
+		}
+		outDims, err := op.infer(n, inDims, md.params)
+		if err != nil {
+			return nil, err
+		}
+		dims[n.outputs[0]] = outDims
+	}
+	for _, out := range b.outputs {
+		if _, ok := dims[out]; !ok {
+			return nil, newError(errOutputNotFound, "%s", out)
+		}
+	}
+	return &VariableProfileTable{dims: dims}, nil
+}
+
+// VariableProfile represents profile information to make real variable.
+type VariableProfile struct {
+	Dtype TypeMenohDtype
+	Dims  []int32
+}
+
+// VariableProfileTable has shapes of all variables. Required to delete after
+// making, call Delete function.
+type VariableProfileTable struct {
+	dims map[string][]int32
+}
+
+// Delete object.
+func (t *VariableProfileTable) Delete() {
+	t.dims = nil
+}
+
+// GetVariableProfile returns profile which setup variable information includes.
+func (t *VariableProfileTable) GetVariableProfile(name string) (*VariableProfile, error) {
+	dims, ok := t.dims[name]
+	if !ok {
+		return nil, newError(errVariableNotFound, "%s", name)
+	}
+	return &VariableProfile{
+		Dtype: TypeFloat,
+		Dims:  append([]int32{}, dims...),
+	}, nil
+}
+
+// ModelBuilder collects external buffers. Required to delete after making,
+// call Delete function.
+type ModelBuilder struct {
+	dims    map[string][]int32
+	buffers map[string]unsafe.Pointer
+}
+
+// Delete object.
+func (b *ModelBuilder) Delete() {
+	b.dims = nil
+	b.buffers = nil
+}
+
+// MakeModelBuilder returns ModelBuilder.
+func MakeModelBuilder(vpt VariableProfileTable) (*ModelBuilder, error) {
+	return &ModelBuilder{dims: vpt.dims, buffers: map[string]unsafe.Pointer{}}, nil
+}
+
+// AttachExternalBuffer attaches data buffer to get data. This process must be done
+// before building Model.
+func (b *ModelBuilder) AttachExternalBuffer(variableName string, bufferPtr unsafe.Pointer) error {
+	if _, ok := b.dims[variableName]; !ok {
+		return newError(errVariableNotFound, "%s", variableName)
+	}
+	b.buffers[variableName] = bufferPtr
+	return nil
+}
+
+// BuildModel returns Model. The backend must be one of Backends, and the
+// backend configuration must be empty or JSON object.
+func (b *ModelBuilder) BuildModel(md ModelData, backend, backendConfig string) (*Model, error) {
+	known := false
+	for _, name := range Backends {
+		known = known || name == backend
+	}
+	if !known {
+		return nil, newError(errInvalidBackendName, "%s", backend)
+	}
+	if backendConfig != "" {
+		var obj map[string]interface{}
+		if err := json.Unmarshal([]byte(backendConfig), &obj); err != nil {
+			return nil, newError(errJSONParseError, "%v", err)
+		}
+	}
+	vars := map[string]*tensor{}
+	for name, dims := range b.dims {
+		t := &tensor{dims: dims}
+		if ptr, ok := b.buffers[name]; ok {
+			t.data = floatsOf(ptr, sizeOf(dims))
+		} else {
+			t.data = make([]float32, sizeOf(dims))
+		}
+		if p, ok := md.params[name]; ok {
+			copy(t.data, p.data)
+			t.dataType = p.dataType
+		}
+		vars[name] = t
+	}
+	return &Model{nodes: md.nodes, vars: vars}, nil
+}
+
+// Variable represents data include data attribution and pointer.
+type Variable struct {
+	Dtype        TypeMenohDtype
+	Dims         []int32
+	BufferHandle unsafe.Pointer
+}
+
+// Model runs nodes in order. Required to delete after making, call Delete
+// function.
+type Model struct {
+	nodes []*node
+	vars  map[string]*tensor
+}
+
+// Delete object.
+func (m *Model) Delete() {
+	m.nodes = nil
+	m.vars = nil
+}
+
+// GetVariable returns Variable, which set the target data information.
+// BufferHandle is nil for an empty variable.
+func (m *Model) GetVariable(name string) (*Variable, error) {
+	t, ok := m.vars[name]
+	if !ok {
+		return nil, newError(errVariableNotFound, "%s", name)
+	}
+	var ptr unsafe.Pointer
+	if len(t.data) > 0 {
+		ptr = unsafe.Pointer(&t.data[0])
+	}
+	return &Variable{
+		Dtype:        TypeFloat,
+		Dims:         append([]int32{}, t.dims...),
+		BufferHandle: ptr,
+	}, nil
+}
+
+// Run calculation.
+func (m *Model) Run() error {
+	for _, n := range m.nodes {
+		inputs := make([]*tensor, len(n.inputs))
+		for i, in := range n.inputs {
+			if in != "" {
+				inputs[i] = m.vars[in]
+			}
+		}
+		if err := operators[n.opType].run(n, inputs, m.vars[n.outputs[0]]); err != nil {
+			// keep the code of the error
+			if e, ok := err.(*errcode.Error); ok {
+				return &errcode.Error{Code: e.Code, Message: fmt.Sprintf("%s: %s", n.opType, e)}
+			}
+			return fmt.Errorf("%s: %v", n.opType, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,215 @@
+package reference
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"unsafe"
+
+	"github.com/pfnet-research/go-menoh/external/errcode"
+)
+
+// mlpModelData makes x -> Gemm(W1, b1) -> Relu -> Gemm(W2, b2) -> y.
+func mlpModelData(t *testing.T) *ModelData {
+	md, err := MakeModelData()
+	if err != nil {
+		t.Fatal(err)
+	}
+	params := []struct {
+		name string
+		dims []int32
+		data []float32
+	}{
+		{"W1", []int32{2, 3}, []float32{1, 0, -1, 0, 1, 0}},
+		{"b1", []int32{2}, []float32{0, 1}},
+		{"W2", []int32{2, 1}, []float32{2, 3}},
+		{"b2", []int32{1}, []float32{0.5}},
+	}
+	for _, p := range params {
+		if err := md.AddParameter(p.name, Variable{
+			Dtype: TypeFloat, Dims: p.dims, BufferHandle: unsafe.Pointer(&p.data[0]),
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	nodes := []struct {
+		op     string
+		inputs []string
+		output string
+		transB bool
+	}{
+		{"Gemm", []string{"x", "W1", "b1"}, "h", true},
+		{"Relu", []string{"h"}, "r", false},
+		{"Gemm", []string{"r", "W2", "b2"}, "y", false},
+	}
+	for _, n := range nodes {
+		mustNil(t, md.AddNewNode(n.op))
+		for _, in := range n.inputs {
+			mustNil(t, md.AddInputNameToCurrentNode(in))
+		}
+		mustNil(t, md.AddOutputNameToCurrentNode(n.output))
+		if n.transB {
+			mustNil(t, md.AddAttributeIntToCurrentNode("transB", 1))
+		}
+	}
+	return md
+}
+
+func mustNil(t *testing.T, err error) {
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestBuildAndRun(t *testing.T) {
+	md := mlpModelData(t)
+	defer md.Delete()
+
+	vptBuilder, err := MakeVariableProfileTableBuilder()
+	mustNil(t, err)
+	defer vptBuilder.Delete()
+	mustNil(t, vptBuilder.AddInputProfile("x", TypeFloat, 1, 3))
+	mustNil(t, vptBuilder.AddOutputProfile("y", TypeFloat))
+	mustNil(t, vptBuilder.AddOutputProfile("h", TypeFloat))
+	vpt, err := vptBuilder.BuildVariableProfileTable(*md)
+	mustNil(t, err)
+	defer vpt.Delete()
+	profile, err := vpt.GetVariableProfile("y")
+	mustNil(t, err)
+	if !sameDims(profile.Dims, []int32{1, 1}) {
+		t.Errorf("y should be [1 1], but %v", profile.Dims)
+	}
+
+	builder, err := MakeModelBuilder(*vpt)
+	mustNil(t, err)
+	defer builder.Delete()
+	input := []float32{1, 2, 3}
+	hidden := make([]float32, 2)
+	mustNil(t, builder.AttachExternalBuffer("x", unsafe.Pointer(&input[0])))
+	mustNil(t, builder.AttachExternalBuffer("h", unsafe.Pointer(&hidden[0])))
+	model, err := builder.BuildModel(*md, "reference", `{"cpu_id": 0}`)
+	mustNil(t, err)
+	defer model.Delete()
+
+	for _, tc := range []struct {
+		input    []float32
+		hidden   []float32
+		expected float32
+	}{
+		// h = [1-3, 2+1] = [-2, 3], r = [0, 3], y = 0*2 + 3*3 + 0.5
+		{[]float32{1, 2, 3}, []float32{-2, 3}, 9.5},
+		// h = [3, 1], y = 3*2 + 1*3 + 0.5
+		{[]float32{3, 0, 0}, []float32{3, 1}, 9.5},
+		{[]float32{0, 1, 0}, []float32{0, 2}, 6.5},
+	} {
+		copy(input, tc.input)
+		mustNil(t, model.Run())
+		y, err := model.GetVariable("y")
+		mustNil(t, err)
+		actual := floatsOf(y.BufferHandle, 1)[0]
+		if actual != tc.expected {
+			t.Errorf("y should be %v with %v, but %v", tc.expected, tc.input, actual)
+		}
+		if hidden[0] != tc.hidden[0] || hidden[1] != tc.hidden[1] {
+			t.Errorf("attached h should be %v, but %v", tc.hidden, hidden)
+		}
+	}
+}
+
+func TestBuildFail(t *testing.T) {
+	md := mlpModelData(t)
+	defer md.Delete()
+	unsupported, _ := MakeModelData()
+	mustNil(t, unsupported.AddNewNode("GRU"))
+	mustNil(t, unsupported.AddInputNameToCurrentNode("x"))
+	mustNil(t, unsupported.AddOutputNameToCurrentNode("y"))
+	multiOutput, _ := MakeModelData()
+	mustNil(t, multiOutput.AddNewNode("Relu"))
+	mustNil(t, multiOutput.AddInputNameToCurrentNode("x"))
+	mustNil(t, multiOutput.AddOutputNameToCurrentNode("y"))
+	mustNil(t, multiOutput.AddOutputNameToCurrentNode("z"))
+
+	testSet := []struct {
+		name     string
+		md       *ModelData
+		inputs   map[string][]int32
+		outputs  []string
+		backend  string
+		config   string
+		expected errcode.Code
+	}{
+		{"no input profile", md, nil, []string{"y"}, "reference", "", errVariableNotFound},
+		{"not used input", md, map[string][]int32{"x": {1, 3}, "z": {1}}, nil, "reference", "", errInputNotFound},
+		{"dimension mismatch", md, map[string][]int32{"x": {1, 4}}, nil, "reference", "", errDimensionMismatch},
+		{"unknown output", md, map[string][]int32{"x": {1, 3}}, []string{"dummy"}, "reference", "", errOutputNotFound},
+		{"unsupported operator", unsupported, map[string][]int32{"x": {1, 3}}, nil, "reference", "", errUnsupportedOperator},
+		{"second output", multiOutput, map[string][]int32{"x": {1, 3}}, []string{"z"}, "reference", "", errUnsupportedOperator},
+		{"invalid backend", md, map[string][]int32{"x": {1, 3}}, nil, "dummy", "", errInvalidBackendName},
+		{"invalid config", md, map[string][]int32{"x": {1, 3}}, nil, "mkldnn", "{", errJSONParseError},
+	}
+	// unused outputs except the first are ignored
+	b, _ := MakeVariableProfileTableBuilder()
+	mustNil(t, b.AddInputProfile("x", TypeFloat, 1, 3))
+	mustNil(t, b.AddOutputProfile("y", TypeFloat))
+	if _, err := b.BuildVariableProfileTable(*multiOutput); err != nil {
+		t.Errorf("the first output should be profiled, %v", err)
+	}
+
+	for _, ts := range testSet {
+		t.Run(ts.name, func(t *testing.T) {
+			err := func() error {
+				b, _ := MakeVariableProfileTableBuilder()
+				for name, dims := range ts.inputs {
+					mustNil(t, b.AddInputProfile(name, TypeFloat, dims...))
+				}
+				for _, name := range ts.outputs {
+					mustNil(t, b.AddOutputProfile(name, TypeFloat))
+				}
+				vpt, err := b.BuildVariableProfileTable(*ts.md)
+				if err != nil {
+					return err
+				}
+				builder, _ := MakeModelBuilder(*vpt)
+				_, err = builder.BuildModel(*ts.md, ts.backend, ts.config)
+				return err
+			}()
+			if errcode.Of(err) != ts.expected || !strings.HasPrefix(err.Error(), ts.expected.String()) {
+				t.Errorf("error should start with '%s', but %v", ts.expected, err)
+			}
+		})
+	}
+}
+
+func TestModelDataFail(t *testing.T) {
+	md, _ := MakeModelData()
+	if err := md.AddInputNameToCurrentNode("x"); err == nil {
+		t.Error("an error should be occurred without node")
+	}
+	data := []float32{1}
+	param := Variable{Dtype: TypeFloat, Dims: []int32{1}, BufferHandle: unsafe.Pointer(&data[0])}
+	mustNil(t, md.AddParameter("p", param))
+	if err := md.AddParameter("p", param); err == nil {
+		t.Error("an error should be occurred with same named parameter")
+	}
+	param.Dtype = TypeMenohDtype(1)
+	if err := md.AddParameter("q", param); err == nil {
+		t.Error("an error should be occurred with invalid dtype")
+	}
+	mustNil(t, md.AddNewNode("Relu"))
+	mustNil(t, md.AddAttributeIntToCurrentNode("a", 1))
+	if err := md.AddAttributeFloatToCurrentNode("a", 1); err == nil {
+		t.Error("an error should be occurred with same named attribute")
+	}
+
+	tempDir, err := ioutil.TempDir("", "go-menoh-test-")
+	mustNil(t, err)
+	defer os.RemoveAll(tempDir)
+	if _, err := MakeModelDataFromONNX(filepath.Join(tempDir, "dummy.onnx")); errcode.Of(err) != errInvalidFilename {
+		t.Errorf("invalid filename error should be occurred, but %v", err)
+	}
+	if _, err := MakeModelDataFromONNXBytes([]byte("dummy data")); errcode.Of(err) != errONNXParseError {
+		t.Errorf("ONNX parse error should be occurred, but %v", err)
+	}
+}
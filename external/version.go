@@ -0,0 +1,34 @@
+// +build !windows,!menoh_reference
+
+package external
+
+/*
+#include <menoh/menoh.h>
+
+#if defined(__has_include)
+#if __has_include(<menoh/version.h>)
+#include <menoh/version.h>
+#endif
+#endif
+
+#ifdef MENOH_MAJOR_VERSION
+static int go_menoh_major_version() { return MENOH_MAJOR_VERSION; }
+static int go_menoh_minor_version() { return MENOH_MINOR_VERSION; }
+static int go_menoh_patch_version() { return MENOH_PATCH_VERSION; }
+#else
+static int go_menoh_major_version() { return -1; }
+static int go_menoh_minor_version() { return -1; }
+static int go_menoh_patch_version() { return -1; }
+#endif
+*/
+import "C"
+
+// HeaderVersion returns Menoh version defined by macros of the header used on
+// building, ok is false when the header does not define them.
+func HeaderVersion() (major, minor, patch int, ok bool) {
+	major = int(C.go_menoh_major_version())
+	if major < 0 {
+		return 0, 0, 0, false
+	}
+	return major, int(C.go_menoh_minor_version()), int(C.go_menoh_patch_version()), true
+}
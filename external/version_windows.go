@@ -0,0 +1,9 @@
+// +build !menoh_reference
+
+package external
+
+// HeaderVersion returns Menoh version defined by macros of the header, but
+// the header is not used on Windows, ok is always false.
+func HeaderVersion() (major, minor, patch int, ok bool) {
+	return 0, 0, 0, false
+}
@@ -0,0 +1,130 @@
+package menoh
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// RunnerHooks are called from runners built with Config.Hooks, to trace
+// build phases and runs. Hooks must be safe for concurrent use when runners
+// share the configuration, like runners of a pool. Without Config.Hooks,
+// runners do not call hooks nor allocate for them.
+type RunnerHooks interface {
+	// OnBuildPhase is called after each phase to build a runner with the
+	// configuration, the phase started at start.
+	OnBuildPhase(conf *Config, phase PhaseDuration, start time.Time, err error)
+
+	// BeforeRun is called before copying the inputs and running.
+	BeforeRun(r *Runner, inputs map[string]Tensor)
+
+	// AfterRun is called after running with the result.
+	AfterRun(r *Runner, inputs map[string]Tensor, err error)
+}
+
+// NopHooks is RunnerHooks doing nothing, embed it to implement a part of
+// hooks.
+type NopHooks struct{}
+
+// OnBuildPhase does nothing.
+func (NopHooks) OnBuildPhase(conf *Config, phase PhaseDuration, start time.Time, err error) {}
+
+// BeforeRun does nothing.
+func (NopHooks) BeforeRun(r *Runner, inputs map[string]Tensor) {}
+
+// AfterRun does nothing.
+func (NopHooks) AfterRun(r *Runner, inputs map[string]Tensor, err error) {}
+
+// Tracer starts spans, implement it as an adapter of a tracing library like
+// OpenTelemetry and pass it to NewTracerHooks.
+type Tracer interface {
+	// StartSpan starts a span with the name at the time.
+	StartSpan(name string, start time.Time) Span
+}
+
+// Span is a span started by Tracer.
+type Span interface {
+	// SetAttribute sets an attribute of the span, values are string, int,
+	// []string or []int32.
+	SetAttribute(key string, value interface{})
+
+	// SetError records the error of the span.
+	SetError(err error)
+
+	// End ends the span at the time.
+	End(end time.Time)
+}
+
+// Attribute keys of spans started by hooks of NewTracerHooks.
+const (
+	AttributeModel   = "menoh.model"
+	AttributeBackend = "menoh.backend"
+	AttributeOutputs = "menoh.outputs"
+
+	// attributes of input shapes are prefixed by it, like
+	// "menoh.input.data.shape" for an input named "data"
+	AttributeInputPrefix = "menoh.input."
+)
+
+// tracerHooks starts a span "menoh.build.<phase>" for each build phase and a
+// span "menoh.run" for each run.
+type tracerHooks struct {
+	tracer Tracer
+
+	mu    sync.Mutex
+	spans map[*Runner]Span
+}
+
+// NewTracerHooks returns RunnerHooks emitting spans of build phases named
+// like "menoh.build.buildModel", and of runs named "menoh.run" with input
+// shapes and output names as attributes.
+func NewTracerHooks(tracer Tracer) RunnerHooks {
+	return &tracerHooks{
+		tracer: tracer,
+		spans:  map[*Runner]Span{},
+	}
+}
+
+func setModelAttributes(span Span, conf *Config) {
+	span.SetAttribute(AttributeModel, modelName(*conf))
+	span.SetAttribute(AttributeBackend, conf.Backend.String())
+}
+
+func (h *tracerHooks) OnBuildPhase(conf *Config, phase PhaseDuration, start time.Time, err error) {
+	span := h.tracer.StartSpan(fmt.Sprintf("menoh.build.%s", phase.Phase), start)
+	setModelAttributes(span, conf)
+	if err != nil {
+		span.SetError(err)
+	}
+	span.End(start.Add(phase.Duration))
+}
+
+func (h *tracerHooks) BeforeRun(r *Runner, inputs map[string]Tensor) {
+	span := h.tracer.StartSpan("menoh.run", time.Now())
+	setModelAttributes(span, &r.conf)
+	for name, t := range inputs {
+		span.SetAttribute(AttributeInputPrefix+name+".shape", t.Shape())
+	}
+	outputs := make([]string, len(r.conf.Outputs))
+	for i, c := range r.conf.Outputs {
+		outputs[i] = c.Name
+	}
+	span.SetAttribute(AttributeOutputs, outputs)
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.spans[r] = span
+}
+
+func (h *tracerHooks) AfterRun(r *Runner, inputs map[string]Tensor, err error) {
+	h.mu.Lock()
+	span, ok := h.spans[r]
+	delete(h.spans, r)
+	h.mu.Unlock()
+	if !ok {
+		return
+	}
+	if err != nil {
+		span.SetError(err)
+	}
+	span.End(time.Now())
+}
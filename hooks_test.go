@@ -0,0 +1,110 @@
+package menoh
+
+import (
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+type testSpan struct {
+	name  string
+	attrs map[string]interface{}
+	err   error
+	ended bool
+}
+
+func (s *testSpan) SetAttribute(key string, value interface{}) { s.attrs[key] = value }
+func (s *testSpan) SetError(err error)                         { s.err = err }
+func (s *testSpan) End(end time.Time)                          { s.ended = true }
+
+type testTracer struct {
+	mu    sync.Mutex
+	spans []*testSpan
+}
+
+func (t *testTracer) StartSpan(name string, start time.Time) Span {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	s := &testSpan{name: name, attrs: map[string]interface{}{}}
+	t.spans = append(t.spans, s)
+	return s
+}
+
+func TestTracerHooks(t *testing.T) {
+	tracer := &testTracer{}
+	conf := fakeConfig()
+	conf.Name = "mlp"
+	conf.Hooks = NewTracerHooks(tracer)
+
+	runner, err := NewRunnerWithBackend(&fakeBackend{}, conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer runner.Stop()
+	input := &FloatTensor{Dims: []int32{1, 3}, Array: []float32{1, 2, 3}}
+	if err := runner.RunWithTensor("input", input); err != nil {
+		t.Fatal(err)
+	}
+	if err := runner.RunWithTensor("dummy", input); err == nil {
+		t.Fatal("an error should be occurred with not attached input")
+	}
+
+	names := []string{}
+	for _, s := range tracer.spans {
+		names = append(names, s.name)
+		if !s.ended {
+			t.Errorf("span %s should be ended", s.name)
+		}
+		if s.attrs[AttributeModel] != "mlp" || s.attrs[AttributeBackend] != "mkldnn" {
+			t.Errorf("span %s should have model attributes, but %v", s.name, s.attrs)
+		}
+	}
+	expected := []string{"menoh.build.makeVariableProfileTableBuilder", "menoh.build.buildVariableProfileTable",
+		"menoh.build.makeModelBuilder", "menoh.build.buildModel", "menoh.run", "menoh.run"}
+	if !reflect.DeepEqual(names, expected) {
+		t.Fatalf("spans should be %v, but %v", expected, names)
+	}
+	run := tracer.spans[4]
+	if shape := run.attrs["menoh.input.input.shape"]; !reflect.DeepEqual(shape, []int32{1, 3}) {
+		t.Errorf("input shape should be an attribute, but %v", run.attrs)
+	}
+	if outputs := run.attrs[AttributeOutputs]; !reflect.DeepEqual(outputs, []string{"output"}) {
+		t.Errorf("output names should be an attribute, but %v", run.attrs)
+	}
+	if run.err != nil || tracer.spans[5].err == nil {
+		t.Error("only the failed run should have an error")
+	}
+}
+
+func TestTracerHooksBuildError(t *testing.T) {
+	tracer := &testTracer{}
+	conf := fakeConfig()
+	conf.Hooks = NewTracerHooks(tracer)
+	if _, err := NewRunnerWithBackend(&fakeBackend{buildErr: errors.New("build error")}, conf); err == nil {
+		t.Fatal("an error should be occurred on building")
+	}
+	last := tracer.spans[len(tracer.spans)-1]
+	if last.name != "menoh.build.buildModel" || last.err == nil {
+		t.Errorf("the failed phase should have an error, but %v", last)
+	}
+}
+
+func TestHooksAllocation(t *testing.T) {
+	for _, hooks := range []RunnerHooks{nil, NopHooks{}} {
+		conf := fakeConfig()
+		conf.Hooks = hooks
+		runner, err := NewRunnerWithBackend(&fakeBackend{}, conf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		allocs := testing.AllocsPerRun(100, func() {
+			runner.Run(nil)
+		})
+		if allocs != 0 {
+			t.Errorf("run with %T hooks should not allocate, but %v", hooks, allocs)
+		}
+		runner.Stop()
+	}
+}
@@ -0,0 +1,3 @@
+package inference
+
+//go:generate protoc -I. -I../tools/onnx --go_out=plugins=grpc,Monnx.proto=github.com/pfnet-research/go-menoh/tools/onnx:. inference.proto
@@ -0,0 +1,220 @@
+/*
+Package inference provides gRPC InferenceService serving Menoh runners.
+Tensors are carried as onnx.TensorProto defined in tools/onnx, clients in
+other languages can use "inference.proto" with ONNX's "onnx.proto".
+
+	service := inference.NewService(inference.Options{PoolSize: 4})
+	defer service.Stop()
+	if err := service.AddModel("mlp", conf); err != nil {
+		...
+	}
+	s := grpc.NewServer()
+	inference.RegisterInferenceServiceServer(s, service)
+	s.Serve(listener)
+
+Deadlines of requests are applied to waiting for an idle runner and checked
+before each run.
+*/
+package inference
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/pfnet-research/go-menoh"
+	"github.com/pfnet-research/go-menoh/internal/serving"
+	"github.com/pfnet-research/go-menoh/tools/onnx"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Options is setup information of Service.
+type Options struct {
+	PoolSize int // number of runners for each model, default is 1
+}
+
+// Service implements InferenceServiceServer with registered models.
+type Service struct {
+	opts   Options
+	models *serving.Models
+}
+
+// NewService returns Service with no model. Require to call Stop function
+// after serving is done.
+func NewService(opts Options) *Service {
+	if opts.PoolSize <= 0 {
+		opts.PoolSize = 1
+	}
+	return &Service{
+		opts:   opts,
+		models: serving.NewModels(),
+	}
+}
+
+// AddModel builds a pool of runners with the configuration and serves it
+// with the name.
+func (s *Service) AddModel(name string, conf menoh.Config) error {
+	if conf.Name == "" {
+		conf.Name = name
+	}
+	pool, err := menoh.NewPool(conf, s.opts.PoolSize)
+	if err != nil {
+		return fmt.Errorf("cannot build runners of '%s', %v", name, err)
+	}
+	if err := s.AddPool(name, pool); err != nil {
+		pool.Stop()
+		return err
+	}
+	return nil
+}
+
+// AddPool serves the pool with the name. The service takes ownership of the
+// pool and stops it on removing.
+func (s *Service) AddPool(name string, pool menoh.InferencerPool) error {
+	if name == "" {
+		return fmt.Errorf("model name must not be empty")
+	}
+	m, err := serving.NewModel(name, pool)
+	if err != nil {
+		return err
+	}
+	return s.models.Add(m)
+}
+
+func makeMetadata(m *serving.Model) *ModelMetadataResponse {
+	metadata := &ModelMetadataResponse{
+		Name:    m.Name,
+		Backend: m.Backend,
+	}
+	for _, in := range m.Inputs {
+		metadata.Inputs = append(metadata.Inputs, &TensorMetadata{
+			Name:     in.Name,
+			DataType: int32(onnx.TensorProto_FLOAT),
+			Dims:     toDims(in.Shape),
+		})
+	}
+	for _, out := range m.Outputs {
+		metadata.Outputs = append(metadata.Outputs, &TensorMetadata{
+			Name:     out.Name,
+			DataType: int32(onnx.TensorProto_FLOAT),
+			Dims:     toDims(out.Shape),
+		})
+	}
+	return metadata
+}
+
+func toDims(shape []int32) []int64 {
+	dims := make([]int64, len(shape))
+	for i, d := range shape {
+		dims[i] = int64(d)
+	}
+	return dims
+}
+
+// RemoveModel stops serving the model and stops its runners, waiting for
+// running requests.
+func (s *Service) RemoveModel(name string) {
+	s.models.Remove(name)
+}
+
+// Stop all models.
+func (s *Service) Stop() {
+	s.models.Stop()
+}
+
+func (s *Service) getModel(name string) (*serving.Model, error) {
+	m, ok := s.models.Get(name)
+	if !ok {
+		return nil, status.Errorf(codes.NotFound, "model '%s' is not found", name)
+	}
+	return m, nil
+}
+
+// ListModels returns names of served models.
+func (s *Service) ListModels(ctx context.Context, req *ListModelsRequest) (*ListModelsResponse, error) {
+	return &ListModelsResponse{Names: s.models.Names()}, nil
+}
+
+// ModelMetadata returns inputs and outputs of the model.
+func (s *Service) ModelMetadata(ctx context.Context, req *ModelMetadataRequest) (*ModelMetadataResponse, error) {
+	m, err := s.getModel(req.GetName())
+	if err != nil {
+		return nil, err
+	}
+	return makeMetadata(m), nil
+}
+
+// Infer runs the model with inputs and returns outputs.
+func (s *Service) Infer(ctx context.Context, req *InferRequest) (*InferResponse, error) {
+	m, err := s.getModel(req.GetModelName())
+	if err != nil {
+		return nil, err
+	}
+	return infer(ctx, m, req)
+}
+
+// InferStream runs requests sent by the client in order, and returns all
+// responses after the client closes the stream.
+func (s *Service) InferStream(stream InferenceService_InferStreamServer) error {
+	resp := &InferStreamResponse{}
+	for {
+		req, err := stream.Recv()
+		if err == io.EOF {
+			return stream.SendAndClose(resp)
+		}
+		if err != nil {
+			return err
+		}
+		m, err := s.getModel(req.GetModelName())
+		if err != nil {
+			return err
+		}
+		r, err := infer(stream.Context(), m, req)
+		if err != nil {
+			return err
+		}
+		resp.Responses = append(resp.Responses, r)
+	}
+}
+
+func infer(ctx context.Context, m *serving.Model, req *InferRequest) (*InferResponse, error) {
+	in := m.NewInputs()
+	for _, proto := range req.GetInputs() {
+		t, err := onnx.ConvertToMenohTensor(proto)
+		if err != nil {
+			return nil, status.Errorf(codes.InvalidArgument, "cannot convert input '%s', %v", proto.GetName(), err)
+		}
+		if err := in.Add(proto.GetName(), t); err != nil {
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		}
+	}
+	inputs, err := in.Tensors()
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	names, err := m.OutputNames(req.GetOutputNames())
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	outputs, err := m.Run(ctx, inputs, names)
+	if err == context.Canceled || err == context.DeadlineExceeded {
+		return nil, status.FromContextError(err).Err()
+	}
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	resp := &InferResponse{
+		ModelName: req.GetModelName(),
+		Id:        req.GetId(),
+	}
+	for _, o := range outputs {
+		out, err := onnx.ConvertToONNXTensor(o.Name, o.Tensor)
+		if err != nil {
+			return nil, status.Errorf(codes.Internal, "cannot convert output '%s', %v", o.Name, err)
+		}
+		resp.Outputs = append(resp.Outputs, out)
+	}
+	return resp, nil
+}
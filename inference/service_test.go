@@ -0,0 +1,253 @@
+package inference
+
+import (
+	"bytes"
+	"context"
+	"net"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/pfnet-research/go-menoh"
+	"github.com/pfnet-research/go-menoh/external/errcode"
+	"github.com/pfnet-research/go-menoh/menohtest"
+	"github.com/pfnet-research/go-menoh/tools/onnx"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// newTestClient serves "mlp" model run by a fake through in-process
+// listener, fc2 is scripted for input [0, 1, 2] and running fails for input
+// [9, 9, 9].
+func newTestClient(t *testing.T) (InferenceServiceClient, func()) {
+	fake := menohtest.New()
+	fake.Output("fc2", 1, 5)
+	fake.Script(
+		map[string][]float32{"input": {0, 1, 2}},
+		map[string][]float32{"fc2": {0, 0, 15, 96, 177}})
+	fake.ScriptError(map[string][]float32{"input": {9, 9, 9}},
+		menohtest.NewError(errcode.UnknownError, "run failure"))
+	service := NewService(Options{})
+	err := service.AddModel("mlp", menoh.Config{
+		Backend: fake.Register("inference_test"),
+		Inputs:  []menoh.InputConfig{{Name: "input", Dtype: menoh.TypeFloat, Dims: []int32{1, 3}}},
+		Outputs: []menoh.OutputConfig{{Name: "fc2", Dtype: menoh.TypeFloat}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	listener := bufconn.Listen(1 << 20)
+	server := grpc.NewServer()
+	RegisterInferenceServiceServer(server, service)
+	go server.Serve(listener)
+
+	conn, err := grpc.Dial("bufnet",
+		grpc.WithDialer(func(string, time.Duration) (net.Conn, error) {
+			return listener.Dial()
+		}),
+		grpc.WithInsecure())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return NewInferenceServiceClient(conn), func() {
+		conn.Close()
+		server.Stop()
+		service.Stop()
+	}
+}
+
+func TestListModelsAndMetadata(t *testing.T) {
+	client, closer := newTestClient(t)
+	defer closer()
+	ctx := context.Background()
+
+	list, err := client.ListModels(ctx, &ListModelsRequest{})
+	if err != nil {
+		t.Fatalf("models should be listed, %v", err)
+	}
+	if len(list.GetNames()) != 1 || list.GetNames()[0] != "mlp" {
+		t.Errorf("model names should be [mlp], but %v", list.GetNames())
+	}
+
+	metadata, err := client.ModelMetadata(ctx, &ModelMetadataRequest{Name: "mlp"})
+	if err != nil {
+		t.Fatalf("metadata should be returned, %v", err)
+	}
+	if len(metadata.GetOutputs()) != 1 || metadata.GetOutputs()[0].GetName() != "fc2" {
+		t.Errorf("metadata should describe outputs, but %v", metadata.GetOutputs())
+	}
+
+	_, err = client.ModelMetadata(ctx, &ModelMetadataRequest{Name: "dummy"})
+	if status.Code(err) != codes.NotFound {
+		t.Errorf("status should be NotFound, but %v", err)
+	}
+}
+
+func testInput(values ...float32) *onnx.TensorProto {
+	dtype := onnx.TensorProto_FLOAT
+	return &onnx.TensorProto{
+		Name:      proto.String("input"),
+		DataType:  &dtype,
+		Dims:      []int64{1, int64(len(values))},
+		FloatData: values,
+	}
+}
+
+func checkResponse(t *testing.T, resp *InferResponse, id string) {
+	t.Helper()
+	if resp.GetModelName() != "mlp" || resp.GetId() != id || len(resp.GetOutputs()) != 1 {
+		t.Fatalf("response should have an output of mlp, but %v", resp)
+	}
+	out := resp.GetOutputs()[0]
+	if out.GetName() != "fc2" || !reflect.DeepEqual(out.GetDims(), []int64{1, 5}) {
+		t.Errorf("output should be fc2 of [1 5], but %s of %v", out.GetName(), out.GetDims())
+	}
+	if !reflect.DeepEqual(out.GetFloatData(), []float32{0, 0, 15, 96, 177}) {
+		t.Errorf("output data should be scripted one, but %v", out.GetFloatData())
+	}
+}
+
+func TestInfer(t *testing.T) {
+	client, closer := newTestClient(t)
+	defer closer()
+
+	resp, err := client.Infer(context.Background(), &InferRequest{
+		ModelName: "mlp",
+		Id:        "1",
+		Inputs:    []*onnx.TensorProto{testInput(0, 1, 2)},
+	})
+	if err != nil {
+		t.Fatalf("inference should succeed, %v", err)
+	}
+	checkResponse(t, resp, "1")
+}
+
+func TestInferStream(t *testing.T) {
+	client, closer := newTestClient(t)
+	defer closer()
+
+	stream, err := client.InferStream(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, id := range []string{"1", "2"} {
+		err := stream.Send(&InferRequest{
+			ModelName: "mlp",
+			Id:        id,
+			Inputs:    []*onnx.TensorProto{testInput(0, 1, 2)},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	resp, err := stream.CloseAndRecv()
+	if err != nil {
+		t.Fatalf("stream should be closed without error, %v", err)
+	}
+	if len(resp.GetResponses()) != 2 {
+		t.Fatalf("responses of all requests should be returned, but %v", resp.GetResponses())
+	}
+	for i, r := range resp.GetResponses() {
+		checkResponse(t, r, []string{"1", "2"}[i])
+	}
+}
+
+func TestInferFail(t *testing.T) {
+	client, closer := newTestClient(t)
+	defer closer()
+
+	floatType := onnx.TensorProto_FLOAT
+	doubleType := onnx.TensorProto_DOUBLE
+	testSet := []struct {
+		name     string
+		req      *InferRequest
+		expected codes.Code
+	}{
+		{"unknown model", &InferRequest{ModelName: "dummy"}, codes.NotFound},
+		{"unknown input", &InferRequest{
+			ModelName: "mlp",
+			Inputs:    []*onnx.TensorProto{{Name: proto.String("dummy"), DataType: &floatType}},
+		}, codes.InvalidArgument},
+		{"unsupported dtype", &InferRequest{
+			ModelName: "mlp",
+			Inputs:    []*onnx.TensorProto{{Name: proto.String("input"), DataType: &doubleType}},
+		}, codes.InvalidArgument},
+		{"unknown output", &InferRequest{
+			ModelName:   "mlp",
+			Inputs:      []*onnx.TensorProto{testInput(0, 1, 2)},
+			OutputNames: []string{"dummy"},
+		}, codes.InvalidArgument},
+		{"missing input", &InferRequest{ModelName: "mlp"}, codes.InvalidArgument},
+		{"duplicated input", &InferRequest{
+			ModelName: "mlp",
+			Inputs:    []*onnx.TensorProto{testInput(0, 1, 2), testInput(0, 1, 2)},
+		}, codes.InvalidArgument},
+		{"run failure", &InferRequest{
+			ModelName: "mlp",
+			Inputs:    []*onnx.TensorProto{testInput(9, 9, 9)},
+		}, codes.Internal},
+	}
+	for _, ts := range testSet {
+		t.Run(ts.name, func(t *testing.T) {
+			_, err := client.Infer(context.Background(), ts.req)
+			if status.Code(err) != ts.expected {
+				t.Errorf("status should be %v, but %v", ts.expected, err)
+			}
+		})
+	}
+
+	t.Run("stream", func(t *testing.T) {
+		stream, err := client.InferStream(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := stream.Send(&InferRequest{ModelName: "dummy"}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := stream.CloseAndRecv(); status.Code(err) != codes.NotFound {
+			t.Errorf("status should be NotFound, but %v", err)
+		}
+	})
+	t.Run("empty stream", func(t *testing.T) {
+		stream, err := client.InferStream(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp, err := stream.CloseAndRecv()
+		if err != nil {
+			t.Fatalf("empty stream should be closed without error, %v", err)
+		}
+		if len(resp.GetResponses()) != 0 {
+			t.Errorf("no response should be returned, but %v", resp.GetResponses())
+		}
+	})
+}
+
+func TestAddModelName(t *testing.T) {
+	fake := menohtest.New()
+	fake.Output("fc2", 1, 5)
+	metrics := menoh.NewMetrics()
+	service := NewService(Options{})
+	defer service.Stop()
+	err := service.AddModel("mlp", menoh.Config{
+		Backend:       fake.Register("inference_test"),
+		ONNXModelPath: "/path/to/model.onnx",
+		Inputs:        []menoh.InputConfig{{Name: "input", Dtype: menoh.TypeFloat, Dims: []int32{1, 3}}},
+		Outputs:       []menoh.OutputConfig{{Name: "fc2", Dtype: menoh.TypeFloat}},
+		Metrics:       metrics,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	buf := &bytes.Buffer{}
+	if _, err := metrics.WriteTo(buf); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), `menoh_runners{model="mlp",`) {
+		t.Errorf("runners should be labeled by the served name, but\n%s", buf)
+	}
+}
@@ -0,0 +1,53 @@
+package menoh
+
+import (
+	"context"
+)
+
+// Inferencer runs a model with input tensors and returns output tensors.
+// Runner, StatefulRunner, sessions of Pool, Batcher and ShapeAdapter
+// implement it, so higher layers like servers and pipelines can compose
+// over an Inferencer instead of *Runner. An Inferencer is used by one
+// caller at a time, tensors returned by it are valid until the next Run.
+type Inferencer interface {
+	// Run with the inputs which are set name and tensor as key-value.
+	Run(inputs map[string]Tensor) error
+
+	// RunWithTensor inputs the tensor with the name, and runs.
+	RunWithTensor(name string, t Tensor) error
+
+	// GetInput returns the input tensor with the name.
+	GetInput(name string) (Tensor, error)
+
+	// GetOutput returns the output tensor with the name.
+	GetOutput(name string) (Tensor, error)
+
+	// Outputs returns all output tensors.
+	Outputs() map[string]Tensor
+
+	// Stop releases the model.
+	Stop()
+}
+
+// InferencerPool makes an Inferencer for each concurrent caller, like Pool,
+// so that servers serve a model without depending on how runs are
+// scheduled.
+type InferencerPool interface {
+	// Config returns the configuration of the model.
+	Config() Config
+
+	// NewSession returns Inferencer for a caller, runs wait for the model
+	// until ctx is done.
+	NewSession(ctx context.Context) Inferencer
+
+	// Stop releases the model, waiting for running calls.
+	Stop()
+}
+
+var (
+	_ Inferencer     = (*Runner)(nil)
+	_ Inferencer     = (*StatefulRunner)(nil)
+	_ Inferencer     = (*Batcher)(nil)
+	_ Inferencer     = (*ShapeAdapter)(nil)
+	_ InferencerPool = (*Pool)(nil)
+)
@@ -0,0 +1,54 @@
+package menoh
+
+import (
+	"fmt"
+	"testing"
+)
+
+// testInferencer runs the inferencer with the input of MLP test data and
+// checks fc2 output.
+func testInferencer(inf Inferencer, expected Tensor) error {
+	input := &FloatTensor{
+		Dims:  []int32{1, 3},
+		Array: []float32{0., 1., 2.},
+	}
+	if err := inf.RunWithTensor("input", input); err != nil {
+		return fmt.Errorf("the inferencer should run without error, %v", err)
+	}
+	in, err := inf.GetInput("input")
+	if err != nil {
+		return err
+	}
+	if !tensorEquals(in, input) {
+		return fmt.Errorf("input should be %v, but %v", input, in)
+	}
+	actual, err := inf.GetOutput("fc2")
+	if err != nil {
+		return err
+	}
+	if !tensorEquals(actual, expected) {
+		return fmt.Errorf("output should be %v, but %v", expected, actual)
+	}
+	if _, ok := inf.Outputs()["fc2"]; !ok {
+		return fmt.Errorf("outputs should include fc2, but %v", inf.Outputs())
+	}
+	return nil
+}
+
+func TestRunnerAsInferencer(t *testing.T) {
+	runner, err := NewRunnerWithBackend(&fakeBackend{}, Config{
+		Backend: TypeMKLDNN,
+		Inputs:  []InputConfig{{Name: "input", Dtype: TypeFloat, Dims: []int32{1, 3}}},
+		Outputs: []OutputConfig{{Name: "fc2", Dtype: TypeFloat}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var inf Inferencer = runner
+	defer inf.Stop()
+
+	expected := &FloatTensor{Dims: []int32{1, 3}, Array: []float32{0., 2., 4.}}
+	if err := testInferencer(inf, expected); err != nil {
+		t.Error(err)
+	}
+}
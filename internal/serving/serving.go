@@ -0,0 +1,230 @@
+/*
+Package serving is a table of models served by menoh.InferencerPool, like
+pools of runners, shared by the HTTP server and the gRPC service. Requests
+are validated against the configuration of the model, because buffers of a
+pooled runner keep inputs of the last run, which can be of another client.
+*/
+package serving
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/pfnet-research/go-menoh"
+)
+
+// Tensor is a name and a shape of an input or an output of a model.
+type Tensor struct {
+	Name  string
+	Shape []int32
+}
+
+// Model is a pool of inferencers served with a name.
+type Model struct {
+	Name    string
+	Backend string
+	Inputs  []Tensor
+	Outputs []Tensor // shapes are determined by the model
+
+	pool menoh.InferencerPool
+}
+
+// NewModel returns Model serving the pool. The model takes ownership of the
+// pool and stops it on stopping.
+func NewModel(name string, pool menoh.InferencerPool) (*Model, error) {
+	conf := pool.Config()
+	m := &Model{
+		Name:    name,
+		Backend: conf.Backend.String(),
+		Inputs:  []Tensor{},
+		Outputs: []Tensor{},
+		pool:    pool,
+	}
+	for _, c := range conf.Inputs {
+		m.Inputs = append(m.Inputs, Tensor{Name: c.Name, Shape: c.Dims})
+	}
+	s := pool.NewSession(context.Background())
+	defer s.Stop()
+	for _, c := range conf.Outputs {
+		t, err := s.GetOutput(c.Name)
+		if err != nil {
+			return nil, err
+		}
+		m.Outputs = append(m.Outputs, Tensor{Name: c.Name, Shape: t.Shape()})
+	}
+	return m, nil
+}
+
+// Stop the runners, waiting for running requests.
+func (m *Model) Stop() {
+	m.pool.Stop()
+}
+
+func find(tensors []Tensor, name string) (Tensor, bool) {
+	for _, t := range tensors {
+		if t.Name == name {
+			return t, true
+		}
+	}
+	return Tensor{}, false
+}
+
+// Inputs collects inputs of a request to the model.
+type Inputs struct {
+	m       *Model
+	tensors map[string]menoh.Tensor
+}
+
+// NewInputs returns empty inputs of a request.
+func (m *Model) NewInputs() *Inputs {
+	return &Inputs{m: m, tensors: map[string]menoh.Tensor{}}
+}
+
+// Add adds the input, unknown, duplicated and misshaped inputs are rejected.
+func (in *Inputs) Add(name string, t menoh.Tensor) error {
+	c, ok := find(in.m.Inputs, name)
+	if !ok {
+		return fmt.Errorf("input '%s' is not found", name)
+	}
+	if _, ok := in.tensors[name]; ok {
+		return fmt.Errorf("input '%s' is duplicated", name)
+	}
+	if !equalShape(t.Shape(), c.Shape) {
+		return fmt.Errorf("shape of input '%s' should be %v, but %v", name, c.Shape, t.Shape())
+	}
+	in.tensors[name] = t
+	return nil
+}
+
+// Tensors returns the inputs, rejected when any input is missing.
+func (in *Inputs) Tensors() (map[string]menoh.Tensor, error) {
+	for _, c := range in.m.Inputs {
+		if _, ok := in.tensors[c.Name]; !ok {
+			return nil, fmt.Errorf("input '%s' is required", c.Name)
+		}
+	}
+	return in.tensors, nil
+}
+
+func equalShape(a, b []int32) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// OutputNames returns the requested output names, or all outputs of the
+// model if none is requested. Unknown outputs are rejected.
+func (m *Model) OutputNames(requested []string) ([]string, error) {
+	for _, name := range requested {
+		if _, ok := find(m.Outputs, name); !ok {
+			return nil, fmt.Errorf("output '%s' is not found", name)
+		}
+	}
+	if len(requested) > 0 {
+		return requested, nil
+	}
+	names := []string{}
+	for _, o := range m.Outputs {
+		names = append(names, o.Name)
+	}
+	return names, nil
+}
+
+// Output is a named output of a run.
+type Output struct {
+	Name   string
+	Tensor menoh.Tensor
+}
+
+// Run runs the inputs on a session of the pool and returns the outputs.
+// Errors of ctx are returned as they are, while waiting for the model or
+// before running.
+func (m *Model) Run(ctx context.Context, inputs map[string]menoh.Tensor, names []string) ([]Output, error) {
+	s := m.pool.NewSession(ctx)
+	defer s.Stop()
+	if err := s.Run(inputs); err != nil {
+		if err == context.Canceled || err == context.DeadlineExceeded {
+			return nil, err
+		}
+		return nil, fmt.Errorf("cannot run, %v", err)
+	}
+	// the session is not run again, its outputs are returned as they are
+	outputs := []Output{}
+	for _, name := range names {
+		t, err := s.GetOutput(name)
+		if err != nil {
+			return nil, fmt.Errorf("cannot get output, %v", err)
+		}
+		outputs = append(outputs, Output{Name: name, Tensor: t})
+	}
+	return outputs, nil
+}
+
+// Models is a table of served models, safe for concurrent use.
+type Models struct {
+	mu     sync.RWMutex
+	models map[string]*Model
+}
+
+// NewModels returns an empty table.
+func NewModels() *Models {
+	return &Models{models: map[string]*Model{}}
+}
+
+// Add serves the model.
+func (t *Models) Add(m *Model) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if _, ok := t.models[m.Name]; ok {
+		return fmt.Errorf("model '%s' is already served", m.Name)
+	}
+	t.models[m.Name] = m
+	return nil
+}
+
+// Get returns the served model.
+func (t *Models) Get(name string) (*Model, bool) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	m, ok := t.models[name]
+	return m, ok
+}
+
+// Remove stops serving the model and stops it, waiting for running
+// requests.
+func (t *Models) Remove(name string) {
+	t.mu.Lock()
+	m, ok := t.models[name]
+	delete(t.models, name)
+	t.mu.Unlock()
+	if ok {
+		m.Stop()
+	}
+}
+
+// Names returns sorted names of served models.
+func (t *Models) Names() []string {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	names := make([]string, 0, len(t.models))
+	for name := range t.models {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Stop all models.
+func (t *Models) Stop() {
+	for _, name := range t.Names() {
+		t.Remove(name)
+	}
+}
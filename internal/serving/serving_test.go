@@ -0,0 +1,113 @@
+package serving
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/pfnet-research/go-menoh"
+	"github.com/pfnet-research/go-menoh/menohtest"
+)
+
+func newTestModel(t *testing.T) *Model {
+	fake := menohtest.New()
+	fake.Output("y", 1, 2)
+	fake.Script(
+		map[string][]float32{"x": {1, 2}, "z": {3}},
+		map[string][]float32{"y": {4, 5}})
+	pool, err := menoh.NewPool(menoh.Config{
+		Backend: fake.Register("serving_test"),
+		Inputs: []menoh.InputConfig{
+			{Name: "x", Dtype: menoh.TypeFloat, Dims: []int32{1, 2}},
+			{Name: "z", Dtype: menoh.TypeFloat, Dims: []int32{1, 1}},
+		},
+		Outputs: []menoh.OutputConfig{{Name: "y", Dtype: menoh.TypeFloat}},
+	}, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m, err := NewModel("test", pool)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return m
+}
+
+func TestModel(t *testing.T) {
+	m := newTestModel(t)
+	defer m.Stop()
+	if !reflect.DeepEqual(m.Outputs, []Tensor{{Name: "y", Shape: []int32{1, 2}}}) {
+		t.Errorf("shapes of outputs should be taken from the runner, but %v", m.Outputs)
+	}
+
+	in := m.NewInputs()
+	if err := in.Add("x", &menoh.FloatTensor{Dims: []int32{1, 2}, Array: []float32{1, 2}}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := in.Tensors(); err == nil || !strings.Contains(err.Error(), "'z' is required") {
+		t.Errorf("missing input should be rejected, but %v", err)
+	}
+	if err := in.Add("x", &menoh.FloatTensor{Dims: []int32{1, 2}, Array: []float32{1, 2}}); err == nil {
+		t.Error("duplicated input should be rejected")
+	}
+	if err := in.Add("z", &menoh.FloatTensor{Dims: []int32{1, 2}, Array: []float32{3, 3}}); err == nil {
+		t.Error("misshaped input should be rejected")
+	}
+	if err := in.Add("w", &menoh.FloatTensor{Dims: []int32{1, 1}, Array: []float32{3}}); err == nil {
+		t.Error("unknown input should be rejected")
+	}
+	if err := in.Add("z", &menoh.FloatTensor{Dims: []int32{1, 1}, Array: []float32{3}}); err != nil {
+		t.Fatal(err)
+	}
+	inputs, err := in.Tensors()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	names, err := m.OutputNames(nil)
+	if err != nil || !reflect.DeepEqual(names, []string{"y"}) {
+		t.Errorf("all outputs should be returned, but %v, %v", names, err)
+	}
+	if _, err := m.OutputNames([]string{"dummy"}); err == nil {
+		t.Error("unknown output should be rejected")
+	}
+	outputs, err := m.Run(context.Background(), inputs, names)
+	if err != nil {
+		t.Fatalf("model should run, %v", err)
+	}
+	if len(outputs) != 1 || outputs[0].Name != "y" {
+		t.Fatalf("output y should be returned, but %v", outputs)
+	}
+	if a, _ := outputs[0].Tensor.FloatArray(); !reflect.DeepEqual(a, []float32{4, 5}) {
+		t.Errorf("output should be scripted one, but %v", a)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := m.Run(ctx, inputs, names); err != context.Canceled {
+		t.Errorf("error of the context should be returned, but %v", err)
+	}
+}
+
+func TestModels(t *testing.T) {
+	models := NewModels()
+	defer models.Stop()
+	m := newTestModel(t)
+	if err := models.Add(m); err != nil {
+		t.Fatal(err)
+	}
+	if err := models.Add(m); err == nil {
+		t.Error("an error should be occurred with the served name")
+	}
+	if got, ok := models.Get("test"); !ok || got != m {
+		t.Error("the model should be got")
+	}
+	if names := models.Names(); !reflect.DeepEqual(names, []string{"test"}) {
+		t.Errorf("names should be [test], but %v", names)
+	}
+	models.Remove("test")
+	if _, ok := models.Get("test"); ok {
+		t.Error("removed model should not be got")
+	}
+}
@@ -0,0 +1,123 @@
+package menoh
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+)
+
+// Logger is a structured logger, messages are logged with key and value
+// pairs. *slog.Logger satisfies it. Set a logger to Config.Logger for
+// runners built with the configuration, or by SetLogger globally.
+type Logger interface {
+	Debug(msg string, keysAndValues ...interface{})
+	Info(msg string, keysAndValues ...interface{})
+	Warn(msg string, keysAndValues ...interface{})
+	Error(msg string, keysAndValues ...interface{})
+}
+
+type nopLogger struct{}
+
+func (nopLogger) Debug(msg string, keysAndValues ...interface{}) {}
+func (nopLogger) Info(msg string, keysAndValues ...interface{})  {}
+func (nopLogger) Warn(msg string, keysAndValues ...interface{})  {}
+func (nopLogger) Error(msg string, keysAndValues ...interface{}) {}
+
+var (
+	globalLoggerMu sync.RWMutex
+	globalLogger   Logger = nopLogger{}
+)
+
+// SetLogger sets the logger used when Config.Logger is not set, and for
+// logs not related to a runner like version detection. The package does not
+// log by default, set nil to be silent again.
+func SetLogger(l Logger) {
+	globalLoggerMu.Lock()
+	defer globalLoggerMu.Unlock()
+	if l == nil {
+		l = nopLogger{}
+	}
+	globalLogger = l
+}
+
+func getLogger() Logger {
+	globalLoggerMu.RLock()
+	defer globalLoggerMu.RUnlock()
+	return globalLogger
+}
+
+// loggerOf returns the logger of the configuration, or the global logger.
+func loggerOf(conf *Config) Logger {
+	if conf.Logger != nil {
+		return conf.Logger
+	}
+	return getLogger()
+}
+
+// stdLogger is Logger writing a line like "INFO msg key=value" to log.Logger.
+type stdLogger struct {
+	logger *log.Logger
+	debug  bool
+}
+
+// NewStdLogger returns Logger writing to the standard logger, lines are
+// formatted like "INFO runner is built model=mlp backend=mkldnn". Debug logs
+// are written only if debug is true.
+func NewStdLogger(logger *log.Logger, debug bool) Logger {
+	return &stdLogger{logger: logger, debug: debug}
+}
+
+func (l *stdLogger) output(level, msg string, keysAndValues []interface{}) {
+	b := &strings.Builder{}
+	b.WriteString(level)
+	b.WriteString(" ")
+	b.WriteString(msg)
+	for i := 0; i < len(keysAndValues); i += 2 {
+		if i+1 < len(keysAndValues) {
+			fmt.Fprintf(b, " %v=%v", keysAndValues[i], keysAndValues[i+1])
+		} else {
+			fmt.Fprintf(b, " %v", keysAndValues[i])
+		}
+	}
+	l.logger.Output(3, b.String())
+}
+
+func (l *stdLogger) Debug(msg string, keysAndValues ...interface{}) {
+	if l.debug {
+		l.output("DEBUG", msg, keysAndValues)
+	}
+}
+
+func (l *stdLogger) Info(msg string, keysAndValues ...interface{}) {
+	l.output("INFO", msg, keysAndValues)
+}
+
+func (l *stdLogger) Warn(msg string, keysAndValues ...interface{}) {
+	l.output("WARN", msg, keysAndValues)
+}
+
+func (l *stdLogger) Error(msg string, keysAndValues ...interface{}) {
+	l.output("ERROR", msg, keysAndValues)
+}
+
+// formatInputs returns inputs like "input[1 3]" for logging.
+func formatInputs(inputs []InputConfig) string {
+	s := make([]string, len(inputs))
+	for i, c := range inputs {
+		s[i] = fmt.Sprintf("%s%v", c.Name, c.Dims)
+	}
+	return strings.Join(s, ",")
+}
+
+// formatOutputs returns outputs like "fc1(internal),fc2" for logging.
+func formatOutputs(outputs []OutputConfig) string {
+	s := make([]string, len(outputs))
+	for i, c := range outputs {
+		s[i] = c.Name
+		if c.FromInternal {
+			s[i] += "(internal)"
+		}
+	}
+	return strings.Join(s, ",")
+}
@@ -0,0 +1,98 @@
+package menoh
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"testing"
+)
+
+type testLogger struct {
+	mu      sync.Mutex
+	entries []string
+}
+
+func (l *testLogger) log(level, msg string, kv []interface{}) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.entries = append(l.entries, fmt.Sprint(level, " ", msg, " ", kv))
+}
+
+func (l *testLogger) Debug(msg string, kv ...interface{}) { l.log("DEBUG", msg, kv) }
+func (l *testLogger) Info(msg string, kv ...interface{})  { l.log("INFO", msg, kv) }
+func (l *testLogger) Warn(msg string, kv ...interface{})  { l.log("WARN", msg, kv) }
+func (l *testLogger) Error(msg string, kv ...interface{}) { l.log("ERROR", msg, kv) }
+
+func (l *testLogger) contains(s string) bool {
+	for _, e := range l.entries {
+		if strings.Contains(e, s) {
+			return true
+		}
+	}
+	return false
+}
+
+func TestRunnerLogger(t *testing.T) {
+	logger := &testLogger{}
+	conf := fakeConfig()
+	conf.Name = "mlp"
+	conf.Logger = logger
+
+	runner, err := NewRunnerWithBackend(&fakeBackend{}, conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	runner.Stop()
+	expected := []string{
+		"DEBUG build phase is done [model mlp phase makeVariableProfileTableBuilder",
+		"INFO runner is built [model mlp backend mkldnn inputs input[1 3] outputs output]",
+		"DEBUG runner is stopped [model mlp]",
+	}
+	for _, e := range expected {
+		if !logger.contains(e) {
+			t.Errorf("logs should include '%s', but %v", e, logger.entries)
+		}
+	}
+
+	backend := &fakeBackend{buildErr: errors.New("menoh invalid backend name error: mkldnn")}
+	if _, err := NewRunnerWithBackend(backend, conf); err == nil {
+		t.Fatal("an error should be occurred on building")
+	}
+	e := "ERROR cannot build runner [model mlp backend mkldnn phase buildModel error menoh invalid backend name error: mkldnn]"
+	if !logger.contains(e) {
+		t.Errorf("logs should include '%s', but %v", e, logger.entries)
+	}
+}
+
+func TestSetLogger(t *testing.T) {
+	logger := &testLogger{}
+	SetLogger(logger)
+	defer SetLogger(nil)
+
+	conf := fakeConfig()
+	conf.BackendConfig = "[]"
+	if _, err := NewRunnerWithBackend(&fakeBackend{}, conf); err == nil {
+		t.Fatal("an error should be occurred with invalid backend config")
+	}
+	if !logger.contains("ERROR invalid backend config") {
+		t.Errorf("the global logger should be used, but %v", logger.entries)
+	}
+
+	SetLogger(nil)
+	if _, ok := getLogger().(nopLogger); !ok {
+		t.Error("logger should be reset to be silent")
+	}
+}
+
+func TestStdLogger(t *testing.T) {
+	buf := &bytes.Buffer{}
+	logger := NewStdLogger(log.New(buf, "", 0), false)
+	logger.Debug("hidden")
+	logger.Info("runner is built", "model", "mlp", "backend", TypeMKLDNN, "odd")
+	if buf.String() != "INFO runner is built model=mlp backend=mkldnn odd\n" {
+		t.Errorf("log should be formatted with key and values, but %q", buf.String())
+	}
+}
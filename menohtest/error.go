@@ -0,0 +1,21 @@
+package menohtest
+
+import (
+	"fmt"
+
+	"github.com/pfnet-research/go-menoh/external/errcode"
+)
+
+// NewError returns an error of Menoh with the code, like errors returned by
+// the library. The detail is formatted by fmt.Sprintf, and the code is read
+// by errcode.Of.
+func NewError(code errcode.Code, format string, args ...interface{}) *errcode.Error {
+	detail := fmt.Sprintf(format, args...)
+	if detail == "" {
+		return &errcode.Error{Code: code}
+	}
+	return &errcode.Error{
+		Code:    code,
+		Message: fmt.Sprintf("menoh %s error: %s", code, detail),
+	}
+}
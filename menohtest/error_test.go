@@ -0,0 +1,20 @@
+package menohtest
+
+import (
+	"testing"
+
+	"github.com/pfnet-research/go-menoh/external/errcode"
+)
+
+func TestNewError(t *testing.T) {
+	err := NewError(errcode.VariableNotFound, "%s", "fc3")
+	if err.Error() != "menoh variable not found error: fc3" {
+		t.Errorf("error message should be like Menoh, but %v", err)
+	}
+	if errcode.Of(err) != errcode.VariableNotFound {
+		t.Errorf("code should be read from the error, but %v", errcode.Of(err))
+	}
+	if e := NewError(errcode.BackendError, ""); e.Error() != "backend error" {
+		t.Errorf("error without detail should be the code, but %v", e)
+	}
+}
@@ -0,0 +1,328 @@
+/*
+Package menohtest provides a fake of Menoh library to unit test applications
+using menoh.Runner without libmenoh.
+
+Fake makes menoh.Backend whose outputs are scripted per input, with failure
+and latency injection, and records calls. Runners of the fake are real
+*menoh.Runner, so they can be passed anywhere the Runner is used.
+
+	fake := menohtest.New()
+	fake.Output("fc2", 1, 5)
+	fake.Script(
+		map[string][]float32{"input": {0, 1, 2}},
+		map[string][]float32{"fc2": {0, 0, 15, 96, 177}})
+	runner, err := fake.NewRunner(conf)
+
+Register makes a TypeBackend for the fake, then menoh.NewRunner and
+menoh.NewPool with the backend type build runners of the fake.
+*/
+package menohtest
+
+import (
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/pfnet-research/go-menoh"
+	"github.com/pfnet-research/go-menoh/external/errcode"
+)
+
+// Call is a record of a method of menoh.Backend called by a runner.
+type Call struct {
+	Method string
+	Inputs map[string][]float32 // copy of input arrays, only for Run
+}
+
+type script struct {
+	inputs  map[string][]float32
+	outputs map[string][]float32
+	err     error
+}
+
+// Fake is a fake of Menoh library, safe for concurrent use by runners.
+type Fake struct {
+	mu sync.Mutex
+
+	dims           map[string][]int32
+	scripts        []script
+	defaultOutputs map[string][]float32
+
+	buildErr     error
+	runErr       error
+	buildLatency time.Duration
+	runLatency   time.Duration
+
+	calls []Call
+}
+
+// New returns Fake without outputs.
+func New() *Fake {
+	return &Fake{
+		dims: map[string][]int32{},
+	}
+}
+
+// Output declares an output variable of the model with the dims. Profiling
+// outputs not declared fails with errcode.VariableNotFound.
+func (f *Fake) Output(name string, dims ...int32) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.dims[name] = dims
+}
+
+// Script sets the outputs for the inputs. Run writes the outputs of the first
+// script whose inputs equal attached arrays, inputs not set in a script match
+// any value.
+func (f *Fake) Script(inputs, outputs map[string][]float32) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.scripts = append(f.scripts, script{inputs: inputs, outputs: outputs})
+}
+
+// ScriptError makes Run fail with err for the inputs, see Script.
+func (f *Fake) ScriptError(inputs map[string][]float32, err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.scripts = append(f.scripts, script{inputs: inputs, err: err})
+}
+
+// Default sets the outputs written when no script matches. Without default,
+// outputs are not updated.
+func (f *Fake) Default(outputs map[string][]float32) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.defaultOutputs = outputs
+}
+
+// FailBuild makes building runners fail with err, like
+// NewError(errcode.InvalidBackendName, "mkldnn"). Set nil to succeed.
+func (f *Fake) FailBuild(err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.buildErr = err
+}
+
+// FailRun makes every Run fail with err. Set nil to succeed.
+func (f *Fake) FailRun(err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.runErr = err
+}
+
+// SetLatency makes building and each Run take the durations.
+func (f *Fake) SetLatency(build, run time.Duration) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.buildLatency = build
+	f.runLatency = run
+}
+
+// Calls returns recorded calls of all runners in order.
+func (f *Fake) Calls() []Call {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]Call{}, f.calls...)
+}
+
+// RunCount returns the number of Run calls.
+func (f *Fake) RunCount() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	n := 0
+	for _, c := range f.calls {
+		if c.Method == "Run" {
+			n++
+		}
+	}
+	return n
+}
+
+// ResetCalls clears recorded calls.
+func (f *Fake) ResetCalls() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.calls = nil
+}
+
+// Backend returns a new menoh.Backend of the fake, a backend is used by a
+// runner.
+func (f *Fake) Backend() menoh.Backend {
+	return &backend{
+		fake:    f,
+		dims:    map[string][]int32{},
+		inputs:  map[string]menoh.Tensor{},
+		outputs: map[string]menoh.Tensor{},
+	}
+}
+
+// NewRunner returns a runner of the fake, conf.ONNXModelPath is not read.
+func (f *Fake) NewRunner(conf menoh.Config) (*menoh.Runner, error) {
+	return menoh.NewRunnerWithBackend(f.Backend(), conf)
+}
+
+// Register makes the backend type named name, and menoh.NewRunner with the
+// type uses the fake. Unregister by menoh.RegisterBackend(t, nil).
+func (f *Fake) Register(name string) menoh.TypeBackend {
+	t := menoh.CustomBackend(name)
+	menoh.RegisterBackend(t, f.Backend)
+	return t
+}
+
+func (f *Fake) record(c Call) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.calls = append(f.calls, c)
+}
+
+// match returns outputs or an error for the inputs.
+func (f *Fake) match(inputs map[string][]float32) (map[string][]float32, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.runErr != nil {
+		return nil, f.runErr
+	}
+	for _, s := range f.scripts {
+		matched := true
+		for name, v := range s.inputs {
+			matched = matched && reflect.DeepEqual(inputs[name], v)
+		}
+		if matched {
+			return s.outputs, s.err
+		}
+	}
+	return f.defaultOutputs, nil
+}
+
+func (f *Fake) outputDims(name string) ([]int32, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	dims, ok := f.dims[name]
+	return dims, ok
+}
+
+// backend is menoh.Backend of a runner.
+type backend struct {
+	fake *Fake
+
+	profiled []menoh.OutputConfig
+	dims     map[string][]int32
+	inputs   map[string]menoh.Tensor
+	outputs  map[string]menoh.Tensor
+}
+
+func (b *backend) LoadModel(path string) error {
+	b.fake.record(Call{Method: "LoadModel"})
+	return nil
+}
+
+func (b *backend) AddProfiles(inputs []menoh.InputConfig, outputs []menoh.OutputConfig) error {
+	b.fake.record(Call{Method: "AddProfiles"})
+	for _, c := range inputs {
+		b.dims[c.Name] = c.Dims
+	}
+	b.profiled = outputs
+	return nil
+}
+
+func (b *backend) ProfileVariables() error {
+	b.fake.record(Call{Method: "ProfileVariables"})
+	b.fake.mu.Lock()
+	defer b.fake.mu.Unlock()
+	for _, c := range b.profiled {
+		dims, ok := b.fake.dims[c.Name]
+		if !ok {
+			return NewError(errcode.VariableNotFound, "%s", c.Name)
+		}
+		b.dims[c.Name] = dims
+	}
+	return nil
+}
+
+func (b *backend) VariableProfile(name string) (menoh.TypeDtype, []int32, error) {
+	b.fake.record(Call{Method: "VariableProfile"})
+	dims, ok := b.dims[name]
+	if !ok {
+		return menoh.TypeFloat, nil, NewError(errcode.VariableNotFound, "%s", name)
+	}
+	return menoh.TypeFloat, dims, nil
+}
+
+func (b *backend) AttachBuffer(name string, t menoh.Tensor) error {
+	b.fake.record(Call{Method: "AttachBuffer"})
+	if _, ok := b.dims[name]; !ok {
+		return NewError(errcode.VariableNotFound, "%s", name)
+	}
+	if _, ok := b.fake.outputDims(name); ok {
+		b.outputs[name] = t
+	} else {
+		b.inputs[name] = t
+	}
+	return nil
+}
+
+func (b *backend) Build(t menoh.TypeBackend, backendConfig string) error {
+	b.fake.record(Call{Method: "Build"})
+	b.fake.mu.Lock()
+	latency, err := b.fake.buildLatency, b.fake.buildErr
+	b.fake.mu.Unlock()
+	time.Sleep(latency)
+	return err
+}
+
+func (b *backend) Run() error {
+	inputs := map[string][]float32{}
+	for name, t := range b.inputs {
+		a, err := t.FloatArray()
+		if err != nil {
+			return err
+		}
+		inputs[name] = append([]float32{}, a...)
+	}
+	b.fake.record(Call{Method: "Run", Inputs: inputs})
+	b.fake.mu.Lock()
+	latency := b.fake.runLatency
+	b.fake.mu.Unlock()
+	time.Sleep(latency)
+
+	outputs, err := b.fake.match(inputs)
+	if err != nil {
+		return err
+	}
+	for name, v := range outputs {
+		t, ok := b.outputs[name]
+		if !ok {
+			continue
+		}
+		if len(v) != t.Size() {
+			return NewError(errcode.DimensionMismatch, "%s, size %d is scripted for %v", name, len(v), t.Shape())
+		}
+		for i, e := range v {
+			if err := t.WriteFloat(i, e); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (b *backend) GetVariable(name string) (menoh.Tensor, error) {
+	b.fake.record(Call{Method: "GetVariable"})
+	if t, ok := b.outputs[name]; ok {
+		return t, nil
+	}
+	dims, ok := b.fake.outputDims(name)
+	if !ok {
+		return nil, NewError(errcode.VariableNotFound, "%s", name)
+	}
+	size := 1
+	for _, d := range dims {
+		size *= int(d)
+	}
+	t := &menoh.FloatTensor{Dims: dims, Array: make([]float32, size)}
+	b.outputs[name] = t
+	return t, nil
+}
+
+func (b *backend) Close() {
+	b.fake.record(Call{Method: "Close"})
+}
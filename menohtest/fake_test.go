@@ -0,0 +1,210 @@
+package menohtest
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/pfnet-research/go-menoh"
+	"github.com/pfnet-research/go-menoh/external/errcode"
+)
+
+func testConfig() menoh.Config {
+	return menoh.Config{
+		Backend: menoh.TypeMKLDNN,
+		Inputs:  []menoh.InputConfig{{Name: "input", Dtype: menoh.TypeFloat, Dims: []int32{1, 3}}},
+		Outputs: []menoh.OutputConfig{
+			{Name: "fc1", Dtype: menoh.TypeFloat, FromInternal: true},
+			{Name: "fc2", Dtype: menoh.TypeFloat},
+		},
+	}
+}
+
+func newTestFake() *Fake {
+	fake := New()
+	fake.Output("fc1", 1, 4)
+	fake.Output("fc2", 1, 2)
+	fake.Script(
+		map[string][]float32{"input": {0, 1, 2}},
+		map[string][]float32{"fc1": {1, 2, 3, 4}, "fc2": {5, 6}})
+	fake.Default(map[string][]float32{"fc2": {-1, -1}})
+	return fake
+}
+
+func floats(t *testing.T, runner *menoh.Runner, name string) []float32 {
+	out, err := runner.GetOutput(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	a, err := out.FloatArray()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return a
+}
+
+func TestFakeScript(t *testing.T) {
+	fake := newTestFake()
+	runner, err := fake.NewRunner(testConfig())
+	if err != nil {
+		t.Fatalf("runner should be created, %v", err)
+	}
+	defer runner.Stop()
+
+	input := &menoh.FloatTensor{Dims: []int32{1, 3}, Array: []float32{0, 1, 2}}
+	if err := runner.RunWithTensor("input", input); err != nil {
+		t.Fatal(err)
+	}
+	if a := floats(t, runner, "fc1"); !reflect.DeepEqual(a, []float32{1, 2, 3, 4}) {
+		t.Errorf("internal output should be scripted, but %v", a)
+	}
+	if a := floats(t, runner, "fc2"); !reflect.DeepEqual(a, []float32{5, 6}) {
+		t.Errorf("output should be scripted, but %v", a)
+	}
+
+	input.Array = []float32{3, 4, 5}
+	if err := runner.RunWithTensor("input", input); err != nil {
+		t.Fatal(err)
+	}
+	if a := floats(t, runner, "fc2"); !reflect.DeepEqual(a, []float32{-1, -1}) {
+		t.Errorf("default output should be written, but %v", a)
+	}
+}
+
+func TestFakeRecord(t *testing.T) {
+	fake := newTestFake()
+	runner, err := fake.NewRunner(testConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+	input := &menoh.FloatTensor{Dims: []int32{1, 3}, Array: []float32{0, 1, 2}}
+	if err := runner.RunWithTensor("input", input); err != nil {
+		t.Fatal(err)
+	}
+	runner.Stop()
+
+	calls := fake.Calls()
+	methods := []string{}
+	for _, c := range calls {
+		methods = append(methods, c.Method)
+	}
+	expected := []string{"LoadModel", "AddProfiles", "ProfileVariables", "VariableProfile",
+		"AttachBuffer", "AttachBuffer", "Build", "GetVariable", "Run", "Close"}
+	if !reflect.DeepEqual(methods, expected) {
+		t.Errorf("calls should be recorded as %v, but %v", expected, methods)
+	}
+	if run := calls[8]; !reflect.DeepEqual(run.Inputs["input"], []float32{0, 1, 2}) {
+		t.Errorf("inputs of Run should be recorded, but %v", run.Inputs)
+	}
+	if fake.RunCount() != 1 {
+		t.Errorf("run count should be 1, but %d", fake.RunCount())
+	}
+	fake.ResetCalls()
+	if len(fake.Calls()) != 0 {
+		t.Error("calls should be cleared")
+	}
+}
+
+func TestFakeFailure(t *testing.T) {
+	t.Run("build error", func(t *testing.T) {
+		fake := newTestFake()
+		fake.FailBuild(NewError(errcode.InvalidBackendName, "mkldnn"))
+		if _, err := fake.NewRunner(testConfig()); errcode.Of(err) != errcode.InvalidBackendName {
+			t.Errorf("build should fail with the code, but %v", err)
+		}
+	})
+	t.Run("undeclared output", func(t *testing.T) {
+		conf := testConfig()
+		conf.Outputs = append(conf.Outputs, menoh.OutputConfig{Name: "fc3", Dtype: menoh.TypeFloat})
+		if _, err := newTestFake().NewRunner(conf); errcode.Of(err) != errcode.VariableNotFound {
+			t.Errorf("profiling should fail with variable not found, but %v", err)
+		}
+	})
+	t.Run("run error", func(t *testing.T) {
+		fake := newTestFake()
+		runner, err := fake.NewRunner(testConfig())
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer runner.Stop()
+		fake.FailRun(NewError(errcode.BackendError, "injected"))
+		if err := runner.Run(nil); errcode.Of(err) != errcode.BackendError {
+			t.Errorf("run should fail with the code, but %v", err)
+		}
+		fake.FailRun(nil)
+		if err := runner.Run(nil); err != nil {
+			t.Errorf("run should succeed after clearing, %v", err)
+		}
+	})
+	t.Run("scripted error", func(t *testing.T) {
+		fake := newTestFake()
+		fake.ScriptError(map[string][]float32{"input": {9, 9, 9}},
+			NewError(errcode.DimensionMismatch, "input"))
+		runner, err := fake.NewRunner(testConfig())
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer runner.Stop()
+		input := &menoh.FloatTensor{Dims: []int32{1, 3}, Array: []float32{9, 9, 9}}
+		if err := runner.RunWithTensor("input", input); errcode.Of(err) != errcode.DimensionMismatch {
+			t.Errorf("run should fail with the scripted error, but %v", err)
+		}
+	})
+	t.Run("scripted size mismatch", func(t *testing.T) {
+		fake := newTestFake()
+		fake.Default(map[string][]float32{"fc2": {1, 2, 3}})
+		runner, err := fake.NewRunner(testConfig())
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer runner.Stop()
+		if err := runner.Run(nil); errcode.Of(err) != errcode.DimensionMismatch {
+			t.Errorf("run should fail with dimension mismatch, but %v", err)
+		}
+	})
+}
+
+func TestFakeLatency(t *testing.T) {
+	fake := newTestFake()
+	fake.SetLatency(10*time.Millisecond, 20*time.Millisecond)
+	start := time.Now()
+	runner, err := fake.NewRunner(testConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer runner.Stop()
+	if d := time.Since(start); d < 10*time.Millisecond {
+		t.Errorf("build should take latency, but %v", d)
+	}
+	start = time.Now()
+	if err := runner.Run(nil); err != nil {
+		t.Fatal(err)
+	}
+	if d := time.Since(start); d < 20*time.Millisecond {
+		t.Errorf("run should take latency, but %v", d)
+	}
+}
+
+func TestFakeRegister(t *testing.T) {
+	fake := newTestFake()
+	backend := fake.Register("menohtest_fake")
+	defer menoh.RegisterBackend(backend, nil)
+
+	conf := testConfig()
+	conf.Backend = backend
+	pool, err := menoh.NewPool(conf, 2)
+	if err != nil {
+		t.Fatalf("pool should be created with the fake, %v", err)
+	}
+	defer pool.Stop()
+	err = pool.Do(context.Background(), func(r *menoh.Runner) error {
+		return r.Run(nil)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fake.RunCount() != 1 {
+		t.Errorf("the fake should be run, but %d", fake.RunCount())
+	}
+}
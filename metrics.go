@@ -0,0 +1,289 @@
+package menoh
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/http"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pfnet-research/go-menoh/external/errcode"
+)
+
+// DefaultBuckets are upper bounds of latency histograms in seconds.
+var DefaultBuckets = []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+// Metrics records runs and builds of runners, set it to Config.Metrics to
+// instrument runners built with the configuration. Metrics are labeled by
+// model name and backend, and written in Prometheus text exposition format.
+// Metrics is safe for concurrent use, a Metrics can be shared by runners of
+// many models.
+type Metrics struct {
+	buckets []float64
+
+	mu            sync.Mutex
+	runs          map[metricKey]uint64
+	runErrors     map[metricKey]uint64
+	buildErrors   map[metricKey]uint64
+	runDuration   map[metricKey]*histogram
+	inputCopy     map[metricKey]*histogram
+	buildDuration map[metricKey]*histogram
+	runners       map[metricKey]int64
+}
+
+// metricKey is a set of label values, extra is a label specific to a metric
+// like build phase, and code is the Menoh error code of failures.
+type metricKey struct {
+	model   string
+	backend string
+	extra   string
+	code    string
+}
+
+type histogram struct {
+	counts []uint64 // count of each bucket, not cumulative
+	sum    float64
+	count  uint64
+}
+
+// NewMetrics returns Metrics with DefaultBuckets.
+func NewMetrics() *Metrics {
+	return NewMetricsWithBuckets(DefaultBuckets)
+}
+
+// NewMetricsWithBuckets returns Metrics with the histogram buckets, upper
+// bounds in seconds in increasing order.
+func NewMetricsWithBuckets(buckets []float64) *Metrics {
+	b := append([]float64{}, buckets...)
+	sort.Float64s(b)
+	return &Metrics{
+		buckets:       b,
+		runs:          map[metricKey]uint64{},
+		runErrors:     map[metricKey]uint64{},
+		buildErrors:   map[metricKey]uint64{},
+		runDuration:   map[metricKey]*histogram{},
+		inputCopy:     map[metricKey]*histogram{},
+		buildDuration: map[metricKey]*histogram{},
+		runners:       map[metricKey]int64{},
+	}
+}
+
+// modelName returns the name of the model for labels, Config.Name or base
+// name of the ONNX file without extension.
+func modelName(conf Config) string {
+	if conf.Name != "" {
+		return conf.Name
+	}
+	base := filepath.Base(conf.ONNXModelPath)
+	if base == "." || base == string(filepath.Separator) {
+		return ""
+	}
+	return strings.TrimSuffix(base, filepath.Ext(base))
+}
+
+func newMetricKey(conf Config, extra string) metricKey {
+	return metricKey{model: modelName(conf), backend: conf.Backend.String(), extra: extra}
+}
+
+func (m *Metrics) observe(hs map[metricKey]*histogram, key metricKey, d time.Duration) {
+	h, ok := hs[key]
+	if !ok {
+		h = &histogram{counts: make([]uint64, len(m.buckets)+1)}
+		hs[key] = h
+	}
+	v := d.Seconds()
+	i := sort.SearchFloat64s(m.buckets, v)
+	h.counts[i]++
+	h.sum += v
+	h.count++
+}
+
+func (m *Metrics) observeRun(conf Config, copyTime, runTime time.Duration, err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	key := newMetricKey(conf, "")
+	m.runs[key]++
+	m.observe(m.inputCopy, key, copyTime)
+	m.observe(m.runDuration, key, runTime)
+	if err != nil {
+		key.code = errorCode(err)
+		m.runErrors[key]++
+	}
+}
+
+func (m *Metrics) observeBuild(conf Config, phases []PhaseDuration, err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, p := range phases {
+		m.observe(m.buildDuration, newMetricKey(conf, p.Phase), p.Duration)
+	}
+	if err == nil {
+		m.runners[newMetricKey(conf, "")]++
+	} else if len(phases) > 0 {
+		// building stops at the failed phase
+		m.countBuildError(conf, phases[len(phases)-1].Phase, err)
+	}
+}
+
+// observeLoadError counts a failure to load the model before building.
+func (m *Metrics) observeLoadError(conf Config, err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.countBuildError(conf, "loadModel", err)
+}
+
+func (m *Metrics) countBuildError(conf Config, phase string, err error) {
+	key := newMetricKey(conf, phase)
+	key.code = errorCode(err)
+	m.buildErrors[key]++
+}
+
+func (m *Metrics) observeStop(conf Config) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.runners[newMetricKey(conf, "")]--
+}
+
+// errorCode returns a label of the Menoh error code of err, like
+// "variable_not_found", or "unknown" for errors not from Menoh.
+func errorCode(err error) string {
+	e, ok := errcode.As(err)
+	if !ok {
+		return "unknown"
+	}
+	return e.Code.Label()
+}
+
+// Handler returns http.Handler writing the metrics in Prometheus text
+// exposition format.
+func (m *Metrics) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		m.WriteTo(w)
+	})
+}
+
+// WriteTo writes the metrics in Prometheus text exposition format.
+func (m *Metrics) WriteTo(w io.Writer) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	cw := &countWriter{w: bufio.NewWriter(w)}
+	m.writeCounter(cw, "menoh_runs_total", "Number of runs.", "", m.runs)
+	m.writeCounter(cw, "menoh_run_errors_total", "Number of failed runs by Menoh error code.", "", m.runErrors)
+	m.writeCounter(cw, "menoh_build_errors_total", "Number of failed builds by phase and Menoh error code.",
+		"phase", m.buildErrors)
+	m.writeHistogram(cw, "menoh_run_duration_seconds", "Latency of runs including copying inputs.", "", m.runDuration)
+	m.writeHistogram(cw, "menoh_input_copy_duration_seconds", "Time to copy inputs to attached buffers.", "", m.inputCopy)
+	m.writeHistogram(cw, "menoh_build_phase_duration_seconds", "Time of each phase to build runners.", "phase", m.buildDuration)
+	fmt.Fprintf(cw, "# HELP menoh_runners Number of live runners.\n# TYPE menoh_runners gauge\n")
+	for _, k := range sortedKeys(m.runners) {
+		fmt.Fprintf(cw, "menoh_runners%s %d\n", k.labels("", ""), m.runners[k])
+	}
+	if cw.err != nil {
+		return cw.n, cw.err
+	}
+	return cw.n, cw.w.Flush()
+}
+
+func (m *Metrics) writeCounter(w io.Writer, name, help, extra string, values map[metricKey]uint64) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s counter\n", name, help, name)
+	keys := make([]metricKey, 0, len(values))
+	for k := range values {
+		keys = append(keys, k)
+	}
+	sortMetricKeys(keys)
+	for _, k := range keys {
+		fmt.Fprintf(w, "%s%s %d\n", name, k.labels(extra, ""), values[k])
+	}
+}
+
+func (m *Metrics) writeHistogram(w io.Writer, name, help, extra string, hs map[metricKey]*histogram) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s histogram\n", name, help, name)
+	keys := make([]metricKey, 0, len(hs))
+	for k := range hs {
+		keys = append(keys, k)
+	}
+	sortMetricKeys(keys)
+	for _, k := range keys {
+		h := hs[k]
+		var cumulative uint64
+		for i, b := range m.buckets {
+			cumulative += h.counts[i]
+			le := strconv.FormatFloat(b, 'g', -1, 64)
+			fmt.Fprintf(w, "%s_bucket%s %d\n", name, k.labels(extra, le), cumulative)
+		}
+		fmt.Fprintf(w, "%s_bucket%s %d\n", name, k.labels(extra, "+Inf"), h.count)
+		fmt.Fprintf(w, "%s_sum%s %s\n", name, k.labels(extra, ""),
+			strconv.FormatFloat(h.sum, 'g', -1, 64))
+		fmt.Fprintf(w, "%s_count%s %d\n", name, k.labels(extra, ""), h.count)
+	}
+}
+
+// labels formats label pairs, extra is the name of the extra label and le
+// is an upper bound of a histogram bucket.
+func (k metricKey) labels(extra, le string) string {
+	s := fmt.Sprintf(`{model="%s",backend="%s"`, escapeLabel(k.model), escapeLabel(k.backend))
+	if extra != "" {
+		s += fmt.Sprintf(`,%s="%s"`, extra, escapeLabel(k.extra))
+	}
+	if k.code != "" {
+		s += fmt.Sprintf(`,code="%s"`, escapeLabel(k.code))
+	}
+	if le != "" {
+		s += fmt.Sprintf(`,le="%s"`, le)
+	}
+	return s + "}"
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func escapeLabel(v string) string {
+	return labelEscaper.Replace(v)
+}
+
+func sortedKeys(values map[metricKey]int64) []metricKey {
+	keys := make([]metricKey, 0, len(values))
+	for k := range values {
+		keys = append(keys, k)
+	}
+	sortMetricKeys(keys)
+	return keys
+}
+
+func sortMetricKeys(keys []metricKey) {
+	sort.Slice(keys, func(i, j int) bool {
+		a, b := keys[i], keys[j]
+		if a.model != b.model {
+			return a.model < b.model
+		}
+		if a.backend != b.backend {
+			return a.backend < b.backend
+		}
+		if a.extra != b.extra {
+			return a.extra < b.extra
+		}
+		return a.code < b.code
+	})
+}
+
+// countWriter counts written bytes and keeps the first error.
+type countWriter struct {
+	w   *bufio.Writer
+	n   int64
+	err error
+}
+
+func (w *countWriter) Write(p []byte) (int, error) {
+	if w.err != nil {
+		return 0, w.err
+	}
+	n, err := w.w.Write(p)
+	w.n += int64(n)
+	w.err = err
+	return n, err
+}
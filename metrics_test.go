@@ -0,0 +1,116 @@
+package menoh
+
+import (
+	"bytes"
+	"errors"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/pfnet-research/go-menoh/external/errcode"
+)
+
+func TestMetrics(t *testing.T) {
+	metrics := NewMetricsWithBuckets([]float64{10, 1})
+	conf := fakeConfig()
+	conf.ONNXModelPath = "/path/to/MLP.onnx"
+	conf.Metrics = metrics
+
+	runner, err := NewRunnerWithBackend(&fakeBackend{}, conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	input := &FloatTensor{Dims: []int32{1, 3}, Array: []float32{1, 2, 3}}
+	if err := runner.RunWithTensor("input", input); err != nil {
+		t.Fatal(err)
+	}
+	if err := runner.RunWithTensor("dummy", input); err == nil {
+		t.Fatal("an error should be occurred with not attached input")
+	}
+
+	buildErr := &fakeBackend{buildErr: &errcode.Error{Code: errcode.InvalidBackendName}}
+	if _, err := NewRunnerWithBackend(buildErr, conf); err == nil {
+		t.Fatal("an error should be occurred on building")
+	}
+	loadErr := &fakeBackend{loadErr: &errcode.Error{Code: errcode.ONNXParseError}}
+	if _, err := NewRunnerWithBackend(loadErr, conf); err == nil {
+		t.Fatal("an error should be occurred on loading")
+	}
+
+	buf := &bytes.Buffer{}
+	if _, err := metrics.WriteTo(buf); err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{
+		"# TYPE menoh_runs_total counter",
+		`menoh_runs_total{model="MLP",backend="mkldnn"} 2`,
+		`menoh_run_errors_total{model="MLP",backend="mkldnn",code="unknown"} 1`,
+		"# TYPE menoh_build_errors_total counter",
+		`menoh_build_errors_total{model="MLP",backend="mkldnn",phase="buildModel",code="invalid_backend_name"} 1`,
+		`menoh_build_errors_total{model="MLP",backend="mkldnn",phase="loadModel",code="onnx_parse_error"} 1`,
+		"# TYPE menoh_run_duration_seconds histogram",
+		`menoh_run_duration_seconds_bucket{model="MLP",backend="mkldnn",le="1"} 2`,
+		`menoh_run_duration_seconds_bucket{model="MLP",backend="mkldnn",le="10"} 2`,
+		`menoh_run_duration_seconds_bucket{model="MLP",backend="mkldnn",le="+Inf"} 2`,
+		`menoh_run_duration_seconds_count{model="MLP",backend="mkldnn"} 2`,
+		`menoh_input_copy_duration_seconds_count{model="MLP",backend="mkldnn"} 2`,
+		`menoh_build_phase_duration_seconds_count{model="MLP",backend="mkldnn",phase="buildModel"} 2`,
+		`menoh_build_phase_duration_seconds_count{model="MLP",backend="mkldnn",phase="makeVariableProfileTableBuilder"} 2`,
+		`menoh_runners{model="MLP",backend="mkldnn"} 1`,
+	}
+	for _, e := range expected {
+		if !strings.Contains(buf.String(), e+"\n") {
+			t.Errorf("metrics should include %s, but\n%s", e, buf)
+		}
+	}
+
+	runner.Stop()
+	rec := httptest.NewRecorder()
+	metrics.Handler().ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	if !strings.HasPrefix(rec.Header().Get("Content-Type"), "text/plain; version=0.0.4") {
+		t.Errorf("content type should be text exposition format, but %v", rec.Header())
+	}
+	if !strings.Contains(rec.Body.String(), `menoh_runners{model="MLP",backend="mkldnn"} 0`) {
+		t.Errorf("stopped runner should not be counted, but\n%s", rec.Body)
+	}
+}
+
+// wrappedError wraps an error with Unwrap method.
+type wrappedError struct {
+	err error
+}
+
+func (e *wrappedError) Error() string { return "cannot run, " + e.err.Error() }
+func (e *wrappedError) Unwrap() error { return e.err }
+
+func TestErrorCode(t *testing.T) {
+	cases := map[error]string{
+		&errcode.Error{Code: errcode.VariableNotFound}:                   "variable_not_found",
+		&wrappedError{&errcode.Error{Code: errcode.UnsupportedOperator}}: "unsupported_operator",
+		errors.New("menoh variable not found error: fc3"):                "unknown",
+		errors.New("input is not attached"):                              "unknown",
+	}
+	for err, expected := range cases {
+		if actual := errorCode(err); actual != expected {
+			t.Errorf("code of '%v' should be %s, but %s", err, expected, actual)
+		}
+	}
+}
+
+func TestModelName(t *testing.T) {
+	if n := modelName(Config{Name: "mlp", ONNXModelPath: "a/b.onnx"}); n != "mlp" {
+		t.Errorf("name should be used, but %v", n)
+	}
+	if n := modelName(Config{ONNXModelPath: "a/b.onnx"}); n != "b" {
+		t.Errorf("base name of the model path should be used, but %v", n)
+	}
+	if n := modelName(Config{}); n != "" {
+		t.Errorf("name should be empty without path, but %v", n)
+	}
+}
+
+func TestEscapeLabel(t *testing.T) {
+	if e := escapeLabel("a\"b\\c\nd"); e != `a\"b\\c\nd` {
+		t.Errorf("label should be escaped, but %v", e)
+	}
+}
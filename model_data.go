@@ -2,8 +2,12 @@ package menoh
 
 import (
 	"errors"
+	"fmt"
+	"io/ioutil"
+	"sync"
 
 	"github.com/pfnet-research/go-menoh/external"
+	"github.com/pfnet-research/go-menoh/external/reference"
 )
 
 // ModelData is a wrap of menoh.ModelData.
@@ -11,6 +15,63 @@ type ModelData struct {
 	external.ModelData
 }
 
+// modelSource is a source of ONNX model to read and replace parameters of
+// ModelData, Menoh does not return them. path is read on demand not to keep
+// large models on memory.
+type modelSource struct {
+	path  string
+	onnx  []byte
+	added []*parameter // parameters added by AddTensorParameter
+}
+
+// sources are kept out of ModelData to make it by a literal of
+// external.ModelData. Entries are added by constructors and
+// AddTensorParameter, and removed by Delete or handing to a runner.
+var (
+	sourcesMu sync.Mutex
+	sources   = map[*ModelData]*modelSource{}
+)
+
+func (m *ModelData) setSource(s *modelSource) {
+	sourcesMu.Lock()
+	defer sourcesMu.Unlock()
+	sources[m] = s
+}
+
+// getSource returns a copy of the source of the model data, empty if not
+// kept.
+func (m *ModelData) getSource() modelSource {
+	sourcesMu.Lock()
+	defer sourcesMu.Unlock()
+	if s, ok := sources[m]; ok {
+		return *s
+	}
+	return modelSource{}
+}
+
+func (m *ModelData) releaseSource() {
+	sourcesMu.Lock()
+	defer sourcesMu.Unlock()
+	delete(sources, m)
+}
+
+// Delete the model data and its source.
+func (m *ModelData) Delete() {
+	m.releaseSource()
+	m.ModelData.Delete()
+}
+
+type parameter struct {
+	name   string
+	tensor *FloatTensor
+}
+
+// ParameterInfo is a name and a shape of a parameter.
+type ParameterInfo struct {
+	Name string
+	Dims []int32
+}
+
 // NewRawModelData return empty ModelData to be setup outer.
 func NewRawModelData() (*ModelData, error) {
 	m, err := external.MakeModelData()
@@ -26,10 +87,14 @@ func NewModelDataFromPath(path string) (*ModelData, error) {
 	if err != nil {
 		return nil, err
 	}
-	return &ModelData{*m}, nil
+	md := &ModelData{*m}
+	md.setSource(&modelSource{path: path})
+	return md, nil
 }
 
-// NewModelDataFromBytes return ModelData from binary data.
+// NewModelDataFromBytes return ModelData from binary data. The data is held
+// to read parameters until the model data is handed to a runner, must not be
+// modified after calling.
 func NewModelDataFromBytes(data []byte) (*ModelData, error) {
 	if len(data) == 0 {
 		return nil, errors.New("ONNX file data is empty")
@@ -38,7 +103,9 @@ func NewModelDataFromBytes(data []byte) (*ModelData, error) {
 	if err != nil {
 		return nil, err
 	}
-	return &ModelData{*m}, nil
+	md := &ModelData{*m}
+	md.setSource(&modelSource{onnx: data})
+	return md, nil
 }
 
 // AddTensorParameter adds tensor to named parameter.
@@ -52,5 +119,127 @@ func (m *ModelData) AddTensorParameter(name string, param Tensor) error {
 		Dims:         param.Shape(),
 		BufferHandle: param.ptr(),
 	}
-	return m.AddParameter(name, variable)
+	if err := m.AddParameter(name, variable); err != nil {
+		return err
+	}
+	// Menoh copies the buffer, so does the model data
+	t, err := cloneTensor(param)
+	if err != nil {
+		return err
+	}
+	sourcesMu.Lock()
+	defer sourcesMu.Unlock()
+	s, ok := sources[m]
+	if !ok {
+		s = &modelSource{}
+		sources[m] = s
+	}
+	s.added = append(s.added, &parameter{name: name, tensor: t.(*FloatTensor)})
+	return nil
+}
+
+func (s modelSource) read() ([]byte, error) {
+	if s.onnx != nil {
+		return s.onnx, nil
+	}
+	if s.path == "" {
+		return nil, nil
+	}
+	return ioutil.ReadFile(s.path)
+}
+
+func (s modelSource) initializers() ([]*reference.Initializer, error) {
+	data, err := s.read()
+	if err != nil {
+		return nil, fmt.Errorf("cannot read ONNX model, %v", err)
+	}
+	if data == nil {
+		return nil, nil
+	}
+	return reference.ReadInitializers(data)
+}
+
+// Parameters returns names and shapes of initializers of ONNX model and
+// parameters added by AddTensorParameter. ONNX model is parsed on each call.
+func (m *ModelData) Parameters() ([]ParameterInfo, error) {
+	s := m.getSource()
+	initializers, err := s.initializers()
+	if err != nil {
+		return nil, err
+	}
+	params := []ParameterInfo{}
+	for _, i := range initializers {
+		params = append(params, ParameterInfo{Name: i.Name, Dims: i.Dims})
+	}
+	for _, p := range s.added {
+		params = append(params, ParameterInfo{Name: p.name, Dims: p.tensor.Shape()})
+	}
+	return params, nil
+}
+
+// GetParameter returns a copy of the named parameter. Values of non-float
+// initializers, like shapes of Reshape, are converted to float32.
+func (m *ModelData) GetParameter(name string) (Tensor, error) {
+	s := m.getSource()
+	for _, p := range s.added {
+		if p.name == name {
+			return cloneTensor(p.tensor)
+		}
+	}
+	initializers, err := s.initializers()
+	if err != nil {
+		return nil, err
+	}
+	for _, i := range initializers {
+		if i.Name == name {
+			return &FloatTensor{Dims: i.Dims, Array: i.Data}, nil
+		}
+	}
+	return nil, fmt.Errorf("parameter %s is not found", name)
+}
+
+// ReplaceParameter replaces the float32 initializer of ONNX model by the
+// tensor with same shape, like fine-tuned weights, without re-exporting
+// ONNX file. The model data is made again from the rewritten model, so
+// nodes added and optimization applied to the model data are discarded,
+// replace parameters before them. Parameters added by AddTensorParameter
+// are added again. Replace parameters before building a runner by
+// NewRunnerWithModelData, the runner takes the model data and its source,
+// replacing and reading parameters of ONNX model fail after that.
+func (m *ModelData) ReplaceParameter(name string, param Tensor) error {
+	array, err := param.FloatArray()
+	if err != nil {
+		return err
+	}
+	s := m.getSource()
+	data, err := s.read()
+	if err != nil {
+		return fmt.Errorf("cannot read ONNX model, %v", err)
+	}
+	if data == nil {
+		return errors.New("parameters of raw model data or model data handed to a runner cannot be replaced")
+	}
+	replaced, err := reference.ReplaceInitializer(data, name, param.Shape(), array)
+	if err != nil {
+		return fmt.Errorf("cannot replace parameter, %v", err)
+	}
+	md, err := external.MakeModelDataFromONNXBytes(replaced)
+	if err != nil {
+		return fmt.Errorf("cannot make model data, %v", err)
+	}
+	for _, p := range s.added {
+		variable := external.Variable{
+			Dtype:        external.TypeFloat,
+			Dims:         p.tensor.Shape(),
+			BufferHandle: p.tensor.ptr(),
+		}
+		if err := md.AddParameter(p.name, variable); err != nil {
+			md.Delete()
+			return err
+		}
+	}
+	m.ModelData.Delete()
+	m.ModelData = *md
+	m.setSource(&modelSource{onnx: replaced, added: s.added})
+	return nil
 }
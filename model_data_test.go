@@ -3,6 +3,7 @@ package menoh
 import (
 	"fmt"
 	"io/ioutil"
+	"reflect"
 	"strings"
 	"testing"
 )
@@ -159,3 +160,142 @@ func TestAddNodeAndSetup(t *testing.T) {
 		t.Fatalf("floats attribute should be added, %v", err)
 	}
 }
+
+func TestParameters(t *testing.T) {
+	path, input, output, err := getTestONNXDataset()
+	if err != nil {
+		t.Fatal(err)
+	}
+	md, err := NewModelDataFromPath(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	params, err := md.Parameters()
+	if err != nil {
+		t.Fatalf("parameters should be returned, %v", err)
+	}
+	if len(params) == 0 {
+		t.Fatal("parameters of the model should be listed")
+	}
+	target := params[0]
+	param, err := md.GetParameter(target.Name)
+	if err != nil {
+		t.Fatalf("parameter %s should be returned, %v", target.Name, err)
+	}
+	if !reflect.DeepEqual(param.Shape(), target.Dims) {
+		t.Errorf("shape of parameter should be %v, but %v", target.Dims, param.Shape())
+	}
+
+	replaced := &FloatTensor{Dims: target.Dims, Array: make([]float32, param.Size())}
+	for i := range replaced.Array {
+		replaced.Array[i] = float32(i)
+	}
+	if err := md.ReplaceParameter(target.Name, replaced); err != nil {
+		t.Fatalf("parameter should be replaced, %v", err)
+	}
+	actual, err := md.GetParameter(target.Name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(actual, Tensor(replaced)) {
+		t.Errorf("parameter should be %v, but %v", replaced, actual)
+	}
+
+	t.Run("shape mismatch", func(t *testing.T) {
+		err := md.ReplaceParameter(target.Name, &FloatTensor{Dims: []int32{1}, Array: []float32{0}})
+		if err == nil {
+			t.Error("an error should be occurred with different shape")
+		}
+	})
+	t.Run("not found", func(t *testing.T) {
+		if _, err := md.GetParameter("dummy"); err == nil {
+			t.Error("an error should be occurred with not existed name")
+		}
+		if err := md.ReplaceParameter("dummy", replaced); err == nil {
+			t.Error("an error should be occurred with not existed name")
+		}
+	})
+
+	// the runner deletes the model data
+	runner, err := NewRunnerWithModelData(md, Config{
+		Backend: TypeMKLDNN,
+		Inputs:  []InputConfig{input},
+		Outputs: []OutputConfig{output},
+	})
+	if err != nil {
+		t.Fatalf("runner should be built with replaced parameter, %v", err)
+	}
+	defer runner.Stop()
+	if err := md.ReplaceParameter(target.Name, replaced); err == nil {
+		t.Error("an error should be occurred with model data handed to the runner")
+	}
+	sourcesMu.Lock()
+	_, kept := sources[md]
+	sourcesMu.Unlock()
+	if kept {
+		t.Error("source of model data handed to the runner should be released")
+	}
+}
+
+func TestModelDataLiteral(t *testing.T) {
+	path, _, _, err := getTestONNXDataset()
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	md, err := NewModelDataFromBytes(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// a model data of the external package is wrapped without a source
+	wrapped := &ModelData{md.ModelData}
+	if params, err := wrapped.Parameters(); err != nil || len(params) != 0 {
+		t.Errorf("wrapped model data should have no parameters, %v, %v", params, err)
+	}
+	if err := wrapped.ReplaceParameter("dummy", &FloatTensor{Dims: []int32{1}, Array: []float32{0}}); err == nil {
+		t.Error("an error should be occurred with wrapped model data")
+	}
+
+	md.Delete()
+	sourcesMu.Lock()
+	_, kept := sources[md]
+	sourcesMu.Unlock()
+	if kept {
+		t.Error("source of deleted model data should be released")
+	}
+}
+
+func TestRawModelDataParameters(t *testing.T) {
+	md, err := NewRawModelData()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer md.Delete()
+	param := &FloatTensor{Dims: []int32{2}, Array: []float32{1, 2}}
+	if err := md.AddTensorParameter("param", param); err != nil {
+		t.Fatal(err)
+	}
+	param.Array[0] = 0
+
+	params, err := md.Parameters()
+	if err != nil {
+		t.Fatalf("parameters should be returned, %v", err)
+	}
+	expected := []ParameterInfo{{Name: "param", Dims: []int32{2}}}
+	if !reflect.DeepEqual(params, expected) {
+		t.Errorf("parameters should be %v, but %v", expected, params)
+	}
+	actual, err := md.GetParameter("param")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a, _ := actual.FloatArray(); !reflect.DeepEqual(a, []float32{1, 2}) {
+		t.Errorf("added parameter should be copied, but %v", a)
+	}
+	if err := md.ReplaceParameter("param", param); err == nil {
+		t.Error("an error should be occurred with raw model data")
+	}
+}
@@ -0,0 +1,81 @@
+package optimize
+
+import (
+	"fmt"
+	"unsafe"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/pfnet-research/go-menoh"
+	"github.com/pfnet-research/go-menoh/external/reference"
+	"github.com/pfnet-research/go-menoh/tools/onnx"
+)
+
+// evalNode runs the node whose inputs are the initializers by the reference
+// interpreter, and returns the output as an initializer.
+func evalNode(n *onnx.NodeProto, inits map[string]*onnx.TensorProto) (*onnx.TensorProto, error) {
+	g := &onnx.GraphProto{Node: []*onnx.NodeProto{n}}
+	for _, in := range n.Input {
+		if in == "" {
+			continue
+		}
+		t, ok := inits[in]
+		if !ok {
+			return nil, fmt.Errorf("%s is not an initializer", in)
+		}
+		g.Initializer = append(g.Initializer, t)
+	}
+	b, err := proto.Marshal(&onnx.ModelProto{Graph: g})
+	if err != nil {
+		return nil, err
+	}
+	modelData, err := reference.MakeModelDataFromONNXBytes(b)
+	if err != nil {
+		return nil, err
+	}
+	defer modelData.Delete()
+
+	out := n.Output[0]
+	vptBuilder, err := reference.MakeVariableProfileTableBuilder()
+	if err != nil {
+		return nil, err
+	}
+	defer vptBuilder.Delete()
+	if err := vptBuilder.AddOutputProfile(out, reference.TypeFloat); err != nil {
+		return nil, err
+	}
+	vpt, err := vptBuilder.BuildVariableProfileTable(*modelData)
+	if err != nil {
+		return nil, err
+	}
+	defer vpt.Delete()
+	vp, err := vpt.GetVariableProfile(out)
+	if err != nil {
+		return nil, err
+	}
+	size := 1
+	for _, d := range vp.Dims {
+		size *= int(d)
+	}
+	if size == 0 {
+		return nil, fmt.Errorf("%s is empty", out)
+	}
+	result := &menoh.FloatTensor{Dims: vp.Dims, Array: make([]float32, size)}
+
+	modelBuilder, err := reference.MakeModelBuilder(*vpt)
+	if err != nil {
+		return nil, err
+	}
+	defer modelBuilder.Delete()
+	if err := modelBuilder.AttachExternalBuffer(out, unsafe.Pointer(&result.Array[0])); err != nil {
+		return nil, err
+	}
+	model, err := modelBuilder.BuildModel(*modelData, reference.Backends[0], "")
+	if err != nil {
+		return nil, err
+	}
+	defer model.Delete()
+	if err := model.Run(); err != nil {
+		return nil, err
+	}
+	return onnx.ConvertToONNXTensor(out, result)
+}
@@ -0,0 +1,267 @@
+/*
+Package optimize provides passes to optimize ONNX graph in Go before building
+runners, and reports what the passes changed.
+
+	model, err := onnx.LoadONNXModelFromFile("model.onnx")
+	report, err := optimize.Optimize(model)
+	fmt.Print(report)
+
+DefaultPasses eliminate Identity and Dropout nodes, fold constant nodes into
+initializers, fuse BatchNormalization into preceding Conv and eliminate
+initializers not used. Graph outputs keep their names. Pruning nodes
+unreachable from requested outputs is done by Menoh library with
+menoh.Config.Optimize.
+*/
+package optimize
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/pfnet-research/go-menoh"
+	"github.com/pfnet-research/go-menoh/tools/onnx"
+)
+
+// Pass is a transformation of ONNX graph. Apply modifies the graph and
+// returns descriptions of changes.
+type Pass struct {
+	Name  string
+	Apply func(g *onnx.GraphProto) ([]string, error)
+}
+
+// Passes to optimize ONNX graph.
+var (
+	// EliminateIdentity removes Identity nodes and Dropout nodes whose masks
+	// are not used, Dropout is identity on inference.
+	EliminateIdentity = Pass{Name: "eliminate_identity", Apply: eliminateIdentity}
+
+	// FoldConstants converts Constant nodes to initializers, and evaluates
+	// nodes whose inputs are all float initializers by the reference
+	// interpreter. Nodes not supported by the interpreter are kept.
+	FoldConstants = Pass{Name: "fold_constants", Apply: foldConstants}
+
+	// FuseBatchNorm folds BatchNormalization into weight and bias of the
+	// preceding Conv, when the output of the Conv is used only by it.
+	FuseBatchNorm = Pass{Name: "fuse_batchnorm", Apply: fuseBatchNorm}
+
+	// EliminateDeadInitializers removes initializers used by no node, and
+	// their entries in graph inputs.
+	EliminateDeadInitializers = Pass{Name: "eliminate_dead_initializers", Apply: eliminateDeadInitializers}
+)
+
+// DefaultPasses are passes applied by Optimize when passes are omitted.
+var DefaultPasses = []Pass{EliminateIdentity, FoldConstants, FuseBatchNorm, EliminateDeadInitializers}
+
+// Change is a change made by a pass.
+type Change struct {
+	Pass        string
+	Description string
+}
+
+// Report is a result of optimization.
+type Report struct {
+	NodesBefore        int
+	NodesAfter         int
+	InitializersBefore int
+	InitializersAfter  int
+	Changes            []Change
+}
+
+// String returns the numbers of nodes and initializers and the changes, a
+// change per line.
+func (r *Report) String() string {
+	b := &bytes.Buffer{}
+	fmt.Fprintf(b, "nodes: %d -> %d, initializers: %d -> %d\n",
+		r.NodesBefore, r.NodesAfter, r.InitializersBefore, r.InitializersAfter)
+	for _, c := range r.Changes {
+		fmt.Fprintf(b, "%s: %s\n", c.Pass, c.Description)
+	}
+	return b.String()
+}
+
+// Optimize applies the passes to the graph of the model in order, or
+// DefaultPasses if passes are omitted.
+func Optimize(model *onnx.ModelProto, passes ...Pass) (*Report, error) {
+	if len(passes) == 0 {
+		passes = DefaultPasses
+	}
+	g := model.GetGraph()
+	if g == nil {
+		return nil, fmt.Errorf("model has no graph")
+	}
+	report := &Report{
+		NodesBefore:        len(g.Node),
+		InitializersBefore: len(g.Initializer),
+	}
+	for _, p := range passes {
+		changes, err := p.Apply(g)
+		if err != nil {
+			return nil, fmt.Errorf("cannot apply %s, %v", p.Name, err)
+		}
+		for _, c := range changes {
+			report.Changes = append(report.Changes, Change{Pass: p.Name, Description: c})
+		}
+	}
+	report.NodesAfter = len(g.Node)
+	report.InitializersAfter = len(g.Initializer)
+	return report, nil
+}
+
+// OptimizeFile optimizes ONNX model of the path by the passes and saves it
+// to the output path.
+func OptimizeFile(path, outPath string, passes ...Pass) (*Report, error) {
+	model, err := onnx.LoadONNXModelFromFile(path)
+	if err != nil {
+		return nil, err
+	}
+	report, err := Optimize(model, passes...)
+	if err != nil {
+		return nil, err
+	}
+	b, err := proto.Marshal(model)
+	if err != nil {
+		return nil, fmt.Errorf("cannot convert ONNX model to binary, %v", err)
+	}
+	if err := ioutil.WriteFile(outPath, b, 0644); err != nil {
+		return nil, fmt.Errorf("cannot save '%s', %v", outPath, err)
+	}
+	return report, nil
+}
+
+// NewRunner returns a runner of the model of conf.ONNXModelPath optimized by
+// the passes, or DefaultPasses if passes are omitted. The optimized model is
+// saved to a temporary file while building, so that any backend can load it.
+func NewRunner(conf menoh.Config, passes ...Pass) (*menoh.Runner, *Report, error) {
+	dir, err := ioutil.TempDir("", "menoh-optimize-")
+	if err != nil {
+		return nil, nil, fmt.Errorf("cannot make temporary directory, %v", err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, filepath.Base(conf.ONNXModelPath))
+	report, err := OptimizeFile(conf.ONNXModelPath, path, passes...)
+	if err != nil {
+		return nil, nil, err
+	}
+	if conf.Name == "" {
+		base := filepath.Base(conf.ONNXModelPath)
+		conf.Name = strings.TrimSuffix(base, filepath.Ext(base))
+	}
+	conf.ONNXModelPath = path
+	runner, err := menoh.NewRunner(conf)
+	if err != nil {
+		return nil, nil, err
+	}
+	return runner, report, nil
+}
+
+// graphOutputs returns the set of names of graph outputs.
+func graphOutputs(g *onnx.GraphProto) map[string]bool {
+	outputs := map[string]bool{}
+	for _, v := range g.GetOutput() {
+		outputs[v.GetName()] = true
+	}
+	return outputs
+}
+
+func initializers(g *onnx.GraphProto) map[string]*onnx.TensorProto {
+	inits := map[string]*onnx.TensorProto{}
+	for _, t := range g.GetInitializer() {
+		inits[t.GetName()] = t
+	}
+	return inits
+}
+
+// uses returns the number of uses of each variable by nodes.
+func uses(g *onnx.GraphProto) map[string]int {
+	n := map[string]int{}
+	for _, node := range g.GetNode() {
+		for _, in := range node.GetInput() {
+			if in != "" {
+				n[in]++
+			}
+		}
+	}
+	return n
+}
+
+func producers(g *onnx.GraphProto) map[string]*onnx.NodeProto {
+	p := map[string]*onnx.NodeProto{}
+	for _, n := range g.GetNode() {
+		for _, out := range n.GetOutput() {
+			if out != "" {
+				p[out] = n
+			}
+		}
+	}
+	return p
+}
+
+// renameInputs replaces inputs of nodes named from by to.
+func renameInputs(g *onnx.GraphProto, from, to string) {
+	for _, n := range g.GetNode() {
+		for i, in := range n.Input {
+			if in == from {
+				n.Input[i] = to
+			}
+		}
+	}
+}
+
+func removeNodes(g *onnx.GraphProto, removed map[*onnx.NodeProto]bool) {
+	if len(removed) == 0 {
+		return
+	}
+	nodes := make([]*onnx.NodeProto, 0, len(g.Node)-len(removed))
+	for _, n := range g.Node {
+		if !removed[n] {
+			nodes = append(nodes, n)
+		}
+	}
+	g.Node = nodes
+}
+
+// nodeLabel returns the name of the node, or its first output if unnamed.
+func nodeLabel(n *onnx.NodeProto) string {
+	if n.GetName() != "" {
+		return n.GetName()
+	}
+	if len(n.GetOutput()) > 0 {
+		return n.GetOutput()[0]
+	}
+	return ""
+}
+
+// uniqueName returns the name, or the name with a suffix not used by any
+// variable of the graph.
+func uniqueName(g *onnx.GraphProto, name string) string {
+	used := map[string]bool{}
+	for _, t := range g.GetInitializer() {
+		used[t.GetName()] = true
+	}
+	for _, v := range g.GetInput() {
+		used[v.GetName()] = true
+	}
+	for _, n := range g.GetNode() {
+		for _, out := range n.GetOutput() {
+			used[out] = true
+		}
+	}
+	candidate := name
+	for i := 1; used[candidate]; i++ {
+		candidate = fmt.Sprintf("%s_%d", name, i)
+	}
+	return candidate
+}
+
+func floatsOf(t *onnx.TensorProto) ([]float32, error) {
+	mt, err := onnx.ConvertToMenohTensor(t)
+	if err != nil {
+		return nil, err
+	}
+	return mt.FloatArray()
+}
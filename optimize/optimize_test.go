@@ -0,0 +1,104 @@
+package optimize
+
+import (
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/pfnet-research/go-menoh"
+	"github.com/pfnet-research/go-menoh/tools/onnx"
+)
+
+func TestOptimize(t *testing.T) {
+	g := makeConvBNGraph()
+	g.Node = append(g.Node, makeNode("Identity", []string{"y"}, []string{"z"}))
+	g.Output = makeValueInfos("z")
+	model := &onnx.ModelProto{Graph: g}
+
+	report, err := Optimize(model)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.NodesBefore != 3 || report.NodesAfter != 1 {
+		t.Errorf("nodes should be 3 -> 1, but %d -> %d", report.NodesBefore, report.NodesAfter)
+	}
+	if report.InitializersBefore != 6 || report.InitializersAfter != 2 {
+		t.Errorf("initializers should be 6 -> 2, but %d -> %d",
+			report.InitializersBefore, report.InitializersAfter)
+	}
+	s := report.String()
+	for _, expected := range []string{
+		"nodes: 3 -> 1, initializers: 6 -> 2",
+		"eliminate_identity: removed Identity 'z'",
+		"fuse_batchnorm: fused BatchNormalization 'z' into Conv 'h'",
+		"eliminate_dead_initializers: removed initializer 'W'",
+	} {
+		if !strings.Contains(s, expected) {
+			t.Errorf("report should contain '%s', but\n%s", expected, s)
+		}
+	}
+
+	if _, err := Optimize(&onnx.ModelProto{}); err == nil {
+		t.Error("model without graph should be error")
+	}
+}
+
+func TestNewRunner(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "go-menoh-test-")
+	if err != nil {
+		t.Fatal("cannot make temporary directory")
+	}
+	defer os.RemoveAll(tempDir)
+	path := filepath.Join(tempDir, "convbn.onnx")
+	b, err := proto.Marshal(&onnx.ModelProto{Graph: makeConvBNGraph()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(path, b, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	conf := menoh.Config{
+		ONNXModelPath: path,
+		Backend:       menoh.TypeReference,
+		Inputs:        []menoh.InputConfig{{Name: "x", Dtype: menoh.TypeFloat, Dims: []int32{1, 2, 2, 2}}},
+		Outputs:       []menoh.OutputConfig{{Name: "y", Dtype: menoh.TypeFloat}},
+	}
+	input := &menoh.FloatTensor{Dims: []int32{1, 2, 2, 2}, Array: []float32{0, 1, 2, 3, 4, 5, 6, 7}}
+	original, err := menoh.NewRunner(conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer original.Stop()
+	optimized, report, err := NewRunner(conf)
+	if err != nil {
+		t.Fatalf("runner of the optimized model should be built, %v", err)
+	}
+	defer optimized.Stop()
+	if report.NodesAfter != 1 {
+		t.Errorf("BatchNormalization should be fused, %v", report)
+	}
+	if name := optimized.Config().Name; name != "convbn" {
+		t.Errorf("name should be of the original model, but %s", name)
+	}
+
+	for _, r := range []*menoh.Runner{original, optimized} {
+		if err := r.RunWithTensor("x", input); err != nil {
+			t.Fatal(err)
+		}
+	}
+	expected, _ := original.GetOutput("y")
+	actual, _ := optimized.GetOutput("y")
+	ea, _ := expected.FloatArray()
+	aa, _ := actual.FloatArray()
+	for i := range ea {
+		if math.Abs(float64(ea[i]-aa[i])) > 1e-5 {
+			t.Errorf("outputs should be same, but %v and %v", ea, aa)
+			break
+		}
+	}
+}
@@ -0,0 +1,259 @@
+package optimize
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/pfnet-research/go-menoh/tools/onnx"
+)
+
+func eliminateIdentity(g *onnx.GraphProto) ([]string, error) {
+	changes := []string{}
+	outputs := graphOutputs(g)
+	inits := initializers(g)
+	used := uses(g)
+	produced := producers(g)
+	removed := map[*onnx.NodeProto]bool{}
+	for _, n := range g.GetNode() {
+		op := n.GetOpType()
+		if (op != "Identity" && op != "Dropout") || len(n.Input) == 0 || len(n.Output) == 0 {
+			continue
+		}
+		if op == "Dropout" && !onlyFirstOutputUsed(n, used, outputs) {
+			continue // mask is used
+		}
+		in, out := n.Input[0], n.Output[0]
+		var from, to string
+		switch {
+		case !outputs[out]:
+			from, to = out, in
+		case !outputs[in] && inits[in] == nil && produced[in] != nil:
+			// keep the name of the graph output
+			p := produced[in]
+			for i, o := range p.Output {
+				if o == in {
+					p.Output[i] = out
+				}
+			}
+			produced[out] = p
+			delete(produced, in)
+			from, to = in, out
+		default:
+			continue
+		}
+		// removed nodes stay in the graph until the end of the pass, they must
+		// not be counted as users of variables
+		n.Input = nil
+		used[in]--
+		renameInputs(g, from, to)
+		used[to] += used[from]
+		delete(used, from)
+		removed[n] = true
+		changes = append(changes, fmt.Sprintf("removed %s '%s'", op, nodeLabel(n)))
+	}
+	removeNodes(g, removed)
+	return changes, nil
+}
+
+func foldConstants(g *onnx.GraphProto) ([]string, error) {
+	changes := []string{}
+	outputs := graphOutputs(g)
+	inits := initializers(g)
+	removed := map[*onnx.NodeProto]bool{}
+	for _, n := range g.GetNode() {
+		if len(n.Output) != 1 || outputs[n.Output[0]] {
+			continue
+		}
+		out := n.Output[0]
+		var t *onnx.TensorProto
+		if n.GetOpType() == "Constant" {
+			for _, a := range n.GetAttribute() {
+				if a.GetName() == "value" && a.GetT() != nil {
+					t = proto.Clone(a.GetT()).(*onnx.TensorProto)
+					t.Name = proto.String(out)
+				}
+			}
+			if t == nil {
+				continue
+			}
+		} else {
+			constant := len(n.Input) > 0
+			for _, in := range n.Input {
+				if in == "" {
+					continue
+				}
+				init, ok := inits[in]
+				constant = constant && ok && init.GetDataType() == onnx.TensorProto_FLOAT
+			}
+			if !constant {
+				continue
+			}
+			var err error
+			if t, err = evalNode(n, inits); err != nil {
+				continue // not supported by the interpreter
+			}
+		}
+		g.Initializer = append(g.Initializer, t)
+		inits[out] = t
+		removed[n] = true
+		changes = append(changes, fmt.Sprintf("folded %s '%s' to an initializer", n.GetOpType(), nodeLabel(n)))
+	}
+	removeNodes(g, removed)
+	return changes, nil
+}
+
+func fuseBatchNorm(g *onnx.GraphProto) ([]string, error) {
+	changes := []string{}
+	outputs := graphOutputs(g)
+	inits := initializers(g)
+	used := uses(g)
+	produced := producers(g)
+	removed := map[*onnx.NodeProto]bool{}
+	for _, bn := range g.GetNode() {
+		if bn.GetOpType() != "BatchNormalization" || len(bn.Input) < 5 || len(bn.Output) == 0 {
+			continue
+		}
+		if !onlyFirstOutputUsed(bn, used, outputs) {
+			continue // training outputs are used
+		}
+		y := bn.Input[0]
+		conv := produced[y]
+		if conv == nil || conv.GetOpType() != "Conv" || removed[conv] || len(conv.Input) < 2 {
+			continue
+		}
+		if outputs[y] || used[y] != 1 {
+			continue
+		}
+		fused, err := fuse(conv, bn, inits)
+		if err != nil {
+			continue // weights are not constant
+		}
+		change := fmt.Sprintf("fused BatchNormalization '%s' into Conv '%s'", nodeLabel(bn), nodeLabel(conv))
+		w := uniqueName(g, bn.Output[0]+"_fused_W")
+		fused[0].Name = proto.String(w)
+		g.Initializer = append(g.Initializer, fused[0])
+		b := uniqueName(g, bn.Output[0]+"_fused_B")
+		fused[1].Name = proto.String(b)
+		g.Initializer = append(g.Initializer, fused[1])
+		for _, in := range conv.Input[1:] {
+			used[in]--
+		}
+		for _, in := range bn.Input {
+			used[in]--
+		}
+		used[w]++
+		used[b]++
+		conv.Input = []string{conv.Input[0], w, b}
+		conv.Output = []string{bn.Output[0]}
+		produced[bn.Output[0]] = conv
+		delete(produced, y)
+		removed[bn] = true
+		changes = append(changes, change)
+	}
+	removeNodes(g, removed)
+	return changes, nil
+}
+
+// onlyFirstOutputUsed returns whether other outputs of the node are neither
+// used by nodes nor graph outputs.
+func onlyFirstOutputUsed(n *onnx.NodeProto, used map[string]int, outputs map[string]bool) bool {
+	for _, out := range n.Output[1:] {
+		if out != "" && (used[out] > 0 || outputs[out]) {
+			return false
+		}
+	}
+	return true
+}
+
+// fuse returns weight and bias of the Conv folding the BatchNormalization,
+// w' = w * scale / sqrt(var + epsilon) per output channel and
+// b' = (b - mean) * scale / sqrt(var + epsilon) + bias.
+func fuse(conv, bn *onnx.NodeProto, inits map[string]*onnx.TensorProto) ([]*onnx.TensorProto, error) {
+	wt, ok := inits[conv.Input[1]]
+	if !ok || len(wt.GetDims()) == 0 {
+		return nil, fmt.Errorf("weight of Conv is not an initializer")
+	}
+	w, err := floatsOf(wt)
+	if err != nil {
+		return nil, err
+	}
+	channels := int(wt.GetDims()[0])
+	if channels == 0 || len(w)%channels != 0 {
+		return nil, fmt.Errorf("invalid weight dims %v", wt.GetDims())
+	}
+	params := make([][]float32, 4) // scale, bias, mean, var
+	for i := range params {
+		t, ok := inits[bn.Input[i+1]]
+		if !ok {
+			return nil, fmt.Errorf("%s is not an initializer", bn.Input[i+1])
+		}
+		if params[i], err = floatsOf(t); err != nil {
+			return nil, err
+		}
+		if len(params[i]) != channels {
+			return nil, fmt.Errorf("size of %s is not %d", bn.Input[i+1], channels)
+		}
+	}
+	b := make([]float32, channels)
+	if len(conv.Input) > 2 && conv.Input[2] != "" {
+		bt, ok := inits[conv.Input[2]]
+		if !ok {
+			return nil, fmt.Errorf("bias of Conv is not an initializer")
+		}
+		if b, err = floatsOf(bt); err != nil {
+			return nil, err
+		}
+		if len(b) != channels {
+			return nil, fmt.Errorf("size of %s is not %d", conv.Input[2], channels)
+		}
+	}
+	epsilon := float32(1e-5)
+	for _, a := range bn.GetAttribute() {
+		if a.GetName() == "epsilon" {
+			epsilon = a.GetF()
+		}
+	}
+
+	size := len(w) / channels
+	fw := make([]float32, len(w))
+	fb := make([]float32, channels)
+	scale, bias, mean, variance := params[0], params[1], params[2], params[3]
+	for c := 0; c < channels; c++ {
+		s := scale[c] / float32(math.Sqrt(float64(variance[c]+epsilon)))
+		for i := c * size; i < (c+1)*size; i++ {
+			fw[i] = w[i] * s
+		}
+		fb[c] = (b[c]-mean[c])*s + bias[c]
+	}
+	dtype := onnx.TensorProto_FLOAT
+	return []*onnx.TensorProto{
+		{DataType: &dtype, Dims: append([]int64{}, wt.GetDims()...), FloatData: fw},
+		{DataType: &dtype, Dims: []int64{int64(channels)}, FloatData: fb},
+	}, nil
+}
+
+func eliminateDeadInitializers(g *onnx.GraphProto) ([]string, error) {
+	changes := []string{}
+	used := uses(g)
+	outputs := graphOutputs(g)
+	dead := map[string]bool{}
+	inits := make([]*onnx.TensorProto, 0, len(g.Initializer))
+	for _, t := range g.Initializer {
+		if used[t.GetName()] == 0 && !outputs[t.GetName()] {
+			dead[t.GetName()] = true
+			changes = append(changes, fmt.Sprintf("removed initializer '%s'", t.GetName()))
+			continue
+		}
+		inits = append(inits, t)
+	}
+	g.Initializer = inits
+	inputs := make([]*onnx.ValueInfoProto, 0, len(g.Input))
+	for _, v := range g.Input {
+		if !dead[v.GetName()] {
+			inputs = append(inputs, v)
+		}
+	}
+	g.Input = inputs
+	return changes, nil
+}
@@ -0,0 +1,223 @@
+package optimize
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/pfnet-research/go-menoh/tools/onnx"
+)
+
+func makeTensor(name string, dims []int64, floats []float32) *onnx.TensorProto {
+	dtype := onnx.TensorProto_FLOAT
+	return &onnx.TensorProto{
+		Name:      proto.String(name),
+		DataType:  &dtype,
+		Dims:      dims,
+		FloatData: floats,
+	}
+}
+
+func makeNode(opType string, inputs, outputs []string, attrs ...*onnx.AttributeProto) *onnx.NodeProto {
+	return &onnx.NodeProto{
+		OpType:    proto.String(opType),
+		Input:     inputs,
+		Output:    outputs,
+		Attribute: attrs,
+	}
+}
+
+func makeValueInfos(names ...string) []*onnx.ValueInfoProto {
+	vs := []*onnx.ValueInfoProto{}
+	for _, name := range names {
+		vs = append(vs, &onnx.ValueInfoProto{Name: proto.String(name)})
+	}
+	return vs
+}
+
+func nodeSummary(g *onnx.GraphProto) []string {
+	s := []string{}
+	for _, n := range g.GetNode() {
+		s = append(s, n.GetOpType()+"("+join(n.GetInput())+")->"+join(n.GetOutput()))
+	}
+	return s
+}
+
+func join(names []string) string {
+	s := ""
+	for i, name := range names {
+		if i > 0 {
+			s += ","
+		}
+		s += name
+	}
+	return s
+}
+
+func TestEliminateIdentity(t *testing.T) {
+	g := &onnx.GraphProto{
+		Node: []*onnx.NodeProto{
+			makeNode("Relu", []string{"x"}, []string{"a"}),
+			makeNode("Identity", []string{"a"}, []string{"b"}),
+			makeNode("Dropout", []string{"b"}, []string{"y", "mask"}),
+			makeNode("Identity", []string{"x"}, []string{"z"}),
+		},
+		Input:  makeValueInfos("x"),
+		Output: makeValueInfos("y", "z"),
+	}
+	changes, err := eliminateIdentity(g)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(changes) != 2 {
+		t.Errorf("2 nodes should be removed, but %v", changes)
+	}
+	// identity from graph input to graph output is kept
+	expected := []string{"Relu(x)->y", "Identity(x)->z"}
+	if actual := nodeSummary(g); !reflect.DeepEqual(actual, expected) {
+		t.Errorf("nodes should be %v, but %v", expected, actual)
+	}
+
+	g = &onnx.GraphProto{
+		Node: []*onnx.NodeProto{
+			makeNode("Dropout", []string{"x"}, []string{"a", "mask"}),
+			makeNode("Add", []string{"a", "mask"}, []string{"y"}),
+		},
+		Output: makeValueInfos("y"),
+	}
+	if changes, _ := eliminateIdentity(g); len(changes) != 0 {
+		t.Errorf("Dropout whose mask is used should be kept, but %v", changes)
+	}
+
+	g = &onnx.GraphProto{
+		Node: []*onnx.NodeProto{
+			makeNode("Dropout", []string{"x"}, []string{"a", "mask"}),
+			makeNode("Relu", []string{"a"}, []string{"y"}),
+		},
+		Output: makeValueInfos("y", "mask"),
+	}
+	if changes, _ := eliminateIdentity(g); len(changes) != 0 {
+		t.Errorf("Dropout whose mask is a graph output should be kept, but %v", changes)
+	}
+}
+
+func TestFoldConstants(t *testing.T) {
+	g := &onnx.GraphProto{
+		Node: []*onnx.NodeProto{
+			makeNode("Constant", nil, []string{"c"}, &onnx.AttributeProto{
+				Name: proto.String("value"),
+				T:    makeTensor("", []int64{2}, []float32{1, 2}),
+			}),
+			makeNode("Add", []string{"c", "W"}, []string{"d"}),
+			makeNode("Mul", []string{"x", "d"}, []string{"y"}),
+		},
+		Initializer: []*onnx.TensorProto{makeTensor("W", []int64{2}, []float32{10, 20})},
+		Input:       makeValueInfos("x", "W"),
+		Output:      makeValueInfos("y"),
+	}
+	changes, err := foldConstants(g)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(changes) != 2 {
+		t.Errorf("Constant and Add should be folded, but %v", changes)
+	}
+	if expected, actual := []string{"Mul(x,d)->y"}, nodeSummary(g); !reflect.DeepEqual(actual, expected) {
+		t.Errorf("nodes should be %v, but %v", expected, actual)
+	}
+	d, ok := initializers(g)["d"]
+	if !ok {
+		t.Fatal("folded value should be an initializer")
+	}
+	if actual, _ := floatsOf(d); !reflect.DeepEqual(actual, []float32{11, 22}) {
+		t.Errorf("folded value should be [11 22], but %v", actual)
+	}
+
+	g = &onnx.GraphProto{
+		Node: []*onnx.NodeProto{
+			makeNode("Tanh", []string{"W"}, []string{"y"}),
+		},
+		Initializer: []*onnx.TensorProto{makeTensor("W", []int64{2}, []float32{10, 20})},
+		Output:      makeValueInfos("y"),
+	}
+	if changes, _ := foldConstants(g); len(changes) != 0 {
+		t.Errorf("graph outputs should not be folded, but %v", changes)
+	}
+}
+
+func TestFuseBatchNorm(t *testing.T) {
+	g := makeConvBNGraph()
+	changes, err := fuseBatchNorm(g)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(changes) != 1 {
+		t.Fatalf("BatchNormalization should be fused, but %v", changes)
+	}
+	expected := []string{"Conv(x,y_fused_W,y_fused_B)->y"}
+	if actual := nodeSummary(g); !reflect.DeepEqual(actual, expected) {
+		t.Errorf("nodes should be %v, but %v", expected, actual)
+	}
+	inits := initializers(g)
+	// scale / sqrt(var + epsilon) is [2, 0.5]
+	w, _ := floatsOf(inits["y_fused_W"])
+	if !reflect.DeepEqual(w, []float32{2, 4, 0.5, 1}) {
+		t.Errorf("weight should be scaled, but %v", w)
+	}
+	b, _ := floatsOf(inits["y_fused_B"])
+	if !reflect.DeepEqual(b, []float32{1, 2}) {
+		t.Errorf("bias should be folded, but %v", b)
+	}
+
+	g = makeConvBNGraph()
+	g.Output = append(g.Output, makeValueInfos("h")...)
+	if changes, _ := fuseBatchNorm(g); len(changes) != 0 {
+		t.Errorf("Conv whose output is used by others should be kept, but %v", changes)
+	}
+}
+
+// makeConvBNGraph returns 1x1 Conv with 2 channels followed by
+// BatchNormalization.
+func makeConvBNGraph() *onnx.GraphProto {
+	return &onnx.GraphProto{
+		Node: []*onnx.NodeProto{
+			makeNode("Conv", []string{"x", "W", "B"}, []string{"h"}),
+			makeNode("BatchNormalization", []string{"h", "scale", "bias", "mean", "var"}, []string{"y"},
+				&onnx.AttributeProto{Name: proto.String("epsilon"), F: proto.Float32(0)}),
+		},
+		Initializer: []*onnx.TensorProto{
+			makeTensor("W", []int64{2, 2, 1, 1}, []float32{1, 2, 1, 2}),
+			makeTensor("B", []int64{2}, []float32{1, 2}),
+			makeTensor("scale", []int64{2}, []float32{2, 1}),
+			makeTensor("bias", []int64{2}, []float32{1, 1.5}),
+			makeTensor("mean", []int64{2}, []float32{1, 1}),
+			makeTensor("var", []int64{2}, []float32{1, 4}),
+		},
+		Input:  makeValueInfos("x", "W", "B", "scale", "bias", "mean", "var"),
+		Output: makeValueInfos("y"),
+	}
+}
+
+func TestEliminateDeadInitializers(t *testing.T) {
+	g := &onnx.GraphProto{
+		Node: []*onnx.NodeProto{
+			makeNode("Add", []string{"x", "W"}, []string{"y"}),
+		},
+		Initializer: []*onnx.TensorProto{
+			makeTensor("W", []int64{1}, []float32{1}),
+			makeTensor("unused", []int64{1}, []float32{1}),
+		},
+		Input:  makeValueInfos("x", "W", "unused"),
+		Output: makeValueInfos("y"),
+	}
+	changes, err := eliminateDeadInitializers(g)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(changes, []string{"removed initializer 'unused'"}) {
+		t.Errorf("unused initializer should be removed, but %v", changes)
+	}
+	if len(g.Initializer) != 1 || len(g.Input) != 2 {
+		t.Errorf("unused initializer should be removed from graph inputs, %v", g.Input)
+	}
+}
@@ -0,0 +1,356 @@
+/*
+Package pipeline runs streaming inference by stages connected with bounded
+channels, like decode, preprocess, inference, postprocess, and a sink:
+
+	p, err := pipeline.New([]pipeline.Stage{
+		pipeline.Func("decode", 2, decode),
+		pipeline.Func("preprocess", 4, preprocess),
+		pipeline.Inference("vgg16", 2, newRunner, nil),
+		pipeline.Func("postprocess", 1, postprocess),
+	}, pipeline.Options{Ordered: true})
+	report, err := p.Run(ctx, source, func(r pipeline.Result) error {
+		...
+	})
+
+where newRunner returns a menoh.Inferencer, like
+
+	newRunner := func() (menoh.Inferencer, error) {
+		return menoh.NewRunner(conf)
+	}
+
+Each stage runs with its workers, an inference worker has its own runner.
+A full channel blocks the previous stage, so the source is read only as fast
+as the slowest stage. The number of items in the pipeline is bounded by the
+buffers of the stages, also when results wait for an earlier item to be
+ordered. Canceling the context stops reading the source, and items in the
+pipeline are drained before Run returns.
+*/
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/pfnet-research/go-menoh"
+)
+
+// Worker processes items of a stage, used by one goroutine.
+type Worker interface {
+	// Process returns the item passed to the next stage.
+	Process(ctx context.Context, item interface{}) (interface{}, error)
+
+	// Close releases the worker after all items are processed.
+	Close()
+}
+
+// Stage is a step of a pipeline.
+type Stage struct {
+	Name      string
+	Workers   int // number of workers processing items concurrently, default is 1
+	Buffer    int // capacity of the output channel, default is Workers
+	NewWorker func() (Worker, error)
+}
+
+func (s Stage) withDefaults() Stage {
+	if s.Workers <= 0 {
+		s.Workers = 1
+	}
+	if s.Buffer <= 0 {
+		s.Buffer = s.Workers
+	}
+	return s
+}
+
+type funcWorker func(ctx context.Context, item interface{}) (interface{}, error)
+
+func (f funcWorker) Process(ctx context.Context, item interface{}) (interface{}, error) {
+	return f(ctx, item)
+}
+
+func (f funcWorker) Close() {}
+
+// Func returns Stage processing items by f with the workers, f is called
+// concurrently.
+func Func(name string, workers int, f func(ctx context.Context, item interface{}) (interface{}, error)) Stage {
+	return Stage{
+		Name:    name,
+		Workers: workers,
+		NewWorker: func() (Worker, error) {
+			return funcWorker(f), nil
+		},
+	}
+}
+
+type inferenceWorker struct {
+	inferencer menoh.Inferencer
+	run        func(context.Context, menoh.Inferencer, interface{}) (interface{}, error)
+}
+
+func (w *inferenceWorker) Process(ctx context.Context, item interface{}) (interface{}, error) {
+	return w.run(ctx, w.inferencer, item)
+}
+
+func (w *inferenceWorker) Close() {
+	w.inferencer.Stop()
+}
+
+// Inference returns Stage running a model with the workers, newInferencer is
+// called for each worker, like a closure of menoh.NewRunner. run sets inputs
+// from the item, runs and returns outputs, the outputs of the inferencer are
+// overwritten by the next item, copy them. When run is nil, items must be
+// map[string]menoh.Tensor of inputs, and copies of outputs are returned.
+func Inference(name string, workers int, newInferencer func() (menoh.Inferencer, error),
+	run func(ctx context.Context, inf menoh.Inferencer, item interface{}) (interface{}, error)) Stage {
+
+	if run == nil {
+		run = runTensors
+	}
+	return Stage{
+		Name:    name,
+		Workers: workers,
+		NewWorker: func() (Worker, error) {
+			inferencer, err := newInferencer()
+			if err != nil {
+				return nil, err
+			}
+			return &inferenceWorker{inferencer: inferencer, run: run}, nil
+		},
+	}
+}
+
+func runTensors(ctx context.Context, inf menoh.Inferencer, item interface{}) (interface{}, error) {
+	inputs, ok := item.(map[string]menoh.Tensor)
+	if !ok {
+		return nil, fmt.Errorf("item should be map[string]menoh.Tensor, but %T", item)
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if err := inf.Run(inputs); err != nil {
+		return nil, err
+	}
+	outputs := map[string]menoh.Tensor{}
+	for name, t := range inf.Outputs() {
+		a, err := t.FloatArray()
+		if err != nil {
+			return nil, err
+		}
+		outputs[name] = &menoh.FloatTensor{
+			Dims:  append([]int32{}, t.Shape()...),
+			Array: append([]float32{}, a...),
+		}
+	}
+	return outputs, nil
+}
+
+// Options is setup information of a pipeline.
+type Options struct {
+	Ordered bool // pass results to the sink in order of the source
+}
+
+// Result is an item passed through all stages.
+type Result struct {
+	Seq   int         // index in the source
+	Item  interface{} // output of the last stage, or input of the failed stage
+	Err   error       // set when a stage failed, later stages are skipped
+	Stage string      // name of the failed stage
+}
+
+// Pipeline is stages connected with channels.
+type Pipeline struct {
+	stages []Stage
+	opts   Options
+}
+
+// New returns Pipeline of the stages.
+func New(stages []Stage, opts Options) (*Pipeline, error) {
+	if len(stages) == 0 {
+		return nil, errors.New("no stage is set")
+	}
+	p := &Pipeline{opts: opts}
+	for i, s := range stages {
+		if s.NewWorker == nil {
+			return nil, fmt.Errorf("NewWorker of stage %d (%s) is not set", i, s.Name)
+		}
+		p.stages = append(p.stages, s.withDefaults())
+	}
+	return p, nil
+}
+
+type envelope struct {
+	seq   int
+	item  interface{}
+	err   error
+	stage string
+}
+
+// Run builds workers of all stages, passes items of the source through the
+// stages until the source is closed or ctx is done, and calls sink with each
+// result from one goroutine. When sink returns an error, the source is no
+// longer read and the error is returned after draining. Workers are closed
+// before returning.
+func (p *Pipeline) Run(ctx context.Context, source <-chan interface{}, sink func(Result) error) (*Report, error) {
+	workers, err := p.newWorkers()
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		for _, ws := range workers {
+			for _, w := range ws {
+				w.Close()
+			}
+		}
+	}()
+
+	start := time.Now()
+	runCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// a slot is taken by each item read from the source until it is passed
+	// to the sink, so that ordering does not hold items without bound
+	slots := make(chan struct{}, p.maxInFlight())
+	in := make(chan envelope, p.stages[0].Buffer)
+	go func() {
+		defer close(in)
+		for seq := 0; ; seq++ {
+			select {
+			case slots <- struct{}{}:
+			case <-runCtx.Done():
+				return
+			}
+			select {
+			case item, ok := <-source:
+				if !ok {
+					return
+				}
+				select {
+				case in <- envelope{seq: seq, item: item}:
+				case <-runCtx.Done():
+					return
+				}
+			case <-runCtx.Done():
+				return
+			}
+		}
+	}()
+
+	stats := make([]*stageStats, len(p.stages))
+	ch := (<-chan envelope)(in)
+	for i, s := range p.stages {
+		stats[i] = &stageStats{}
+		ch = p.runStage(runCtx, s, workers[i], ch, stats[i])
+	}
+
+	report := &Report{}
+	var sinkErr error
+	emit := func(e envelope) {
+		<-slots
+		report.Items++
+		if e.err != nil {
+			report.Errors++
+		}
+		if sinkErr != nil {
+			return
+		}
+		sinkErr = sink(Result{Seq: e.seq, Item: e.item, Err: e.err, Stage: e.stage})
+		if sinkErr != nil {
+			cancel()
+		}
+	}
+	pending := map[int]envelope{}
+	next := 0
+	for e := range ch {
+		if !p.opts.Ordered {
+			emit(e)
+			continue
+		}
+		pending[e.seq] = e
+		for {
+			e, ok := pending[next]
+			if !ok {
+				break
+			}
+			delete(pending, next)
+			next++
+			emit(e)
+		}
+	}
+
+	report.Elapsed = time.Since(start)
+	for i, s := range p.stages {
+		report.Stages = append(report.Stages, stats[i].summary(s, report.Elapsed))
+	}
+	if sinkErr != nil {
+		return report, sinkErr
+	}
+	return report, ctx.Err()
+}
+
+// maxInFlight returns the number of items the channels and the workers can
+// hold.
+func (p *Pipeline) maxInFlight() int {
+	n := p.stages[0].Buffer
+	for _, s := range p.stages {
+		n += s.Workers + s.Buffer
+	}
+	return n
+}
+
+func (p *Pipeline) newWorkers() ([][]Worker, error) {
+	workers := [][]Worker{}
+	for _, s := range p.stages {
+		ws := []Worker{}
+		for i := 0; i < s.Workers; i++ {
+			w, err := s.NewWorker()
+			if err != nil {
+				for _, ws := range append(workers, ws) {
+					for _, w := range ws {
+						w.Close()
+					}
+				}
+				return nil, fmt.Errorf("cannot make worker of stage %s, %v", s.Name, err)
+			}
+			ws = append(ws, w)
+		}
+		workers = append(workers, ws)
+	}
+	return workers, nil
+}
+
+// runStage processes items from in by the workers and returns the output
+// channel, closed after all workers are done. Failed items are passed as is.
+func (p *Pipeline) runStage(ctx context.Context, s Stage, workers []Worker, in <-chan envelope,
+	stats *stageStats) <-chan envelope {
+
+	out := make(chan envelope, s.Buffer)
+	var wg sync.WaitGroup
+	for _, w := range workers {
+		wg.Add(1)
+		go func(w Worker) {
+			defer wg.Done()
+			for e := range in {
+				if e.err == nil {
+					start := time.Now()
+					item, err := w.Process(ctx, e.item)
+					stats.processed(time.Since(start), err)
+					if err != nil {
+						e.err, e.stage = err, s.Name
+					} else {
+						e.item = item
+					}
+				}
+				start := time.Now()
+				out <- e
+				stats.waited(time.Since(start))
+			}
+		}(w)
+	}
+	go func() {
+		wg.Wait()
+		close(out)
+	}()
+	return out
+}
@@ -0,0 +1,378 @@
+package pipeline
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/pfnet-research/go-menoh"
+	"github.com/pfnet-research/go-menoh/external/errcode"
+	"github.com/pfnet-research/go-menoh/menohtest"
+)
+
+func source(n int) <-chan interface{} {
+	ch := make(chan interface{})
+	go func() {
+		defer close(ch)
+		for i := 0; i < n; i++ {
+			ch <- i
+		}
+	}()
+	return ch
+}
+
+// inferenceConfig returns configuration of a fake multiplying input by 10,
+// for inputs less than n.
+func inferenceConfig(n int) (*menohtest.Fake, menoh.Config) {
+	fake := menohtest.New()
+	fake.Output("y", 1, 1)
+	for i := 0; i < n; i++ {
+		fake.Script(map[string][]float32{"x": {float32(i)}}, map[string][]float32{"y": {float32(10 * i)}})
+	}
+	return fake, menoh.Config{
+		Backend: fake.Register("pipeline_test"),
+		Inputs:  []menoh.InputConfig{{Name: "x", Dtype: menoh.TypeFloat, Dims: []int32{1, 1}}},
+		Outputs: []menoh.OutputConfig{{Name: "y", Dtype: menoh.TypeFloat}},
+	}
+}
+
+func newRunner(conf menoh.Config) func() (menoh.Inferencer, error) {
+	return func() (menoh.Inferencer, error) {
+		return menoh.NewRunner(conf)
+	}
+}
+
+// testStages returns stages converting i to 10*i by the fake, shuffling
+// order by latency of the first stage.
+func testStages(conf menoh.Config) []Stage {
+	return []Stage{
+		Func("preprocess", 4, func(ctx context.Context, item interface{}) (interface{}, error) {
+			i := item.(int)
+			time.Sleep(time.Duration(i%3) * time.Millisecond)
+			return map[string]menoh.Tensor{
+				"x": &menoh.FloatTensor{Dims: []int32{1, 1}, Array: []float32{float32(i)}},
+			}, nil
+		}),
+		Inference("inference", 2, newRunner(conf), nil),
+		Func("postprocess", 1, func(ctx context.Context, item interface{}) (interface{}, error) {
+			a, err := item.(map[string]menoh.Tensor)["y"].FloatArray()
+			if err != nil {
+				return nil, err
+			}
+			return int(a[0]), nil
+		}),
+	}
+}
+
+func TestRun(t *testing.T) {
+	fake, conf := inferenceConfig(20)
+	for _, ordered := range []bool{true, false} {
+		p, err := New(testStages(conf), Options{Ordered: ordered})
+		if err != nil {
+			t.Fatal(err)
+		}
+		fake.ResetCalls()
+		seqs, items := []int{}, []int{}
+		report, err := p.Run(context.Background(), source(20), func(r Result) error {
+			if r.Err != nil {
+				return r.Err
+			}
+			seqs = append(seqs, r.Seq)
+			items = append(items, r.Item.(int))
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("pipeline should run without error, %v", err)
+		}
+		if ordered && !sort.IntsAreSorted(seqs) {
+			t.Errorf("results should be ordered, but %v", seqs)
+		}
+		sort.Ints(items)
+		for i, v := range items {
+			if v != 10*i {
+				t.Fatalf("items should be converted by all stages, but %v", items)
+			}
+		}
+		if len(items) != 20 || report.Items != 20 || report.Errors != 0 {
+			t.Errorf("all items should be passed to the sink, but %d, %+v", len(items), report)
+		}
+		if n := fake.RunCount(); n != 20 {
+			t.Errorf("runners should run 20 times, but %d", n)
+		}
+		names := []string{}
+		for _, s := range report.Stages {
+			names = append(names, s.Name)
+			if s.Items != 20 {
+				t.Errorf("stage %s should process 20 items, but %d", s.Name, s.Items)
+			}
+		}
+		if !reflect.DeepEqual(names, []string{"preprocess", "inference", "postprocess"}) {
+			t.Errorf("stats of all stages should be reported, but %v", names)
+		}
+		if report.Stages[0].Workers != 4 || report.Stages[0].Throughput <= 0 {
+			t.Errorf("throughput of the stage should be reported, but %+v", report.Stages[0])
+		}
+	}
+}
+
+type countingWorker struct {
+	closed *int32
+	f      func(item interface{}) (interface{}, error)
+}
+
+func (w *countingWorker) Process(ctx context.Context, item interface{}) (interface{}, error) {
+	return w.f(item)
+}
+
+func (w *countingWorker) Close() {
+	atomic.AddInt32(w.closed, 1)
+}
+
+func countingStage(name string, workers int, closed *int32, f func(item interface{}) (interface{}, error)) Stage {
+	return Stage{
+		Name:    name,
+		Workers: workers,
+		NewWorker: func() (Worker, error) {
+			return &countingWorker{closed: closed, f: f}, nil
+		},
+	}
+}
+
+func identity(item interface{}) (interface{}, error) {
+	return item, nil
+}
+
+func TestRunError(t *testing.T) {
+	var closed int32
+	var skipped int32
+	p, err := New([]Stage{
+		countingStage("check", 2, &closed, func(item interface{}) (interface{}, error) {
+			if item.(int) == 3 {
+				return nil, errors.New("invalid item")
+			}
+			return item, nil
+		}),
+		countingStage("next", 1, &closed, func(item interface{}) (interface{}, error) {
+			if item.(int) == 3 {
+				atomic.AddInt32(&skipped, 1)
+			}
+			return item, nil
+		}),
+	}, Options{Ordered: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	failed := []Result{}
+	report, err := p.Run(context.Background(), source(5), func(r Result) error {
+		if r.Err != nil {
+			failed = append(failed, r)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(failed) != 1 || failed[0].Seq != 3 || failed[0].Stage != "check" || failed[0].Item != 3 {
+		t.Errorf("the failed item should be passed with the stage, but %+v", failed)
+	}
+	if skipped != 0 {
+		t.Error("later stages should be skipped for the failed item")
+	}
+	if report.Items != 5 || report.Errors != 1 || report.Stages[0].Errors != 1 || report.Stages[1].Items != 4 {
+		t.Errorf("errors should be reported, but %+v", report)
+	}
+	if closed != 3 {
+		t.Errorf("all workers should be closed, but %d", closed)
+	}
+}
+
+func TestRunStop(t *testing.T) {
+	done := make(chan struct{})
+	defer close(done)
+	infinite := func() <-chan interface{} {
+		ch := make(chan interface{})
+		go func() {
+			for i := 0; ; i++ {
+				select {
+				case ch <- i:
+				case <-done:
+					return
+				}
+			}
+		}()
+		return ch
+	}
+	var closed int32
+	p, err := New([]Stage{countingStage("identity", 2, &closed, identity)}, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("cancel", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		n := 0
+		_, err := p.Run(ctx, infinite(), func(r Result) error {
+			n++
+			if n == 10 {
+				cancel()
+			}
+			return nil
+		})
+		if err != context.Canceled {
+			t.Errorf("the error of the context should be returned, but %v", err)
+		}
+	})
+	t.Run("sink error", func(t *testing.T) {
+		sinkErr := errors.New("sink error")
+		n := 0
+		report, err := p.Run(context.Background(), infinite(), func(r Result) error {
+			n++
+			if n == 10 {
+				return sinkErr
+			}
+			return nil
+		})
+		if err != sinkErr {
+			t.Errorf("the error of the sink should be returned, but %v", err)
+		}
+		if n != 10 || report.Items < 10 {
+			t.Errorf("the sink should not be called after the error, but %d calls, %+v", n, report)
+		}
+	})
+	if closed != 4 {
+		t.Errorf("all workers should be closed, but %d", closed)
+	}
+}
+
+func TestBackpressure(t *testing.T) {
+	for _, ordered := range []bool{false, true} {
+		var mu sync.Mutex
+		sent := 0
+		ch := make(chan interface{})
+		go func() {
+			defer close(ch)
+			for i := 0; i < 20; i++ {
+				ch <- i
+				mu.Lock()
+				sent++
+				mu.Unlock()
+			}
+		}()
+		p, err := New([]Stage{{
+			Name:    "identity",
+			Workers: 2,
+			Buffer:  1,
+			NewWorker: func() (Worker, error) {
+				return &countingWorker{closed: new(int32), f: func(item interface{}) (interface{}, error) {
+					// later items wait for the first one to be ordered
+					if item.(int) == 0 {
+						time.Sleep(20 * time.Millisecond)
+					}
+					return item, nil
+				}}, nil
+			},
+		}}, Options{Ordered: ordered})
+		if err != nil {
+			t.Fatal(err)
+		}
+		received := 0
+		_, err = p.Run(context.Background(), ch, func(r Result) error {
+			time.Sleep(time.Millisecond)
+			received++
+			mu.Lock()
+			defer mu.Unlock()
+			// the input and output channels and the workers
+			if sent-received > 4 {
+				t.Errorf("source should be blocked, but %d items are sent for %d received, ordered: %v",
+					sent, received, ordered)
+			}
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if received != 20 {
+			t.Errorf("all items should be received, but %d", received)
+		}
+	}
+}
+
+type contextKey struct{}
+
+func TestInferenceContext(t *testing.T) {
+	_, conf := inferenceConfig(1)
+	p, err := New([]Stage{
+		Inference("inference", 1, newRunner(conf),
+			func(ctx context.Context, inf menoh.Inferencer, item interface{}) (interface{}, error) {
+				return ctx.Value(contextKey{}), nil
+			}),
+	}, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.WithValue(context.Background(), contextKey{}, "value")
+	_, err = p.Run(ctx, source(1), func(r Result) error {
+		if r.Item != "value" {
+			t.Errorf("context of Run should be passed, but %v", r.Item)
+		}
+		return r.Err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestNewWorkerFail(t *testing.T) {
+	fake, conf := inferenceConfig(0)
+	fake.FailBuild(menohtest.NewError(errcode.InvalidBackendName, "pipeline_test"))
+	var closed int32
+	p, err := New([]Stage{
+		countingStage("identity", 2, &closed, identity),
+		Inference("inference", 1, newRunner(conf), nil),
+	}, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = p.Run(context.Background(), source(1), func(r Result) error { return nil })
+	if err == nil || !strings.Contains(err.Error(), "stage inference") {
+		t.Errorf("an error should be occurred with the stage, but %v", err)
+	}
+	if closed != 2 {
+		t.Errorf("built workers should be closed, but %d", closed)
+	}
+}
+
+func TestNew(t *testing.T) {
+	if _, err := New(nil, Options{}); err == nil {
+		t.Error("an error should be occurred without stage")
+	}
+	if _, err := New([]Stage{{Name: "empty"}}, Options{}); err == nil {
+		t.Error("an error should be occurred without NewWorker")
+	}
+}
+
+func TestWriteTable(t *testing.T) {
+	report := &Report{
+		Elapsed: time.Second,
+		Items:   10,
+		Stages: []StageStats{
+			{Name: "inference", Workers: 2, Items: 10, Busy: time.Second, Throughput: 10, Utilization: 0.5},
+		},
+	}
+	buf := &bytes.Buffer{}
+	if err := report.WriteTable(buf); err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range []string{"inference", "10.00", "50.0%", "10 items (0 errors)"} {
+		if !strings.Contains(buf.String(), s) {
+			t.Errorf("table should contain '%s', but\n%s", s, buf.String())
+		}
+	}
+}
@@ -0,0 +1,91 @@
+package pipeline
+
+import (
+	"fmt"
+	"io"
+	"sync"
+	"text/tabwriter"
+	"time"
+)
+
+// StageStats is statistics of a stage.
+type StageStats struct {
+	Name    string        `json:"name"`
+	Workers int           `json:"workers"`
+	Items   int           `json:"items"`   // processed items, excluding ones failed in earlier stages
+	Errors  int           `json:"errors"`  // items failed in the stage
+	Busy    time.Duration `json:"busy"`    // total time of workers processing items
+	Blocked time.Duration `json:"blocked"` // total time of workers waiting for the next stage
+
+	Throughput  float64 `json:"throughput"`  // processed items per second
+	Utilization float64 `json:"utilization"` // ratio of busy time to elapsed time of all workers
+}
+
+// Report is statistics of a run of a pipeline. A stage with high
+// utilization is a bottleneck, and stages before it are blocked.
+type Report struct {
+	Elapsed time.Duration `json:"elapsed"`
+	Items   int           `json:"items"`  // items passed to the sink
+	Errors  int           `json:"errors"` // failed items passed to the sink
+	Stages  []StageStats  `json:"stages"`
+}
+
+// WriteTable writes the report as human-readable table.
+func (r *Report) WriteTable(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "stage\tworkers\titems\terrors\titems/s\tutilization\tbusy\tblocked")
+	for _, s := range r.Stages {
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%d\t%.2f\t%.1f%%\t%s\t%s\n", s.Name, s.Workers, s.Items, s.Errors,
+			s.Throughput, 100*s.Utilization, formatDuration(s.Busy), formatDuration(s.Blocked))
+	}
+	fmt.Fprintln(tw)
+	fmt.Fprintf(tw, "total\t%d items (%d errors) in %s\n", r.Items, r.Errors, formatDuration(r.Elapsed))
+	return tw.Flush()
+}
+
+func formatDuration(d time.Duration) string {
+	return fmt.Sprintf("%.3fms", float64(d)/float64(time.Millisecond))
+}
+
+// stageStats is counted by workers of a stage.
+type stageStats struct {
+	mu          sync.Mutex
+	items       int
+	errors      int
+	busy        time.Duration
+	blockedTime time.Duration
+}
+
+func (s *stageStats) processed(d time.Duration, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.items++
+	if err != nil {
+		s.errors++
+	}
+	s.busy += d
+}
+
+func (s *stageStats) waited(d time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.blockedTime += d
+}
+
+func (s *stageStats) summary(stage Stage, elapsed time.Duration) StageStats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	stats := StageStats{
+		Name:    stage.Name,
+		Workers: stage.Workers,
+		Items:   s.items,
+		Errors:  s.errors,
+		Busy:    s.busy,
+		Blocked: s.blockedTime,
+	}
+	if elapsed > 0 {
+		stats.Throughput = float64(s.items) / elapsed.Seconds()
+		stats.Utilization = float64(s.busy) / float64(elapsed) / float64(stage.Workers)
+	}
+	return stats
+}
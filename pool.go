@@ -0,0 +1,197 @@
+package menoh
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// Pool is a set of runners built with same configuration. A runner is not
+// safe to run concurrently, a pool lends each runner to only one caller at
+// a time by Do. Pool is InferencerPool, each caller runs on its own session
+// made by NewSession, which keeps outputs of its own runs.
+type Pool struct {
+	conf    Config
+	runners []*Runner
+	idle    chan *Runner
+	outputs map[string][]int32 // shapes of outputs
+
+	mu      sync.RWMutex
+	stopped bool
+}
+
+// NewPool returns Pool which has size runners built with the configuration.
+// Require to call Stop function after the process is done.
+func NewPool(conf Config, size int) (*Pool, error) {
+	if size <= 0 {
+		return nil, errors.New("pool size must be positive")
+	}
+	p := &Pool{
+		conf: conf,
+		idle: make(chan *Runner, size),
+	}
+	for i := 0; i < size; i++ {
+		r, err := NewRunner(conf)
+		if err != nil {
+			p.Stop()
+			return nil, err
+		}
+		p.runners = append(p.runners, r)
+		p.idle <- r
+	}
+	p.outputs = map[string][]int32{}
+	for name, t := range p.runners[0].Outputs() {
+		p.outputs[name] = t.Shape()
+	}
+	return p, nil
+}
+
+// Config returns the configuration used to build runners.
+func (p *Pool) Config() Config {
+	return p.conf
+}
+
+// Size returns number of runners.
+func (p *Pool) Size() int {
+	return len(p.runners)
+}
+
+// Do calls f with an idle runner, waiting until a runner becomes idle or
+// ctx is done. Outputs of the runner are valid only in f, copy them to use
+// after returning.
+func (p *Pool) Do(ctx context.Context, f func(*Runner) error) error {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	if p.stopped {
+		return errors.New("pool is stopped")
+	}
+	select {
+	case r := <-p.idle:
+		defer func() { p.idle <- r }()
+		return f(r)
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// NewSession returns Inferencer running on idle runners of the pool, for a
+// caller. Runs wait for an idle runner until ctx is done, and the error of
+// ctx is returned. All inputs are required on each Run, because buffers of
+// a runner keep inputs of another session. The session keeps copies of
+// inputs and outputs of its last Run, zero before running. A session is not
+// safe to run concurrently, make a session for each caller. Stopping a
+// session does not stop the pool.
+func (p *Pool) NewSession(ctx context.Context) Inferencer {
+	s := &poolSession{
+		pool:        p,
+		ctx:         ctx,
+		lastInputs:  map[string]Tensor{},
+		lastOutputs: map[string]Tensor{},
+	}
+	for _, c := range p.conf.Inputs {
+		s.lastInputs[c.Name] = zeroTensor(c.Dims)
+	}
+	for name, dims := range p.outputs {
+		s.lastOutputs[name] = zeroTensor(dims)
+	}
+	return s
+}
+
+func zeroTensor(dims []int32) *FloatTensor {
+	size := 1
+	for _, d := range dims {
+		size *= int(d)
+	}
+	return &FloatTensor{
+		Dims:  append([]int32{}, dims...),
+		Array: make([]float32, size),
+	}
+}
+
+// Stop all runners, waiting for running calls of Do.
+func (p *Pool) Stop() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.stopped {
+		return
+	}
+	p.stopped = true
+	for _, r := range p.runners {
+		r.Stop()
+	}
+}
+
+// poolSession is Inferencer of a caller of Pool.
+type poolSession struct {
+	pool    *Pool
+	ctx     context.Context
+	stopped bool
+
+	// copies of inputs and outputs of the last Run
+	lastInputs  map[string]Tensor
+	lastOutputs map[string]Tensor
+}
+
+func (s *poolSession) Run(inputs map[string]Tensor) error {
+	if s.stopped {
+		return errors.New("session is stopped")
+	}
+	for _, c := range s.pool.conf.Inputs {
+		if _, ok := inputs[c.Name]; !ok {
+			return fmt.Errorf("input %s is required", c.Name)
+		}
+	}
+	var in, out map[string]Tensor
+	err := s.pool.Do(s.ctx, func(r *Runner) error {
+		// ctx can be done while waiting for a runner
+		if err := s.ctx.Err(); err != nil {
+			return err
+		}
+		if err := r.Run(inputs); err != nil {
+			return err
+		}
+		var err error
+		if in, err = cloneTensors(r.inputs); err != nil {
+			return err
+		}
+		out, err = cloneTensors(r.Outputs())
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	s.lastInputs, s.lastOutputs = in, out
+	return nil
+}
+
+func (s *poolSession) RunWithTensor(name string, t Tensor) error {
+	return s.Run(map[string]Tensor{
+		name: t,
+	})
+}
+
+func (s *poolSession) GetInput(name string) (Tensor, error) {
+	t, ok := s.lastInputs[name]
+	if !ok {
+		return nil, fmt.Errorf("%s is not attached", name)
+	}
+	return t, nil
+}
+
+func (s *poolSession) GetOutput(name string) (Tensor, error) {
+	t, ok := s.lastOutputs[name]
+	if !ok {
+		return nil, fmt.Errorf("%s is not found", name)
+	}
+	return t, nil
+}
+
+func (s *poolSession) Outputs() map[string]Tensor {
+	return s.lastOutputs
+}
+
+// Stop the session, the pool is not stopped.
+func (s *poolSession) Stop() {
+	s.stopped = true
+}
@@ -0,0 +1,169 @@
+package menoh
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+func getPool(size int) (*Pool, error) {
+	runner, err := getRunner()
+	if err != nil {
+		return nil, err
+	}
+	conf := runner.conf
+	runner.Stop()
+	return NewPool(conf, size)
+}
+
+func TestNewPool(t *testing.T) {
+	pool, err := getPool(2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pool.Stop()
+	if pool.Size() != 2 {
+		t.Errorf("pool should have 2 runners, but %d", pool.Size())
+	}
+
+	// fail
+	if _, err := NewPool(pool.Config(), 0); err == nil {
+		t.Error("an error should be occurred with zero size")
+	}
+	if _, err := NewPool(Config{}, 1); err == nil {
+		t.Error("an error should be occurred with invalid config")
+	}
+}
+
+func TestPoolDo(t *testing.T) {
+	pool, err := getPool(2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pool.Stop()
+
+	t.Run("run concurrently", func(t *testing.T) {
+		var wg sync.WaitGroup
+		for i := 0; i < 8; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				err := pool.Do(context.Background(), func(r *Runner) error {
+					input := &FloatTensor{
+						Dims:  []int32{1, 3},
+						Array: []float32{0., 1., 2.},
+					}
+					if err := r.RunWithTensor("input", input); err != nil {
+						return err
+					}
+					actual, err := r.GetOutput("fc2")
+					if err != nil {
+						return err
+					}
+					expected := &FloatTensor{
+						Dims:  []int32{1, 5},
+						Array: []float32{0., 0., 15., 96., 177},
+					}
+					if !tensorEquals(actual, expected) {
+						t.Errorf(`output variable should equal to expected array
+   expected: %v
+   actual  : %v`, expected, actual)
+					}
+					return nil
+				})
+				if err != nil {
+					t.Errorf("the pool should run without error, %v", err)
+				}
+			}()
+		}
+		wg.Wait()
+	})
+
+	t.Run("cancel waiting", func(t *testing.T) {
+		release := make(chan struct{})
+		var wg sync.WaitGroup
+		for i := 0; i < pool.Size(); i++ {
+			wg.Add(1)
+			go pool.Do(context.Background(), func(*Runner) error {
+				wg.Done()
+				<-release
+				return nil
+			})
+		}
+		wg.Wait()
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+		if err := pool.Do(ctx, func(*Runner) error { return nil }); err != context.DeadlineExceeded {
+			t.Errorf("waiting should be canceled by deadline, but %v", err)
+		}
+		close(release)
+	})
+}
+
+func TestPoolSession(t *testing.T) {
+	typeBackend := CustomBackend("pool_test_backend")
+	RegisterBackend(typeBackend, func() Backend { return &fakeBackend{} })
+	defer RegisterBackend(typeBackend, nil)
+	conf := fakeConfig()
+	conf.Backend = typeBackend
+	pool, err := NewPool(conf, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pool.Stop()
+
+	t.Run("run concurrently", func(t *testing.T) {
+		var wg sync.WaitGroup
+		for i := 0; i < 8; i++ {
+			wg.Add(1)
+			go func(v float32) {
+				defer wg.Done()
+				s := pool.NewSession(context.Background())
+				defer s.Stop()
+				out, _ := s.GetOutput("output")
+				if !tensorEquals(out, &FloatTensor{Dims: []int32{1, 3}, Array: make([]float32, 3)}) {
+					t.Errorf("output should be zero before running, but %v", out)
+				}
+				for j := 0; j < 10; j++ {
+					input := &FloatTensor{Dims: []int32{1, 3}, Array: []float32{v, v, v}}
+					if err := s.RunWithTensor("input", input); err != nil {
+						t.Error(err)
+						return
+					}
+					out, err := s.GetOutput("output")
+					if err != nil {
+						t.Error(err)
+						return
+					}
+					expected := &FloatTensor{Dims: []int32{1, 3}, Array: []float32{2 * v, 2 * v, 2 * v}}
+					if !tensorEquals(out, expected) {
+						t.Errorf("output of the session should be %v, but %v", expected, out)
+					}
+				}
+			}(float32(i))
+		}
+		wg.Wait()
+	})
+
+	t.Run("fail", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		s := pool.NewSession(ctx)
+		if err := s.Run(nil); err == nil {
+			t.Error("an error should be occurred without inputs")
+		}
+		cancel()
+		input := &FloatTensor{Dims: []int32{1, 3}, Array: []float32{1, 2, 3}}
+		if err := s.RunWithTensor("input", input); err != context.Canceled {
+			t.Errorf("the error of the context should be returned, but %v", err)
+		}
+		s = pool.NewSession(context.Background())
+		s.Stop()
+		if err := s.RunWithTensor("input", input); err == nil {
+			t.Error("an error should be occurred after stopping the session")
+		}
+		if err := pool.Do(context.Background(), func(*Runner) error { return nil }); err != nil {
+			t.Errorf("the pool should not be stopped by the session, %v", err)
+		}
+	})
+}
@@ -0,0 +1,164 @@
+/*
+Package profile taps every intermediate variable of an ONNX model and reports
+statistics per layer after each run, to find numerical issues like NaN or
+overflow in converted models.
+
+	profiler, err := profile.NewProfiler(conf, os.Stderr)
+	if err != nil {
+		return err
+	}
+	defer profiler.Stop()
+	err = profiler.RunWithTensor("input", input) // dumps stats of all layers
+
+Profiler implements menoh.Inferencer, so it can replace a runner while
+debugging.
+*/
+package profile
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/pfnet-research/go-menoh"
+	"github.com/pfnet-research/go-menoh/tools/onnx"
+)
+
+// Layer is an intermediate variable, an output of a node.
+type Layer struct {
+	Name   string
+	OpType string
+}
+
+// Layers returns outputs of nodes in the graph in order, outputs of Constant
+// nodes are excluded as they are parameters.
+func Layers(model *onnx.ModelProto) []Layer {
+	layers := []Layer{}
+	for _, n := range model.GetGraph().GetNode() {
+		if n.GetOpType() == "Constant" {
+			continue
+		}
+		for _, out := range n.GetOutput() {
+			if out == "" {
+				continue
+			}
+			layers = append(layers, Layer{Name: out, OpType: n.GetOpType()})
+		}
+	}
+	return layers
+}
+
+// Tap returns a copy of the configuration where the layers not configured as
+// outputs are added as internal outputs.
+func Tap(conf menoh.Config, layers []Layer) menoh.Config {
+	configured := map[string]bool{}
+	for _, c := range conf.Outputs {
+		configured[c.Name] = true
+	}
+	outputs := append([]menoh.OutputConfig{}, conf.Outputs...)
+	for _, l := range layers {
+		if configured[l.Name] {
+			continue
+		}
+		outputs = append(outputs, menoh.OutputConfig{
+			Name:         l.Name,
+			Dtype:        menoh.TypeFloat,
+			FromInternal: true,
+		})
+	}
+	conf.Outputs = outputs
+	return conf
+}
+
+// Profiler is a runner with all layers tapped, computing statistics of the
+// layers after each run.
+type Profiler struct {
+	runner *menoh.Runner
+	layers []Layer
+	w      io.Writer
+	stats  []Stats
+}
+
+var _ menoh.Inferencer = (*Profiler)(nil)
+
+// NewProfiler returns Profiler of the model of conf.ONNXModelPath. Statistics
+// are written to w after each run as a table, set nil not to write. Require
+// to call Stop function after the process is done.
+func NewProfiler(conf menoh.Config, w io.Writer) (*Profiler, error) {
+	model, err := onnx.LoadONNXModelFromFile(conf.ONNXModelPath)
+	if err != nil {
+		return nil, err
+	}
+	layers := Layers(model)
+	runner, err := menoh.NewRunner(Tap(conf, layers))
+	if err != nil {
+		return nil, fmt.Errorf("cannot build runner with all layers, %v", err)
+	}
+	return &Profiler{
+		runner: runner,
+		layers: layers,
+		w:      w,
+	}, nil
+}
+
+// Layers returns the tapped layers in order of the graph.
+func (p *Profiler) Layers() []Layer {
+	return p.layers
+}
+
+// Run with the inputs, and computes statistics of the layers.
+func (p *Profiler) Run(inputs map[string]menoh.Tensor) error {
+	if err := p.runner.Run(inputs); err != nil {
+		return err
+	}
+	stats := make([]Stats, 0, len(p.layers))
+	for _, l := range p.layers {
+		t, err := p.runner.GetOutput(l.Name)
+		if err != nil {
+			return err
+		}
+		s, err := Compute(t)
+		if err != nil {
+			return fmt.Errorf("cannot compute stats of %s, %v", l.Name, err)
+		}
+		s.Layer = l
+		stats = append(stats, s)
+	}
+	p.stats = stats
+	if p.w != nil {
+		return WriteStats(p.w, stats)
+	}
+	return nil
+}
+
+// RunWithTensor inputs the tensor with the name, and runs, see Run.
+func (p *Profiler) RunWithTensor(name string, t menoh.Tensor) error {
+	return p.Run(map[string]menoh.Tensor{
+		name: t,
+	})
+}
+
+// Stats returns statistics of the layers of the last run in order of the
+// graph.
+func (p *Profiler) Stats() []Stats {
+	return p.stats
+}
+
+// GetInput returns the input tensor attached to the model.
+func (p *Profiler) GetInput(name string) (menoh.Tensor, error) {
+	return p.runner.GetInput(name)
+}
+
+// GetOutput returns the output tensor, including tapped layers.
+func (p *Profiler) GetOutput(name string) (menoh.Tensor, error) {
+	return p.runner.GetOutput(name)
+}
+
+// Outputs returns all outputs including tapped layers.
+func (p *Profiler) Outputs() map[string]menoh.Tensor {
+	return p.runner.Outputs()
+}
+
+// Stop the runner.
+func (p *Profiler) Stop() {
+	p.runner.Stop()
+}
@@ -0,0 +1,120 @@
+package profile
+
+import (
+	"bytes"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/pfnet-research/go-menoh"
+	"github.com/pfnet-research/go-menoh/menohtest"
+	"github.com/pfnet-research/go-menoh/tools/onnx"
+)
+
+func makeTestModel() *onnx.ModelProto {
+	node := func(opType string, inputs, outputs []string) *onnx.NodeProto {
+		return &onnx.NodeProto{OpType: proto.String(opType), Input: inputs, Output: outputs}
+	}
+	return &onnx.ModelProto{
+		Graph: &onnx.GraphProto{
+			Node: []*onnx.NodeProto{
+				node("Constant", nil, []string{"W"}),
+				node("Gemm", []string{"input", "W"}, []string{"fc1"}),
+				node("Relu", []string{"fc1"}, []string{"relu1"}),
+				node("Softmax", []string{"relu1"}, []string{"prob"}),
+			},
+		},
+	}
+}
+
+func TestLayers(t *testing.T) {
+	expected := []Layer{{"fc1", "Gemm"}, {"relu1", "Relu"}, {"prob", "Softmax"}}
+	if actual := Layers(makeTestModel()); !reflect.DeepEqual(actual, expected) {
+		t.Errorf("layers should be %v, but %v", expected, actual)
+	}
+}
+
+func TestTap(t *testing.T) {
+	conf := menoh.Config{
+		Outputs: []menoh.OutputConfig{{Name: "prob", Dtype: menoh.TypeFloat}},
+	}
+	tapped := Tap(conf, Layers(makeTestModel()))
+	if len(conf.Outputs) != 1 {
+		t.Errorf("original config should not be changed, but %v", conf.Outputs)
+	}
+	expected := []menoh.OutputConfig{
+		{Name: "prob", Dtype: menoh.TypeFloat},
+		{Name: "fc1", Dtype: menoh.TypeFloat, FromInternal: true},
+		{Name: "relu1", Dtype: menoh.TypeFloat, FromInternal: true},
+	}
+	if !reflect.DeepEqual(tapped.Outputs, expected) {
+		t.Errorf("outputs should be %v, but %v", expected, tapped.Outputs)
+	}
+}
+
+func TestProfiler(t *testing.T) {
+	dir, err := ioutil.TempDir("", "go-menoh-test-")
+	if err != nil {
+		t.Fatal("cannot make temporary directory")
+	}
+	defer os.RemoveAll(dir)
+	b, err := proto.Marshal(makeTestModel())
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, "model.onnx")
+	if err := ioutil.WriteFile(path, b, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	fake := menohtest.New()
+	fake.Output("fc1", 1, 2)
+	fake.Output("relu1", 1, 2)
+	fake.Output("prob", 1, 2)
+	nan := float32(math.NaN())
+	fake.Default(map[string][]float32{
+		"fc1":   {-1, 3},
+		"relu1": {0, 3},
+		"prob":  {nan, nan},
+	})
+	backend := fake.Register("profile_test_fake")
+	defer menoh.RegisterBackend(backend, nil)
+
+	buf := &bytes.Buffer{}
+	profiler, err := NewProfiler(menoh.Config{
+		ONNXModelPath: path,
+		Backend:       backend,
+		Inputs:        []menoh.InputConfig{{Name: "input", Dtype: menoh.TypeFloat, Dims: []int32{1, 3}}},
+	}, buf)
+	if err != nil {
+		t.Fatalf("profiler should be created, %v", err)
+	}
+	defer profiler.Stop()
+
+	input := &menoh.FloatTensor{Dims: []int32{1, 3}, Array: []float32{1, 2, 3}}
+	if err := profiler.RunWithTensor("input", input); err != nil {
+		t.Fatal(err)
+	}
+	stats := profiler.Stats()
+	if len(stats) != 3 {
+		t.Fatalf("stats of 3 layers should be computed, but %v", stats)
+	}
+	if s := stats[0]; s.Name != "fc1" || s.Min != -1 || s.Max != 3 || s.Mean != 1 || s.Std != 2 {
+		t.Errorf("stats of fc1 is wrong, %+v", s)
+	}
+	if s := stats[2]; s.NaN != 2 || !s.HasIssue() {
+		t.Errorf("NaN of prob should be counted, %+v", s)
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 4 || !strings.HasPrefix(lines[3], "prob") || !strings.HasSuffix(lines[3], "!") {
+		t.Errorf("stats should be dumped after run, but\n%s", buf)
+	}
+	if _, err := profiler.GetOutput("relu1"); err != nil {
+		t.Errorf("tapped layer should be an output, %v", err)
+	}
+}
@@ -0,0 +1,83 @@
+package profile
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"text/tabwriter"
+
+	"github.com/pfnet-research/go-menoh"
+)
+
+// Stats is statistics of values of a layer. Min, Max, Mean and Std are of
+// finite values, NaN when no value is finite.
+type Stats struct {
+	Layer
+	Size int
+	Min  float64
+	Max  float64
+	Mean float64
+	Std  float64 // population standard deviation
+	NaN  int     // number of NaN
+	Inf  int     // number of +Inf and -Inf
+}
+
+// Compute returns statistics of the tensor, Layer is not set.
+func Compute(t menoh.Tensor) (Stats, error) {
+	a, err := t.FloatArray()
+	if err != nil {
+		return Stats{}, err
+	}
+	s := Stats{
+		Size: len(a),
+		Min:  math.Inf(1),
+		Max:  math.Inf(-1),
+	}
+	n := 0
+	var sum, sumSq float64
+	for _, f := range a {
+		v := float64(f)
+		switch {
+		case math.IsNaN(v):
+			s.NaN++
+			continue
+		case math.IsInf(v, 0):
+			s.Inf++
+			continue
+		}
+		n++
+		sum += v
+		sumSq += v * v
+		s.Min = math.Min(s.Min, v)
+		s.Max = math.Max(s.Max, v)
+	}
+	if n == 0 {
+		s.Min, s.Max, s.Mean, s.Std = math.NaN(), math.NaN(), math.NaN(), math.NaN()
+		return s, nil
+	}
+	s.Mean = sum / float64(n)
+	// clamp negative variance by rounding error
+	s.Std = math.Sqrt(math.Max(sumSq/float64(n)-s.Mean*s.Mean, 0))
+	return s, nil
+}
+
+// HasIssue returns true when the layer has NaN or Inf values.
+func (s Stats) HasIssue() bool {
+	return s.NaN > 0 || s.Inf > 0
+}
+
+// WriteStats writes the statistics as human-readable table, layers having
+// NaN or Inf are marked with "!".
+func WriteStats(w io.Writer, stats []Stats) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "layer\top\tsize\tmin\tmax\tmean\tstd\tnan\tinf\t")
+	for _, s := range stats {
+		mark := ""
+		if s.HasIssue() {
+			mark = "!"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%d\t%.6g\t%.6g\t%.6g\t%.6g\t%d\t%d\t%s\n",
+			s.Name, s.OpType, s.Size, s.Min, s.Max, s.Mean, s.Std, s.NaN, s.Inf, mark)
+	}
+	return tw.Flush()
+}
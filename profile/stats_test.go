@@ -0,0 +1,52 @@
+package profile
+
+import (
+	"bytes"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/pfnet-research/go-menoh"
+)
+
+func TestCompute(t *testing.T) {
+	inf := float32(math.Inf(1))
+	s, err := Compute(&menoh.FloatTensor{
+		Dims:  []int32{6},
+		Array: []float32{1, 2, 3, 4, inf, float32(math.NaN())},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Size != 6 || s.Min != 1 || s.Max != 4 || s.Mean != 2.5 || s.NaN != 1 || s.Inf != 1 {
+		t.Errorf("stats of finite values should be computed, but %+v", s)
+	}
+	if math.Abs(s.Std-math.Sqrt(1.25)) > 1e-9 {
+		t.Errorf("std should be %v, but %v", math.Sqrt(1.25), s.Std)
+	}
+
+	s, err = Compute(&menoh.FloatTensor{Dims: []int32{1}, Array: []float32{inf}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !math.IsNaN(s.Mean) || !math.IsNaN(s.Min) || s.Inf != 1 {
+		t.Errorf("stats without finite value should be NaN, but %+v", s)
+	}
+}
+
+func TestWriteStats(t *testing.T) {
+	buf := &bytes.Buffer{}
+	err := WriteStats(buf, []Stats{
+		{Layer: Layer{"fc1", "Gemm"}, Size: 2, Min: -1, Max: 3, Mean: 1, Std: 2},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 || !strings.HasPrefix(lines[0], "layer") {
+		t.Fatalf("stats should be a table, but\n%s", buf)
+	}
+	if f := strings.Fields(lines[1]); strings.Join(f, " ") != "fc1 Gemm 2 -1 3 1 2 0 0" {
+		t.Errorf("row is wrong, %v", f)
+	}
+}
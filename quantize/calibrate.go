@@ -0,0 +1,221 @@
+package quantize
+
+import (
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/pfnet-research/go-menoh"
+	"github.com/pfnet-research/go-menoh/conformance"
+	"github.com/pfnet-research/go-menoh/profile"
+	"github.com/pfnet-research/go-menoh/tools/onnx"
+)
+
+// Method is a method to decide ranges of activations.
+type Method int
+
+// Methods of calibration.
+const (
+	// MinMax takes min and max values over datasets.
+	MinMax Method = iota
+	// Percentile clips ranges at Options.Percentile of absolute values,
+	// collected by histograms, to ignore outliers.
+	Percentile
+)
+
+// ParseMethod returns Method of the name, "minmax" or "percentile".
+func ParseMethod(name string) (Method, error) {
+	switch name {
+	case "minmax":
+		return MinMax, nil
+	case "percentile":
+		return Percentile, nil
+	default:
+		return MinMax, fmt.Errorf("method '%s' is not supported", name)
+	}
+}
+
+// Range is a range of values of a variable.
+type Range struct {
+	Min float32 `json:"min"`
+	Max float32 `json:"max"`
+}
+
+// Calibrate runs the datasets through runners of the configuration, with
+// FromInternal taps on all layers, and returns ranges of graph inputs and
+// activations. Inputs of conf are configured by the datasets, a runner is
+// rebuilt when input shapes change.
+func Calibrate(conf menoh.Config, datasets []*conformance.Dataset, opts Options) (map[string]Range, error) {
+	opts = opts.withDefaults()
+	model, err := onnx.LoadONNXModelFromFile(conf.ONNXModelPath)
+	if err != nil {
+		return nil, err
+	}
+	layers := profile.Layers(model)
+
+	ranges := map[string]Range{}
+	err = runDatasets(conf, layers, datasets, func(name string, values []float32) {
+		r, ok := ranges[name]
+		for _, v := range values {
+			if math.IsNaN(float64(v)) || math.IsInf(float64(v), 0) {
+				continue
+			}
+			if !ok {
+				r, ok = Range{Min: v, Max: v}, true
+			}
+			if v < r.Min {
+				r.Min = v
+			}
+			if v > r.Max {
+				r.Max = v
+			}
+		}
+		if ok {
+			ranges[name] = r
+		}
+	})
+	if err != nil || opts.Method == MinMax {
+		return ranges, err
+	}
+
+	// histograms of absolute values in [0, max(|min|, |max|)]
+	histograms := map[string][]uint64{}
+	err = runDatasets(conf, layers, datasets, func(name string, values []float32) {
+		limit := absLimit(ranges[name])
+		if limit == 0 {
+			return
+		}
+		h, ok := histograms[name]
+		if !ok {
+			h = make([]uint64, opts.Bins)
+			histograms[name] = h
+		}
+		for _, v := range values {
+			if math.IsNaN(float64(v)) || math.IsInf(float64(v), 0) {
+				continue
+			}
+			b := int(math.Abs(float64(v)) / float64(limit) * float64(opts.Bins))
+			if b >= opts.Bins {
+				b = opts.Bins - 1
+			}
+			h[b]++
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	for name, h := range histograms {
+		threshold := percentile(h, opts.Percentile) * absLimit(ranges[name])
+		r := ranges[name]
+		if r.Min < -threshold {
+			r.Min = -threshold
+		}
+		if r.Max > threshold {
+			r.Max = threshold
+		}
+		ranges[name] = r
+	}
+	return ranges, nil
+}
+
+func absLimit(r Range) float32 {
+	if -r.Min > r.Max {
+		return -r.Min
+	}
+	return r.Max
+}
+
+// percentile returns upper bound of the bin at the percentile, as a ratio to
+// the range of the histogram.
+func percentile(h []uint64, p float64) float32 {
+	var total uint64
+	for _, c := range h {
+		total += c
+	}
+	target := uint64(math.Ceil(float64(total) * p / 100))
+	var cumulative uint64
+	for i, c := range h {
+		cumulative += c
+		if cumulative >= target {
+			return float32(i+1) / float32(len(h))
+		}
+	}
+	return 1
+}
+
+// runDatasets runs the datasets and calls f with values of inputs and all
+// layers.
+func runDatasets(conf menoh.Config, layers []profile.Layer, datasets []*conformance.Dataset,
+	f func(name string, values []float32)) error {
+
+	var runner *menoh.Runner
+	defer func() {
+		if runner != nil {
+			runner.Stop()
+		}
+	}()
+	var inputs []menoh.InputConfig
+	for _, d := range datasets {
+		if c := inputConfigs(d.Inputs); runner == nil || !sameInputs(inputs, c) {
+			if runner != nil {
+				runner.Stop()
+				runner = nil
+			}
+			tapped := conf
+			tapped.Inputs = c
+			r, err := menoh.NewRunner(profile.Tap(tapped, layers))
+			if err != nil {
+				return fmt.Errorf("cannot build runner, %v", err)
+			}
+			runner, inputs = r, c
+		}
+		if err := runner.Run(d.Inputs); err != nil {
+			return fmt.Errorf("cannot run %s, %v", d.Name, err)
+		}
+		for name, t := range d.Inputs {
+			values, err := t.FloatArray()
+			if err != nil {
+				return err
+			}
+			f(name, values)
+		}
+		for name, t := range runner.Outputs() {
+			values, err := t.FloatArray()
+			if err != nil {
+				return err
+			}
+			f(name, values)
+		}
+	}
+	return nil
+}
+
+func inputConfigs(inputs map[string]menoh.Tensor) []menoh.InputConfig {
+	names := make([]string, 0, len(inputs))
+	for name := range inputs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	configs := make([]menoh.InputConfig, len(names))
+	for i, name := range names {
+		configs[i] = menoh.InputConfig{Name: name, Dtype: menoh.TypeFloat, Dims: inputs[name].Shape()}
+	}
+	return configs
+}
+
+func sameInputs(a, b []menoh.InputConfig) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i].Name != b[i].Name || len(a[i].Dims) != len(b[i].Dims) {
+			return false
+		}
+		for j := range a[i].Dims {
+			if a[i].Dims[j] != b[i].Dims[j] {
+				return false
+			}
+		}
+	}
+	return true
+}
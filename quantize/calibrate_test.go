@@ -0,0 +1,83 @@
+package quantize
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/pfnet-research/go-menoh"
+	"github.com/pfnet-research/go-menoh/conformance"
+)
+
+func TestCalibrate(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "go-menoh-test-")
+	if err != nil {
+		t.Fatal("cannot make temporary directory")
+	}
+	defer os.RemoveAll(tempDir)
+	conf := menoh.Config{ONNXModelPath: writeModel(t, tempDir), Backend: menoh.TypeReference}
+
+	ranges, err := Calibrate(conf, makeDatasets(), Options{})
+	if err != nil {
+		t.Fatalf("model should be calibrated, %v", err)
+	}
+	for _, name := range []string{"x", "h", "y"} {
+		if _, ok := ranges[name]; !ok {
+			t.Errorf("range of %s should be collected", name)
+		}
+	}
+	if r := ranges["y"]; r.Min != 0 {
+		t.Errorf("min of Relu output should be 0, but %v", r)
+	}
+
+	// outlier 100 is clipped
+	x := make([]float32, 100)
+	for i := range x {
+		x[i] = float32(i%10) / 10
+	}
+	x[0] = 100
+	datasets := []*conformance.Dataset{{
+		Name:   "test_data_set_0",
+		Inputs: map[string]menoh.Tensor{"x": &menoh.FloatTensor{Dims: []int32{25, 4}, Array: x}},
+	}}
+	ranges, err = Calibrate(conf, datasets, Options{Method: Percentile, Percentile: 99, Bins: 1000})
+	if err != nil {
+		t.Fatalf("model should be calibrated, %v", err)
+	}
+	if r := ranges["x"]; r.Min != 0 || r.Max > 1 {
+		t.Errorf("outlier should be clipped, but %v", r)
+	}
+
+	conf.Backend = menoh.TypeMKLDNN
+	conf.ONNXModelPath = "not_found.onnx"
+	if _, err := Calibrate(conf, datasets, Options{}); err == nil {
+		t.Error("missing model should be error")
+	}
+}
+
+func TestPercentile(t *testing.T) {
+	h := []uint64{50, 40, 9, 0, 1}
+	var testCases = []struct {
+		p        float64
+		expected float32
+	}{
+		{50, 0.2},
+		{90, 0.4},
+		{99, 0.6},
+		{100, 1},
+	}
+	for _, tc := range testCases {
+		if actual := percentile(h, tc.p); actual != tc.expected {
+			t.Errorf("%v percentile should be %v, but %v", tc.p, tc.expected, actual)
+		}
+	}
+}
+
+func TestParseMethod(t *testing.T) {
+	if m, err := ParseMethod("percentile"); err != nil || m != Percentile {
+		t.Errorf("percentile should be parsed, %v", err)
+	}
+	if _, err := ParseMethod("entropy"); err == nil {
+		t.Error("unknown method should be error")
+	}
+}
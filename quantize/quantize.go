@@ -0,0 +1,443 @@
+/*
+Package quantize provides post-training int8 quantization of ONNX models.
+
+Calibrate runs a calibration dataset through runners with FromInternal taps
+on activations and collects their ranges, by min and max values or by
+histograms clipped at a percentile. Quantize rewrites the graph to int8
+weights, per tensor or per channel, with DequantizeLinear, and inserts
+QuantizeLinear and DequantizeLinear on activations input to the quantized
+operators, Conv and Gemm by default.
+
+	result, err := quantize.QuantizeFile(conf, datasets, "model_int8.onnx", quantize.Options{})
+	fmt.Print(result.Report)
+	result.Accuracy.WriteTable(os.Stdout)
+
+Menoh does not run QuantizeLinear and DequantizeLinear, so the quantized
+model cannot be served by Menoh backends. It runs by the reference
+interpreter, menoh.TypeReference, which compares the accuracy with the float
+model, and QuantizeFile requires all operators of the model to be supported
+by the interpreter.
+*/
+package quantize
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/pfnet-research/go-menoh"
+	"github.com/pfnet-research/go-menoh/compare"
+	"github.com/pfnet-research/go-menoh/conformance"
+	"github.com/pfnet-research/go-menoh/external/reference"
+	"github.com/pfnet-research/go-menoh/tools/onnx"
+)
+
+// Options is setup information to quantize.
+type Options struct {
+	Method     Method  // default is MinMax
+	Percentile float64 // percentile of Percentile method, default is 99.99
+	Bins       int     // number of histogram bins of Percentile method, default is 2048
+
+	PerChannel  bool     // scale weights per output channel, requires opset 13
+	WeightsOnly bool     // keep activations float
+	Ops         []string // quantized operator types, default is Conv and Gemm
+}
+
+func (o Options) withDefaults() Options {
+	if o.Percentile <= 0 {
+		o.Percentile = 99.99
+	}
+	if o.Bins <= 0 {
+		o.Bins = 2048
+	}
+	if len(o.Ops) == 0 {
+		o.Ops = []string{"Conv", "Gemm"}
+	}
+	return o
+}
+
+// Report is a result of quantization.
+type Report struct {
+	Weights        []string `json:"weights"`         // weights quantized to int8
+	Activations    []string `json:"activations"`     // activations quantized to uint8
+	FloatBytes     int      `json:"float_bytes"`     // size of the quantized weights in float32
+	QuantizedBytes int      `json:"quantized_bytes"` // size of int8 weights with their scales and zero points
+}
+
+// String returns summary of the report.
+func (r *Report) String() string {
+	b := &bytes.Buffer{}
+	fmt.Fprintf(b, "weights: %d, %d bytes -> %d bytes\n", len(r.Weights), r.FloatBytes, r.QuantizedBytes)
+	fmt.Fprintf(b, "activations: %d\n", len(r.Activations))
+	return b.String()
+}
+
+// opset versions required by QuantizeLinear and DequantizeLinear
+const (
+	perTensorOpset  = 10
+	perChannelOpset = 13
+)
+
+// Quantize rewrites the graph of the model with int8 weights and, unless
+// Options.WeightsOnly, quantized activations whose ranges are given. Weights
+// are symmetric int8, activations are asymmetric uint8. The opset of the
+// default domain is raised to the version required by the quantization
+// operators.
+func Quantize(model *onnx.ModelProto, ranges map[string]Range, opts Options) (*Report, error) {
+	opts = opts.withDefaults()
+	g := model.GetGraph()
+	if g == nil {
+		return nil, fmt.Errorf("model has no graph")
+	}
+	if opts.PerChannel {
+		requireOpset(model, perChannelOpset)
+	} else {
+		requireOpset(model, perTensorOpset)
+	}
+	quantized := map[string]bool{}
+	for _, op := range opts.Ops {
+		quantized[op] = true
+	}
+	inits := map[string]*onnx.TensorProto{}
+	for _, t := range g.GetInitializer() {
+		inits[t.GetName()] = t
+	}
+
+	report := &Report{Weights: []string{}, Activations: []string{}}
+	weightNodes := []*onnx.NodeProto{}
+	nodes := []*onnx.NodeProto{}
+	weights := map[string]bool{}
+	activations := map[string]string{} // activation to dequantized one
+	for _, n := range g.GetNode() {
+		if !quantized[n.GetOpType()] || len(n.Input) < 2 {
+			nodes = append(nodes, n)
+			continue
+		}
+		if w, ok := inits[n.Input[1]]; ok && !weights[w.GetName()] && w.GetDataType() == onnx.TensorProto_FLOAT {
+			dq, err := quantizeWeight(g, w, weightAxis(n, w), opts.PerChannel, report)
+			if err != nil {
+				return nil, fmt.Errorf("cannot quantize %s, %v", w.GetName(), err)
+			}
+			weightNodes = append(weightNodes, dq)
+			weights[w.GetName()] = true
+			report.Weights = append(report.Weights, w.GetName())
+		}
+		x := n.Input[0]
+		if r, ok := ranges[x]; ok && !opts.WeightsOnly && inits[x] == nil {
+			if _, done := activations[x]; !done {
+				qdq, dequantized := quantizeActivation(g, x, r)
+				nodes = append(nodes, qdq...)
+				activations[x] = dequantized
+				report.Activations = append(report.Activations, x)
+			}
+			n.Input[0] = activations[x]
+		}
+		nodes = append(nodes, n)
+	}
+	g.Node = append(weightNodes, nodes...)
+
+	// float weights are replaced by outputs of DequantizeLinear
+	initializers := []*onnx.TensorProto{}
+	for _, t := range g.Initializer {
+		if !weights[t.GetName()] {
+			initializers = append(initializers, t)
+		}
+	}
+	g.Initializer = initializers
+	inputs := []*onnx.ValueInfoProto{}
+	for _, v := range g.Input {
+		if !weights[v.GetName()] {
+			inputs = append(inputs, v)
+		}
+	}
+	g.Input = inputs
+	return report, nil
+}
+
+// requireOpset raises the opset version of the default domain.
+func requireOpset(model *onnx.ModelProto, version int64) {
+	for _, o := range model.OpsetImport {
+		if o.GetDomain() == "" || o.GetDomain() == "ai.onnx" {
+			if o.GetVersion() < version {
+				o.Version = proto.Int64(version)
+			}
+			return
+		}
+	}
+	model.OpsetImport = append(model.OpsetImport, &onnx.OperatorSetIdProto{
+		Domain:  proto.String(""),
+		Version: proto.Int64(version),
+	})
+}
+
+// weightAxis returns the axis of output channels of the weight.
+func weightAxis(n *onnx.NodeProto, w *onnx.TensorProto) int {
+	switch n.GetOpType() {
+	case "Gemm":
+		for _, a := range n.GetAttribute() {
+			if a.GetName() == "transB" && a.GetI() != 0 {
+				return 0
+			}
+		}
+		return 1
+	case "MatMul":
+		return len(w.GetDims()) - 1
+	default:
+		return 0
+	}
+}
+
+// quantizeWeight adds int8 weight, scale and zero point, and returns
+// DequantizeLinear node whose output replaces the weight. Values are rounded
+// in [-127, 127] so that the range is symmetric.
+func quantizeWeight(g *onnx.GraphProto, w *onnx.TensorProto, axis int, perChannel bool,
+	report *Report) (*onnx.NodeProto, error) {
+
+	mt, err := onnx.ConvertToMenohTensor(w)
+	if err != nil {
+		return nil, err
+	}
+	values, err := mt.FloatArray()
+	if err != nil {
+		return nil, err
+	}
+	dims := w.GetDims()
+	channels, inner := 1, len(values)
+	if perChannel && axis >= 0 && axis < len(dims) && dims[axis] > 0 {
+		channels, inner = int(dims[axis]), 1
+		for _, d := range dims[axis+1:] {
+			inner *= int(d)
+		}
+	} else {
+		perChannel = false
+	}
+
+	scales := make([]float32, channels)
+	for i, v := range values {
+		c := i / inner % channels
+		if a := float32(math.Abs(float64(v))); a > scales[c] {
+			scales[c] = a
+		}
+	}
+	for c := range scales {
+		scales[c] /= 127
+		if scales[c] == 0 {
+			scales[c] = 1
+		}
+	}
+	q := make([]byte, len(values))
+	for i, v := range values {
+		q[i] = byte(int8(clamp(math.RoundToEven(float64(v/scales[i/inner%channels])), -127, 127)))
+	}
+
+	name := w.GetName()
+	qName := uniqueName(g, name+"_quantized")
+	sName := uniqueName(g, name+"_scale")
+	zName := uniqueName(g, name+"_zero_point")
+	scaleDims := []int64{}
+	if perChannel {
+		scaleDims = []int64{int64(channels)}
+	}
+	g.Initializer = append(g.Initializer,
+		makeRawTensor(qName, onnx.TensorProto_INT8, dims, q),
+		makeFloatTensor(sName, scaleDims, scales),
+		makeRawTensor(zName, onnx.TensorProto_INT8, scaleDims, make([]byte, channels)))
+	n := &onnx.NodeProto{
+		Name:   proto.String(name + "_DequantizeLinear"),
+		OpType: proto.String("DequantizeLinear"),
+		Input:  []string{qName, sName, zName},
+		Output: []string{name},
+	}
+	if perChannel {
+		n.Attribute = append(n.Attribute, &onnx.AttributeProto{
+			Name: proto.String("axis"),
+			Type: onnx.AttributeProto_INT.Enum(),
+			I:    proto.Int64(int64(axis)),
+		})
+	}
+	report.FloatBytes += 4 * len(values)
+	report.QuantizedBytes += len(values) + 5*channels
+	return n, nil
+}
+
+// quantizeActivation adds uint8 scale and zero point of the range, and
+// returns QuantizeLinear and DequantizeLinear nodes and the name of the
+// dequantized activation. The range is extended to include zero.
+func quantizeActivation(g *onnx.GraphProto, x string, r Range) ([]*onnx.NodeProto, string) {
+	lower := math.Min(float64(r.Min), 0)
+	upper := math.Max(float64(r.Max), 0)
+	scale := float32((upper - lower) / 255)
+	if scale == 0 {
+		scale = 1
+	}
+	zeroPoint := byte(clamp(math.RoundToEven(-lower/float64(scale)), 0, 255))
+
+	sName := uniqueName(g, x+"_scale")
+	zName := uniqueName(g, x+"_zero_point")
+	g.Initializer = append(g.Initializer,
+		makeFloatTensor(sName, []int64{}, []float32{scale}),
+		makeRawTensor(zName, onnx.TensorProto_UINT8, []int64{}, []byte{zeroPoint}))
+	quantized := uniqueName(g, x+"_quantized")
+	dequantized := uniqueName(g, x+"_dequantized")
+	return []*onnx.NodeProto{
+		{
+			Name:   proto.String(x + "_QuantizeLinear"),
+			OpType: proto.String("QuantizeLinear"),
+			Input:  []string{x, sName, zName},
+			Output: []string{quantized},
+		},
+		{
+			Name:   proto.String(x + "_DequantizeLinear"),
+			OpType: proto.String("DequantizeLinear"),
+			Input:  []string{quantized, sName, zName},
+			Output: []string{dequantized},
+		},
+	}, dequantized
+}
+
+func clamp(v, lower, upper float64) float64 {
+	return math.Max(lower, math.Min(upper, v))
+}
+
+func makeFloatTensor(name string, dims []int64, values []float32) *onnx.TensorProto {
+	return &onnx.TensorProto{
+		Name:      proto.String(name),
+		DataType:  onnx.TensorProto_FLOAT.Enum(),
+		Dims:      dims,
+		FloatData: values,
+	}
+}
+
+func makeRawTensor(name string, dtype onnx.TensorProto_DataType, dims []int64, raw []byte) *onnx.TensorProto {
+	return &onnx.TensorProto{
+		Name:     proto.String(name),
+		DataType: dtype.Enum(),
+		Dims:     dims,
+		RawData:  raw,
+	}
+}
+
+// uniqueName returns the name, or the name with a suffix not used by any
+// variable of the graph.
+func uniqueName(g *onnx.GraphProto, name string) string {
+	used := map[string]bool{}
+	for _, t := range g.GetInitializer() {
+		used[t.GetName()] = true
+	}
+	for _, v := range g.GetInput() {
+		used[v.GetName()] = true
+	}
+	for _, v := range g.GetValueInfo() {
+		used[v.GetName()] = true
+	}
+	for _, n := range g.GetNode() {
+		for _, out := range n.GetOutput() {
+			used[out] = true
+		}
+	}
+	candidate := name
+	for i := 1; used[candidate]; i++ {
+		candidate = fmt.Sprintf("%s_%d", name, i)
+	}
+	return candidate
+}
+
+// Result is a result of QuantizeFile.
+type Result struct {
+	Ranges   map[string]Range
+	Report   *Report
+	Accuracy *compare.Report // outputs of the quantized model compared with the float model
+}
+
+// QuantizeFile calibrates the model of conf.ONNXModelPath with the datasets,
+// saves the quantized model to outPath, and compares outputs of the
+// quantized model with the float model on the datasets, see Evaluate. It
+// fails before calibrating if the reference interpreter does not support
+// operators of the model.
+func QuantizeFile(conf menoh.Config, datasets []*conformance.Dataset, outPath string, opts Options) (
+	*Result, error) {
+
+	model, err := onnx.LoadONNXModelFromFile(conf.ONNXModelPath)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkReference(model); err != nil {
+		return nil, err
+	}
+	ranges, err := Calibrate(conf, datasets, opts)
+	if err != nil {
+		return nil, fmt.Errorf("cannot calibrate, %v", err)
+	}
+	outputs := outputNames(conf, model)
+	report, err := Quantize(model, ranges, opts)
+	if err != nil {
+		return nil, err
+	}
+	b, err := proto.Marshal(model)
+	if err != nil {
+		return nil, fmt.Errorf("cannot convert ONNX model to binary, %v", err)
+	}
+	if err := ioutil.WriteFile(outPath, b, 0644); err != nil {
+		return nil, fmt.Errorf("cannot save '%s', %v", outPath, err)
+	}
+	accuracy, err := Evaluate(conf, outPath, datasets, outputs)
+	if err != nil {
+		return nil, fmt.Errorf("cannot compare accuracy, %v", err)
+	}
+	return &Result{Ranges: ranges, Report: report, Accuracy: accuracy}, nil
+}
+
+// checkReference checks the reference interpreter supports all operators of
+// the model, to run the quantized model.
+func checkReference(model *onnx.ModelProto) error {
+	unsupported := map[string]bool{}
+	for _, n := range model.GetGraph().GetNode() {
+		if !reference.IsSupported(n.GetOpType()) {
+			unsupported[n.GetOpType()] = true
+		}
+	}
+	if len(unsupported) == 0 {
+		return nil
+	}
+	ops := make([]string, 0, len(unsupported))
+	for op := range unsupported {
+		ops = append(ops, op)
+	}
+	sort.Strings(ops)
+	return fmt.Errorf("cannot run quantized model by the reference interpreter, unsupported operators %s",
+		strings.Join(ops, ", "))
+}
+
+// Evaluate compares the outputs of the quantized model run by the reference
+// interpreter with the float model run by conf.Backend on the datasets.
+func Evaluate(conf menoh.Config, quantizedPath string, datasets []*conformance.Dataset, outputs []string) (
+	*compare.Report, error) {
+
+	quantized := conf
+	quantized.ONNXModelPath = quantizedPath
+	quantized.Backend = menoh.TypeReference
+	quantized.BackendConfig = ""
+	return compare.Run(&compare.RunnerTarget{Config: conf}, &compare.RunnerTarget{Config: quantized},
+		datasets, compare.Options{Outputs: outputs})
+}
+
+// outputNames returns outputs configured in conf, or graph outputs.
+func outputNames(conf menoh.Config, model *onnx.ModelProto) []string {
+	names := []string{}
+	for _, c := range conf.Outputs {
+		if !c.FromInternal {
+			names = append(names, c.Name)
+		}
+	}
+	if len(names) > 0 {
+		return names
+	}
+	for _, v := range model.GetGraph().GetOutput() {
+		names = append(names, v.GetName())
+	}
+	return names
+}
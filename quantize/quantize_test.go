@@ -0,0 +1,226 @@
+package quantize
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/pfnet-research/go-menoh"
+	"github.com/pfnet-research/go-menoh/conformance"
+	"github.com/pfnet-research/go-menoh/tools/onnx"
+)
+
+// makeModel returns Gemm with W [3, 4] followed by Relu.
+func makeModel() *onnx.ModelProto {
+	return &onnx.ModelProto{
+		OpsetImport: []*onnx.OperatorSetIdProto{{Domain: proto.String(""), Version: proto.Int64(8)}},
+		Graph: &onnx.GraphProto{
+			Node: []*onnx.NodeProto{
+				{
+					OpType: proto.String("Gemm"),
+					Input:  []string{"x", "W", "b"},
+					Output: []string{"h"},
+					Attribute: []*onnx.AttributeProto{
+						{Name: proto.String("transB"), I: proto.Int64(1), Type: onnx.AttributeProto_INT.Enum()},
+					},
+				},
+				{OpType: proto.String("Relu"), Input: []string{"h"}, Output: []string{"y"}},
+			},
+			Initializer: []*onnx.TensorProto{
+				makeFloatTensor("W", []int64{3, 4}, []float32{
+					1, -2, 0.5, 0,
+					0.1, 0.2, -0.3, 0.4,
+					-1, 1, -1, 1,
+				}),
+				makeFloatTensor("b", []int64{3}, []float32{0.5, 0, -0.5}),
+			},
+			Input:  []*onnx.ValueInfoProto{{Name: proto.String("x")}, {Name: proto.String("W")}},
+			Output: []*onnx.ValueInfoProto{{Name: proto.String("y")}},
+		},
+	}
+}
+
+func findNode(g *onnx.GraphProto, opType string) *onnx.NodeProto {
+	for _, n := range g.GetNode() {
+		if n.GetOpType() == opType {
+			return n
+		}
+	}
+	return nil
+}
+
+func findInitializer(g *onnx.GraphProto, name string) *onnx.TensorProto {
+	for _, t := range g.GetInitializer() {
+		if t.GetName() == name {
+			return t
+		}
+	}
+	return nil
+}
+
+func TestQuantize(t *testing.T) {
+	model := makeModel()
+	report, err := Quantize(model, map[string]Range{"x": {Min: -1, Max: 3}, "h": {Min: 0, Max: 1}}, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Weights) != 1 || report.Weights[0] != "W" {
+		t.Errorf("W should be quantized, but %v", report.Weights)
+	}
+	// h is not an input of quantized operators
+	if len(report.Activations) != 1 || report.Activations[0] != "x" {
+		t.Errorf("x should be quantized, but %v", report.Activations)
+	}
+	if report.FloatBytes != 48 || report.QuantizedBytes != 17 {
+		t.Errorf("sizes should be 48 -> 17 bytes, but %d -> %d", report.FloatBytes, report.QuantizedBytes)
+	}
+	if v := model.OpsetImport[0].GetVersion(); v != perTensorOpset {
+		t.Errorf("opset should be raised to %d, but %d", perTensorOpset, v)
+	}
+
+	g := model.Graph
+	ops := []string{}
+	for _, n := range g.Node {
+		ops = append(ops, n.GetOpType())
+	}
+	expected := []string{"DequantizeLinear", "QuantizeLinear", "DequantizeLinear", "Gemm", "Relu"}
+	if len(ops) != len(expected) {
+		t.Fatalf("nodes should be %v, but %v", expected, ops)
+	}
+	for i := range ops {
+		if ops[i] != expected[i] {
+			t.Fatalf("nodes should be %v, but %v", expected, ops)
+		}
+	}
+	if g.Node[0].Output[0] != "W" || g.Node[3].Input[0] != "x_dequantized" {
+		t.Errorf("weight and activation should be dequantized, %v, %v", g.Node[0], g.Node[3])
+	}
+	if findInitializer(g, "W") != nil || len(g.Input) != 1 {
+		t.Error("float weight should be removed")
+	}
+	q := findInitializer(g, "W_quantized")
+	if q == nil || q.GetDataType() != onnx.TensorProto_INT8 || len(q.RawData) != 12 {
+		t.Fatalf("int8 weight should be added, but %v", q)
+	}
+	// scale is 2 / 127
+	if int8(q.RawData[1]) != -127 || int8(q.RawData[0]) != 64 {
+		t.Errorf("weight should be quantized symmetrically, but %v", q.RawData)
+	}
+	if s := findInitializer(g, "x_scale"); s == nil || s.FloatData[0] != float32(4)/255 {
+		t.Errorf("scale of activation should be of the range, but %v", s)
+	}
+	if z := findInitializer(g, "x_zero_point"); z == nil || z.RawData[0] != 64 {
+		t.Errorf("zero point of activation should be of the range, but %v", z)
+	}
+}
+
+func TestQuantizePerChannel(t *testing.T) {
+	model := makeModel()
+	report, err := Quantize(model, nil, Options{PerChannel: true, WeightsOnly: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Activations) != 0 {
+		t.Errorf("activations should not be quantized, but %v", report.Activations)
+	}
+	if v := model.OpsetImport[0].GetVersion(); v != perChannelOpset {
+		t.Errorf("opset should be raised to %d, but %d", perChannelOpset, v)
+	}
+	g := model.Graph
+	s := findInitializer(g, "W_scale")
+	if s == nil || len(s.Dims) != 1 || s.Dims[0] != 3 {
+		t.Fatalf("scales should be per output channel, but %v", s)
+	}
+	if s.FloatData[0] != float32(2)/127 || s.FloatData[1] != float32(0.4)/127 {
+		t.Errorf("scales should be of max values of channels, but %v", s.FloatData)
+	}
+	dq := findNode(g, "DequantizeLinear")
+	if dq == nil || len(dq.Attribute) != 1 || dq.Attribute[0].GetI() != 0 {
+		t.Errorf("axis of transposed weight should be 0, but %v", dq)
+	}
+}
+
+func makeDatasets() []*conformance.Dataset {
+	datasets := []*conformance.Dataset{}
+	for i, x := range [][]float32{{0, 1, 2, 3}, {-1, 0.5, 1, 2}, {2, 2, -1, 0.5}} {
+		datasets = append(datasets, &conformance.Dataset{
+			Name:   fmt.Sprintf("test_data_set_%d", i),
+			Inputs: map[string]menoh.Tensor{"x": &menoh.FloatTensor{Dims: []int32{1, 4}, Array: x}},
+		})
+	}
+	return datasets
+}
+
+func writeModel(t *testing.T, dir string) string {
+	b, err := proto.Marshal(makeModel())
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, "gemm.onnx")
+	if err := ioutil.WriteFile(path, b, 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestQuantizeFile(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "go-menoh-test-")
+	if err != nil {
+		t.Fatal("cannot make temporary directory")
+	}
+	defer os.RemoveAll(tempDir)
+	conf := menoh.Config{ONNXModelPath: writeModel(t, tempDir), Backend: menoh.TypeReference}
+	outPath := filepath.Join(tempDir, "gemm_int8.onnx")
+
+	for _, opts := range []Options{{}, {PerChannel: true}} {
+		result, err := QuantizeFile(conf, makeDatasets(), outPath, opts)
+		if err != nil {
+			t.Fatalf("model should be quantized with %+v, %v", opts, err)
+		}
+		if _, err := os.Stat(outPath); err != nil {
+			t.Errorf("quantized model should be saved, %v", err)
+		}
+		if r := result.Ranges["x"]; r.Min != -1 || r.Max != 3 {
+			t.Errorf("range of input should be calibrated, but %v", r)
+		}
+		accuracy := result.Accuracy
+		if accuracy.Samples != 3 || len(accuracy.Outputs) != 1 || accuracy.Outputs[0].Name != "y" {
+			t.Fatalf("graph output should be compared, %+v", accuracy)
+		}
+		if s := accuracy.Outputs[0]; s.MaxAbsError == 0 || s.MaxAbsError > 0.1 || s.MinCosine < 0.99 {
+			t.Errorf("quantized model should be close to the float model, %+v", s)
+		}
+	}
+}
+
+func TestQuantizeFileUnsupported(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "go-menoh-test-")
+	if err != nil {
+		t.Fatal("cannot make temporary directory")
+	}
+	defer os.RemoveAll(tempDir)
+	model := makeModel()
+	model.Graph.Node[1].OpType = proto.String("Tanh")
+	b, err := proto.Marshal(model)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(tempDir, "tanh.onnx")
+	if err := ioutil.WriteFile(path, b, 0644); err != nil {
+		t.Fatal(err)
+	}
+	conf := menoh.Config{ONNXModelPath: path, Backend: menoh.TypeReference}
+	outPath := filepath.Join(tempDir, "tanh_int8.onnx")
+
+	_, err = QuantizeFile(conf, makeDatasets(), outPath, Options{})
+	if err == nil || !strings.Contains(err.Error(), "Tanh") {
+		t.Errorf("unsupported operator should be reported, %v", err)
+	}
+	if _, err := os.Stat(outPath); !os.IsNotExist(err) {
+		t.Errorf("quantized model should not be saved, %v", err)
+	}
+}
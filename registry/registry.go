@@ -0,0 +1,447 @@
+/*
+Package registry manages multiple versions of multiple ONNX models placed in
+a directory tree:
+
+	root
+	  |- mnist
+	  |    |- 1
+	  |    |    |- model.onnx
+	  |    |    |- config.json
+	  |    |- 2
+	  |         ...
+	  |- vgg16
+	       ...
+
+Version directories are named by positive integers. The configuration file,
+config.json, config.yaml or config.yml, describes inputs and outputs of the
+model in the format of menoh.LoadConfig, like
+
+	backend: mkldnn
+	inputs:
+	  - name: input
+	    dtype: float32
+	    dims: [1, 3]
+	outputs:
+	  - name: fc2
+	    dtype: float32
+
+The model path in the configuration is ignored, model.onnx in the version
+directory is always used.
+
+Registry watches the tree, loads new versions and retires removed versions.
+Retired versions are stopped after in-flight runs are drained.
+*/
+package registry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/pfnet-research/go-menoh"
+)
+
+// DefaultVersion is a version alias to the default version of a model, the
+// latest one unless set by SetDefaultVersion.
+const DefaultVersion int64 = 0
+
+const modelFileName = "model.onnx"
+
+var errStopped = errors.New("registry is stopped")
+
+var defaultConfigFileNames = []string{"config.json", "config.yaml", "config.yml"}
+
+// Options is setup information of Registry.
+type Options struct {
+	PoolSize       int           // number of runners for each version, default is 1
+	PollInterval   time.Duration // interval to scan the tree on watching, default is 10 seconds
+	ConfigFileName string        // default is the first found of config.json, config.yaml and config.yml
+
+	// OnError is called when a version cannot be loaded, or with version 0
+	// when the directory of a model cannot be read, optional.
+	OnError func(name string, version int64, err error)
+}
+
+// Registry holds loaded model versions.
+type Registry struct {
+	root string
+	opts Options
+
+	reloadMu sync.Mutex // serializes Reload and Stop
+	stopped  bool       // guarded by reloadMu
+	retiring sync.WaitGroup
+
+	mu       sync.RWMutex
+	models   map[string]map[int64]*version
+	defaults map[string]int64
+	failed   map[string]time.Time // model directory -> modified time on failure
+}
+
+type version struct {
+	name     string
+	version  int64
+	dir      string
+	pool     menoh.InferencerPool
+	inFlight sync.WaitGroup
+}
+
+// New returns Registry loading models under the root directory. Require to
+// call Stop function after the process is done.
+func New(root string, opts Options) (*Registry, error) {
+	if opts.PoolSize <= 0 {
+		opts.PoolSize = 1
+	}
+	if opts.PollInterval <= 0 {
+		opts.PollInterval = 10 * time.Second
+	}
+	r := &Registry{
+		root:     root,
+		opts:     opts,
+		models:   map[string]map[int64]*version{},
+		defaults: map[string]int64{},
+		failed:   map[string]time.Time{},
+	}
+	if err := r.Reload(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// configFileNames returns candidates of the configuration file name.
+func (o Options) configFileNames() []string {
+	if o.ConfigFileName != "" {
+		return []string{o.ConfigFileName}
+	}
+	return defaultConfigFileNames
+}
+
+// findConfig returns the path of the first existing configuration file in
+// the directory, or empty string if none exists. The file is loaded by
+// menoh.LoadConfig.
+func findConfig(dir string, configFileNames []string) string {
+	for _, name := range configFileNames {
+		if path := filepath.Join(dir, name); exists(path) {
+			return path
+		}
+	}
+	return ""
+}
+
+// scan returns version directories keyed by model name and version, and
+// errors of model directories which cannot be read, keyed by model name.
+func scan(root string, configFileNames []string) (map[string]map[int64]string, map[string]error, error) {
+	modelDirs, err := ioutil.ReadDir(root)
+	if err != nil {
+		return nil, nil, fmt.Errorf("cannot read '%s', %v", root, err)
+	}
+	found := map[string]map[int64]string{}
+	unreadable := map[string]error{}
+	for _, m := range modelDirs {
+		if !m.IsDir() {
+			continue
+		}
+		dir := filepath.Join(root, m.Name())
+		versionDirs, err := ioutil.ReadDir(dir)
+		if err != nil {
+			unreadable[m.Name()] = fmt.Errorf("cannot read '%s', %v", dir, err)
+			continue
+		}
+		for _, v := range versionDirs {
+			ver, err := strconv.ParseInt(v.Name(), 10, 64)
+			if !v.IsDir() || err != nil || ver <= 0 {
+				continue
+			}
+			dir := filepath.Join(root, m.Name(), v.Name())
+			if !exists(filepath.Join(dir, modelFileName)) ||
+				findConfig(dir, configFileNames) == "" {
+				continue
+			}
+			if found[m.Name()] == nil {
+				found[m.Name()] = map[int64]string{}
+			}
+			found[m.Name()][ver] = dir
+		}
+	}
+	return found, unreadable, nil
+}
+
+func exists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+// Reload scans the tree once, loads new versions and retires removed ones.
+// Reloads are serialized, so a version is loaded once even if Watch and a
+// manual Reload run at the same time. A model whose directory cannot be read
+// is reported to OnError and keeps loaded versions.
+func (r *Registry) Reload() error {
+	r.reloadMu.Lock()
+	defer r.reloadMu.Unlock()
+	if r.stopped {
+		return errStopped
+	}
+	found, unreadable, err := scan(r.root, r.opts.configFileNames())
+	if err != nil {
+		return err
+	}
+	for name, err := range unreadable {
+		if r.opts.OnError != nil {
+			r.opts.OnError(name, 0, err)
+		}
+	}
+
+	// build new versions without lock, building takes time
+	loaded := []*version{}
+	for name, versions := range found {
+		for ver, dir := range versions {
+			if r.has(name, ver) || !r.shouldLoad(dir) {
+				continue
+			}
+			v, err := r.load(name, ver, dir)
+			if err != nil {
+				r.mu.Lock()
+				r.failed[dir] = modTime(dir)
+				r.mu.Unlock()
+				if r.opts.OnError != nil {
+					r.opts.OnError(name, ver, err)
+				}
+				continue
+			}
+			loaded = append(loaded, v)
+		}
+	}
+
+	r.mu.Lock()
+	for _, v := range loaded {
+		if r.models[v.name] == nil {
+			r.models[v.name] = map[int64]*version{}
+		}
+		r.models[v.name][v.version] = v
+		delete(r.failed, v.dir)
+	}
+	retired := []*version{}
+	for name, versions := range r.models {
+		if _, ok := unreadable[name]; ok {
+			continue
+		}
+		for ver, v := range versions {
+			if _, ok := found[name][ver]; ok {
+				continue
+			}
+			delete(versions, ver)
+			retired = append(retired, v)
+		}
+		if len(versions) == 0 {
+			delete(r.models, name)
+		}
+		if def, ok := r.defaults[name]; ok && versions[def] == nil {
+			delete(r.defaults, name)
+		}
+	}
+	r.mu.Unlock()
+
+	for _, v := range retired {
+		r.retiring.Add(1)
+		go func(v *version) {
+			defer r.retiring.Done()
+			v.retire()
+		}(v)
+	}
+	return nil
+}
+
+func (r *Registry) has(name string, ver int64) bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	_, ok := r.models[name][ver]
+	return ok
+}
+
+// shouldLoad returns false when loading the directory failed and files are
+// not modified after that.
+func (r *Registry) shouldLoad(dir string) bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	t, ok := r.failed[dir]
+	return !ok || modTime(dir).After(t)
+}
+
+// modTime returns the latest modified time of files in the directory.
+func modTime(dir string) time.Time {
+	var latest time.Time
+	files, _ := ioutil.ReadDir(dir)
+	for _, f := range files {
+		if f.ModTime().After(latest) {
+			latest = f.ModTime()
+		}
+	}
+	return latest
+}
+
+func (r *Registry) load(name string, ver int64, dir string) (*version, error) {
+	conf, err := menoh.LoadConfig(findConfig(dir, r.opts.configFileNames()))
+	if err != nil {
+		return nil, err
+	}
+	conf.ONNXModelPath = filepath.Join(dir, modelFileName)
+	if conf.Name == "" {
+		conf.Name = name
+	}
+	pool, err := menoh.NewPool(conf, r.opts.PoolSize)
+	if err != nil {
+		return nil, fmt.Errorf("cannot build runners of '%s' version %d, %v", name, ver, err)
+	}
+	return &version{
+		name:    name,
+		version: ver,
+		dir:     dir,
+		pool:    pool,
+	}, nil
+}
+
+// retire waits for in-flight runs and stops runners.
+func (v *version) retire() {
+	v.inFlight.Wait()
+	v.pool.Stop()
+}
+
+// Watch reloads the tree every poll interval until ctx is done or the
+// registry is stopped. Errors on scanning are passed to OnError with empty
+// name.
+func (r *Registry) Watch(ctx context.Context) {
+	ticker := time.NewTicker(r.opts.PollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			err := r.Reload()
+			if err == errStopped {
+				return
+			}
+			if err != nil && r.opts.OnError != nil {
+				r.opts.OnError("", 0, err)
+			}
+		}
+	}
+}
+
+// Models returns names of loaded models.
+func (r *Registry) Models() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	names := make([]string, 0, len(r.models))
+	for name := range r.models {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Versions returns loaded versions of the model in ascending order.
+func (r *Registry) Versions(name string) []int64 {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	versions := make([]int64, 0, len(r.models[name]))
+	for ver := range r.models[name] {
+		versions = append(versions, ver)
+	}
+	sort.Slice(versions, func(i, j int) bool { return versions[i] < versions[j] })
+	return versions
+}
+
+// SetDefaultVersion sets the version aliased by DefaultVersion. The alias
+// is reset to the latest when the version is retired.
+func (r *Registry) SetDefaultVersion(name string, ver int64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.models[name][ver]; !ok {
+		return fmt.Errorf("model '%s' version %d is not loaded", name, ver)
+	}
+	r.defaults[name] = ver
+	return nil
+}
+
+// resolve returns the version, DefaultVersion is resolved to the alias or the
+// latest version. The caller must hold the lock.
+func (r *Registry) resolve(name string, ver int64) (*version, error) {
+	versions, ok := r.models[name]
+	if !ok {
+		return nil, fmt.Errorf("model '%s' is not found", name)
+	}
+	if ver == DefaultVersion {
+		if def, ok := r.defaults[name]; ok {
+			ver = def
+		} else {
+			for v := range versions {
+				if v > ver {
+					ver = v
+				}
+			}
+		}
+	}
+	v, ok := versions[ver]
+	if !ok {
+		return nil, fmt.Errorf("model '%s' version %d is not found", name, ver)
+	}
+	return v, nil
+}
+
+// Config returns configuration of the model version.
+func (r *Registry) Config(name string, ver int64) (menoh.Config, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	v, err := r.resolve(name, ver)
+	if err != nil {
+		return menoh.Config{}, err
+	}
+	return v.pool.Config(), nil
+}
+
+// Do calls f with a session of the model version, whose runs wait for an
+// idle runner until ctx is done. The version is not stopped until f returns
+// even if it is retired. The session is valid only in f.
+func (r *Registry) Do(ctx context.Context, name string, ver int64, f func(menoh.Inferencer) error) error {
+	r.mu.RLock()
+	v, err := r.resolve(name, ver)
+	if err != nil {
+		r.mu.RUnlock()
+		return err
+	}
+	v.inFlight.Add(1)
+	r.mu.RUnlock()
+	defer v.inFlight.Done()
+	s := v.pool.NewSession(ctx)
+	defer s.Stop()
+	return f(s)
+}
+
+// Stop all versions, waiting for in-flight runs and versions retired by
+// Reload. Reload fails after stopping.
+func (r *Registry) Stop() {
+	r.reloadMu.Lock()
+	defer r.reloadMu.Unlock()
+	r.stopped = true
+	r.mu.Lock()
+	retired := []*version{}
+	for _, versions := range r.models {
+		for _, v := range versions {
+			retired = append(retired, v)
+		}
+	}
+	r.models = map[string]map[int64]*version{}
+	r.defaults = map[string]int64{}
+	r.mu.Unlock()
+	for _, v := range retired {
+		v.retire()
+	}
+	r.retiring.Wait()
+}
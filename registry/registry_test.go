@@ -0,0 +1,337 @@
+package registry
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pfnet-research/go-menoh"
+	"github.com/pfnet-research/go-menoh/menohtest"
+)
+
+const testConfig = `{
+  "backend": "mkldnn",
+  "inputs": [{"name": "input", "dtype": "float32", "dims": [1, 3]}],
+  "outputs": [
+    {"name": "fc1", "dtype": "float32", "from_internal": true},
+    {"name": "fc2", "dtype": "float32"}
+  ]
+}`
+
+// putVersion makes root/name/version directory with the model and the
+// config file.
+func putVersion(t *testing.T, root, name, ver string, model []byte) {
+	dir := filepath.Join(root, name, ver)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, modelFileName), model, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "config.json"), []byte(testConfig), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestScan(t *testing.T) {
+	root, err := ioutil.TempDir("", "go-menoh-test-")
+	if err != nil {
+		t.Fatal("cannot make temporary directory")
+	}
+	defer os.RemoveAll(root)
+	putVersion(t, root, "mlp", "1", []byte("dummy"))
+	putVersion(t, root, "mlp", "10", []byte("dummy"))
+	putVersion(t, root, "mlp", "latest", []byte("dummy"))
+	putVersion(t, root, "mlp", "0", []byte("dummy"))
+	if err := os.MkdirAll(filepath.Join(root, "empty", "1"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	yamlDir := filepath.Join(root, "yaml", "1")
+	if err := os.MkdirAll(yamlDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{modelFileName, "config.yaml"} {
+		if err := ioutil.WriteFile(filepath.Join(yamlDir, name), []byte("dummy"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	found, unreadable, err := scan(root, defaultConfigFileNames)
+	if err != nil {
+		t.Fatalf("tree should be scanned, %v", err)
+	}
+	if len(unreadable) != 0 {
+		t.Errorf("all model directories should be read, but %v", unreadable)
+	}
+	if len(found) != 2 || len(found["mlp"]) != 2 || len(found["yaml"]) != 1 {
+		t.Fatalf("version 1 and 10 of mlp and version 1 of yaml should be found, but %v", found)
+	}
+	if found["mlp"][10] != filepath.Join(root, "mlp", "10") {
+		t.Errorf("version 10 should be placed on its directory, but %v", found["mlp"][10])
+	}
+
+	if found, _, _ := scan(root, []string{"model.json"}); len(found) != 0 {
+		t.Errorf("no version should be found with other config file name, but %v", found)
+	}
+	if _, _, err := scan(filepath.Join(root, "dummy"), defaultConfigFileNames); err == nil {
+		t.Error("an error should be occurred with not existed root")
+	}
+
+	t.Run("unreadable model directory", func(t *testing.T) {
+		locked := filepath.Join(root, "locked")
+		if err := os.MkdirAll(locked, 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chmod(locked, 0); err != nil {
+			t.Fatal(err)
+		}
+		defer os.Chmod(locked, 0755)
+		if _, err := ioutil.ReadDir(locked); err == nil {
+			t.Skip("permissions are not enforced, like by root")
+		}
+		found, unreadable, err := scan(root, defaultConfigFileNames)
+		if err != nil {
+			t.Fatalf("tree should be scanned skipping unreadable directory, %v", err)
+		}
+		if len(found) != 2 || unreadable["locked"] == nil {
+			t.Errorf("locked model should be reported, but %v, %v", found, unreadable)
+		}
+	})
+}
+
+func TestRegistry(t *testing.T) {
+	model, err := ioutil.ReadFile(filepath.Join("..", "test_data", "MLP.onnx"))
+	if err != nil {
+		t.Fatal("ONNX file is not found, please put the file to test_data/MLP.onnx")
+	}
+	root, err := ioutil.TempDir("", "go-menoh-test-")
+	if err != nil {
+		t.Fatal("cannot make temporary directory")
+	}
+	defer os.RemoveAll(root)
+	putVersion(t, root, "mlp", "1", model)
+	putVersion(t, root, "broken", "1", []byte("dummy"))
+
+	failures := map[string]int{}
+	r, err := New(root, Options{
+		PoolSize: 2,
+		OnError: func(name string, ver int64, err error) {
+			failures[name]++
+		},
+	})
+	if err != nil {
+		t.Fatalf("registry should be created, %v", err)
+	}
+	defer r.Stop()
+
+	if models := r.Models(); len(models) != 1 || models[0] != "mlp" {
+		t.Errorf("only mlp should be loaded, but %v", models)
+	}
+	if failures["broken"] != 1 {
+		t.Errorf("broken model should be reported once, but %d", failures["broken"])
+	}
+
+	run := func(ver int64) error {
+		return r.Do(context.Background(), "mlp", ver, func(inf menoh.Inferencer) error {
+			return inf.RunWithTensor("input", &menoh.FloatTensor{
+				Dims:  []int32{1, 3},
+				Array: []float32{0., 1., 2.},
+			})
+		})
+	}
+
+	t.Run("add new version", func(t *testing.T) {
+		putVersion(t, root, "mlp", "2", model)
+		if err := r.Reload(); err != nil {
+			t.Fatal(err)
+		}
+		if versions := r.Versions("mlp"); len(versions) != 2 || versions[1] != 2 {
+			t.Errorf("versions should be [1 2], but %v", versions)
+		}
+		if failures["broken"] != 1 {
+			t.Errorf("not modified broken model should not be loaded again")
+		}
+		if err := run(DefaultVersion); err != nil {
+			t.Errorf("default version should run, %v", err)
+		}
+	})
+
+	t.Run("pin default version", func(t *testing.T) {
+		if err := r.SetDefaultVersion("mlp", 1); err != nil {
+			t.Fatal(err)
+		}
+		if err := r.SetDefaultVersion("mlp", 3); err == nil {
+			t.Error("an error should be occurred with not loaded version")
+		}
+	})
+
+	t.Run("retire version while running", func(t *testing.T) {
+		var wg sync.WaitGroup
+		for i := 0; i < 8; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				if err := run(1); err != nil {
+					// the version can be removed before acquiring
+					if _, cerr := r.Config("mlp", 1); cerr == nil {
+						t.Errorf("loaded version should run, %v", err)
+					}
+				}
+			}()
+		}
+		if err := os.RemoveAll(filepath.Join(root, "mlp", "1")); err != nil {
+			t.Fatal(err)
+		}
+		if err := r.Reload(); err != nil {
+			t.Fatal(err)
+		}
+		wg.Wait()
+		if versions := r.Versions("mlp"); len(versions) != 1 || versions[0] != 2 {
+			t.Errorf("versions should be [2], but %v", versions)
+		}
+		if err := run(1); err == nil {
+			t.Error("an error should be occurred with retired version")
+		}
+		if err := run(DefaultVersion); err != nil {
+			t.Errorf("default version should be reset to the latest, %v", err)
+		}
+	})
+}
+
+func TestReloadConcurrently(t *testing.T) {
+	fake := menohtest.New()
+	fake.Output("fc2", 1, 5)
+	// building takes time, so that reloads overlap
+	fake.SetLatency(10*time.Millisecond, 0)
+	fake.Register("registry_test")
+	root, err := ioutil.TempDir("", "go-menoh-test-")
+	if err != nil {
+		t.Fatal("cannot make temporary directory")
+	}
+	defer os.RemoveAll(root)
+	r, err := New(root, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	config := []byte(`backend: registry_test
+inputs:
+  - name: input
+    dims: [1, 3]
+outputs:
+  - name: fc2
+`)
+	for _, ver := range []string{"1", "2"} {
+		dir := filepath.Join(root, "mlp", ver)
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(filepath.Join(dir, modelFileName), []byte("dummy"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(filepath.Join(dir, "config.yaml"), config, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := r.Reload(); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+	if versions := r.Versions("mlp"); len(versions) != 2 {
+		t.Errorf("versions should be [1 2], but %v", versions)
+	}
+	if n := countCalls(fake, "LoadModel"); n != 2 {
+		t.Errorf("each version should be loaded once, but %d runners are built", n)
+	}
+
+	if err := os.RemoveAll(filepath.Join(root, "mlp", "1")); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	r.Stop()
+	if n := countCalls(fake, "Close"); n != 2 {
+		t.Errorf("retired and loaded versions should be stopped, but %d runners are closed", n)
+	}
+	if err := r.Reload(); err == nil {
+		t.Error("an error should be occurred after stopping")
+	}
+}
+
+func countCalls(fake *menohtest.Fake, method string) int {
+	n := 0
+	for _, c := range fake.Calls() {
+		if c.Method == method {
+			n++
+		}
+	}
+	return n
+}
+
+func TestReloadUnreadable(t *testing.T) {
+	fake := menohtest.New()
+	fake.Output("fc2", 1, 5)
+	fake.Register("registry_unreadable_test")
+	root, err := ioutil.TempDir("", "go-menoh-test-")
+	if err != nil {
+		t.Fatal("cannot make temporary directory")
+	}
+	defer os.RemoveAll(root)
+	dir := filepath.Join(root, "mlp", "1")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, modelFileName), []byte("dummy"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	config := []byte(`backend: registry_unreadable_test
+inputs:
+  - name: input
+    dims: [1, 3]
+outputs:
+  - name: fc2
+`)
+	if err := ioutil.WriteFile(filepath.Join(dir, "config.yaml"), config, 0644); err != nil {
+		t.Fatal(err)
+	}
+	failures := map[string][]int64{}
+	r, err := New(root, Options{
+		OnError: func(name string, ver int64, err error) {
+			failures[name] = append(failures[name], ver)
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Stop()
+
+	locked := filepath.Join(root, "mlp")
+	if err := os.Chmod(locked, 0); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chmod(locked, 0755)
+	if _, err := ioutil.ReadDir(locked); err == nil {
+		t.Skip("permissions are not enforced, like by root")
+	}
+	if err := r.Reload(); err != nil {
+		t.Fatalf("unreadable model directory should be skipped, %v", err)
+	}
+	if v := failures["mlp"]; len(v) != 1 || v[0] != 0 {
+		t.Errorf("unreadable model directory should be reported with version 0, but %v", v)
+	}
+	if versions := r.Versions("mlp"); len(versions) != 1 {
+		t.Errorf("loaded versions should be kept, but %v", versions)
+	}
+}
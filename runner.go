@@ -11,96 +11,133 @@ Tensor represents number array for in/out variable, similar to ONNX's Tensor.
 package menoh
 
 import (
-	"errors"
 	"fmt"
-
-	"github.com/pfnet-research/go-menoh/external"
+	"time"
 )
 
 // Runner setups Menoh model with profiling and executes with input variables.
 // A runner supports to call Run (or RunWithTensor) method repeatedly until
 // stopping.
 type Runner struct {
-	modelData    *external.ModelData
-	vptBuilder   *external.VariableProfileTableBuilder
-	vpTable      *external.VariableProfileTable
-	modelBuilder *external.ModelBuilder
-	model        *external.Model
+	backend Backend
 
 	conf Config
 
 	inputs  map[string]Tensor
 	outputs map[string]Tensor
+
+	buildDurations []PhaseDuration
+	live           bool // counted as a live runner by metrics
+}
+
+// PhaseDuration is elapsed time of a phase to build a runner.
+type PhaseDuration struct {
+	Phase    string        `json:"phase"`
+	Duration time.Duration `json:"duration"`
 }
 
 // NewRunner returns Runner using configuration, the runner setup Menoh model
 // and ready for execution. Require to call Stop function after the process is done.
+// The model is run by Backend registered for conf.Backend if any, otherwise
+// by Menoh library.
 func NewRunner(conf Config) (*Runner, error) {
-	modelData, err := external.MakeModelDataFromONNX(conf.ONNXModelPath)
-	if err != nil {
+	logger := loggerOf(&conf)
+	if err := validateBackendConfig(conf.BackendConfig); err != nil {
+		logger.Error("invalid backend config", "model", modelName(conf), "error", err)
 		return nil, err
 	}
-	return buildRunner(modelData, conf)
+	backend, registered := registeredBackend(conf.Backend)
+	if registered {
+		logger.Debug("backend is selected", "backend", conf.Backend, "engine", "registered")
+	} else {
+		if err := checkLibraryVersion(conf); err != nil {
+			logger.Error("linked Menoh is too old", "backend", conf.Backend, "error", err)
+			return nil, err
+		}
+		logger.Debug("backend is selected", "backend", conf.Backend, "engine", "menoh")
+		backend = newBindingBackend(nil)
+	}
+	return newRunnerWithModel(backend, conf)
 }
 
 // NewRunnerWithModelData returns Runner using configuration and ONNX model.
 // The ONNX model is passed on memory, not use conf.ONNXModelPath.
-// Spec of a returned runner is same as NewRunner, see docs of the function.
+// Spec of a returned runner is same as NewRunner, see docs of the function,
+// except that the model is always run by Menoh library. The runner takes
+// modelData and deletes it on stopping, its parameters cannot be replaced
+// after calling. The source of the model data, like ONNX bytes, is released.
 func NewRunnerWithModelData(modelData *ModelData, conf Config) (*Runner, error) {
-	return buildRunner(&modelData.ModelData, conf)
+	logger := loggerOf(&conf)
+	if err := validateBackendConfig(conf.BackendConfig); err != nil {
+		logger.Error("invalid backend config", "model", modelName(conf), "error", err)
+		return nil, err
+	}
+	if err := checkLibraryVersion(conf); err != nil {
+		logger.Error("linked Menoh is too old", "backend", conf.Backend, "error", err)
+		return nil, err
+	}
+	// the runner deletes the model data even if building fails
+	modelData.releaseSource()
+	return buildRunner(newBindingBackend(&modelData.ModelData), conf)
 }
 
-func (r *Runner) makeVariableProfileTableBuilder() error {
-	vptBuilder, err := external.MakeVariableProfileTableBuilder()
-	if err != nil {
-		return err
-	}
-	r.vptBuilder = vptBuilder
-	for _, c := range r.conf.Inputs {
-		menohDtype, _ := toMenohDtype(c.Dtype)
-		if err = vptBuilder.AddInputProfile(c.Name, menohDtype, c.Dims...); err != nil {
-			return err
-		}
+// NewRunnerWithBackend returns Runner running the model of conf.ONNXModelPath
+// by the backend, like a fake for unit testing. The runner closes the backend
+// on stopping.
+func NewRunnerWithBackend(backend Backend, conf Config) (*Runner, error) {
+	if err := validateBackendConfig(conf.BackendConfig); err != nil {
+		loggerOf(&conf).Error("invalid backend config", "model", modelName(conf), "error", err)
+		return nil, err
 	}
-	for _, c := range r.conf.Outputs {
-		menohDtype, _ := toMenohDtype(c.Dtype)
-		if err = vptBuilder.AddOutputProfile(c.Name, menohDtype); err != nil {
-			return err
+	return newRunnerWithModel(backend, conf)
+}
+
+func newRunnerWithModel(backend Backend, conf Config) (*Runner, error) {
+	if err := backend.LoadModel(conf.ONNXModelPath); err != nil {
+		loggerOf(&conf).Error("cannot load model", "path", conf.ONNXModelPath, "error", err)
+		if conf.Metrics != nil {
+			conf.Metrics.observeLoadError(conf, err)
 		}
+		backend.Close()
+		return nil, err
 	}
-	return nil
+	return buildRunner(backend, conf)
+}
+
+func (r *Runner) makeVariableProfileTableBuilder() error {
+	return r.backend.AddProfiles(r.conf.Inputs, r.conf.Outputs)
 }
 
 func (r *Runner) buildVariableProfileTable() error {
-	vpt, err := r.vptBuilder.BuildVariableProfileTable(*r.modelData)
-	if err != nil {
+	if err := r.backend.ProfileVariables(); err != nil {
 		return err
 	}
-	r.vpTable = vpt
 	for _, c := range r.conf.Outputs {
 		if !c.FromInternal {
 			continue
 		}
-		vp, err := vpt.GetVariableProfile(c.Name)
+		dtype, dims, err := r.backend.VariableProfile(c.Name)
 		if err != nil {
 			return err
 		}
-		dtype, _ := toDtype(vp.Dtype)
-		tensor := newTensorHandle(dtype, vp.Dims...)
-		r.outputs[c.Name] = tensor
+		r.outputs[c.Name] = newTensorHandle(dtype, dims...)
 	}
 	return nil
 }
 
-func (r *Runner) makeModelBuilder() error {
-	modelBuilder, err := external.MakeModelBuilder(*r.vpTable)
-	if err != nil {
-		return err
+func (r *Runner) optimize() error {
+	optimizer, ok := r.backend.(Optimizer)
+	if !ok {
+		loggerOf(&r.conf).Debug("backend does not optimize", "model", modelName(r.conf))
+		return nil
 	}
-	r.modelBuilder = modelBuilder
+	return optimizer.Optimize()
+}
+
+func (r *Runner) makeModelBuilder() error {
 	for _, c := range r.conf.Inputs {
 		tensor := newTensorHandle(c.Dtype, c.Dims...)
-		if err := modelBuilder.AttachExternalBuffer(c.Name, tensor.ptr()); err != nil {
+		if err := r.backend.AttachBuffer(c.Name, tensor); err != nil {
 			return err
 		}
 		r.inputs[c.Name] = tensor
@@ -109,8 +146,7 @@ func (r *Runner) makeModelBuilder() error {
 		if !c.FromInternal {
 			continue
 		}
-		tensor := r.outputs[c.Name]
-		if err := modelBuilder.AttachExternalBuffer(c.Name, tensor.ptr()); err != nil {
+		if err := r.backend.AttachBuffer(c.Name, r.outputs[c.Name]); err != nil {
 			return err
 		}
 	}
@@ -118,33 +154,28 @@ func (r *Runner) makeModelBuilder() error {
 }
 
 func (r *Runner) buildModel() error {
-	model, err := r.modelBuilder.BuildModel(
-		*r.modelData, r.conf.Backend.String(), r.conf.BackendConfig)
-	if err != nil {
+	if err := r.backend.Build(r.conf.Backend, r.conf.BackendConfig); err != nil {
 		return err
 	}
-	r.model = model
 	for _, c := range r.conf.Outputs {
 		if c.FromInternal {
 			continue
 		}
-		out, err := model.GetVariable(c.Name)
+		tensor, err := r.backend.GetVariable(c.Name)
 		if err != nil {
 			return err
 		}
-		dtype, _ := toDtype(out.Dtype)
-		tensor := newTensorHandleByPtr(dtype, out.BufferHandle, out.Dims...)
 		r.outputs[c.Name] = tensor
 	}
 	return nil
 }
 
-func buildRunner(modelData *external.ModelData, conf Config) (runner *Runner, err error) {
+func buildRunner(backend Backend, conf Config) (runner *Runner, err error) {
 	runner = &Runner{
-		modelData: modelData,
-		conf:      conf,
-		inputs:    map[string]Tensor{},
-		outputs:   map[string]Tensor{},
+		backend: backend,
+		conf:    conf,
+		inputs:  map[string]Tensor{},
+		outputs: map[string]Tensor{},
 	}
 	defer func() {
 		if err != nil {
@@ -153,22 +184,62 @@ func buildRunner(modelData *external.ModelData, conf Config) (runner *Runner, er
 		}
 	}()
 
-	if err = runner.makeVariableProfileTableBuilder(); err != nil {
-		return
+	logger := loggerOf(&runner.conf)
+	type phase struct {
+		name  string
+		build func() error
+	}
+	phases := []phase{
+		{"makeVariableProfileTableBuilder", runner.makeVariableProfileTableBuilder},
+		{"buildVariableProfileTable", runner.buildVariableProfileTable},
+	}
+	if conf.Optimize {
+		phases = append(phases, phase{"optimize", runner.optimize})
 	}
-	if err = runner.buildVariableProfileTable(); err != nil {
-		return
+	phases = append(phases,
+		phase{"makeModelBuilder", runner.makeModelBuilder},
+		phase{"buildModel", runner.buildModel})
+	for _, p := range phases {
+		start := time.Now()
+		err = p.build()
+		d := PhaseDuration{
+			Phase:    p.name,
+			Duration: time.Since(start),
+		}
+		runner.buildDurations = append(runner.buildDurations, d)
+		if conf.Hooks != nil {
+			conf.Hooks.OnBuildPhase(&runner.conf, d, start, err)
+		}
+		logger.Debug("build phase is done", "model", modelName(conf), "phase", d.Phase, "duration", d.Duration)
+		if err != nil {
+			logger.Error("cannot build runner", "model", modelName(conf), "backend", conf.Backend,
+				"phase", d.Phase, "error", err)
+			break
+		}
 	}
-	if err = runner.makeModelBuilder(); err != nil {
-		return
+	if err == nil {
+		logger.Info("runner is built", "model", modelName(conf), "backend", conf.Backend,
+			"inputs", formatInputs(conf.Inputs), "outputs", formatOutputs(conf.Outputs))
 	}
-	if err = runner.buildModel(); err != nil {
-		return
+	if conf.Metrics != nil {
+		conf.Metrics.observeBuild(conf, runner.buildDurations, err)
+		runner.live = err == nil
 	}
 
 	return
 }
 
+// Config returns the configuration used to build the runner.
+func (r *Runner) Config() Config {
+	return r.conf
+}
+
+// BuildDurations returns elapsed time of each phase to build the runner, in
+// order of execution.
+func (r *Runner) BuildDurations() []PhaseDuration {
+	return r.buildDurations
+}
+
 // GetInput returns a Tensor attached to the target model.
 func (r *Runner) GetInput(name string) (Tensor, error) {
 	tensor, ok := r.inputs[name]
@@ -188,6 +259,35 @@ func (r *Runner) RunWithTensor(name string, t Tensor) error {
 // Run with the inputs which are set name and tensor as key-value.
 // If nothing to input, set nil.
 func (r *Runner) Run(inputs map[string]Tensor) error {
+	if r.conf.Hooks == nil {
+		return r.run(inputs)
+	}
+	r.conf.Hooks.BeforeRun(r, inputs)
+	err := r.run(inputs)
+	r.conf.Hooks.AfterRun(r, inputs, err)
+	return err
+}
+
+func (r *Runner) run(inputs map[string]Tensor) error {
+	if r.conf.Metrics == nil {
+		if err := r.SetInputs(inputs); err != nil {
+			return err
+		}
+		return r.backend.Run()
+	}
+	start := time.Now()
+	err := r.SetInputs(inputs)
+	copyTime := time.Since(start)
+	if err == nil {
+		err = r.backend.Run()
+	}
+	r.conf.Metrics.observeRun(r.conf, copyTime, time.Since(start), err)
+	return err
+}
+
+// SetInputs copies the inputs, which are set name and tensor as key-value,
+// to the attached variables without running.
+func (r *Runner) SetInputs(inputs map[string]Tensor) error {
 	for n, t := range inputs {
 		tensor, ok := r.inputs[n]
 		if !ok {
@@ -197,7 +297,7 @@ func (r *Runner) Run(inputs map[string]Tensor) error {
 			return fmt.Errorf("cannot update array, %v", err)
 		}
 	}
-	return r.model.Run()
+	return nil
 }
 
 // Outputs all variables set by the configuration.
@@ -216,37 +316,12 @@ func (r *Runner) GetOutput(name string) (Tensor, error) {
 
 // Stop the runner.
 func (r *Runner) Stop() {
-	if r.model != nil {
-		r.model.Delete()
-	}
-	if r.modelBuilder != nil {
-		r.modelBuilder.Delete()
-	}
-	if r.vpTable != nil {
-		r.vpTable.Delete()
+	loggerOf(&r.conf).Debug("runner is stopped", "model", modelName(r.conf))
+	if r.live {
+		r.conf.Metrics.observeStop(r.conf)
+		r.live = false
 	}
-	if r.vptBuilder != nil {
-		r.vptBuilder.Delete()
-	}
-	if r.modelData != nil {
-		r.modelData.Delete()
-	}
-}
-
-func toMenohDtype(dtype TypeDtype) (external.TypeMenohDtype, error) {
-	switch dtype {
-	case TypeFloat:
-		return external.TypeFloat, nil
-	default:
-		return -1, errors.New("not supported dtype")
-	}
-}
-
-func toDtype(mdtype external.TypeMenohDtype) (TypeDtype, error) {
-	switch mdtype {
-	case external.TypeFloat:
-		return TypeFloat, nil
-	default:
-		return -1, errors.New("not supported menoh dtype")
+	if r.backend != nil {
+		r.backend.Close()
 	}
 }
@@ -421,3 +421,57 @@ func tensorEquals(t1, t2 Tensor) bool {
 	}
 	return true
 }
+
+func TestBuildDurations(t *testing.T) {
+	runner, err := getRunner()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer runner.Stop()
+
+	expected := []string{
+		"makeVariableProfileTableBuilder",
+		"buildVariableProfileTable",
+		"makeModelBuilder",
+		"buildModel",
+	}
+	actual := runner.BuildDurations()
+	if len(actual) != len(expected) {
+		t.Fatalf("durations of %d phases should be recorded, but %v", len(expected), actual)
+	}
+	for i, p := range expected {
+		if actual[i].Phase != p {
+			t.Errorf("phase %d should be %s, but %s", i, p, actual[i].Phase)
+		}
+	}
+}
+
+func TestSetInputs(t *testing.T) {
+	runner, err := getRunner()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer runner.Stop()
+
+	inputs := map[string]Tensor{
+		"input": &FloatTensor{
+			Dims:  []int32{1, 3},
+			Array: []float32{0., 1., 2.},
+		},
+	}
+	if err := runner.SetInputs(inputs); err != nil {
+		t.Fatalf("inputs should be set without error, %v", err)
+	}
+	actual, err := runner.GetInput("input")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !tensorEquals(actual, inputs["input"]) {
+		t.Errorf(`input variable should equal to set array
+   expected: %v
+   actual  : %v`, inputs["input"], actual)
+	}
+	if err := runner.SetInputs(map[string]Tensor{"dummy_input": &FloatTensor{}}); err == nil {
+		t.Error("an error should be occurred with non profiled input")
+	}
+}
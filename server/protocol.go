@@ -0,0 +1,137 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/pfnet-research/go-menoh"
+)
+
+// datatypeFP32 is a name of float32 type in the protocol, Menoh supports only
+// float.
+const datatypeFP32 = "FP32"
+
+// TensorMetadata describes a tensor of a model.
+type TensorMetadata struct {
+	Name     string  `json:"name"`
+	Datatype string  `json:"datatype"`
+	Shape    []int64 `json:"shape"`
+}
+
+// ModelMetadata is a response of model metadata endpoint.
+type ModelMetadata struct {
+	Name     string           `json:"name"`
+	Versions []string         `json:"versions,omitempty"`
+	Platform string           `json:"platform"`
+	Backend  string           `json:"backend"`
+	Inputs   []TensorMetadata `json:"inputs"`
+	Outputs  []TensorMetadata `json:"outputs"`
+}
+
+// RequestInput is a named tensor in an inference request. Data is a flat
+// or nested array in row-major order.
+type RequestInput struct {
+	Name     string          `json:"name"`
+	Shape    []int64         `json:"shape"`
+	Datatype string          `json:"datatype"`
+	Data     json.RawMessage `json:"data"`
+}
+
+// RequestOutput specifies an output to be returned.
+type RequestOutput struct {
+	Name string `json:"name"`
+}
+
+// InferRequest is a request of inference endpoint.
+type InferRequest struct {
+	ID      string          `json:"id,omitempty"`
+	Inputs  []RequestInput  `json:"inputs"`
+	Outputs []RequestOutput `json:"outputs,omitempty"`
+}
+
+// ResponseOutput is a named tensor in an inference response.
+type ResponseOutput struct {
+	Name     string    `json:"name"`
+	Shape    []int64   `json:"shape"`
+	Datatype string    `json:"datatype"`
+	Data     []float32 `json:"data"`
+}
+
+// InferResponse is a response of inference endpoint.
+type InferResponse struct {
+	ModelName string           `json:"model_name"`
+	ID        string           `json:"id,omitempty"`
+	Outputs   []ResponseOutput `json:"outputs"`
+}
+
+// ErrorResponse is a response on failure.
+type ErrorResponse struct {
+	Error string `json:"error"`
+}
+
+// toTensor converts the request input to Menoh's tensor. Numeric datatypes
+// other than FP32 are converted to float32.
+func (in *RequestInput) toTensor() (menoh.Tensor, error) {
+	switch in.Datatype {
+	case datatypeFP32, "FP16", "FP64", "INT8", "INT16", "INT32", "INT64",
+		"UINT8", "UINT16", "UINT32", "UINT64", "BOOL":
+	default:
+		return nil, fmt.Errorf("datatype '%s' of '%s' is not supported", in.Datatype, in.Name)
+	}
+	var data interface{}
+	if err := json.Unmarshal(in.Data, &data); err != nil {
+		return nil, fmt.Errorf("cannot parse data of '%s', %v", in.Name, err)
+	}
+	floats := []float32{}
+	if err := flatten(data, &floats); err != nil {
+		return nil, fmt.Errorf("invalid data of '%s', %v", in.Name, err)
+	}
+	size := int64(1)
+	dims := make([]int32, len(in.Shape))
+	for i, d := range in.Shape {
+		if d < 0 {
+			return nil, fmt.Errorf("shape of '%s' must not be negative", in.Name)
+		}
+		size *= d
+		dims[i] = int32(d)
+	}
+	if size != int64(len(floats)) {
+		return nil, fmt.Errorf("size of data of '%s' is %d, but shape %v requires %d",
+			in.Name, len(floats), in.Shape, size)
+	}
+	return &menoh.FloatTensor{
+		Dims:  dims,
+		Array: floats,
+	}, nil
+}
+
+func flatten(v interface{}, floats *[]float32) error {
+	switch e := v.(type) {
+	case float64:
+		*floats = append(*floats, float32(e))
+	case bool:
+		if e {
+			*floats = append(*floats, 1)
+		} else {
+			*floats = append(*floats, 0)
+		}
+	case []interface{}:
+		for _, c := range e {
+			if err := flatten(c, floats); err != nil {
+				return err
+			}
+		}
+	default:
+		return errors.New("data must be numbers or nested arrays of numbers")
+	}
+	return nil
+}
+
+func toShape(dims []int32) []int64 {
+	shape := make([]int64, len(dims))
+	for i, d := range dims {
+		shape[i] = int64(d)
+	}
+	return shape
+}
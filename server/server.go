@@ -0,0 +1,260 @@
+/*
+Package server provides HTTP/JSON inference server of Menoh runners, following
+KServe (TF-Serving) v2 inference protocol.
+
+	GET  /v2/health/live
+	GET  /v2/health/ready
+	GET  /v2/models/{name}
+	GET  /v2/models/{name}/ready
+	POST /v2/models/{name}/infer
+
+Each model is served by a pool of runners, requests to the same model are
+executed concurrently up to the pool size.
+*/
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/pfnet-research/go-menoh"
+	"github.com/pfnet-research/go-menoh/internal/serving"
+)
+
+// Options is setup information of Server.
+type Options struct {
+	MaxRequestBytes int64 // max size of request body, default is 16 MiB
+	PoolSize        int   // number of runners for each model, default is 1
+}
+
+// Server is a HTTP handler serving registered models.
+type Server struct {
+	opts   Options
+	models *serving.Models
+}
+
+// NewServer returns Server with no model. Require to call Stop function
+// after serving is done.
+func NewServer(opts Options) *Server {
+	if opts.MaxRequestBytes <= 0 {
+		opts.MaxRequestBytes = 16 << 20
+	}
+	if opts.PoolSize <= 0 {
+		opts.PoolSize = 1
+	}
+	return &Server{
+		opts:   opts,
+		models: serving.NewModels(),
+	}
+}
+
+// AddModel builds a pool of runners with the configuration and serves it
+// with the name.
+func (s *Server) AddModel(name string, conf menoh.Config) error {
+	if conf.Name == "" {
+		conf.Name = name
+	}
+	pool, err := menoh.NewPool(conf, s.opts.PoolSize)
+	if err != nil {
+		return fmt.Errorf("cannot build runners of '%s', %v", name, err)
+	}
+	if err := s.AddPool(name, pool); err != nil {
+		pool.Stop()
+		return err
+	}
+	return nil
+}
+
+// AddPool serves the pool with the name. The server takes ownership of the
+// pool and stops it on removing.
+func (s *Server) AddPool(name string, pool menoh.InferencerPool) error {
+	if name == "" || strings.Contains(name, "/") {
+		return fmt.Errorf("invalid model name '%s'", name)
+	}
+	m, err := serving.NewModel(name, pool)
+	if err != nil {
+		return err
+	}
+	return s.models.Add(m)
+}
+
+func makeMetadata(m *serving.Model) ModelMetadata {
+	metadata := ModelMetadata{
+		Name:     m.Name,
+		Platform: "onnx",
+		Backend:  m.Backend,
+		Inputs:   []TensorMetadata{},
+		Outputs:  []TensorMetadata{},
+	}
+	for _, in := range m.Inputs {
+		metadata.Inputs = append(metadata.Inputs, TensorMetadata{
+			Name:     in.Name,
+			Datatype: datatypeFP32,
+			Shape:    toShape(in.Shape),
+		})
+	}
+	for _, out := range m.Outputs {
+		metadata.Outputs = append(metadata.Outputs, TensorMetadata{
+			Name:     out.Name,
+			Datatype: datatypeFP32,
+			Shape:    toShape(out.Shape),
+		})
+	}
+	return metadata
+}
+
+// RemoveModel stops serving the model and stops its runners, waiting for
+// running requests.
+func (s *Server) RemoveModel(name string) {
+	s.models.Remove(name)
+}
+
+// Models returns names of served models.
+func (s *Server) Models() []string {
+	return s.models.Names()
+}
+
+// Stop all models.
+func (s *Server) Stop() {
+	s.models.Stop()
+}
+
+// ServeHTTP implements http.Handler.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	path := strings.Trim(r.URL.Path, "/")
+	switch {
+	case path == "v2/health/live":
+		s.handleHealth(w, r, true)
+	case path == "v2/health/ready":
+		s.handleHealth(w, r, len(s.Models()) > 0)
+	case strings.HasPrefix(path, "v2/models/"):
+		parts := strings.Split(strings.TrimPrefix(path, "v2/models/"), "/")
+		m, ok := s.models.Get(parts[0])
+		if !ok {
+			writeError(w, http.StatusNotFound, fmt.Sprintf("model '%s' is not found", parts[0]))
+			return
+		}
+		switch {
+		case len(parts) == 1:
+			s.handleMetadata(w, r, m)
+		case len(parts) == 2 && parts[1] == "ready":
+			s.handleHealth(w, r, true)
+		case len(parts) == 2 && parts[1] == "infer":
+			s.handleInfer(w, r, m)
+		default:
+			writeError(w, http.StatusNotFound, "not found")
+		}
+	default:
+		writeError(w, http.StatusNotFound, "not found")
+	}
+}
+
+func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request, ok bool) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	if !ok {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+func (s *Server) handleMetadata(w http.ResponseWriter, r *http.Request, m *serving.Model) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	writeJSON(w, http.StatusOK, makeMetadata(m))
+}
+
+func (s *Server) handleInfer(w http.ResponseWriter, r *http.Request, m *serving.Model) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	if r.ContentLength > s.opts.MaxRequestBytes {
+		writeError(w, http.StatusRequestEntityTooLarge, "request body is too large")
+		return
+	}
+	body := http.MaxBytesReader(w, r.Body, s.opts.MaxRequestBytes)
+	req := &InferRequest{}
+	if err := json.NewDecoder(body).Decode(req); err != nil {
+		if strings.Contains(err.Error(), "request body too large") {
+			writeError(w, http.StatusRequestEntityTooLarge, "request body is too large")
+			return
+		}
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("cannot parse request, %v", err))
+		return
+	}
+	resp, status, err := infer(r.Context(), m, req)
+	if err != nil {
+		writeError(w, status, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// infer runs the request with a runner of the model, returns HTTP status on
+// failure.
+func infer(ctx context.Context, m *serving.Model, req *InferRequest) (*InferResponse, int, error) {
+	in := m.NewInputs()
+	for i := range req.Inputs {
+		t, err := req.Inputs[i].toTensor()
+		if err != nil {
+			return nil, http.StatusBadRequest, err
+		}
+		if err := in.Add(req.Inputs[i].Name, t); err != nil {
+			return nil, http.StatusBadRequest, err
+		}
+	}
+	inputs, err := in.Tensors()
+	if err != nil {
+		return nil, http.StatusBadRequest, err
+	}
+	requested := []string{}
+	for _, o := range req.Outputs {
+		requested = append(requested, o.Name)
+	}
+	names, err := m.OutputNames(requested)
+	if err != nil {
+		return nil, http.StatusBadRequest, err
+	}
+
+	outputs, err := m.Run(ctx, inputs, names)
+	if err == context.Canceled || err == context.DeadlineExceeded {
+		return nil, http.StatusServiceUnavailable, err
+	}
+	if err != nil {
+		return nil, http.StatusInternalServerError, err
+	}
+	resp := &InferResponse{
+		ModelName: m.Name,
+		ID:        req.ID,
+		Outputs:   []ResponseOutput{},
+	}
+	for _, o := range outputs {
+		data, _ := o.Tensor.FloatArray()
+		resp.Outputs = append(resp.Outputs, ResponseOutput{
+			Name:     o.Name,
+			Shape:    toShape(o.Tensor.Shape()),
+			Datatype: datatypeFP32,
+			Data:     data,
+		})
+	}
+	return resp, http.StatusOK, nil
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, msg string) {
+	writeJSON(w, status, ErrorResponse{Error: msg})
+}
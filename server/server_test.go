@@ -0,0 +1,198 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/pfnet-research/go-menoh"
+	"github.com/pfnet-research/go-menoh/external/errcode"
+	"github.com/pfnet-research/go-menoh/menohtest"
+)
+
+// newTestServer returns a server serving "mlp" model run by a fake, fc2 is
+// scripted for input [0, 1, 2] and running fails for input [9, 9, 9].
+func newTestServer(t *testing.T, opts Options) *Server {
+	fake := menohtest.New()
+	fake.Output("fc2", 1, 5)
+	fake.Script(
+		map[string][]float32{"input": {0, 1, 2}},
+		map[string][]float32{"fc2": {0, 0, 15, 96, 177}})
+	fake.ScriptError(map[string][]float32{"input": {9, 9, 9}},
+		menohtest.NewError(errcode.UnknownError, "run failure"))
+	s := NewServer(opts)
+	err := s.AddModel("mlp", menoh.Config{
+		Backend: fake.Register("server_test"),
+		Inputs:  []menoh.InputConfig{{Name: "input", Dtype: menoh.TypeFloat, Dims: []int32{1, 3}}},
+		Outputs: []menoh.OutputConfig{{Name: "fc2", Dtype: menoh.TypeFloat}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func TestHealth(t *testing.T) {
+	testSet := []struct {
+		name     string
+		server   *Server
+		path     string
+		expected int
+	}{
+		{"live", NewServer(Options{}), "/v2/health/live", http.StatusOK},
+		{"not ready without model", NewServer(Options{}), "/v2/health/ready", http.StatusServiceUnavailable},
+		{"ready", newTestServer(t, Options{}), "/v2/health/ready", http.StatusOK},
+		{"model ready", newTestServer(t, Options{}), "/v2/models/mlp/ready", http.StatusOK},
+		{"model not found", newTestServer(t, Options{}), "/v2/models/dummy/ready", http.StatusNotFound},
+	}
+	for _, ts := range testSet {
+		t.Run(ts.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			defer ts.server.Stop()
+			ts.server.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, ts.path, nil))
+			if rec.Code != ts.expected {
+				t.Errorf("status should be %d, but %d", ts.expected, rec.Code)
+			}
+		})
+	}
+}
+
+func TestMetadata(t *testing.T) {
+	s := newTestServer(t, Options{})
+	defer s.Stop()
+	server := httptest.NewServer(s)
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "/v2/models/mlp")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("status should be 200, but %d", resp.StatusCode)
+	}
+	actual := ModelMetadata{}
+	if err := json.NewDecoder(resp.Body).Decode(&actual); err != nil {
+		t.Fatal(err)
+	}
+	if actual.Name != "mlp" || len(actual.Inputs) != 1 || actual.Inputs[0].Datatype != "FP32" {
+		t.Errorf("metadata should describe the model, but %+v", actual)
+	}
+}
+
+const validInput = `{"name": "input", "shape": [1, 3], "datatype": "FP32", "data": [0, 1, 2]}`
+
+func TestInfer(t *testing.T) {
+	s := newTestServer(t, Options{})
+	defer s.Stop()
+	server := httptest.NewServer(s)
+	defer server.Close()
+
+	resp, err := http.Post(server.URL+"/v2/models/mlp/infer", "application/json",
+		bytes.NewBufferString(`{"id": "1", "inputs": [`+validInput+`]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("status should be 200, but %d", resp.StatusCode)
+	}
+	actual := InferResponse{}
+	if err := json.NewDecoder(resp.Body).Decode(&actual); err != nil {
+		t.Fatal(err)
+	}
+	expected := InferResponse{
+		ModelName: "mlp",
+		ID:        "1",
+		Outputs: []ResponseOutput{{
+			Name:     "fc2",
+			Shape:    []int64{1, 5},
+			Datatype: "FP32",
+			Data:     []float32{0, 0, 15, 96, 177},
+		}},
+	}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("response should be %+v, but %+v", expected, actual)
+	}
+}
+
+func TestInferFail(t *testing.T) {
+	testSet := []struct {
+		name     string
+		method   string
+		body     string
+		expected int
+		msg      string
+	}{
+		{"invalid method", http.MethodGet, "", http.StatusMethodNotAllowed, "method"},
+		{"invalid JSON", http.MethodPost, "{", http.StatusBadRequest, "cannot parse"},
+		{"too large", http.MethodPost, `{"inputs": [` + strings.Repeat(" ", 512) + `]}`,
+			http.StatusRequestEntityTooLarge, "too large"},
+		{"unknown input", http.MethodPost,
+			`{"inputs": [{"name": "dummy", "shape": [1], "datatype": "FP32", "data": [0]}]}`,
+			http.StatusBadRequest, "not found"},
+		{"unknown output", http.MethodPost,
+			`{"inputs": [` + validInput + `], "outputs": [{"name": "dummy"}]}`,
+			http.StatusBadRequest, "not found"},
+		{"missing input", http.MethodPost, `{"inputs": []}`, http.StatusBadRequest, "required"},
+		{"duplicated input", http.MethodPost, `{"inputs": [` + validInput + `, ` + validInput + `]}`,
+			http.StatusBadRequest, "duplicated"},
+		{"invalid shape", http.MethodPost,
+			`{"inputs": [{"name": "input", "shape": [3, 1], "datatype": "FP32", "data": [0, 1, 2]}]}`,
+			http.StatusBadRequest, "should be [1 3]"},
+		{"run failure", http.MethodPost,
+			`{"inputs": [{"name": "input", "shape": [1, 3], "datatype": "FP32", "data": [9, 9, 9]}]}`,
+			http.StatusInternalServerError, "run failure"},
+		{"unsupported datatype", http.MethodPost,
+			`{"inputs": [{"name": "input", "shape": [1, 3], "datatype": "BYTES", "data": [0, 1, 2]}]}`,
+			http.StatusBadRequest, "not supported"},
+		{"shape mismatch", http.MethodPost,
+			`{"inputs": [{"name": "input", "shape": [1, 3], "datatype": "FP32", "data": [0, 1]}]}`,
+			http.StatusBadRequest, "requires 3"},
+	}
+	s := newTestServer(t, Options{MaxRequestBytes: 256})
+	defer s.Stop()
+	for _, ts := range testSet {
+		t.Run(ts.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			req := httptest.NewRequest(ts.method, "/v2/models/mlp/infer", bytes.NewBufferString(ts.body))
+			s.ServeHTTP(rec, req)
+			if rec.Code != ts.expected {
+				t.Errorf("status should be %d, but %d", ts.expected, rec.Code)
+			}
+			resp := ErrorResponse{}
+			if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("error response should be JSON, %v", err)
+			}
+			if !strings.Contains(resp.Error, ts.msg) {
+				t.Errorf("error message should contain '%s', but '%s'", ts.msg, resp.Error)
+			}
+		})
+	}
+}
+
+func TestToTensor(t *testing.T) {
+	in := &RequestInput{
+		Name:     "input",
+		Shape:    []int64{2, 2},
+		Datatype: "INT32",
+		Data:     json.RawMessage(`[[1, 2], [3, 4]]`),
+	}
+	actual, err := in.toTensor()
+	if err != nil {
+		t.Fatalf("input should be converted, %v", err)
+	}
+	floats, _ := actual.FloatArray()
+	for i, f := range []float32{1, 2, 3, 4} {
+		if floats[i] != f {
+			t.Fatalf("array should be flattened, but %v", floats)
+		}
+	}
+	if len(actual.Shape()) != 2 || actual.Shape()[0] != 2 {
+		t.Errorf("shape should be [2 2], but %v", actual.Shape())
+	}
+}
@@ -0,0 +1,134 @@
+package menoh
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ShapeAdapter runs inputs of any shape by building an Inferencer for each
+// shape of inputs, because Menoh fixes shapes of variables on building.
+// Inferencers are kept for later runs with the same shapes, up to maxShapes,
+// and the oldest one is stopped to build another.
+type ShapeAdapter struct {
+	conf          Config
+	maxShapes     int
+	newInferencer func(Config) (Inferencer, error)
+
+	inferencers map[string]Inferencer // by shapes of inputs
+	built       []string              // keys of inferencers in order of building
+	last        Inferencer            // inferencer of the last Run
+}
+
+// NewShapeAdapter returns ShapeAdapter building inferencers with the
+// configuration whose input dims are replaced by shapes of inputs.
+// newInferencer builds an inferencer, NewRunner if nil. maxShapes is the
+// number of kept inferencers, all inferencers are kept if it is not
+// positive. Require to call Stop function after the process is done.
+func NewShapeAdapter(conf Config, maxShapes int, newInferencer func(Config) (Inferencer, error)) *ShapeAdapter {
+	if newInferencer == nil {
+		newInferencer = func(conf Config) (Inferencer, error) {
+			return NewRunner(conf)
+		}
+	}
+	return &ShapeAdapter{
+		conf:          conf,
+		maxShapes:     maxShapes,
+		newInferencer: newInferencer,
+		inferencers:   map[string]Inferencer{},
+	}
+}
+
+// Shapes returns number of kept inferencers.
+func (a *ShapeAdapter) Shapes() int {
+	return len(a.inferencers)
+}
+
+// inferencer returns the inferencer for shapes of the inputs, building it
+// if not kept. Inputs not given keep configured dims.
+func (a *ShapeAdapter) inferencer(inputs map[string]Tensor) (Inferencer, error) {
+	for name := range inputs {
+		if !hasInput(a.conf.Inputs, name) {
+			return nil, fmt.Errorf("input %s is not configured", name)
+		}
+	}
+	conf := a.conf
+	conf.Inputs = make([]InputConfig, len(a.conf.Inputs))
+	key := ""
+	for i, c := range a.conf.Inputs {
+		if t, ok := inputs[c.Name]; ok {
+			c.Dims = append([]int32{}, t.Shape()...)
+		}
+		conf.Inputs[i] = c
+		key += fmt.Sprintf("%s%v;", c.Name, c.Dims)
+	}
+	if inf, ok := a.inferencers[key]; ok {
+		return inf, nil
+	}
+	inf, err := a.newInferencer(conf)
+	if err != nil {
+		return nil, fmt.Errorf("cannot build for shapes of inputs, %v", err)
+	}
+	if a.maxShapes > 0 && len(a.built) >= a.maxShapes {
+		oldest := a.built[0]
+		a.inferencers[oldest].Stop()
+		delete(a.inferencers, oldest)
+		a.built = a.built[1:]
+	}
+	a.inferencers[key] = inf
+	a.built = append(a.built, key)
+	return inf, nil
+}
+
+// Run runs the inputs on the inferencer for their shapes.
+func (a *ShapeAdapter) Run(inputs map[string]Tensor) error {
+	inf, err := a.inferencer(inputs)
+	if err != nil {
+		return err
+	}
+	a.last = inf
+	return inf.Run(inputs)
+}
+
+// RunWithTensor inputs the tensor with the name, and runs.
+func (a *ShapeAdapter) RunWithTensor(name string, t Tensor) error {
+	return a.Run(map[string]Tensor{
+		name: t,
+	})
+}
+
+var errNotRun = errors.New("inferencer is not run yet")
+
+// GetInput returns the input of the inferencer of the last Run.
+func (a *ShapeAdapter) GetInput(name string) (Tensor, error) {
+	if a.last == nil {
+		return nil, errNotRun
+	}
+	return a.last.GetInput(name)
+}
+
+// GetOutput returns the output of the inferencer of the last Run.
+func (a *ShapeAdapter) GetOutput(name string) (Tensor, error) {
+	if a.last == nil {
+		return nil, errNotRun
+	}
+	return a.last.GetOutput(name)
+}
+
+// Outputs returns all outputs of the inferencer of the last Run, nil before
+// running.
+func (a *ShapeAdapter) Outputs() map[string]Tensor {
+	if a.last == nil {
+		return nil
+	}
+	return a.last.Outputs()
+}
+
+// Stop all kept inferencers.
+func (a *ShapeAdapter) Stop() {
+	for _, inf := range a.inferencers {
+		inf.Stop()
+	}
+	a.inferencers = map[string]Inferencer{}
+	a.built = nil
+	a.last = nil
+}
@@ -0,0 +1,67 @@
+package menoh
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestShapeAdapter(t *testing.T) {
+	built := []*doubler{}
+	a := NewShapeAdapter(doublerConfig(1, 3), 2, func(conf Config) (Inferencer, error) {
+		if conf.Inputs[0].Dims[0] > 4 {
+			return nil, errors.New("too large")
+		}
+		d := newDoubler(conf.Inputs[0].Dims)
+		built = append(built, d)
+		return d, nil
+	})
+	defer a.Stop()
+	if _, err := a.GetOutput("y"); err == nil {
+		t.Error("an error should be occurred before running")
+	}
+
+	run := func(rows int32) {
+		t.Helper()
+		x := &FloatTensor{Dims: []int32{rows, 3}, Array: make([]float32, 3*rows)}
+		x.Array[0] = 1
+		if err := a.RunWithTensor("x", x); err != nil {
+			t.Fatalf("inputs of %d rows should be run, %v", rows, err)
+		}
+		y, err := a.GetOutput("y")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(y.Shape(), x.Dims) || y.(*FloatTensor).Array[0] != 2 {
+			t.Errorf("output of the shape should be returned, but %v", y)
+		}
+	}
+	run(1)
+	run(2)
+	run(1)
+	if len(built) != 2 || a.Shapes() != 2 {
+		t.Errorf("an inferencer should be built for each shape, but %d", len(built))
+	}
+	run(3)
+	if len(built) != 3 || !built[0].stopped || built[1].stopped {
+		t.Error("the oldest inferencer should be stopped over max shapes")
+	}
+	if a.Shapes() != 2 {
+		t.Errorf("2 inferencers should be kept, but %d", a.Shapes())
+	}
+
+	x := &FloatTensor{Dims: []int32{5, 3}, Array: make([]float32, 15)}
+	if err := a.RunWithTensor("x", x); err == nil {
+		t.Error("an error should be occurred when building fails")
+	}
+	if err := a.RunWithTensor("z", x); err == nil {
+		t.Error("an error should be occurred with not configured input")
+	}
+
+	a.Stop()
+	for i, d := range built {
+		if !d.stopped {
+			t.Errorf("inferencer %d should be stopped", i)
+		}
+	}
+}
@@ -0,0 +1,269 @@
+package menoh
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// StateConfig maps an output of a step to the input of the next step, like
+// hidden state "h_n" to "h_0" of RNN and LSTM models exported with explicit
+// state inputs and outputs.
+type StateConfig struct {
+	Output string
+	Input  string
+}
+
+// StatefulRunner runs a sequence model step by step, feeding state outputs
+// of a Run back as state inputs of the next Run. States are held by Session,
+// so one runner can serve multiple streams, runs of sessions are serialized
+// on the runner. As Inferencer, the runner runs on its default session.
+type StatefulRunner struct {
+	runner *Runner
+	states []StateConfig
+
+	mu      sync.Mutex // guards runner
+	session *Session
+}
+
+// NewStatefulRunner returns StatefulRunner built with the configuration.
+// Inputs and outputs of states must be configured, and each state output must
+// have the same size as its input. Require to call Stop function after the
+// process is done.
+func NewStatefulRunner(conf Config, states []StateConfig) (*StatefulRunner, error) {
+	if err := validateStates(conf, states); err != nil {
+		return nil, err
+	}
+	runner, err := NewRunner(conf)
+	if err != nil {
+		return nil, err
+	}
+	for _, s := range states {
+		in, _ := runner.GetInput(s.Input)
+		out, err := runner.GetOutput(s.Output)
+		if err != nil {
+			runner.Stop()
+			return nil, err
+		}
+		if in.Size() != out.Size() {
+			runner.Stop()
+			return nil, fmt.Errorf("state %s has %d values, but %d values of input %s",
+				s.Output, out.Size(), in.Size(), s.Input)
+		}
+	}
+	r := &StatefulRunner{
+		runner: runner,
+		states: states,
+	}
+	r.session = r.NewSession()
+	return r, nil
+}
+
+func validateStates(conf Config, states []StateConfig) error {
+	if len(states) == 0 {
+		return errors.New("no state is configured")
+	}
+	inputs := map[string]bool{}
+	for _, c := range conf.Inputs {
+		inputs[c.Name] = true
+	}
+	outputs := map[string]bool{}
+	for _, c := range conf.Outputs {
+		outputs[c.Name] = true
+	}
+	fed := map[string]bool{}
+	for _, s := range states {
+		if !inputs[s.Input] {
+			return fmt.Errorf("state input %s is not configured", s.Input)
+		}
+		if !outputs[s.Output] {
+			return fmt.Errorf("state output %s is not configured", s.Output)
+		}
+		if fed[s.Input] {
+			return fmt.Errorf("state input %s is fed by multiple outputs", s.Input)
+		}
+		fed[s.Input] = true
+	}
+	return nil
+}
+
+// Config returns the configuration used to build the runner.
+func (r *StatefulRunner) Config() Config {
+	return r.runner.Config()
+}
+
+// States returns mapping of state outputs to inputs.
+func (r *StatefulRunner) States() []StateConfig {
+	return r.states
+}
+
+// NewSession returns Session with zero states, for a stream.
+func (r *StatefulRunner) NewSession() *Session {
+	s := &Session{
+		r:      r,
+		states: map[string]*FloatTensor{},
+	}
+	for _, c := range r.states {
+		in, _ := r.runner.GetInput(c.Input)
+		s.states[c.Input] = &FloatTensor{
+			Dims:  append([]int32{}, in.Shape()...),
+			Array: make([]float32, in.Size()),
+		}
+	}
+	return s
+}
+
+// Run runs one step on the default session, see Session.Run.
+func (r *StatefulRunner) Run(inputs map[string]Tensor) error {
+	return r.session.Run(inputs)
+}
+
+// RunWithTensor inputs the tensor with the name, and runs one step on the
+// default session.
+func (r *StatefulRunner) RunWithTensor(name string, t Tensor) error {
+	return r.Run(map[string]Tensor{
+		name: t,
+	})
+}
+
+// Reset sets states of the default session to zero.
+func (r *StatefulRunner) Reset() {
+	r.session.Reset()
+}
+
+// GetInput returns the input of the last Run on the default session,
+// including states, see Session.GetInput.
+func (r *StatefulRunner) GetInput(name string) (Tensor, error) {
+	return r.session.GetInput(name)
+}
+
+// GetOutput returns the output of the last Run on the default session, see
+// Session.GetOutput.
+func (r *StatefulRunner) GetOutput(name string) (Tensor, error) {
+	return r.session.GetOutput(name)
+}
+
+// Outputs returns all outputs of the last Run on the default session, see
+// Session.Outputs.
+func (r *StatefulRunner) Outputs() map[string]Tensor {
+	return r.session.Outputs()
+}
+
+// Stop the runner.
+func (r *StatefulRunner) Stop() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.runner.Stop()
+}
+
+// Session is states of a stream run by StatefulRunner. A session is not safe
+// to run concurrently, make a session for each stream.
+type Session struct {
+	r      *StatefulRunner
+	states map[string]*FloatTensor // by input name
+
+	// inputs and outputs of the last Run, copied from buffers of the runner
+	lastInputs  map[string]Tensor
+	lastOutputs map[string]Tensor
+}
+
+// Run runs one step with the inputs and the states, then keeps state outputs
+// as the next states. All inputs except states are required, because buffers
+// of the runner keep inputs of the last run, which can be of another session.
+// A state input given in the inputs overrides the held state, like an initial
+// state.
+func (s *Session) Run(inputs map[string]Tensor) error {
+	for _, c := range s.r.runner.conf.Inputs {
+		if _, ok := s.states[c.Name]; ok {
+			continue
+		}
+		if _, ok := inputs[c.Name]; !ok {
+			return fmt.Errorf("input %s is required", c.Name)
+		}
+	}
+	feed := map[string]Tensor{}
+	for name, t := range s.states {
+		feed[name] = t
+	}
+	for name, t := range inputs {
+		feed[name] = t
+	}
+
+	s.r.mu.Lock()
+	defer s.r.mu.Unlock()
+	runner := s.r.runner
+	if err := runner.Run(feed); err != nil {
+		return err
+	}
+	in, err := cloneTensors(runner.inputs)
+	if err != nil {
+		return err
+	}
+	out, err := cloneTensors(runner.Outputs())
+	if err != nil {
+		return err
+	}
+	for _, c := range s.r.states {
+		next, err := out[c.Output].FloatArray()
+		if err != nil {
+			return err
+		}
+		copy(s.states[c.Input].Array, next)
+	}
+	s.lastInputs = in
+	s.lastOutputs = out
+	return nil
+}
+
+// RunWithTensor inputs the tensor with the name, and runs one step.
+func (s *Session) RunWithTensor(name string, t Tensor) error {
+	return s.Run(map[string]Tensor{
+		name: t,
+	})
+}
+
+// Reset sets the states to zero.
+func (s *Session) Reset() {
+	for _, t := range s.states {
+		for i := range t.Array {
+			t.Array[i] = 0
+		}
+	}
+}
+
+// State returns a copy of the state fed to the input at the next Run.
+func (s *Session) State(input string) (Tensor, error) {
+	t, ok := s.states[input]
+	if !ok {
+		return nil, fmt.Errorf("%s is not a state input", input)
+	}
+	return cloneTensor(t)
+}
+
+// GetInput returns the input of the last Run, including states. The tensor
+// is held by the session until the next Run and shared with other callers,
+// it must not be modified.
+func (s *Session) GetInput(name string) (Tensor, error) {
+	t, ok := s.lastInputs[name]
+	if !ok {
+		return nil, fmt.Errorf("%s is not attached", name)
+	}
+	return t, nil
+}
+
+// GetOutput returns the output of the last Run. The tensor is held by the
+// session until the next Run and shared with other callers, it must not be
+// modified.
+func (s *Session) GetOutput(name string) (Tensor, error) {
+	t, ok := s.lastOutputs[name]
+	if !ok {
+		return nil, fmt.Errorf("%s is not found", name)
+	}
+	return t, nil
+}
+
+// Outputs returns all outputs of the last Run, held by the session like
+// GetOutput.
+func (s *Session) Outputs() map[string]Tensor {
+	return s.lastOutputs
+}
@@ -0,0 +1,166 @@
+package menoh
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// rnnBackend outputs the sum of input "x" and state "h_0" to "y" and "h_n".
+type rnnBackend struct {
+	fakeBackend
+}
+
+func (b *rnnBackend) Run() error {
+	x, _ := b.inputs["x"].FloatArray()
+	h, _ := b.inputs["h_0"].FloatArray()
+	for _, out := range b.outputs {
+		for i := range x {
+			out.Array[i] = x[i] + h[i]
+		}
+	}
+	return nil
+}
+
+// statefulConfig registers rnnBackend and returns the configuration, call the
+// returned function to unregister.
+func statefulConfig() (Config, func()) {
+	typeBackend := CustomBackend("stateful_test_backend")
+	RegisterBackend(typeBackend, func() Backend { return &rnnBackend{} })
+	unregister := func() { RegisterBackend(typeBackend, nil) }
+	return Config{
+		Backend: typeBackend,
+		Inputs: []InputConfig{
+			{Name: "x", Dtype: TypeFloat, Dims: []int32{1, 3}},
+			{Name: "h_0", Dtype: TypeFloat, Dims: []int32{1, 3}},
+		},
+		Outputs: []OutputConfig{{Name: "y", Dtype: TypeFloat}, {Name: "h_n", Dtype: TypeFloat}},
+	}, unregister
+}
+
+func checkFloats(t *testing.T, tensor Tensor, err error, expected []float32) {
+	t.Helper()
+	if err != nil {
+		t.Fatal(err)
+	}
+	actual, _ := tensor.FloatArray()
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("values should be %v, but %v", expected, actual)
+	}
+}
+
+func TestStatefulRunner(t *testing.T) {
+	conf, unregister := statefulConfig()
+	defer unregister()
+	runner, err := NewStatefulRunner(conf, []StateConfig{{Output: "h_n", Input: "h_0"}})
+	if err != nil {
+		t.Fatalf("stateful runner should be built, %v", err)
+	}
+	defer runner.Stop()
+
+	x := &FloatTensor{Dims: []int32{1, 3}, Array: []float32{1, 2, 3}}
+	for step := 1; step <= 3; step++ {
+		if err := runner.RunWithTensor("x", x); err != nil {
+			t.Fatalf("step %d should run without error, %v", step, err)
+		}
+	}
+	y, err := runner.GetOutput("y")
+	checkFloats(t, y, err, []float32{3, 6, 9})
+	h, err := runner.GetInput("h_0")
+	checkFloats(t, h, err, []float32{2, 4, 6})
+
+	runner.Reset()
+	if err := runner.RunWithTensor("x", x); err != nil {
+		t.Fatal(err)
+	}
+	y, err = runner.GetOutput("y")
+	checkFloats(t, y, err, []float32{1, 2, 3})
+
+	t.Run("initial state", func(t *testing.T) {
+		session := runner.NewSession()
+		h0 := &FloatTensor{Dims: []int32{1, 3}, Array: []float32{10, 10, 10}}
+		if err := session.Run(map[string]Tensor{"x": x, "h_0": h0}); err != nil {
+			t.Fatal(err)
+		}
+		state, err := session.State("h_0")
+		checkFloats(t, state, err, []float32{11, 12, 13})
+	})
+}
+
+func TestStatefulRunnerSessions(t *testing.T) {
+	conf, unregister := statefulConfig()
+	defer unregister()
+	runner, err := NewStatefulRunner(conf, []StateConfig{{Output: "h_n", Input: "h_0"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer runner.Stop()
+
+	a, b := runner.NewSession(), runner.NewSession()
+	ones := &FloatTensor{Dims: []int32{1, 3}, Array: []float32{1, 1, 1}}
+	twos := &FloatTensor{Dims: []int32{1, 3}, Array: []float32{2, 2, 2}}
+	for i := 0; i < 3; i++ {
+		if err := a.RunWithTensor("x", ones); err != nil {
+			t.Fatal(err)
+		}
+		if err := b.RunWithTensor("x", twos); err != nil {
+			t.Fatal(err)
+		}
+	}
+	y, err := a.GetOutput("y")
+	checkFloats(t, y, err, []float32{3, 3, 3})
+	y, err = b.GetOutput("y")
+	checkFloats(t, y, err, []float32{6, 6, 6})
+
+	b.Reset()
+	state, err := b.State("h_0")
+	checkFloats(t, state, err, []float32{0, 0, 0})
+	state, err = a.State("h_0")
+	checkFloats(t, state, err, []float32{3, 3, 3})
+	if _, err := a.State("x"); err == nil {
+		t.Error("an error should be occurred with not state input")
+	}
+
+	// x of a is left in the runner, but not used by b
+	if err := b.Run(nil); err == nil || !strings.Contains(err.Error(), "x is required") {
+		t.Errorf("an error should be occurred without x, but %v", err)
+	}
+	state, err = b.State("h_0")
+	checkFloats(t, state, err, []float32{0, 0, 0})
+}
+
+func TestNewStatefulRunnerFail(t *testing.T) {
+	conf, unregister := statefulConfig()
+	defer unregister()
+	testSet := []struct {
+		name   string
+		states []StateConfig
+		msg    string
+	}{
+		{"no state", nil, "no state"},
+		{"unknown input", []StateConfig{{Output: "h_n", Input: "c_0"}}, "c_0 is not configured"},
+		{"unknown output", []StateConfig{{Output: "c_n", Input: "h_0"}}, "c_n is not configured"},
+		{"duplicated input", []StateConfig{{Output: "h_n", Input: "h_0"}, {Output: "y", Input: "h_0"}},
+			"multiple outputs"},
+	}
+	for _, ts := range testSet {
+		t.Run(ts.name, func(t *testing.T) {
+			_, err := NewStatefulRunner(conf, ts.states)
+			if err == nil {
+				t.Fatal("an error should be occurred")
+			}
+			if !strings.Contains(err.Error(), ts.msg) {
+				t.Errorf("error message should contain '%s', but %v", ts.msg, err)
+			}
+		})
+	}
+
+	t.Run("size mismatch", func(t *testing.T) {
+		mismatched := conf
+		mismatched.Inputs = []InputConfig{conf.Inputs[0], {Name: "h_0", Dtype: TypeFloat, Dims: []int32{1, 2}}}
+		_, err := NewStatefulRunner(mismatched, []StateConfig{{Output: "h_n", Input: "h_0"}})
+		if err == nil || !strings.Contains(err.Error(), "values") {
+			t.Errorf("an error should be occurred with different size of state, %v", err)
+		}
+	})
+}
@@ -45,6 +45,10 @@ func newTensorHandleByPtr(dtype TypeDtype, ptr unsafe.Pointer, dims ...int32) Te
 	for _, d := range dims {
 		len *= int(d)
 	}
+	if len == 0 {
+		// an empty variable has no buffer
+		return &FloatTensor{Dims: dims, Array: []float32{}}
+	}
 	return &FloatTensor{
 		Dims:  dims,
 		Array: (*[1 << 31]float32)(ptr)[:len],
@@ -71,6 +75,31 @@ func updateArray(src, dst Tensor) error {
 	return nil
 }
 
+// cloneTensor returns a tensor with copied dims and array.
+func cloneTensor(t Tensor) (Tensor, error) {
+	a, err := t.FloatArray()
+	if err != nil {
+		return nil, err
+	}
+	return &FloatTensor{
+		Dims:  append([]int32{}, t.Shape()...),
+		Array: append([]float32{}, a...),
+	}, nil
+}
+
+// cloneTensors returns copies of the tensors.
+func cloneTensors(ts map[string]Tensor) (map[string]Tensor, error) {
+	copied := map[string]Tensor{}
+	for name, t := range ts {
+		c, err := cloneTensor(t)
+		if err != nil {
+			return nil, err
+		}
+		copied[name] = c
+	}
+	return copied, nil
+}
+
 // FloatTensor represents float32 Tessor.
 type FloatTensor struct {
 	Dims  []int32
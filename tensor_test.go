@@ -106,3 +106,31 @@ func TestFloatTensorWriteFloat(t *testing.T) {
 		}
 	})
 }
+
+func TestCloneTensor(t *testing.T) {
+	src := &FloatTensor{Dims: []int32{1, 2}, Array: []float32{1, 2}}
+	c, err := cloneTensor(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	src.Array[0] = 3
+	src.Dims[1] = 3
+	expected := &FloatTensor{Dims: []int32{1, 2}, Array: []float32{1, 2}}
+	if !tensorEquals(c, expected) {
+		t.Errorf("cloned tensor should not share the array, but %v", c)
+	}
+}
+
+func TestNewTensorHandleByPtr(t *testing.T) {
+	array := []float32{1, 2, 3}
+	tensor := newTensorHandleByPtr(TypeFloat, unsafe.Pointer(&array[0]), 1, 3)
+	array[1] = 4
+	if a, _ := tensor.FloatArray(); len(a) != 3 || a[1] != 4 {
+		t.Errorf("tensor should share the buffer, but %v", a)
+	}
+
+	empty := newTensorHandleByPtr(TypeFloat, nil, 1, 0)
+	if empty.Size() != 0 || len(empty.Shape()) != 2 {
+		t.Errorf("empty tensor should be returned for no buffer, but %v", empty)
+	}
+}
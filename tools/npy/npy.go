@@ -0,0 +1,546 @@
+/*
+Package npy provides reader and writer of NumPy's ".npy" and ".npz" files
+to exchange arrays with Menoh's Tensor.
+
+All numeric dtypes of NumPy, with both byte orders and both C and Fortran
+memory layouts, can be loaded. Because Menoh supports only float, loaded
+values are converted to float32 and ordered in C (row-major) layout.
+*/
+package npy
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/pfnet-research/go-menoh"
+)
+
+var magic = []byte("\x93NUMPY")
+
+// Dtype represents NumPy's array-protocol type string, like "<f4".
+type Dtype struct {
+	Kind      byte             // 'b' (bool), 'i' (int), 'u' (uint) or 'f' (float)
+	Size      int              // byte size of an element
+	ByteOrder binary.ByteOrder // byte order of an element
+}
+
+// Dtypes used in general.
+var (
+	Bool    = Dtype{Kind: 'b', Size: 1, ByteOrder: binary.LittleEndian}
+	Int8    = Dtype{Kind: 'i', Size: 1, ByteOrder: binary.LittleEndian}
+	Int16   = Dtype{Kind: 'i', Size: 2, ByteOrder: binary.LittleEndian}
+	Int32   = Dtype{Kind: 'i', Size: 4, ByteOrder: binary.LittleEndian}
+	Int64   = Dtype{Kind: 'i', Size: 8, ByteOrder: binary.LittleEndian}
+	Uint8   = Dtype{Kind: 'u', Size: 1, ByteOrder: binary.LittleEndian}
+	Uint16  = Dtype{Kind: 'u', Size: 2, ByteOrder: binary.LittleEndian}
+	Uint32  = Dtype{Kind: 'u', Size: 4, ByteOrder: binary.LittleEndian}
+	Uint64  = Dtype{Kind: 'u', Size: 8, ByteOrder: binary.LittleEndian}
+	Float16 = Dtype{Kind: 'f', Size: 2, ByteOrder: binary.LittleEndian}
+	Float32 = Dtype{Kind: 'f', Size: 4, ByteOrder: binary.LittleEndian}
+	Float64 = Dtype{Kind: 'f', Size: 8, ByteOrder: binary.LittleEndian}
+)
+
+// ParseDtype returns Dtype from array-protocol type string, like "<f4".
+func ParseDtype(descr string) (Dtype, error) {
+	if len(descr) < 3 {
+		return Dtype{}, fmt.Errorf("dtype '%s' is not supported", descr)
+	}
+	d := Dtype{Kind: descr[1]}
+	switch descr[0] {
+	case '<', '|', '=':
+		d.ByteOrder = binary.LittleEndian
+	case '>':
+		d.ByteOrder = binary.BigEndian
+	default:
+		return Dtype{}, fmt.Errorf("byte order of dtype '%s' is not supported", descr)
+	}
+	size, err := strconv.Atoi(descr[2:])
+	if err != nil {
+		return Dtype{}, fmt.Errorf("dtype '%s' is not supported", descr)
+	}
+	d.Size = size
+	if !d.valid() {
+		return Dtype{}, fmt.Errorf("dtype '%s' is not supported", descr)
+	}
+	return d, nil
+}
+
+func (d Dtype) valid() bool {
+	switch d.Kind {
+	case 'b':
+		return d.Size == 1
+	case 'i', 'u':
+		return d.Size == 1 || d.Size == 2 || d.Size == 4 || d.Size == 8
+	case 'f':
+		return d.Size == 2 || d.Size == 4 || d.Size == 8
+	default:
+		return false
+	}
+}
+
+// String returns array-protocol type string.
+func (d Dtype) String() string {
+	order := "<"
+	if d.Size == 1 {
+		order = "|"
+	} else if d.ByteOrder == binary.BigEndian {
+		order = ">"
+	}
+	return fmt.Sprintf("%s%c%d", order, d.Kind, d.Size)
+}
+
+// Header is a header of ".npy" format.
+type Header struct {
+	Dtype        Dtype
+	FortranOrder bool
+	Shape        []int
+}
+
+// Size returns number of elements.
+func (h *Header) Size() int {
+	size := 1
+	for _, d := range h.Shape {
+		size *= d
+	}
+	return size
+}
+
+// ReadHeader reads magic string and header of ".npy" format from r.
+func ReadHeader(r io.Reader) (*Header, error) {
+	prefix := make([]byte, len(magic)+2)
+	if _, err := io.ReadFull(r, prefix); err != nil {
+		return nil, fmt.Errorf("cannot read magic string, %v", err)
+	}
+	if !bytes.Equal(prefix[:len(magic)], magic) {
+		return nil, errors.New("magic string is not matched, not a npy format")
+	}
+	var headerLen int
+	switch major := prefix[len(magic)]; major {
+	case 1:
+		var l uint16
+		if err := binary.Read(r, binary.LittleEndian, &l); err != nil {
+			return nil, fmt.Errorf("cannot read header length, %v", err)
+		}
+		headerLen = int(l)
+	case 2, 3:
+		var l uint32
+		if err := binary.Read(r, binary.LittleEndian, &l); err != nil {
+			return nil, fmt.Errorf("cannot read header length, %v", err)
+		}
+		headerLen = int(l)
+	default:
+		return nil, fmt.Errorf("npy format version %d is not supported", major)
+	}
+	header := make([]byte, headerLen)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return nil, fmt.Errorf("cannot read header, %v", err)
+	}
+	return parseHeader(string(header))
+}
+
+// parseHeader parses Python dictionary literal, like
+// "{'descr': '<f4', 'fortran_order': False, 'shape': (2, 3), }"
+func parseHeader(s string) (*Header, error) {
+	s = strings.TrimSpace(s)
+	if !strings.HasPrefix(s, "{") || !strings.HasSuffix(s, "}") {
+		return nil, fmt.Errorf("header '%s' is not a dictionary", s)
+	}
+	s = s[1 : len(s)-1]
+	h := &Header{}
+	var hasDescr, hasOrder, hasShape bool
+	for len(strings.TrimSpace(s)) > 0 {
+		s = strings.TrimLeft(s, " ,")
+		if s == "" {
+			break
+		}
+		key, rest, err := parseQuoted(s)
+		if err != nil {
+			return nil, err
+		}
+		rest = strings.TrimSpace(rest)
+		if !strings.HasPrefix(rest, ":") {
+			return nil, fmt.Errorf("value of '%s' is not found in header", key)
+		}
+		rest = strings.TrimSpace(rest[1:])
+		switch key {
+		case "descr":
+			var descr string
+			if descr, rest, err = parseQuoted(rest); err != nil {
+				return nil, err
+			}
+			if h.Dtype, err = ParseDtype(descr); err != nil {
+				return nil, err
+			}
+			hasDescr = true
+		case "fortran_order":
+			switch {
+			case strings.HasPrefix(rest, "True"):
+				h.FortranOrder = true
+				rest = rest[len("True"):]
+			case strings.HasPrefix(rest, "False"):
+				rest = rest[len("False"):]
+			default:
+				return nil, errors.New("value of 'fortran_order' must be True or False")
+			}
+			hasOrder = true
+		case "shape":
+			end := strings.Index(rest, ")")
+			if !strings.HasPrefix(rest, "(") || end < 0 {
+				return nil, errors.New("value of 'shape' must be a tuple")
+			}
+			for _, e := range strings.Split(rest[1:end], ",") {
+				e = strings.TrimSpace(e)
+				if e == "" {
+					continue
+				}
+				d, err := strconv.Atoi(strings.TrimSuffix(e, "L"))
+				if err != nil || d < 0 {
+					return nil, fmt.Errorf("invalid dimension '%s' in shape", e)
+				}
+				h.Shape = append(h.Shape, d)
+			}
+			rest = rest[end+1:]
+			hasShape = true
+		default:
+			return nil, fmt.Errorf("unknown key '%s' in header", key)
+		}
+		s = rest
+	}
+	if !hasDescr || !hasOrder || !hasShape {
+		return nil, errors.New("header must have 'descr', 'fortran_order' and 'shape'")
+	}
+	return h, nil
+}
+
+func parseQuoted(s string) (string, string, error) {
+	if s == "" || (s[0] != '\'' && s[0] != '"') {
+		return "", "", fmt.Errorf("quoted string is expected at '%s'", s)
+	}
+	end := strings.IndexByte(s[1:], s[0])
+	if end < 0 {
+		return "", "", fmt.Errorf("quoted string is not closed at '%s'", s)
+	}
+	return s[1 : end+1], s[end+2:], nil
+}
+
+// ReadNpy reads ".npy" format data from r and returns as Menoh's tensor.
+func ReadNpy(r io.Reader) (menoh.Tensor, error) {
+	h, err := ReadHeader(r)
+	if err != nil {
+		return nil, err
+	}
+	size := h.Size()
+	raw := make([]byte, size*h.Dtype.Size)
+	if _, err := io.ReadFull(r, raw); err != nil {
+		return nil, fmt.Errorf("cannot read array data, %v", err)
+	}
+	floats := decode(raw, h.Dtype, size)
+	if h.FortranOrder {
+		floats = reorder(floats, h.Shape, true)
+	}
+	dims := make([]int32, len(h.Shape))
+	for i, d := range h.Shape {
+		dims[i] = int32(d)
+	}
+	return &menoh.FloatTensor{
+		Dims:  dims,
+		Array: floats,
+	}, nil
+}
+
+// LoadNpyFromFile returns Menoh's tensor loaded from ".npy" file placed on
+// the path.
+func LoadNpyFromFile(path string) (menoh.Tensor, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("cannot load '%s', %v", path, err)
+	}
+	defer f.Close()
+	return ReadNpy(bufio.NewReader(f))
+}
+
+// WriteOptions is setup information to write ".npy" format.
+type WriteOptions struct {
+	Dtype        Dtype // stored dtype, default is Float32
+	FortranOrder bool  // if true, store the array with Fortran (column-major) order
+}
+
+// WriteNpy writes the tensor to w with ".npy" format, as little endian
+// float32 array in C order.
+func WriteNpy(w io.Writer, t menoh.Tensor) error {
+	return WriteNpyWithOptions(w, t, WriteOptions{})
+}
+
+// WriteNpyWithOptions writes the tensor to w with ".npy" format, the dtype
+// and the order are set by options.
+func WriteNpyWithOptions(w io.Writer, t menoh.Tensor, opts WriteOptions) error {
+	dtype := opts.Dtype
+	if dtype.Kind == 0 {
+		dtype = Float32
+	}
+	if dtype.ByteOrder == nil {
+		dtype.ByteOrder = binary.LittleEndian
+	}
+	if !dtype.valid() {
+		return fmt.Errorf("dtype '%s' is not supported", dtype)
+	}
+	floats, err := t.FloatArray()
+	if err != nil {
+		return err
+	}
+	shape := make([]int, len(t.Shape()))
+	for i, d := range t.Shape() {
+		shape[i] = int(d)
+	}
+	if opts.FortranOrder {
+		floats = reorder(floats, shape, false)
+	}
+	if _, err := w.Write(formatHeader(&Header{
+		Dtype:        dtype,
+		FortranOrder: opts.FortranOrder,
+		Shape:        shape,
+	})); err != nil {
+		return err
+	}
+	_, err = w.Write(encode(floats, dtype))
+	return err
+}
+
+// SaveNpyToFile saves the tensor to the path with ".npy" format.
+func SaveNpyToFile(path string, t menoh.Tensor) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("cannot create '%s', %v", path, err)
+	}
+	if err := WriteNpy(f, t); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+func formatHeader(h *Header) []byte {
+	shape := make([]string, len(h.Shape))
+	for i, d := range h.Shape {
+		shape[i] = strconv.Itoa(d)
+	}
+	shapeStr := strings.Join(shape, ", ")
+	if len(shape) == 1 {
+		shapeStr += ","
+	}
+	order := "False"
+	if h.FortranOrder {
+		order = "True"
+	}
+	dict := fmt.Sprintf("{'descr': '%s', 'fortran_order': %s, 'shape': (%s), }",
+		h.Dtype, order, shapeStr)
+
+	// total header length is aligned to 64 bytes, terminated by '\n'
+	major, lenSize := byte(1), 2
+	padded := len(magic) + 2 + lenSize + len(dict) + 1
+	if padded+(64-padded%64)%64 > math.MaxUint16 {
+		major, lenSize = 2, 4
+		padded = len(magic) + 2 + lenSize + len(dict) + 1
+	}
+	dict += strings.Repeat(" ", (64-padded%64)%64) + "\n"
+
+	buf := bytes.NewBuffer(nil)
+	buf.Write(magic)
+	buf.Write([]byte{major, 0})
+	if major == 1 {
+		binary.Write(buf, binary.LittleEndian, uint16(len(dict)))
+	} else {
+		binary.Write(buf, binary.LittleEndian, uint32(len(dict)))
+	}
+	buf.WriteString(dict)
+	return buf.Bytes()
+}
+
+func decode(raw []byte, dtype Dtype, size int) []float32 {
+	floats := make([]float32, size)
+	order := dtype.ByteOrder
+	for i := range floats {
+		b := raw[i*dtype.Size : (i+1)*dtype.Size]
+		switch dtype.Kind {
+		case 'b':
+			if b[0] != 0 {
+				floats[i] = 1
+			}
+		case 'i':
+			switch dtype.Size {
+			case 1:
+				floats[i] = float32(int8(b[0]))
+			case 2:
+				floats[i] = float32(int16(order.Uint16(b)))
+			case 4:
+				floats[i] = float32(int32(order.Uint32(b)))
+			case 8:
+				floats[i] = float32(int64(order.Uint64(b)))
+			}
+		case 'u':
+			switch dtype.Size {
+			case 1:
+				floats[i] = float32(b[0])
+			case 2:
+				floats[i] = float32(order.Uint16(b))
+			case 4:
+				floats[i] = float32(order.Uint32(b))
+			case 8:
+				floats[i] = float32(order.Uint64(b))
+			}
+		case 'f':
+			switch dtype.Size {
+			case 2:
+				floats[i] = float16ToFloat32(order.Uint16(b))
+			case 4:
+				floats[i] = math.Float32frombits(order.Uint32(b))
+			case 8:
+				floats[i] = float32(math.Float64frombits(order.Uint64(b)))
+			}
+		}
+	}
+	return floats
+}
+
+func encode(floats []float32, dtype Dtype) []byte {
+	raw := make([]byte, len(floats)*dtype.Size)
+	order := dtype.ByteOrder
+	for i, f := range floats {
+		b := raw[i*dtype.Size : (i+1)*dtype.Size]
+		switch dtype.Kind {
+		case 'b':
+			if f != 0 {
+				b[0] = 1
+			}
+		case 'i':
+			switch dtype.Size {
+			case 1:
+				b[0] = byte(int8(f))
+			case 2:
+				order.PutUint16(b, uint16(int16(f)))
+			case 4:
+				order.PutUint32(b, uint32(int32(f)))
+			case 8:
+				order.PutUint64(b, uint64(int64(f)))
+			}
+		case 'u':
+			switch dtype.Size {
+			case 1:
+				b[0] = uint8(f)
+			case 2:
+				order.PutUint16(b, uint16(f))
+			case 4:
+				order.PutUint32(b, uint32(f))
+			case 8:
+				order.PutUint64(b, uint64(f))
+			}
+		case 'f':
+			switch dtype.Size {
+			case 2:
+				order.PutUint16(b, float32ToFloat16(f))
+			case 4:
+				order.PutUint32(b, math.Float32bits(f))
+			case 8:
+				order.PutUint64(b, math.Float64bits(float64(f)))
+			}
+		}
+	}
+	return raw
+}
+
+// reorder converts array order between C (row-major) and Fortran
+// (column-major). If fromFortran is true, src is Fortran ordered and returned
+// array is C ordered, and vice versa.
+func reorder(src []float32, shape []int, fromFortran bool) []float32 {
+	if len(shape) < 2 {
+		return src
+	}
+	cStrides := make([]int, len(shape))
+	fStrides := make([]int, len(shape))
+	c, f := 1, 1
+	for i := range shape {
+		cStrides[len(shape)-1-i] = c
+		c *= shape[len(shape)-1-i]
+		fStrides[i] = f
+		f *= shape[i]
+	}
+	dst := make([]float32, len(src))
+	index := make([]int, len(shape))
+	for ci := range src {
+		fi := 0
+		for k, idx := range index {
+			fi += idx * fStrides[k]
+		}
+		if fromFortran {
+			dst[ci] = src[fi]
+		} else {
+			dst[fi] = src[ci]
+		}
+		// increment multi-dimensional index in C order
+		for k := len(index) - 1; k >= 0; k-- {
+			index[k]++
+			if index[k] < shape[k] {
+				break
+			}
+			index[k] = 0
+		}
+	}
+	return dst
+}
+
+func float16ToFloat32(h uint16) float32 {
+	sign := uint32(h>>15) << 31
+	exp := uint32(h>>10) & 0x1f
+	frac := uint32(h) & 0x3ff
+	switch {
+	case exp == 0x1f: // Inf or NaN
+		return math.Float32frombits(sign | 0xff<<23 | frac<<13)
+	case exp == 0 && frac == 0: // zero
+		return math.Float32frombits(sign)
+	case exp == 0: // subnormal
+		for frac&0x400 == 0 {
+			frac <<= 1
+			exp--
+		}
+		exp++
+		frac &= 0x3ff
+	}
+	return math.Float32frombits(sign | (exp+127-15)<<23 | frac<<13)
+}
+
+func float32ToFloat16(f float32) uint16 {
+	bits := math.Float32bits(f)
+	sign := uint16(bits>>16) & 0x8000
+	exp := int32(bits>>23) & 0xff
+	frac := bits & 0x7fffff
+	switch {
+	case exp == 0xff: // Inf or NaN
+		if frac != 0 {
+			return sign | 0x7e00
+		}
+		return sign | 0x7c00
+	case exp-127+15 >= 0x1f: // overflow
+		return sign | 0x7c00
+	case exp-127+15 <= 0: // subnormal or underflow
+		shift := uint32(14 - (exp - 127 + 15))
+		if shift > 24 {
+			return sign
+		}
+		frac |= 0x800000
+		return sign | uint16((frac+(1<<(shift-1)))>>shift)
+	}
+	// round to nearest
+	h := uint32(sign) | uint32(exp-127+15)<<10 | frac>>13
+	if frac&0x1000 != 0 {
+		h++
+	}
+	return uint16(h)
+}
@@ -0,0 +1,231 @@
+package npy
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/pfnet-research/go-menoh"
+)
+
+func makeNpy(dict string, data []byte) []byte {
+	buf := bytes.NewBuffer(nil)
+	buf.Write(magic)
+	buf.Write([]byte{1, 0})
+	binary.Write(buf, binary.LittleEndian, uint16(len(dict)+1))
+	buf.WriteString(dict + "\n")
+	buf.Write(data)
+	return buf.Bytes()
+}
+
+func TestParseDtype(t *testing.T) {
+	testSet := []struct {
+		descr    string
+		expected Dtype
+	}{
+		{"<f4", Float32},
+		{"|u1", Uint8},
+		{"|b1", Bool},
+		{">i8", Dtype{Kind: 'i', Size: 8, ByteOrder: binary.BigEndian}},
+		{"<f2", Float16},
+	}
+	for _, ts := range testSet {
+		actual, err := ParseDtype(ts.descr)
+		if err != nil {
+			t.Errorf("'%s' should be parsed, %v", ts.descr, err)
+			continue
+		}
+		if actual != ts.expected {
+			t.Errorf("'%s' should be parsed to %v, but %v", ts.descr, ts.expected, actual)
+		}
+		if actual.String() != ts.descr {
+			t.Errorf("dtype should be formatted to '%s', but '%s'", ts.descr, actual)
+		}
+	}
+
+	for _, descr := range []string{"", "<c8", "<f1", "<U4", "*f4"} {
+		if _, err := ParseDtype(descr); err == nil {
+			t.Errorf("'%s' should not be parsed", descr)
+		}
+	}
+}
+
+func TestReadNpy(t *testing.T) {
+	t.Run("float32 C order", func(t *testing.T) {
+		data := make([]byte, 6*4)
+		for i := 0; i < 6; i++ {
+			binary.LittleEndian.PutUint32(data[i*4:], math.Float32bits(float32(i)))
+		}
+		npy := makeNpy("{'descr': '<f4', 'fortran_order': False, 'shape': (2, 3), }", data)
+		actual, err := ReadNpy(bytes.NewReader(npy))
+		if err != nil {
+			t.Fatalf("npy should be read, %v", err)
+		}
+		expected := &menoh.FloatTensor{
+			Dims:  []int32{2, 3},
+			Array: []float32{0, 1, 2, 3, 4, 5},
+		}
+		checkTensor(t, actual, expected)
+	})
+
+	t.Run("big endian int16 Fortran order", func(t *testing.T) {
+		// [[0, 1, 2], [3, 4, -5]] stored with column-major
+		data := make([]byte, 6*2)
+		for i, v := range []int16{0, 3, 1, 4, 2, -5} {
+			binary.BigEndian.PutUint16(data[i*2:], uint16(v))
+		}
+		npy := makeNpy("{'descr': '>i2', 'fortran_order': True, 'shape': (2, 3), }", data)
+		actual, err := ReadNpy(bytes.NewReader(npy))
+		if err != nil {
+			t.Fatalf("npy should be read, %v", err)
+		}
+		expected := &menoh.FloatTensor{
+			Dims:  []int32{2, 3},
+			Array: []float32{0, 1, 2, 3, 4, -5},
+		}
+		checkTensor(t, actual, expected)
+	})
+
+	t.Run("float16 1-d", func(t *testing.T) {
+		data := make([]byte, 3*2)
+		for i, v := range []uint16{0x3c00, 0xc000, 0x3555} {
+			binary.LittleEndian.PutUint16(data[i*2:], v)
+		}
+		npy := makeNpy("{'descr': '<f2', 'fortran_order': False, 'shape': (3,), }", data)
+		actual, err := ReadNpy(bytes.NewReader(npy))
+		if err != nil {
+			t.Fatalf("npy should be read, %v", err)
+		}
+		expected := &menoh.FloatTensor{
+			Dims:  []int32{3},
+			Array: []float32{1, -2, 0.33325195},
+		}
+		checkTensor(t, actual, expected)
+	})
+
+	// fail
+	testSet := []struct {
+		name string
+		data []byte
+	}{
+		{"empty", []byte{}},
+		{"invalid magic", []byte("\x93NUMPX\x01\x00\x00\x00")},
+		{"unsupported dtype", makeNpy("{'descr': '<c8', 'fortran_order': False, 'shape': (1,), }", make([]byte, 8))},
+		{"lack of key", makeNpy("{'descr': '<f4', 'shape': (1,), }", make([]byte, 4))},
+		{"short data", makeNpy("{'descr': '<f4', 'fortran_order': False, 'shape': (2,), }", make([]byte, 4))},
+	}
+	for _, ts := range testSet {
+		t.Run(ts.name, func(t *testing.T) {
+			actual, err := ReadNpy(bytes.NewReader(ts.data))
+			if err == nil {
+				t.Error("an error should be occurred")
+			}
+			if actual != nil {
+				t.Errorf("tensor should be nil, but %v", actual)
+			}
+		})
+	}
+}
+
+func TestWriteNpy(t *testing.T) {
+	input := &menoh.FloatTensor{
+		Dims:  []int32{2, 3},
+		Array: []float32{0, 1, 2, 3, 4, -5},
+	}
+	testSet := []WriteOptions{
+		{},
+		{Dtype: Float64, FortranOrder: true},
+		{Dtype: Dtype{Kind: 'i', Size: 4, ByteOrder: binary.BigEndian}},
+		{Dtype: Float16, FortranOrder: true},
+	}
+	for _, opts := range testSet {
+		t.Run(opts.Dtype.String(), func(t *testing.T) {
+			buf := bytes.NewBuffer(nil)
+			if err := WriteNpyWithOptions(buf, input, opts); err != nil {
+				t.Fatalf("npy should be written, %v", err)
+			}
+			headerLen := int(binary.LittleEndian.Uint16(buf.Bytes()[8:10]))
+			if (10+headerLen)%64 != 0 {
+				t.Errorf("header should be aligned to 64 bytes, but %d", 10+headerLen)
+			}
+			actual, err := ReadNpy(buf)
+			if err != nil {
+				t.Fatalf("written npy should be read, %v", err)
+			}
+			checkTensor(t, actual, input)
+		})
+	}
+
+	t.Run("invalid dtype", func(t *testing.T) {
+		opts := WriteOptions{Dtype: Dtype{Kind: 'c', Size: 8}}
+		if err := WriteNpyWithOptions(ioutil.Discard, input, opts); err == nil {
+			t.Error("an error should be occurred")
+		}
+	})
+}
+
+func TestSaveAndLoadNpyFile(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "go-menoh-test-")
+	if err != nil {
+		t.Fatal("cannot make temporary directory")
+	}
+	defer os.RemoveAll(tempDir)
+
+	expected := &menoh.FloatTensor{
+		Dims:  []int32{1, 2, 2},
+		Array: []float32{0.1, 0.2, 0.3, 0.4},
+	}
+	path := filepath.Join(tempDir, "test.npy")
+	if err := SaveNpyToFile(path, expected); err != nil {
+		t.Fatalf("npy file should be saved, %v", err)
+	}
+	actual, err := LoadNpyFromFile(path)
+	if err != nil {
+		t.Fatalf("npy file should be loaded, %v", err)
+	}
+	checkTensor(t, actual, expected)
+
+	if _, err := LoadNpyFromFile(filepath.Join(tempDir, "dummy.npy")); err == nil {
+		t.Error("loading not existed file should be failed")
+	}
+}
+
+func TestFloat16(t *testing.T) {
+	for _, f := range []float32{0, 1, -2, 0.5, 65504, 6.1035156e-05, 5.9604645e-08} {
+		if actual := float16ToFloat32(float32ToFloat16(f)); actual != f {
+			t.Errorf("%v should be converted to half and back, but %v", f, actual)
+		}
+	}
+	if h := float32ToFloat16(float32(math.Inf(1))); h != 0x7c00 {
+		t.Errorf("+Inf should be 0x7c00, but %#x", h)
+	}
+	if f := float16ToFloat32(0x7e00); !math.IsNaN(float64(f)) {
+		t.Errorf("0x7e00 should be NaN, but %v", f)
+	}
+}
+
+func checkTensor(t *testing.T, actual, expected menoh.Tensor) {
+	t.Helper()
+	if len(actual.Shape()) != len(expected.Shape()) {
+		t.Fatalf("shape should be %v, but %v", expected.Shape(), actual.Shape())
+	}
+	for i, d := range expected.Shape() {
+		if actual.Shape()[i] != d {
+			t.Fatalf("shape should be %v, but %v", expected.Shape(), actual.Shape())
+		}
+	}
+	af, _ := actual.FloatArray()
+	ef, _ := expected.FloatArray()
+	if len(af) != len(ef) {
+		t.Fatalf("array should be %v, but %v", ef, af)
+	}
+	for i, f := range ef {
+		if math.Abs(float64(af[i]-f)) > 1e-3*math.Max(1, math.Abs(float64(f))) {
+			t.Fatalf("array should be %v, but %v", ef, af)
+		}
+	}
+}
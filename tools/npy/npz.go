@@ -0,0 +1,152 @@
+package npy
+
+import (
+	"archive/zip"
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/pfnet-research/go-menoh"
+)
+
+// ReadNpz reads ".npz" archive from r and returns tensors keyed by array
+// name. The returned map can be passed to Runner.Run directly when the names
+// are same as input variable names.
+func ReadNpz(r io.ReaderAt, size int64) (map[string]menoh.Tensor, error) {
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read npz archive, %v", err)
+	}
+	tensors := map[string]menoh.Tensor{}
+	for _, f := range zr.File {
+		if !strings.HasSuffix(f.Name, ".npy") {
+			continue
+		}
+		name := strings.TrimSuffix(f.Name, ".npy")
+		t, err := readNpzEntry(f)
+		if err != nil {
+			return nil, fmt.Errorf("cannot read '%s' in npz archive, %v", name, err)
+		}
+		tensors[name] = t
+	}
+	return tensors, nil
+}
+
+func readNpzEntry(f *zip.File) (menoh.Tensor, error) {
+	rc, err := f.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+	return ReadNpy(bufio.NewReader(rc))
+}
+
+// LoadNpzFromFile returns tensors loaded from ".npz" file placed on the path.
+func LoadNpzFromFile(path string) (map[string]menoh.Tensor, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("cannot load '%s', %v", path, err)
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return nil, fmt.Errorf("cannot load '%s', %v", path, err)
+	}
+	return ReadNpz(f, info.Size())
+}
+
+// WriteNpz writes tensors to w as ".npz" archive, each tensor is stored
+// with ".npy" format named by the key. If compress is true, arrays are
+// compressed like numpy.savez_compressed.
+func WriteNpz(w io.Writer, tensors map[string]menoh.Tensor, compress bool) error {
+	names := make([]string, 0, len(tensors))
+	for name := range tensors {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	method := zip.Store
+	if compress {
+		method = zip.Deflate
+	}
+	zw := zip.NewWriter(w)
+	for _, name := range names {
+		fw, err := zw.CreateHeader(&zip.FileHeader{
+			Name:   name + ".npy",
+			Method: method,
+		})
+		if err != nil {
+			return fmt.Errorf("cannot add '%s' to npz archive, %v", name, err)
+		}
+		if err := WriteNpy(fw, tensors[name]); err != nil {
+			return fmt.Errorf("cannot write '%s' to npz archive, %v", name, err)
+		}
+	}
+	return zw.Close()
+}
+
+// SaveNpzToFile saves tensors to the path as ".npz" archive.
+func SaveNpzToFile(path string, tensors map[string]menoh.Tensor, compress bool) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("cannot create '%s', %v", path, err)
+	}
+	if err := WriteNpz(f, tensors, compress); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// SplitBatch splits tensors along the first axis into mini-batches which
+// have batchSize samples. All tensors must have same size on the first axis
+// and the size must be divisible by batchSize. Each returned map has same
+// keys as tensors, suitable for input of Runner.Run configured with the
+// batch size.
+func SplitBatch(tensors map[string]menoh.Tensor, batchSize int) ([]map[string]menoh.Tensor, error) {
+	if batchSize <= 0 {
+		return nil, fmt.Errorf("batch size must be positive, but %d", batchSize)
+	}
+	total := -1
+	for name, t := range tensors {
+		shape := t.Shape()
+		if len(shape) == 0 {
+			return nil, fmt.Errorf("'%s' is scalar, cannot split", name)
+		}
+		if total >= 0 && int(shape[0]) != total {
+			return nil, fmt.Errorf("size of first axis of '%s' is %d, others are %d",
+				name, shape[0], total)
+		}
+		total = int(shape[0])
+	}
+	if total <= 0 {
+		return []map[string]menoh.Tensor{}, nil
+	}
+	if total%batchSize != 0 {
+		return nil, fmt.Errorf("size of first axis %d is not divisible by batch size %d",
+			total, batchSize)
+	}
+
+	batches := make([]map[string]menoh.Tensor, total/batchSize)
+	for i := range batches {
+		batches[i] = map[string]menoh.Tensor{}
+	}
+	for name, t := range tensors {
+		floats, err := t.FloatArray()
+		if err != nil {
+			return nil, fmt.Errorf("cannot get float array of '%s', %v", name, err)
+		}
+		dims := append([]int32{int32(batchSize)}, t.Shape()[1:]...)
+		chunk := len(floats) / total * batchSize
+		for i := range batches {
+			batches[i][name] = &menoh.FloatTensor{
+				Dims:  dims,
+				Array: floats[i*chunk : (i+1)*chunk],
+			}
+		}
+	}
+	return batches, nil
+}
@@ -0,0 +1,121 @@
+package npy
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/pfnet-research/go-menoh"
+)
+
+func TestWriteAndReadNpz(t *testing.T) {
+	expected := map[string]menoh.Tensor{
+		"input": &menoh.FloatTensor{
+			Dims:  []int32{2, 3},
+			Array: []float32{0, 1, 2, 3, 4, 5},
+		},
+		"label": &menoh.FloatTensor{
+			Dims:  []int32{2},
+			Array: []float32{1, 0},
+		},
+	}
+	for _, compress := range []bool{false, true} {
+		buf := bytes.NewBuffer(nil)
+		if err := WriteNpz(buf, expected, compress); err != nil {
+			t.Fatalf("npz should be written, %v", err)
+		}
+		actual, err := ReadNpz(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+		if err != nil {
+			t.Fatalf("written npz should be read, %v", err)
+		}
+		if len(actual) != len(expected) {
+			t.Fatalf("npz should have %d arrays, but %d", len(expected), len(actual))
+		}
+		for name, e := range expected {
+			a, ok := actual[name]
+			if !ok {
+				t.Fatalf("npz should have '%s'", name)
+			}
+			checkTensor(t, a, e)
+		}
+	}
+
+	t.Run("invalid archive", func(t *testing.T) {
+		data := []byte("dummy data")
+		if _, err := ReadNpz(bytes.NewReader(data), int64(len(data))); err == nil {
+			t.Error("an error should be occurred")
+		}
+	})
+}
+
+func TestSaveAndLoadNpzFile(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "go-menoh-test-")
+	if err != nil {
+		t.Fatal("cannot make temporary directory")
+	}
+	defer os.RemoveAll(tempDir)
+
+	expected := map[string]menoh.Tensor{
+		"x": &menoh.FloatTensor{
+			Dims:  []int32{1, 2},
+			Array: []float32{0.5, -0.5},
+		},
+	}
+	path := filepath.Join(tempDir, "test.npz")
+	if err := SaveNpzToFile(path, expected, true); err != nil {
+		t.Fatalf("npz file should be saved, %v", err)
+	}
+	actual, err := LoadNpzFromFile(path)
+	if err != nil {
+		t.Fatalf("npz file should be loaded, %v", err)
+	}
+	checkTensor(t, actual["x"], expected["x"])
+}
+
+func TestSplitBatch(t *testing.T) {
+	tensors := map[string]menoh.Tensor{
+		"input": &menoh.FloatTensor{
+			Dims:  []int32{4, 2},
+			Array: []float32{0, 1, 2, 3, 4, 5, 6, 7},
+		},
+		"mask": &menoh.FloatTensor{
+			Dims:  []int32{4},
+			Array: []float32{1, 0, 1, 0},
+		},
+	}
+	t.Run("split by 2", func(t *testing.T) {
+		batches, err := SplitBatch(tensors, 2)
+		if err != nil {
+			t.Fatalf("tensors should be split, %v", err)
+		}
+		if len(batches) != 2 {
+			t.Fatalf("tensors should be split to 2 batches, but %d", len(batches))
+		}
+		checkTensor(t, batches[1]["input"], &menoh.FloatTensor{
+			Dims:  []int32{2, 2},
+			Array: []float32{4, 5, 6, 7},
+		})
+		checkTensor(t, batches[1]["mask"], &menoh.FloatTensor{
+			Dims:  []int32{2},
+			Array: []float32{1, 0},
+		})
+	})
+
+	// fail
+	t.Run("not divisible", func(t *testing.T) {
+		if _, err := SplitBatch(tensors, 3); err == nil {
+			t.Error("an error should be occurred")
+		}
+	})
+	t.Run("different batch size", func(t *testing.T) {
+		invalid := map[string]menoh.Tensor{
+			"a": &menoh.FloatTensor{Dims: []int32{2}, Array: []float32{0, 1}},
+			"b": &menoh.FloatTensor{Dims: []int32{3}, Array: []float32{0, 1, 2}},
+		}
+		if _, err := SplitBatch(invalid, 1); err == nil {
+			t.Error("an error should be occurred")
+		}
+	})
+}
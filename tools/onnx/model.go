@@ -0,0 +1,52 @@
+package onnx
+
+import (
+	"fmt"
+	"io/ioutil"
+
+	"github.com/golang/protobuf/proto"
+)
+
+// LoadONNXModelFromFile returns ONNX's Model instance loaded from the path.
+func LoadONNXModelFromFile(path string) (*ModelProto, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("cannot load '%s', %v", path, err)
+	}
+	model := &ModelProto{}
+	if err := proto.Unmarshal(b, model); err != nil {
+		return nil, fmt.Errorf("cannot convert to ONNX model, %v", err)
+	}
+	return model, nil
+}
+
+// GraphInputs returns input variables of the graph to be fed on running,
+// initializers (parameters) listed in graph inputs are excluded.
+func GraphInputs(g *GraphProto) []*ValueInfoProto {
+	initializers := map[string]bool{}
+	for _, t := range g.GetInitializer() {
+		initializers[t.GetName()] = true
+	}
+	inputs := []*ValueInfoProto{}
+	for _, v := range g.GetInput() {
+		if !initializers[v.GetName()] {
+			inputs = append(inputs, v)
+		}
+	}
+	return inputs
+}
+
+// ValueInfoDims returns dimension sizes of the variable. A dimension whose
+// size is not fixed, like symbolic batch size, is set -1.
+func ValueInfoDims(v *ValueInfoProto) []int32 {
+	dim := v.GetType().GetTensorType().GetShape().GetDim()
+	dims := make([]int32, len(dim))
+	for i, d := range dim {
+		if _, ok := d.GetValue().(*TensorShapeProto_Dimension_DimValue); ok {
+			dims[i] = int32(d.GetDimValue())
+		} else {
+			dims[i] = -1
+		}
+	}
+	return dims
+}
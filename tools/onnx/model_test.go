@@ -0,0 +1,90 @@
+package onnx
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/golang/protobuf/proto"
+)
+
+func makeTestModel() *ModelProto {
+	floatType := TensorProto_FLOAT
+	return &ModelProto{
+		Graph: &GraphProto{
+			Initializer: []*TensorProto{
+				{Name: proto.String("W"), DataType: &floatType, Dims: []int64{3, 2}},
+			},
+			Input: []*ValueInfoProto{
+				makeValueInfo("x", &TensorShapeProto_Dimension{
+					Value: &TensorShapeProto_Dimension_DimParam{DimParam: "N"},
+				}, &TensorShapeProto_Dimension{
+					Value: &TensorShapeProto_Dimension_DimValue{DimValue: 3},
+				}),
+				makeValueInfo("W"),
+			},
+			Output: []*ValueInfoProto{makeValueInfo("y")},
+		},
+	}
+}
+
+func makeValueInfo(name string, dims ...*TensorShapeProto_Dimension) *ValueInfoProto {
+	return &ValueInfoProto{
+		Name: proto.String(name),
+		Type: &TypeProto{
+			Value: &TypeProto_TensorType{
+				TensorType: &TypeProto_Tensor{
+					Shape: &TensorShapeProto{Dim: dims},
+				},
+			},
+		},
+	}
+}
+
+func TestLoadONNXModelFromFile(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "go-menoh-test-")
+	if err != nil {
+		t.Fatal("cannot make temporary directory")
+	}
+	defer os.RemoveAll(tempDir)
+
+	b, err := proto.Marshal(makeTestModel())
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(tempDir, "model.onnx")
+	if err := ioutil.WriteFile(path, b, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("valid path", func(t *testing.T) {
+		model, err := LoadONNXModelFromFile(path)
+		if err != nil {
+			t.Fatalf("model should be loaded, %v", err)
+		}
+		if n := len(model.GetGraph().GetInput()); n != 2 {
+			t.Errorf("loaded model should have 2 inputs, but %d", n)
+		}
+	})
+	t.Run("invalid path", func(t *testing.T) {
+		model, err := LoadONNXModelFromFile(filepath.Join(tempDir, "dummy.onnx"))
+		if err == nil {
+			t.Error("loading model should be failed with not existed path")
+		}
+		if model != nil {
+			t.Errorf("loading model should be nil but get %v", model)
+		}
+	})
+}
+
+func TestGraphInputs(t *testing.T) {
+	inputs := GraphInputs(makeTestModel().GetGraph())
+	if len(inputs) != 1 || inputs[0].GetName() != "x" {
+		t.Fatalf("graph inputs should be only 'x', but %v", inputs)
+	}
+	dims := ValueInfoDims(inputs[0])
+	if len(dims) != 2 || dims[0] != -1 || dims[1] != 3 {
+		t.Errorf("dims should be [-1 3], but %v", dims)
+	}
+}
@@ -48,6 +48,38 @@ func ConvertToMenohTensor(t *TensorProto) (menoh.Tensor, error) {
 	}
 }
 
+// ConvertToONNXTensor converts from Menoh's tensor to ONNX's tensor named
+// the name.
+func ConvertToONNXTensor(name string, t menoh.Tensor) (*TensorProto, error) {
+	floats, err := t.FloatArray()
+	if err != nil {
+		return nil, err
+	}
+	dims := make([]int64, len(t.Shape()))
+	for i, d := range t.Shape() {
+		dims[i] = int64(d)
+	}
+	dtype := TensorProto_FLOAT
+	return &TensorProto{
+		Name:      &name,
+		DataType:  &dtype,
+		Dims:      dims,
+		FloatData: floats,
+	}, nil
+}
+
+// SaveONNXTensorToFile saves ONNX's Tensor to the path.
+func SaveONNXTensorToFile(path string, t *TensorProto) error {
+	b, err := proto.Marshal(t)
+	if err != nil {
+		return fmt.Errorf("cannot convert ONNX tensor to binary, %v", err)
+	}
+	if err := ioutil.WriteFile(path, b, 0644); err != nil {
+		return fmt.Errorf("cannot save '%s', %v", path, err)
+	}
+	return nil
+}
+
 func convertToFloat32Array(raw []byte) []float32 {
 	bitLength := 4
 	length := len(raw) / bitLength
@@ -10,6 +10,7 @@ import (
 	"testing"
 
 	"github.com/golang/protobuf/proto"
+	"github.com/pfnet-research/go-menoh"
 )
 
 func TestLoadONNXTensorFromFile(t *testing.T) {
@@ -194,3 +195,45 @@ func convertFloat32ArrayToRaw(floats []float32) []byte {
 	}
 	return raw
 }
+
+func TestConvertToONNXTensor(t *testing.T) {
+	input := &menoh.FloatTensor{
+		Dims:  []int32{1, 3},
+		Array: []float32{0.1, 0.2, 0.3},
+	}
+	actual, err := ConvertToONNXTensor("x", input)
+	if err != nil {
+		t.Fatalf("converting should success, but %v", err)
+	}
+	if actual.GetName() != "x" {
+		t.Errorf("converted tensor should be named 'x', but %v", actual.GetName())
+	}
+	if actual.GetDataType() != TensorProto_FLOAT {
+		t.Errorf("converted tensor should be float, but %v", actual.GetDataType())
+	}
+	if !checkInt64s(actual.GetDims(), []int64{1, 3}) {
+		t.Errorf("converted dims should be [1 3], but %v", actual.GetDims())
+	}
+	if !checkFloats(actual.GetFloatData(), input.Array) {
+		t.Errorf("converted array should be %v, but %v", input.Array, actual.GetFloatData())
+	}
+
+	t.Run("save and load", func(t *testing.T) {
+		tempDir, err := ioutil.TempDir("", "go-menoh-test-")
+		if err != nil {
+			t.Fatal("cannot make temporary directory")
+		}
+		defer os.RemoveAll(tempDir)
+		path := filepath.Join(tempDir, "x.pb")
+		if err := SaveONNXTensorToFile(path, actual); err != nil {
+			t.Fatalf("tensor should be saved, %v", err)
+		}
+		loaded, err := LoadONNXTensorFromFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !checkFloats(loaded.GetFloatData(), input.Array) {
+			t.Errorf("loaded array should be %v, but %v", input.Array, loaded.GetFloatData())
+		}
+	})
+}
@@ -0,0 +1,124 @@
+package menoh
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/pfnet-research/go-menoh/external"
+)
+
+// Version is a version of Menoh library.
+type Version struct {
+	Major, Minor, Patch int
+
+	// Estimated is true when the version is not taken from the library but
+	// estimated by probing features, the version is a lower bound.
+	Estimated bool
+}
+
+func (v Version) String() string {
+	s := fmt.Sprintf("%d.%d.%d", v.Major, v.Minor, v.Patch)
+	if v.Estimated {
+		s += " or later"
+	}
+	return s
+}
+
+// Less returns true when v is older than o.
+func (v Version) Less(o Version) bool {
+	if v.Major != o.Major {
+		return v.Major < o.Major
+	}
+	if v.Minor != o.Minor {
+		return v.Minor < o.Minor
+	}
+	return v.Patch < o.Patch
+}
+
+var (
+	libraryVersionOnce sync.Once
+	libraryVersion     Version
+	libraryVersionErr  error
+)
+
+// LibraryVersion returns the version of linked Menoh. The version is taken
+// from version macros of the header on building, or estimated by probing
+// backends when the macros are not available, like on Windows.
+func LibraryVersion() (Version, error) {
+	libraryVersionOnce.Do(func() {
+		if major, minor, patch, ok := external.HeaderVersion(); ok {
+			libraryVersion = Version{Major: major, Minor: minor, Patch: patch}
+			return
+		}
+		libraryVersion, libraryVersionErr = estimateVersion()
+		if libraryVersionErr != nil {
+			getLogger().Error("cannot detect Menoh version", "error", libraryVersionErr)
+		} else {
+			getLogger().Warn("Menoh version is estimated by probing backends", "version", libraryVersion)
+		}
+	})
+	return libraryVersion, libraryVersionErr
+}
+
+// estimateVersion returns the oldest version having supported backends.
+func estimateVersion() (Version, error) {
+	if probeBackend(TypeGeneric) == nil {
+		return Version{Major: 1, Minor: 1, Patch: 0, Estimated: true}, nil
+	}
+	if probeBackend(TypeMKLDNN) == nil {
+		return Version{Major: 1, Minor: 0, Patch: 0, Estimated: true}, nil
+	}
+	return Version{}, errors.New("cannot detect Menoh version, no backend is available")
+}
+
+// backendVersions are the first Menoh versions having the backends.
+var backendVersions = map[TypeBackend]Version{
+	TypeMKLDNN:                    {Major: 1, Minor: 0, Patch: 0},
+	TypeMKLDNNWithGenericFallback: {Major: 1, Minor: 1, Patch: 0},
+	TypeGeneric:                   {Major: 1, Minor: 1, Patch: 0},
+}
+
+// VersionError is an error that linked Menoh is too old for a feature.
+type VersionError struct {
+	Feature  string
+	Required Version
+	Linked   Version
+}
+
+func (e *VersionError) Error() string {
+	return fmt.Sprintf("%s requires Menoh %v or later, but %v is linked",
+		e.Feature, e.Required, e.Linked)
+}
+
+// RequireVersion returns VersionError when linked Menoh is older than the
+// required version. An estimated version is compared as its lower bound.
+func RequireVersion(feature string, required Version) error {
+	linked, err := LibraryVersion()
+	if err != nil {
+		return err
+	}
+	if linked.Less(required) {
+		return &VersionError{Feature: feature, Required: required, Linked: linked}
+	}
+	return nil
+}
+
+// checkLibraryVersion returns VersionError when linked Menoh is known to be
+// too old for the configuration. Not to probe on building every runner, the
+// check is skipped when the version is not taken from the library.
+func checkLibraryVersion(conf Config) error {
+	major, minor, patch, ok := external.HeaderVersion()
+	if !ok {
+		return nil
+	}
+	linked := Version{Major: major, Minor: minor, Patch: patch}
+	if required, ok := backendVersions[conf.Backend]; ok && linked.Less(required) {
+		return &VersionError{
+			Feature:  fmt.Sprintf("backend '%v'", conf.Backend),
+			Required: required,
+			Linked:   linked,
+		}
+	}
+	return nil
+}
@@ -0,0 +1,90 @@
+package menoh
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestVersion(t *testing.T) {
+	v := Version{Major: 1, Minor: 1, Patch: 1}
+	if s := v.String(); s != "1.1.1" {
+		t.Errorf("version should be 1.1.1, but %v", s)
+	}
+	if s := (Version{Major: 1, Estimated: true}).String(); s != "1.0.0 or later" {
+		t.Errorf("estimated version should be 1.0.0 or later, but %v", s)
+	}
+	testSet := []struct {
+		older, newer Version
+	}{
+		{Version{Major: 1}, Version{Major: 2}},
+		{Version{Major: 1, Minor: 9}, Version{Major: 2}},
+		{Version{Major: 1, Minor: 0, Patch: 9}, Version{Major: 1, Minor: 1}},
+		{Version{Major: 1, Minor: 1, Patch: 0}, Version{Major: 1, Minor: 1, Patch: 1}},
+	}
+	for _, ts := range testSet {
+		if !ts.older.Less(ts.newer) {
+			t.Errorf("%v should be older than %v", ts.older, ts.newer)
+		}
+		if ts.newer.Less(ts.older) {
+			t.Errorf("%v should not be older than %v", ts.newer, ts.older)
+		}
+	}
+	if v.Less(v) {
+		t.Error("same version should not be older")
+	}
+}
+
+func TestVersionError(t *testing.T) {
+	err := &VersionError{
+		Feature:  "backend 'generic'",
+		Required: Version{Major: 1, Minor: 1},
+		Linked:   Version{Major: 1, Estimated: true},
+	}
+	expected := "backend 'generic' requires Menoh 1.1.0 or later, but 1.0.0 or later is linked"
+	if err.Error() != expected {
+		t.Errorf("error message should be %v, but %v", expected, err)
+	}
+}
+
+func TestLibraryVersion(t *testing.T) {
+	v, err := LibraryVersion()
+	if err != nil {
+		t.Fatalf("version should be detected, %v", err)
+	}
+	if v.Less(Version{Major: 1}) {
+		t.Errorf("version should be 1.0.0 or later, but %v", v)
+	}
+	if err := RequireVersion("test", Version{Major: 1}); err != nil {
+		t.Errorf("1.0.0 should be satisfied, %v", err)
+	}
+	err = RequireVersion("test", Version{Major: 1 << 20})
+	if _, ok := err.(*VersionError); !ok {
+		t.Errorf("VersionError should be occurred with future version, but %v", err)
+	}
+	if err != nil && !strings.HasPrefix(err.Error(), "test requires") {
+		t.Errorf("error message should have the feature, but %v", err)
+	}
+}
+
+func TestCapabilities(t *testing.T) {
+	c, err := Capabilities()
+	if err != nil {
+		t.Fatalf("capabilities should be probed, %v", err)
+	}
+	if len(c.Dtypes) == 0 || c.Dtypes[0] != TypeFloat {
+		t.Errorf("float should be supported, but %v", c.Dtypes)
+	}
+	ops := c.Operators[TypeMKLDNN]
+	found := false
+	for _, op := range ops {
+		if op == "Relu" {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("MKL-DNN should support Relu, but %v", ops)
+	}
+	if err := ProbeOperator(TypeMKLDNN, "NotExistedOp"); err == nil {
+		t.Error("an error should be occurred with unknown operator")
+	}
+}